@@ -0,0 +1,35 @@
+package bitrise
+
+import (
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/bitrise-io/bitrise/utils"
+	envmanModels "github.com/bitrise-io/envman/models"
+)
+
+// ChangedFilesOfBuild returns the files changed by the build's commit (BITRISE_GIT_COMMIT, or HEAD),
+// compared to the pull request's target branch, or to the previous commit.
+func ChangedFilesOfBuild(envList envmanModels.EnvsJSONListModel) ([]string, error) {
+	return ChangedFilesOfBuildWithBaseBranch(getEnv(configs.GitBranchDestEnvKey, envList), envList)
+}
+
+// ChangedFilesOfBuildWithBaseBranch returns the files changed by the build's commit,
+// compared to the given pull request target branch, or to the previous commit if it's empty.
+func ChangedFilesOfBuildWithBaseBranch(prTargetBranch string, envList envmanModels.EnvsJSONListModel) ([]string, error) {
+	baseRef := utils.ChangedFilesBaseRef(prTargetBranch)
+	return utils.GitChangedFiles(getEnv(configs.BitriseSourceDirEnvKey, envList), baseRef, getEnv(configs.GitCommitEnvKey, envList))
+}
+
+// ChangedFilesOfBuildSince returns the files changed by the build's commit, compared to the given git ref (e.g. "origin/main").
+func ChangedFilesOfBuildSince(baseRef string, envList envmanModels.EnvsJSONListModel) ([]string, error) {
+	return utils.GitChangedFiles(getEnv(configs.BitriseSourceDirEnvKey, envList), baseRef, getEnv(configs.GitCommitEnvKey, envList))
+}
+
+// HasRelevantChanges reports whether any of the changed files passes the filter.
+func HasRelevantChanges(filter models.ChangedFilesFilterModel, changedFiles []string) (bool, error) {
+	relevantFiles, err := utils.FilterPathsWithGlobs(changedFiles, filter.Include, filter.Exclude)
+	if err != nil {
+		return false, err
+	}
+	return len(relevantFiles) > 0, nil
+}
@@ -0,0 +1,550 @@
+package bitrise
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/bitrise-io/bitrise/models"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/fileutil"
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+const (
+	yamlMergeTag      = "!!merge"
+	yamlNullTag       = "!!null"
+	yamlStrTag        = "!!str"
+	defaultYAMLIndent = 2
+)
+
+// ConfigModelFromYAMLBytesWithoutDefaults returns the config as it is defined in the yaml, without filling the missing defaults,
+// so that an edited config can be saved without the defaults.
+func ConfigModelFromYAMLBytesWithoutDefaults(configBytes []byte) (bitriseData models.BitriseDataModel, warnings []string, err error) {
+	// the envs are not normalized either, normalizing adds empty options to them
+	if err = yaml.Unmarshal(configBytes, &bitriseData); err != nil {
+		return
+	}
+	warnings, err = bitriseData.Validate()
+	return
+}
+
+// ConfigYAMLPreservingFormat returns the config as yaml, in the format of the original yaml:
+// the comments, anchors & aliases, quoting styles and the key order of the original yaml are kept,
+// only the semantic changes of the config are applied.
+// If the config does not differ from the original yaml, the original yaml is returned as it is.
+func ConfigYAMLPreservingFormat(originalYAML []byte, config models.BitriseDataModel) ([]byte, error) {
+	updatedYAML, err := generateYAML(config)
+	if err != nil {
+		return []byte{}, err
+	}
+	return YAMLPreservingFormat(originalYAML, updatedYAML)
+}
+
+// SaveConfigToFilePreservingFormat saves the config to the given path, in the format of the original yaml.
+func SaveConfigToFilePreservingFormat(pth string, originalYAML []byte, config models.BitriseDataModel) error {
+	contBytes, err := ConfigYAMLPreservingFormat(originalYAML, config)
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteBytesToFile(pth, contBytes)
+}
+
+// YAMLPreservingFormat applies the semantic differences of the updated yaml to the original yaml,
+// and returns the result in the format of the original yaml: only the changed entries' lines are replaced,
+// the other lines (e.g. blank lines, indentation) are kept as they are.
+func YAMLPreservingFormat(originalYAML, updatedYAML []byte) ([]byte, error) {
+	var original yamlv3.Node
+	if err := yamlv3.Unmarshal(originalYAML, &original); err != nil {
+		return []byte{}, err
+	}
+	var updated yamlv3.Node
+	if err := yamlv3.Unmarshal(updatedYAML, &updated); err != nil {
+		return []byte{}, err
+	}
+	if original.Kind != yamlv3.DocumentNode || len(original.Content) == 0 {
+		// empty original yaml, nothing to preserve
+		return updatedYAML, nil
+	}
+
+	// the layouts are read before the merge modifies the original nodes
+	originalLayout, originalLayoutErr := newYAMLLayout(originalYAML, &original)
+	updatedLayout, updatedLayoutErr := newYAMLLayout(updatedYAML, &updated)
+
+	merger := newYAMLMerger()
+	merged := merger.merge(&original, &updated)
+	if !merger.isChanged {
+		return originalYAML, nil
+	}
+
+	if originalLayoutErr == nil && updatedLayoutErr == nil {
+		splicer := yamlSplicer{original: originalLayout, updated: updatedLayout, merger: merger}
+		if splicedYAML, err := splicer.splice(merged); err == nil && isYAMLEqual(splicedYAML, merged) {
+			return splicedYAML, nil
+		}
+	}
+
+	// the original layout is not supported (e.g. flow style root), the merged node tree is encoded
+	return encodeYAMLPreservingIndent(merged, detectYAMLIndent(originalYAML))
+}
+
+// isYAMLEqual reports whether the yaml represents the node tree.
+func isYAMLEqual(content []byte, node *yamlv3.Node) bool {
+	var contentNode yamlv3.Node
+	if err := yamlv3.Unmarshal(content, &contentNode); err != nil {
+		return false
+	}
+	return isYAMLNodeEqual(&contentNode, node)
+}
+
+func encodeYAMLPreservingIndent(merged *yamlv3.Node, indent int) ([]byte, error) {
+	clearYAMLMergeTags(merged)
+
+	var buf bytes.Buffer
+	encoder := yamlv3.NewEncoder(&buf)
+	encoder.SetIndent(indent)
+	if err := encoder.Encode(merged); err != nil {
+		return []byte{}, err
+	}
+	if err := encoder.Close(); err != nil {
+		return []byte{}, err
+	}
+	return buf.Bytes(), nil
+}
+
+// clearYAMLMergeTags lets the merge keys be resolved implicitly,
+// otherwise they are encoded with an explicit tag (!!merge <<: *anchor).
+func clearYAMLMergeTags(node *yamlv3.Node) {
+	if node.Kind == yamlv3.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Tag == yamlMergeTag {
+				node.Content[i].Tag = ""
+			}
+		}
+	}
+	for _, child := range node.Content {
+		clearYAMLMergeTags(child)
+	}
+}
+
+// detectYAMLIndent returns the indentation of the first indented line of the yaml.
+func detectYAMLIndent(content []byte) int {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if indent := len(line) - len(trimmed); indent > 0 {
+			if indent > 8 {
+				return defaultYAMLIndent
+			}
+			return indent
+		}
+	}
+	return defaultYAMLIndent
+}
+
+// yamlMerger applies the updated yaml node tree to the original one,
+// keeping the original nodes, and so their comments and styles, wherever the value does not change.
+type yamlMerger struct {
+	isChanged bool
+	// dirty are the original nodes which are modified
+	dirty map[*yamlv3.Node]bool
+	// counterparts are the updated nodes of the modified and replaced original nodes
+	counterparts map[*yamlv3.Node]*yamlv3.Node
+	renamedKeys  map[*yamlv3.Node]bool
+}
+
+func newYAMLMerger() *yamlMerger {
+	return &yamlMerger{
+		dirty:        map[*yamlv3.Node]bool{},
+		counterparts: map[*yamlv3.Node]*yamlv3.Node{},
+		renamedKeys:  map[*yamlv3.Node]bool{},
+	}
+}
+
+func (merger *yamlMerger) merge(original, updated *yamlv3.Node) *yamlv3.Node {
+	if isYAMLNodeEqual(original, updated) {
+		return original
+	}
+
+	// an empty value (key:) has no text to replace
+	if original.Kind == yamlv3.AliasNode || original.Kind != updated.Kind || isYAMLNodeEmpty(original) {
+		merger.isChanged = true
+
+		replacement := *updated
+		replacement.HeadComment = original.HeadComment
+		replacement.LineComment = original.LineComment
+		replacement.FootComment = original.FootComment
+		merger.counterparts[&replacement] = updated
+		return &replacement
+	}
+
+	merger.dirty[original] = true
+	merger.counterparts[original] = updated
+	switch original.Kind {
+	case yamlv3.DocumentNode:
+		if len(original.Content) > 0 && len(updated.Content) > 0 {
+			original.Content[0] = merger.merge(original.Content[0], updated.Content[0])
+		}
+	case yamlv3.ScalarNode:
+		merger.isChanged = true
+		original.Value = updated.Value
+		original.Tag = updated.Tag
+		if original.Style == 0 {
+			// the new value might need quoting, e.g. a string value of 'true'
+			original.Style = updated.Style
+		}
+	case yamlv3.SequenceNode:
+		merger.mergeSequence(original, updated)
+	case yamlv3.MappingNode:
+		merger.mergeMapping(original, updated)
+	}
+	return original
+}
+
+// mergeSequence merges the items of the same ID (e.g. the same step), so that inserting, removing
+// or moving an item keeps the other items' comments. Items without ID are merged by their position.
+func (merger *yamlMerger) mergeSequence(original, updated *yamlv3.Node) {
+	if len(original.Content) != len(updated.Content) {
+		merger.isChanged = true
+	}
+
+	originalIDs, isOriginalIdentified := yamlSequenceItemIDs(original)
+	updatedIDs, isUpdatedIdentified := yamlSequenceItemIDs(updated)
+	if !isOriginalIdentified || !isUpdatedIdentified {
+		content := make([]*yamlv3.Node, 0, len(updated.Content))
+		for i, item := range updated.Content {
+			if i < len(original.Content) {
+				content = append(content, merger.merge(original.Content[i], item))
+			} else {
+				content = append(content, item)
+			}
+		}
+		original.Content = content
+		return
+	}
+
+	matches := alignYAMLSequence(originalIDs, updatedIDs, func(originalIdx, updatedIdx int) bool {
+		return isYAMLNodeEqual(original.Content[originalIdx], updated.Content[updatedIdx])
+	})
+
+	content := make([]*yamlv3.Node, 0, len(updated.Content))
+	next := 0
+	for updatedIdx, item := range updated.Content {
+		idx := matches[updatedIdx]
+		if idx == -1 {
+			merger.isChanged = true
+			content = append(content, item)
+			continue
+		}
+
+		if idx != next {
+			merger.isChanged = true
+		}
+		next = idx + 1
+		content = append(content, merger.merge(original.Content[idx], item))
+	}
+	original.Content = content
+}
+
+// alignYAMLSequence returns the index of the matching original item for each updated item, or -1 for the new items.
+// The items of the same ID are matched in order (as a longest common subsequence), preferring the equal items,
+// so that of the items with the same ID (e.g. the same step twice) the edited one is matched by its position.
+// The remaining items of the same ID are matched as moved items.
+func alignYAMLSequence(originalIDs, updatedIDs []string, isEqual func(originalIdx, updatedIdx int) bool) []int {
+	// a match is worth more than all the equal items together
+	matchScore := len(updatedIDs) + 1
+
+	// scores[i][j] is the best score of aligning originalIDs[i:] and updatedIDs[j:]
+	scores := make([][]int, len(originalIDs)+1)
+	equals := make([][]bool, len(originalIDs)+1)
+	for i := range scores {
+		scores[i] = make([]int, len(updatedIDs)+1)
+		equals[i] = make([]bool, len(updatedIDs)+1)
+	}
+	for i := len(originalIDs) - 1; i >= 0; i-- {
+		for j := len(updatedIDs) - 1; j >= 0; j-- {
+			score := scores[i+1][j]
+			if scores[i][j+1] > score {
+				score = scores[i][j+1]
+			}
+			if originalIDs[i] == updatedIDs[j] {
+				equals[i][j] = isEqual(i, j)
+				matched := scores[i+1][j+1] + matchScore
+				if equals[i][j] {
+					matched++
+				}
+				if matched > score {
+					score = matched
+				}
+			}
+			scores[i][j] = score
+		}
+	}
+
+	matches := make([]int, len(updatedIDs))
+	isMatched := make([]bool, len(originalIDs))
+	for i, j := 0, 0; j < len(updatedIDs); {
+		switch {
+		case i < len(originalIDs) && originalIDs[i] == updatedIDs[j] && scores[i][j] == scores[i+1][j+1]+matchScore+boolToInt(equals[i][j]):
+			matches[j] = i
+			isMatched[i] = true
+			i++
+			j++
+		case i < len(originalIDs) && scores[i][j] == scores[i+1][j]:
+			i++
+		default:
+			matches[j] = -1
+			j++
+		}
+	}
+
+	// moved items
+	for j, idx := range matches {
+		if idx != -1 {
+			continue
+		}
+		for i := range originalIDs {
+			if isMatched[i] || originalIDs[i] != updatedIDs[j] {
+				continue
+			}
+			if idx == -1 || (!isEqual(idx, j) && isEqual(i, j)) {
+				idx = i
+			}
+		}
+		if idx != -1 {
+			matches[j] = idx
+			isMatched[idx] = true
+		}
+	}
+	return matches
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// yamlSequenceItemIDs returns the IDs of the sequence items, if all of them have one.
+func yamlSequenceItemIDs(node *yamlv3.Node) ([]string, bool) {
+	ids := make([]string, 0, len(node.Content))
+	for _, item := range node.Content {
+		id, ok := yamlSequenceItemID(item)
+		if !ok {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+// yamlSequenceItemID returns the ID of the config list items: the step, env, workflow and stage list items
+// are single key mappings (beside the env's opts), the other items are identified by their value.
+func yamlSequenceItemID(node *yamlv3.Node) (string, bool) {
+	node = resolveYAMLAlias(node)
+	switch node.Kind {
+	case yamlv3.ScalarNode:
+		return node.Value, true
+	case yamlv3.MappingNode:
+		id := ""
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if key == envmanModels.OptionsKey {
+				continue
+			}
+			if id != "" {
+				return "", false
+			}
+			id = key
+		}
+		return id, id != ""
+	}
+	return "", false
+}
+
+func (merger *yamlMerger) mergeMapping(original, updated *yamlv3.Node) {
+	updatedValues := map[string]*yamlv3.Node{}
+	for i := 0; i+1 < len(updated.Content); i += 2 {
+		updatedValues[updated.Content[i].Value] = updated.Content[i+1]
+	}
+
+	originalKeys := map[string]bool{}
+	for i := 0; i+1 < len(original.Content); i += 2 {
+		originalKeys[original.Content[i].Value] = true
+	}
+	// renamedKey returns the new key of a removed key, if a new key has the same value, e.g. a renamed workflow
+	renamedKey := func(value *yamlv3.Node, ownKeys map[string]bool) (string, bool) {
+		for i := 0; i+1 < len(updated.Content); i += 2 {
+			key := updated.Content[i].Value
+			if !originalKeys[key] && !ownKeys[key] && isYAMLNodeEqual(value, updated.Content[i+1]) {
+				return key, true
+			}
+		}
+		return "", false
+	}
+
+	content := make([]*yamlv3.Node, 0, len(original.Content))
+	ownKeys := map[string]bool{}
+	var mergeSources []*yamlv3.Node
+	for i := 0; i+1 < len(original.Content); i += 2 {
+		key, value := original.Content[i], original.Content[i+1]
+		if key.Tag == yamlMergeTag {
+			// the merged values are compared to the updated values below
+			content = append(content, key, value)
+			mergeSources = append(mergeSources, value)
+			continue
+		}
+
+		updatedValue, ok := updatedValues[key.Value]
+		if !ok {
+			merger.isChanged = true
+
+			newKey, isRenamed := renamedKey(value, ownKeys)
+			if !isRenamed {
+				continue
+			}
+			key.Value = newKey
+			merger.renamedKeys[key] = true
+			updatedValue = updatedValues[newKey]
+		}
+		ownKeys[key.Value] = true
+		content = append(content, key, merger.merge(value, updatedValue))
+	}
+
+	for i := 0; i+1 < len(updated.Content); i += 2 {
+		key, value := updated.Content[i], updated.Content[i+1]
+		if ownKeys[key.Value] {
+			continue
+		}
+		mergedValue := yamlMergedValue(mergeSources, key.Value)
+		if mergedValue != nil && isYAMLNodeEqual(mergedValue, value) {
+			continue
+		}
+		if mergedValue == nil && isYAMLNodeEmptyString(value) {
+			// a missing key is the same as an empty string for the config models, e.g. project_type: ""
+			continue
+		}
+
+		merger.isChanged = true
+		content = append(content, key, value)
+	}
+
+	original.Content = content
+}
+
+// yamlMergedValue returns the value of the key, inherited with a merge key (<<: *anchor).
+func yamlMergedValue(mergeSources []*yamlv3.Node, key string) *yamlv3.Node {
+	for _, source := range mergeSources {
+		source = resolveYAMLAlias(source)
+		sources := []*yamlv3.Node{source}
+		if source.Kind == yamlv3.SequenceNode {
+			sources = source.Content
+		}
+
+		for _, aSource := range sources {
+			if value, ok := yamlMappingPairs(resolveYAMLAlias(aSource))[key]; ok {
+				return value
+			}
+		}
+	}
+	return nil
+}
+
+func resolveYAMLAlias(node *yamlv3.Node) *yamlv3.Node {
+	for node.Kind == yamlv3.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// yamlMappingPairs returns the key-value pairs of the mapping, including the merged ones.
+func yamlMappingPairs(node *yamlv3.Node) map[string]*yamlv3.Node {
+	pairs := map[string]*yamlv3.Node{}
+	if node.Kind != yamlv3.MappingNode {
+		return pairs
+	}
+
+	var mergeSources []*yamlv3.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Tag == yamlMergeTag {
+			mergeSources = append(mergeSources, node.Content[i+1])
+			continue
+		}
+		pairs[node.Content[i].Value] = node.Content[i+1]
+	}
+
+	for _, source := range mergeSources {
+		source = resolveYAMLAlias(source)
+		sources := []*yamlv3.Node{source}
+		if source.Kind == yamlv3.SequenceNode {
+			sources = source.Content
+		}
+
+		for _, aSource := range sources {
+			for key, value := range yamlMappingPairs(resolveYAMLAlias(aSource)) {
+				if _, ok := pairs[key]; !ok {
+					pairs[key] = value
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// isYAMLNodeEqual reports whether the nodes represent the same value, regardless of their styles and comments.
+// Scalars are compared by their string value, as the config models read numbers and bools as strings too.
+func isYAMLNodeEqual(a, b *yamlv3.Node) bool {
+	a, b = resolveYAMLAlias(a), resolveYAMLAlias(b)
+	if a.Kind != b.Kind {
+		// e.g. an empty workflow (primary:) is marshalled as an empty mapping (primary: {})
+		return isYAMLNodeEmpty(a) && isYAMLNodeEmpty(b)
+	}
+
+	switch a.Kind {
+	case yamlv3.DocumentNode, yamlv3.SequenceNode:
+		if len(a.Content) != len(b.Content) {
+			return false
+		}
+		for i := range a.Content {
+			if !isYAMLNodeEqual(a.Content[i], b.Content[i]) {
+				return false
+			}
+		}
+		return true
+	case yamlv3.MappingNode:
+		aPairs, bPairs := yamlMappingPairs(a), yamlMappingPairs(b)
+		if len(aPairs) != len(bPairs) {
+			return false
+		}
+		for key, aValue := range aPairs {
+			bValue, ok := bPairs[key]
+			if !ok || !isYAMLNodeEqual(aValue, bValue) {
+				return false
+			}
+		}
+		return true
+	case yamlv3.ScalarNode:
+		return a.Value == b.Value || (a.Tag == yamlNullTag && b.Tag == yamlNullTag)
+	}
+	return false
+}
+
+func isYAMLNodeEmptyString(node *yamlv3.Node) bool {
+	return node.Kind == yamlv3.ScalarNode && node.Tag == yamlStrTag && node.Value == ""
+}
+
+func isYAMLNodeEmpty(node *yamlv3.Node) bool {
+	switch node.Kind {
+	case yamlv3.ScalarNode:
+		return node.Tag == yamlNullTag
+	case yamlv3.MappingNode, yamlv3.SequenceNode:
+		return len(node.Content) == 0
+	}
+	return false
+}
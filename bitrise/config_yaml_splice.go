@@ -0,0 +1,406 @@
+package bitrise
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+var errYAMLLayoutNotSupported = errors.New("yaml layout not supported")
+
+// yamlEntry is the line range of a block mapping entry (key: value) or a block sequence item (- item).
+type yamlEntry struct {
+	// start and end are the indexes of the first and the last line of the entry
+	start, end int
+	// col is the column of the key or the dash
+	col int
+}
+
+// yamlCollectionLayout is the layout of a block mapping or a block sequence.
+type yamlCollectionLayout struct {
+	// start is the index of the first line of the first entry
+	start int
+	col   int
+	// leading are the blank and comment lines before the entries (but the first one), by the entry's key or item node
+	leading map[*yamlv3.Node][]string
+	// tail are the blank and comment lines after the last entry, which belong to the collection
+	tail []string
+	// isBlankSeparated is true if every entry is preceded by a blank line, e.g. the workflows
+	isBlankSeparated bool
+}
+
+// yamlLayout is the line layout of the block collections of a yaml document.
+type yamlLayout struct {
+	lines []string
+	// entries are the line ranges of the entries, by their key, value and item nodes
+	entries     map[*yamlv3.Node]yamlEntry
+	collections map[*yamlv3.Node]yamlCollectionLayout
+}
+
+// newYAMLLayout reads the layout of the document's block collections,
+// it has to be called before the document's nodes are modified.
+func newYAMLLayout(content []byte, document *yamlv3.Node) (*yamlLayout, error) {
+	if document.Kind != yamlv3.DocumentNode || len(document.Content) == 0 {
+		return nil, errYAMLLayoutNotSupported
+	}
+
+	layout := &yamlLayout{
+		lines:       strings.Split(strings.TrimSuffix(string(content), "\n"), "\n"),
+		entries:     map[*yamlv3.Node]yamlEntry{},
+		collections: map[*yamlv3.Node]yamlCollectionLayout{},
+	}
+	if err := layout.read(document.Content[0], len(layout.lines)); err != nil {
+		return nil, err
+	}
+	return layout, nil
+}
+
+// read reads the layout of the collection, which ends before the limit line.
+func (layout *yamlLayout) read(node *yamlv3.Node, limit int) error {
+	if node.Style&yamlv3.FlowStyle != 0 || len(node.Content) == 0 {
+		return nil
+	}
+
+	type entryNodes struct {
+		key, value *yamlv3.Node
+		start, col int
+	}
+	var entries []entryNodes
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			entries = append(entries, entryNodes{key: key, value: node.Content[i+1], start: key.Line - 1, col: key.Column - 1})
+		}
+	case yamlv3.SequenceNode:
+		for _, item := range node.Content {
+			line, col, err := layout.dashPosition(item)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entryNodes{value: item, start: line, col: col})
+		}
+	default:
+		return nil
+	}
+
+	collection := yamlCollectionLayout{
+		start:            entries[0].start,
+		col:              entries[0].col,
+		leading:          map[*yamlv3.Node][]string{},
+		isBlankSeparated: len(entries) > 1,
+	}
+	prevEnd := -1
+	for i, entry := range entries {
+		end := limit - 1
+		if i+1 < len(entries) {
+			end = entries[i+1].start - 1
+		}
+		if entry.start <= prevEnd || entry.start > end || entry.col != collection.col {
+			return errYAMLLayoutNotSupported
+		}
+		end = layout.trimEntryEnd(entry.start, end, entry.col)
+
+		layout.entries[entry.value] = yamlEntry{start: entry.start, end: end, col: entry.col}
+		entryNode := entry.value
+		if entry.key != nil {
+			layout.entries[entry.key] = layout.entries[entry.value]
+			entryNode = entry.key
+		}
+
+		if i > 0 {
+			leading := layout.lines[prevEnd+1 : entry.start]
+			collection.leading[entryNode] = leading
+			if len(leading) == 0 || strings.TrimSpace(leading[0]) != "" {
+				collection.isBlankSeparated = false
+			}
+		}
+
+		if err := layout.read(entry.value, end+1); err != nil {
+			return err
+		}
+		prevEnd = end
+	}
+	collection.tail = layout.lines[prevEnd+1 : limit]
+
+	layout.collections[node] = collection
+	return nil
+}
+
+// trimEntryEnd drops the blank lines and the comments of the next entry (not indented more than the entry) from the entry's end.
+func (layout *yamlLayout) trimEntryEnd(start, end, col int) int {
+	for end > start {
+		line := layout.lines[end]
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed != "" && (!strings.HasPrefix(trimmed, "#") || len(line)-len(trimmed) > col) {
+			break
+		}
+		end--
+	}
+	return end
+}
+
+// dashPosition returns the line and the column of the sequence item's dash.
+func (layout *yamlLayout) dashPosition(item *yamlv3.Node) (int, int, error) {
+	line := item.Line - 1
+	if line < 0 || line >= len(layout.lines) {
+		return 0, 0, errYAMLLayoutNotSupported
+	}
+
+	// - item
+	runes := []rune(layout.lines[line])
+	for col := item.Column - 2; col >= 0 && col < len(runes); col-- {
+		if runes[col] == '-' && (col+1 == len(runes) || runes[col+1] == ' ') {
+			return line, col, nil
+		}
+	}
+
+	// -
+	//   item
+	for line--; line >= 0; line-- {
+		trimmed := strings.TrimSpace(layout.lines[line])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if trimmed == "-" || strings.HasPrefix(trimmed, "- ") {
+			return line, len(layout.lines[line]) - len(strings.TrimLeft(layout.lines[line], " ")), nil
+		}
+		break
+	}
+	return 0, 0, errYAMLLayoutNotSupported
+}
+
+// yamlSplicer renders the merged node tree in the layout of the original yaml:
+// the lines of the unchanged entries are kept as they are, changed scalars are replaced in place,
+// the new and the replaced entries are copied from the updated yaml.
+type yamlSplicer struct {
+	original, updated *yamlLayout
+	merger            *yamlMerger
+}
+
+func (splicer yamlSplicer) splice(merged *yamlv3.Node) ([]byte, error) {
+	root := merged.Content[0]
+	collection, ok := splicer.original.collections[root]
+	if !ok {
+		return nil, errYAMLLayoutNotSupported
+	}
+
+	body, err := splicer.renderCollection(root)
+	if err != nil {
+		return nil, err
+	}
+	lines := append(append([]string{}, splicer.original.lines[:collection.start]...), body...)
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+func (splicer yamlSplicer) renderCollection(node *yamlv3.Node) ([]string, error) {
+	collection, ok := splicer.original.collections[node]
+	if !ok || len(node.Content) == 0 {
+		return nil, errYAMLLayoutNotSupported
+	}
+
+	var lines []string
+	render := func(idx int, entryNode, key, value *yamlv3.Node) error {
+		if leading, ok := collection.leading[entryNode]; ok {
+			lines = append(lines, leading...)
+		} else if idx > 0 && collection.isBlankSeparated {
+			lines = append(lines, "")
+		}
+
+		entryLines, err := splicer.renderEntry(key, value, collection.col)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, entryLines...)
+		return nil
+	}
+
+	if node.Kind == yamlv3.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := render(i, node.Content[i], node.Content[i], node.Content[i+1]); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		for i, item := range node.Content {
+			if err := render(i, item, nil, item); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return append(lines, collection.tail...), nil
+}
+
+// renderEntry returns the lines of the mapping entry (or the sequence item if key is nil),
+// the part of the first line before the column is left blank.
+func (splicer yamlSplicer) renderEntry(key, value *yamlv3.Node, col int) ([]string, error) {
+	entry, ok := splicer.original.entries[value]
+	if !ok {
+		return splicer.updatedEntryLines(value, col)
+	}
+
+	lines := append([]string{}, splicer.original.lines[entry.start:entry.end+1]...)
+	lines[0] = blankPrefix(lines[0], col)
+	if key != nil && splicer.merger.renamedKeys[key] {
+		if err := replaceYAMLScalar(lines, key.Line-1-entry.start, key); err != nil {
+			return splicer.updatedEntryLines(value, col)
+		}
+	}
+	if !splicer.merger.dirty[value] {
+		return lines, nil
+	}
+
+	switch value.Kind {
+	case yamlv3.ScalarNode:
+		if err := replaceYAMLScalarValue(lines, value.Line-1-entry.start, value); err == nil {
+			return lines, nil
+		}
+	case yamlv3.MappingNode, yamlv3.SequenceNode:
+		if collection, ok := splicer.original.collections[value]; ok && len(value.Content) > 0 {
+			body, err := splicer.renderCollection(value)
+			if err != nil {
+				return nil, err
+			}
+
+			if collection.start > entry.start {
+				return append(lines[:collection.start-entry.start], body...), nil
+			}
+
+			// the collection starts on the dash line of the sequence item: - key: value
+			prefix := string([]rune(splicer.original.lines[entry.start])[:collection.col])
+			if first := []rune(body[0]); len(first) >= collection.col && strings.TrimSpace(string(first[:collection.col])) == "" {
+				body[0] = prefix + string(first[collection.col:])
+				return body, nil
+			}
+			return append([]string{strings.TrimRight(prefix, " ")}, body...), nil
+		}
+	}
+	return splicer.updatedEntryLines(value, col)
+}
+
+// updatedEntryLines returns the lines of the updated yaml's entry, indented to the column.
+func (splicer yamlSplicer) updatedEntryLines(value *yamlv3.Node, col int) ([]string, error) {
+	if counterpart, ok := splicer.merger.counterparts[value]; ok {
+		value = counterpart
+	}
+	entry, ok := splicer.updated.entries[value]
+	if !ok {
+		return nil, errYAMLLayoutNotSupported
+	}
+
+	lines := append([]string{}, splicer.updated.lines[entry.start:entry.end+1]...)
+	lines[0] = blankPrefix(lines[0], entry.col)
+	indent := strings.Repeat(" ", abs(col-entry.col))
+	for i, line := range lines {
+		switch {
+		case line == "":
+		case col > entry.col:
+			lines[i] = indent + line
+		case strings.HasPrefix(line, indent):
+			lines[i] = strings.TrimPrefix(line, indent)
+		default:
+			return nil, errYAMLLayoutNotSupported
+		}
+	}
+	return lines, nil
+}
+
+// replaceYAMLScalarValue replaces the scalar value in place, if it is a single line plain or quoted scalar.
+func replaceYAMLScalarValue(lines []string, idx int, node *yamlv3.Node) error {
+	if node.Anchor != "" || idx < 0 || idx >= len(lines) {
+		return errYAMLLayoutNotSupported
+	}
+	for _, line := range lines[idx+1:] {
+		// a multiline scalar
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return errYAMLLayoutNotSupported
+		}
+	}
+	return replaceYAMLScalar(lines, idx, node)
+}
+
+// replaceYAMLScalar replaces the single line scalar (a key or a value) starting at the node's column with the node's value.
+func replaceYAMLScalar(lines []string, idx int, node *yamlv3.Node) error {
+	if idx < 0 || idx >= len(lines) {
+		return errYAMLLayoutNotSupported
+	}
+	runes := []rune(lines[idx])
+	start := node.Column - 1
+	end, err := yamlScalarEnd(runes, start)
+	if err != nil {
+		return err
+	}
+
+	scalarBytes, err := yamlv3.Marshal(&yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: node.Tag, Value: node.Value, Style: node.Style})
+	if err != nil {
+		return err
+	}
+	scalar := strings.TrimSuffix(string(scalarBytes), "\n")
+	if strings.Contains(scalar, "\n") {
+		return errYAMLLayoutNotSupported
+	}
+
+	lines[idx] = string(runes[:start]) + scalar + string(runes[end:])
+	return nil
+}
+
+// yamlScalarEnd returns the end column of the single line plain or quoted scalar starting at the column.
+func yamlScalarEnd(runes []rune, start int) (int, error) {
+	if start < 0 || start >= len(runes) {
+		return 0, errYAMLLayoutNotSupported
+	}
+
+	switch runes[start] {
+	case '"':
+		for i := start + 1; i < len(runes); i++ {
+			if runes[i] == '\\' {
+				i++
+			} else if runes[i] == '"' {
+				return i + 1, nil
+			}
+		}
+	case '\'':
+		for i := start + 1; i < len(runes); i++ {
+			if runes[i] != '\'' {
+				continue
+			}
+			if i+1 < len(runes) && runes[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1, nil
+		}
+	case '|', '>', '!', '&', '*', '[', '{':
+	default:
+		end := len(runes)
+		for i := start; i < len(runes); i++ {
+			if (runes[i] == ':' && (i+1 == len(runes) || runes[i+1] == ' ')) || (runes[i] == '#' && i > start && runes[i-1] == ' ') {
+				end = i
+				break
+			}
+		}
+		for end > start && runes[end-1] == ' ' {
+			end--
+		}
+		return end, nil
+	}
+	return 0, fmt.Errorf("%s: no single line scalar at column %d", errYAMLLayoutNotSupported, start+1)
+}
+
+// blankPrefix replaces the part of the line before the column with spaces, e.g. the dash of a sequence item.
+func blankPrefix(line string, col int) string {
+	runes := []rune(line)
+	if len(runes) < col {
+		return line
+	}
+	return strings.Repeat(" ", col) + string(runes[col:])
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,351 @@
+package bitrise
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestYAMLPreservingFormat(t *testing.T) {
+	t.Log("comments, quoting styles, anchors and key order are kept")
+	{
+		originalYAML := `# top comment
+format_version: 11
+default_step_lib_source: https://github.com/bitrise-io/bitrise-steplib.git
+
+app:
+  envs:
+  - A: "quoted" # line comment
+  - B: 'single'
+
+# workflows
+workflows:
+  _base: &base
+    envs:
+    - X: y
+  primary:
+    <<: *base
+    steps:
+    - script:
+        inputs:
+        - content: |
+            echo hi
+  secondary:
+`
+		updatedYAML := `format_version: "11"
+default_step_lib_source: https://github.com/bitrise-io/bitrise-steplib.git
+app:
+  envs:
+  - A: quoted
+  - B: changed
+workflows:
+  _base:
+    envs:
+    - X: y
+  primary:
+    envs:
+    - X: y
+    steps:
+    - script:
+        title: new
+        inputs:
+        - content: |
+            echo hi
+  secondary: {}
+`
+		expectedYAML := `# top comment
+format_version: 11
+default_step_lib_source: https://github.com/bitrise-io/bitrise-steplib.git
+
+app:
+  envs:
+  - A: "quoted" # line comment
+  - B: 'changed'
+
+# workflows
+workflows:
+  _base: &base
+    envs:
+    - X: y
+  primary:
+    <<: *base
+    steps:
+    - script:
+        inputs:
+        - content: |
+            echo hi
+        title: new
+  secondary:
+`
+
+		merged, err := YAMLPreservingFormat([]byte(originalYAML), []byte(updatedYAML))
+		require.NoError(t, err)
+		require.Equal(t, expectedYAML, string(merged))
+	}
+
+	t.Log("removed keys and items, changed alias")
+	{
+		originalYAML := `envs:
+- A: &value a # comment of A
+- B: *value
+- C: c
+workflows:
+  primary:
+    title: Primary
+    steps: []
+`
+		updatedYAML := `envs:
+- A: a
+- B: b
+workflows:
+  primary:
+    steps: []
+`
+		expectedYAML := `envs:
+- A: &value a # comment of A
+- B: b
+workflows:
+  primary:
+    steps: []
+`
+
+		merged, err := YAMLPreservingFormat([]byte(originalYAML), []byte(updatedYAML))
+		require.NoError(t, err)
+		require.Equal(t, expectedYAML, string(merged))
+	}
+
+	t.Log("no semantic change - the original yaml is kept as it is")
+	{
+		originalYAML := `format_version: 11   # version
+
+workflows:
+    primary:  # the default workflow
+`
+		merged, err := YAMLPreservingFormat([]byte(originalYAML), []byte("format_version: \"11\"\nworkflows:\n  primary: {}\n"))
+		require.NoError(t, err)
+		require.Equal(t, originalYAML, string(merged))
+	}
+
+	t.Log("inserted and moved list items keep the comments")
+	{
+		originalYAML := `workflows:
+  primary:
+    steps:
+    - script@1:
+        title: Build # build it
+    - deploy@2:
+        inputs:
+        - content: |-
+            echo deploy
+`
+		updatedYAML := `workflows:
+  primary:
+    steps:
+    - git-clone@8: {}
+    - deploy@2:
+        inputs:
+        - content: |-
+            echo deploy
+    - script@1:
+        title: Build
+`
+		expectedYAML := `workflows:
+  primary:
+    steps:
+    - git-clone@8: {}
+    - deploy@2:
+        inputs:
+        - content: |-
+            echo deploy
+    - script@1:
+        title: Build # build it
+`
+
+		merged, err := YAMLPreservingFormat([]byte(originalYAML), []byte(updatedYAML))
+		require.NoError(t, err)
+		require.Equal(t, expectedYAML, string(merged))
+	}
+
+	t.Log("list items of the same ID are matched by position")
+	{
+		originalYAML := `steps:
+- script@1: # first
+    title: A
+- script@1: # second
+    title: B
+`
+		updatedYAML := `steps:
+- script@1:
+    title: B
+- script@1:
+    title: B
+`
+		expectedYAML := `steps:
+- script@1: # first
+    title: B
+- script@1: # second
+    title: B
+`
+
+		merged, err := YAMLPreservingFormat([]byte(originalYAML), []byte(updatedYAML))
+		require.NoError(t, err)
+		require.Equal(t, expectedYAML, string(merged))
+	}
+
+	t.Log("renamed key stays in place")
+	{
+		originalYAML := `workflows:
+  primary:
+    # the main workflow
+    title: Primary
+  other: {}
+`
+		updatedYAML := `workflows:
+  other: {}
+  build:
+    title: Primary
+`
+		expectedYAML := `workflows:
+  build:
+    # the main workflow
+    title: Primary
+  other: {}
+`
+
+		merged, err := YAMLPreservingFormat([]byte(originalYAML), []byte(updatedYAML))
+		require.NoError(t, err)
+		require.Equal(t, expectedYAML, string(merged))
+	}
+
+	t.Log("blank lines and indented sequences are kept, new entries follow the blank line separation")
+	{
+		originalYAML := `workflows:
+
+  primary:
+    steps:
+      - script@1:
+          title: Build
+
+      - deploy@2: {}
+
+  secondary:
+    after_run:
+      - primary
+`
+		updatedYAML := `workflows:
+  primary:
+    steps:
+    - script@1:
+        title: Test
+    - deploy@2: {}
+  secondary:
+    after_run:
+    - primary
+  third:
+    steps:
+    - script@1: {}
+`
+		expectedYAML := `workflows:
+
+  primary:
+    steps:
+      - script@1:
+          title: Test
+
+      - deploy@2: {}
+
+  secondary:
+    after_run:
+      - primary
+
+  third:
+    steps:
+    - script@1: {}
+`
+
+		merged, err := YAMLPreservingFormat([]byte(originalYAML), []byte(updatedYAML))
+		require.NoError(t, err)
+		require.Equal(t, expectedYAML, string(merged))
+	}
+
+	t.Log("removed first key of a list item and empty value")
+	{
+		originalYAML := `steps:
+- script@1:
+    inputs:
+    - A: a # comment
+      opts:
+        is_expand: false
+    title:
+`
+		updatedYAML := `steps:
+- script@1:
+    inputs:
+    - opts:
+        is_expand: false
+    title: Build
+`
+		expectedYAML := `steps:
+- script@1:
+    inputs:
+    - opts:
+        is_expand: false
+    title: Build
+`
+
+		merged, err := YAMLPreservingFormat([]byte(originalYAML), []byte(updatedYAML))
+		require.NoError(t, err)
+		require.Equal(t, expectedYAML, string(merged))
+	}
+
+	t.Log("flow style root is encoded")
+	{
+		merged, err := YAMLPreservingFormat([]byte("{key: value, other: b}\n"), []byte("key: changed\nother: b\n"))
+		require.NoError(t, err)
+		require.Equal(t, "{key: changed, other: b}\n", string(merged))
+	}
+
+	t.Log("string values which need quoting")
+	{
+		merged, err := YAMLPreservingFormat([]byte("key: value\n"), []byte("key: \"true\"\n"))
+		require.NoError(t, err)
+		require.Equal(t, "key: \"true\"\n", string(merged))
+	}
+}
+
+func TestConfigYAMLPreservingFormat(t *testing.T) {
+	configYAML := `format_version: 11
+default_step_lib_source: https://github.com/bitrise-io/bitrise-steplib.git
+
+# the main workflow
+workflows:
+  primary:
+    envs:
+    - KEY: value # an env
+      opts:
+        is_expand: true
+    steps:
+    - script@1:
+        title: Hello # greeting
+`
+
+	config, warnings, err := ConfigModelFromYAMLBytes([]byte(configYAML))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+	require.NoError(t, config.RemoveRedundantFields())
+
+	normalizedYAML, err := ConfigYAMLPreservingFormat([]byte(configYAML), config)
+	require.NoError(t, err)
+	require.Equal(t, `format_version: 11
+default_step_lib_source: https://github.com/bitrise-io/bitrise-steplib.git
+
+# the main workflow
+workflows:
+  primary:
+    envs:
+    - KEY: value # an env
+    steps:
+    - script@1:
+        title: Hello # greeting
+`, string(normalizedYAML))
+}
@@ -0,0 +1,102 @@
+package bitrise
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseJSONPath splits a JSONPath expression into object keys (string) and array indexes (int).
+// Supported syntax: $, .key, ['key'], ["key"] and [index], e.g. $.builds[0]['app name']
+func parseJSONPath(expression string) ([]interface{}, error) {
+	if !strings.HasPrefix(expression, "$") {
+		return nil, fmt.Errorf("invalid JSONPath (%s): should start with $", expression)
+	}
+
+	selectors := []interface{}{}
+	rest := expression[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid JSONPath (%s): empty key", expression)
+			}
+			selectors = append(selectors, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("invalid JSONPath (%s): missing ]", expression)
+			}
+			selector := rest[1:end]
+			if len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0] {
+				selectors = append(selectors, selector[1:len(selector)-1])
+			} else if index, err := strconv.Atoi(selector); err == nil && index >= 0 {
+				selectors = append(selectors, index)
+			} else {
+				return nil, fmt.Errorf("invalid JSONPath (%s): invalid selector [%s]", expression, selector)
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSONPath (%s): unexpected character (%c)", expression, rest[0])
+		}
+	}
+	return selectors, nil
+}
+
+// JSONPath returns the value of the JSON document at the JSONPath expression (e.g. $.builds[0].name).
+// String values are returned as is, other values are JSON encoded.
+func JSONPath(expression, document string) (string, error) {
+	selectors, err := parseJSONPath(expression)
+	if err != nil {
+		return "", err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(document)))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return "", fmt.Errorf("invalid JSON, error: %s", err)
+	}
+
+	for _, selector := range selectors {
+		switch selector := selector.(type) {
+		case string:
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("%s: not an object, can not select key (%s)", expression, selector)
+			}
+			if value, ok = object[selector]; !ok {
+				return "", fmt.Errorf("%s: key (%s) not found", expression, selector)
+			}
+		case int:
+			array, ok := value.([]interface{})
+			if !ok {
+				return "", fmt.Errorf("%s: not an array, can not select index (%d)", expression, selector)
+			}
+			if selector >= len(array) {
+				return "", fmt.Errorf("%s: index (%d) out of range, the array has %d items", expression, selector, len(array))
+			}
+			value = array[selector]
+		default:
+			return "", errors.New("unknown JSONPath selector")
+		}
+	}
+
+	if str, ok := value.(string); ok {
+		return str, nil
+	}
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(valueBytes), nil
+}
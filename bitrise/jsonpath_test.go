@@ -0,0 +1,57 @@
+package bitrise
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPath(t *testing.T) {
+	document := `{"version": "1.2.0", "build": {"number": 42, "ok": true, "apps": [{"name": "App", "app id": "io.bitrise.app"}]}, "empty": null}`
+
+	for expression, expected := range map[string]string{
+		"$":                            document,
+		"$.version":                    "1.2.0",
+		"$.build.number":               "42",
+		"$.build.ok":                   "true",
+		"$.build.apps[0].name":         "App",
+		"$.build.apps[0]['app id']":    "io.bitrise.app",
+		`$["build"].apps[0]["app id"]`: "io.bitrise.app",
+		"$.build.apps":                 `[{"app id":"io.bitrise.app","name":"App"}]`,
+		"$.empty":                      "null",
+	} {
+		t.Log(expression)
+		{
+			value, err := JSONPath(expression, document)
+			require.NoError(t, err)
+			if expression == "$" {
+				require.JSONEq(t, expected, value)
+			} else {
+				require.Equal(t, expected, value)
+			}
+		}
+	}
+
+	for expression, expectedErr := range map[string]string{
+		"version":              "invalid JSONPath (version): should start with $",
+		"$.build.":             "invalid JSONPath ($.build.): empty key",
+		"$.build.apps[0":       "invalid JSONPath ($.build.apps[0): missing ]",
+		"$.build.apps[-1]":     "invalid JSONPath ($.build.apps[-1]): invalid selector [-1]",
+		"$.missing":            "$.missing: key (missing) not found",
+		"$.version.major":      "$.version.major: not an object, can not select key (major)",
+		"$.build[0]":           "$.build[0]: not an array, can not select index (0)",
+		"$.build.apps[1].name": "$.build.apps[1].name: index (1) out of range, the array has 1 items",
+	} {
+		t.Log(expression)
+		{
+			_, err := JSONPath(expression, document)
+			require.EqualError(t, err, expectedErr)
+		}
+	}
+
+	t.Log("invalid JSON")
+	{
+		_, err := JSONPath("$.a", `{"a":`)
+		require.Error(t, err)
+	}
+}
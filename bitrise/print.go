@@ -49,6 +49,14 @@ func getTrimmedStepName(stepRunResult models.StepRunResultsModel) string {
 			titleBox = fmt.Sprintf("%s", title)
 		}
 		break
+	case models.StepRunStatusCodeSkippedWithNoRelevantChanges:
+		titleBox = fmt.Sprintf("%s (skipped: no relevant changes)", title)
+		if len(titleBox) > titleBoxWidth {
+			dif := len(titleBox) - titleBoxWidth
+			title = stringutil.MaxFirstCharsWithDots(title, len(title)-dif)
+			titleBox = fmt.Sprintf("%s (skipped: no relevant changes)", title)
+		}
+		break
 	case models.StepRunStatusCodeFailed, models.StepRunStatusCodeFailedSkippable:
 		titleBox = fmt.Sprintf("%s (exit code: %d)", title, stepRunResult.ExitCode)
 		if len(titleBox) > titleBoxWidth {
@@ -89,7 +97,7 @@ func getRunningStepHeaderMainSection(stepInfo stepmanModels.StepInfoModel, idx i
 	return content
 }
 
-func getRunningStepHeaderSubSection(step stepmanModels.StepModel, stepInfo stepmanModels.StepInfoModel) string {
+func getRunningStepHeaderSubSection(step models.StepModel, stepInfo stepmanModels.StepInfoModel) string {
 
 	idRow := ""
 	{
@@ -212,7 +220,7 @@ func getRunningStepFooterMainSection(stepRunResult models.StepRunResultsModel) s
 		icon = "!"
 		coloringFunc = colorstring.Yellow
 		break
-	case models.StepRunStatusCodeSkipped, models.StepRunStatusCodeSkippedWithRunIf:
+	case models.StepRunStatusCodeSkipped, models.StepRunStatusCodeSkippedWithRunIf, models.StepRunStatusCodeSkippedWithNoRelevantChanges:
 		icon = "-"
 		coloringFunc = colorstring.Blue
 		break
@@ -529,7 +537,7 @@ func getRunningStepFooterSubSection(stepRunResult models.StepRunResultsModel) st
 }
 
 // PrintRunningStepHeader ...
-func PrintRunningStepHeader(stepInfo stepmanModels.StepInfoModel, step stepmanModels.StepModel, idx int) {
+func PrintRunningStepHeader(stepInfo stepmanModels.StepInfoModel, step models.StepModel, idx int) {
 	sep := fmt.Sprintf("+%s+", strings.Repeat("-", stepRunSummaryBoxWidthInChars-2))
 
 	fmt.Println(sep)
@@ -127,7 +127,7 @@ func TestGetRunningStepHeaderSubSection(t *testing.T) {
 		Version: longStr,
 	}
 
-	actual := getRunningStepHeaderSubSection(stepmanModels.StepModel{}, stepInfo)
+	actual := getRunningStepHeaderSubSection(models.StepModel{}, stepInfo)
 	require.NotEqual(t, "", actual)
 }
 
@@ -437,7 +437,7 @@ func TestPrintRunningStepHeader(t *testing.T) {
 		},
 		Version: "",
 	}
-	step := stepmanModels.StepModel{}
+	step := models.NewStepModel(stepmanModels.StepModel{})
 	PrintRunningStepHeader(stepInfo, step, 0)
 
 	stepInfo.Step.Title = pointers.NewStringPtr(longStr)
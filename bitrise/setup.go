@@ -116,6 +116,10 @@ func doSetupToolkits() error {
 		toolkitName := aCoreTK.ToolkitName()
 		isInstallRequired, checkResult, err := aCoreTK.Check()
 		if err != nil {
+			if !toolkits.IsInstalledByToolkit(aCoreTK) {
+				log.Warnf("Failed to perform toolkit check (%s), steps using the %s toolkit might not run, error: %s", toolkitName, toolkitName, err)
+				continue
+			}
 			return fmt.Errorf("Failed to perform toolkit check (%s), error: %s", toolkitName, err)
 		}
 
@@ -131,6 +135,10 @@ func doSetupToolkits() error {
 			}
 		}
 		if isInstallRequired {
+			if !toolkits.IsInstalledByToolkit(aCoreTK) {
+				// the interpreter is only required by the steps using the toolkit, Install warned about it
+				continue
+			}
 			return fmt.Errorf("Toolkit (%s) still reports that it isn't (properly) installed", toolkitName)
 		}
 
@@ -0,0 +1,54 @@
+package bitrise
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/tools"
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoreToolDownloadSHA256Sums(t *testing.T) {
+	fakeHomePth, err := pathutil.NormalizedOSTempDirPath("_FAKE_HOME")
+	require.NoError(t, err)
+	originalHome := os.Getenv("HOME")
+
+	defer func() {
+		require.NoError(t, os.Setenv("HOME", originalHome))
+		require.NoError(t, os.RemoveAll(fakeHomePth))
+	}()
+
+	require.NoError(t, os.Setenv("HOME", fakeHomePth))
+
+	mirrorDir, err := pathutil.NormalizedOSTempDirPath("setup-mirror")
+	require.NoError(t, err)
+	require.NoError(t, configs.EnsureBitriseConfigDirExists())
+	require.NoError(t, fileutil.WriteStringToFile(filepath.Join(configs.GetBitriseHomeDirPath(), "config.json"), fmt.Sprintf(`{"download_mirror":%q}`, mirrorDir)))
+
+	coreTools := map[string]string{"envman": minEnvmanVersion, "stepman": minStepmanVersion}
+	for toolname, toolVersion := range coreTools {
+		releaseDir := filepath.Join(mirrorDir, "bitrise-io", toolname, "releases", "download", toolVersion)
+		require.NoError(t, pathutil.EnsureDirExist(releaseDir))
+
+		checksums := ""
+		for _, unameGOOS := range []string{"Darwin", "Linux"} {
+			checksums += fmt.Sprintf("%064x  %s-%s-x86_64\n", len(checksums), toolname, unameGOOS)
+		}
+		require.NoError(t, fileutil.WriteStringToFile(filepath.Join(releaseDir, "checksums.txt"), checksums))
+	}
+
+	for toolname, toolVersion := range coreTools {
+		for _, unameGOOS := range []string{"Darwin", "Linux"} {
+			downloadURL := tools.GitHubToolDownloadURL(toolname, "bitrise-io", toolVersion, unameGOOS, "x86_64")
+
+			sum, err := tools.DownloadSHA256(downloadURL)
+			require.NoError(t, err, downloadURL)
+			require.Len(t, sum, 64, downloadURL)
+		}
+	}
+}
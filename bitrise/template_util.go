@@ -3,13 +3,18 @@ package bitrise
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"os"
+	"regexp"
 	"strings"
 	"text/template"
 
+	log "github.com/Sirupsen/logrus"
+	"github.com/bitrise-io/bitrise/configs"
 	"github.com/bitrise-io/bitrise/models"
 	envmanModels "github.com/bitrise-io/envman/models"
 	"github.com/bitrise-io/goinp/goinp"
+	ver "github.com/hashicorp/go-version"
 )
 
 // TemplateDataModel ...
@@ -20,6 +25,36 @@ type TemplateDataModel struct {
 	IsCI          bool
 	IsPR          bool
 	PullRequestID string
+	// Steps are the steps of the build with an id, by id: {{ .Steps.build.Status }}, {{ .Steps.build.Outputs.BITRISE_IPA_PATH }}
+	Steps map[string]StepTemplateDataModel
+
+	envList envmanModels.EnvsJSONListModel
+}
+
+// StepTemplateDataModel is the last run of a step with an id.
+type StepTemplateDataModel struct {
+	// Status is the name of the step run status: success, failed, failed_skippable or skipped
+	Status  string
+	Outputs map[string]string
+}
+
+func createStepsTemplateData(buildResults models.BuildRunResultsModel) map[string]StepTemplateDataModel {
+	steps := map[string]StepTemplateDataModel{}
+	for _, result := range buildResults.OrderedResults() {
+		if result.ID == "" {
+			continue
+		}
+
+		outputs := map[string]string{}
+		for key, value := range buildResults.StepOutputs[result.Idx] {
+			outputs[key] = value
+		}
+		steps[result.ID] = StepTemplateDataModel{
+			Status:  models.StepRunStatusName(result.Status),
+			Outputs: outputs,
+		}
+	}
+	return steps
 }
 
 func getEnv(key string, envList envmanModels.EnvsJSONListModel) string {
@@ -33,7 +68,15 @@ func getEnv(key string, envList envmanModels.EnvsJSONListModel) string {
 	return os.Getenv(key)
 }
 
-func createTemplateDataModel(isCI, isPR bool, buildResults models.BuildRunResultsModel) TemplateDataModel {
+func isEnvExists(key string, envList envmanModels.EnvsJSONListModel) bool {
+	if _, ok := envList[key]; ok {
+		return true
+	}
+	_, ok := os.LookupEnv(key)
+	return ok
+}
+
+func createTemplateDataModel(isCI, isPR bool, buildResults models.BuildRunResultsModel, envList envmanModels.EnvsJSONListModel) TemplateDataModel {
 	isBuildOK := !buildResults.IsBuildFailed()
 
 	return TemplateDataModel{
@@ -42,7 +85,131 @@ func createTemplateDataModel(isCI, isPR bool, buildResults models.BuildRunResult
 		IsBuildOK:     isBuildOK,
 		IsCI:          isCI,
 		IsPR:          isPR,
+		Steps:         createStepsTemplateData(buildResults),
+		envList:       envList,
+	}
+}
+
+// ChangedFiles reports whether any of the files changed by the build's commit matches any of the globs (e.g. "apps/ios/**").
+// The changes are compared to the pull request's target branch, or to the previous commit.
+// If the changed files can not be determined (e.g. not a git repository) every change is considered relevant,
+// the same way as by the changed_files property of the steps and trigger map items.
+func (data TemplateDataModel) ChangedFiles(globs ...string) (bool, error) {
+	changedFiles, err := ChangedFilesOfBuild(data.envList)
+	if err != nil {
+		log.Warnf("Failed to determine the changed files, considering every change relevant, error: %s", err)
+		return true, nil
+	}
+	return HasRelevantChanges(models.ChangedFilesFilterModel{Include: globs}, changedFiles)
+}
+
+// ChangedFilesSince reports whether any of the files changed by the build's commit since the given git ref
+// (e.g. "origin/main") matches any of the globs. Otherwise the same as ChangedFiles.
+func (data TemplateDataModel) ChangedFilesSince(baseRef string, globs ...string) (bool, error) {
+	changedFiles, err := ChangedFilesOfBuildSince(baseRef, data.envList)
+	if err != nil {
+		log.Warnf("Failed to determine the changed files since %s, considering every change relevant, error: %s", baseRef, err)
+		return true, nil
+	}
+	return HasRelevantChanges(models.ChangedFilesFilterModel{Include: globs}, changedFiles)
+}
+
+func matchEnvWithRegexp(key, pattern string, envList envmanModels.EnvsJSONListModel) (bool, error) {
+	exp, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, fmt.Errorf("invalid regexp (%s), error: %s", pattern, err)
+	}
+	return exp.MatchString(getEnv(key, envList)), nil
+}
+
+// BranchMatches reports whether the build's git branch matches the regexp.
+func (data TemplateDataModel) BranchMatches(pattern string) (bool, error) {
+	return matchEnvWithRegexp(configs.GitBranchEnvKey, pattern, data.envList)
+}
+
+// TagMatches reports whether the build's git tag matches the regexp.
+func (data TemplateDataModel) TagMatches(pattern string) (bool, error) {
+	return matchEnvWithRegexp(configs.GitTagEnvKey, pattern, data.envList)
+}
+
+// CommitMessageMatches reports whether the build's commit message matches the regexp.
+func (data TemplateDataModel) CommitMessageMatches(pattern string) (bool, error) {
+	return matchEnvWithRegexp(configs.GitMessageEnvKey, pattern, data.envList)
+}
+
+// stepResult returns the last run of the step with the given id, or if no step has the id, of the step with the given step ID.
+// The step ID is not unique if the same step is used more than once (e.g. two script steps),
+// the last run of those steps is returned, a specific one can be referenced only by its id.
+func (data TemplateDataModel) stepResult(id string) (models.StepRunResultsModel, bool) {
+	var stepResult, idResult models.StepRunResultsModel
+	stepFound, idFound := false, false
+	for _, result := range data.BuildResults.OrderedResults() {
+		if result.ID == id {
+			idResult, idFound = result, true
+		}
+		if result.StepInfo.ID == id {
+			stepResult, stepFound = result, true
+		}
+	}
+	if idFound {
+		return idResult, true
 	}
+	return stepResult, stepFound
+}
+
+// stepStatus returns the status of the step's last run.
+func (data TemplateDataModel) stepStatus(id string) (int, bool) {
+	result, found := data.stepResult(id)
+	return result.Status, found
+}
+
+// StepFailed reports whether the step with the given ID failed, including the failures of skippable steps.
+func (data TemplateDataModel) StepFailed(id string) bool {
+	status, found := data.stepStatus(id)
+	return found && (status == models.StepRunStatusCodeFailed || status == models.StepRunStatusCodeFailedSkippable)
+}
+
+// StepSucceeded reports whether the step with the given ID succeeded.
+func (data TemplateDataModel) StepSucceeded(id string) bool {
+	status, found := data.stepStatus(id)
+	return found && status == models.StepRunStatusCodeSuccess
+}
+
+// StepSkipped reports whether the step with the given ID was skipped.
+func (data TemplateDataModel) StepSkipped(id string) bool {
+	status, found := data.stepStatus(id)
+	return found && (status == models.StepRunStatusCodeSkipped ||
+		status == models.StepRunStatusCodeSkippedWithRunIf ||
+		status == models.StepRunStatusCodeSkippedWithNoRelevantChanges)
+}
+
+// SemverCompare reports whether the version matches the constraint (e.g. ">= 1.2, < 2.0").
+func (data TemplateDataModel) SemverCompare(constraint, version string) (bool, error) {
+	constraints, err := ver.NewConstraint(constraint)
+	if err != nil {
+		return false, fmt.Errorf("invalid version constraint (%s), error: %s", constraint, err)
+	}
+
+	v, err := ver.NewVersion(version)
+	if err != nil {
+		return false, fmt.Errorf("invalid version (%s), error: %s", version, err)
+	}
+	return constraints.Check(v), nil
+}
+
+// Output returns the value of the output of the step's last run, empty string if the step did not export the output.
+// The step is referenced by its id, or by its step ID.
+func (data TemplateDataModel) Output(stepID, key string) (string, error) {
+	result, found := data.stepResult(stepID)
+	if !found {
+		return "", fmt.Errorf("step (%s) has not run yet", stepID)
+	}
+	return data.BuildResults.StepOutputs[result.Idx][key], nil
+}
+
+// EnvExists reports whether the env is defined, even with an empty value.
+func (data TemplateDataModel) EnvExists(key string) bool {
+	return isEnvExists(key, data.envList)
 }
 
 // EvaluateTemplateToString ...
@@ -55,6 +222,8 @@ func EvaluateTemplateToString(expStr string, isCI, isPR bool, buildResults model
 		expStr = "{{" + expStr + "}}"
 	}
 
+	templateData := createTemplateDataModel(isCI, isPR, buildResults, envList)
+
 	var templateFuncMap = template.FuncMap{
 		"getenv": func(key string) string {
 			return getEnv(key, envList)
@@ -62,6 +231,18 @@ func EvaluateTemplateToString(expStr string, isCI, isPR bool, buildResults model
 		"enveq": func(key, expectedValue string) bool {
 			return (getEnv(key, envList) == expectedValue)
 		},
+		"envExists":            templateData.EnvExists,
+		"changedFiles":         templateData.ChangedFiles,
+		"changedFilesSince":    templateData.ChangedFilesSince,
+		"branchMatches":        templateData.BranchMatches,
+		"tagMatches":           templateData.TagMatches,
+		"commitMessageMatches": templateData.CommitMessageMatches,
+		"stepFailed":           templateData.StepFailed,
+		"stepSucceeded":        templateData.StepSucceeded,
+		"stepSkipped":          templateData.StepSkipped,
+		"semverCompare":        templateData.SemverCompare,
+		"output":               templateData.Output,
+		"jsonpath":             JSONPath,
 	}
 
 	tmpl := template.New("EvaluateTemplateToBool").Funcs(templateFuncMap)
@@ -70,7 +251,6 @@ func EvaluateTemplateToString(expStr string, isCI, isPR bool, buildResults model
 		return "", err
 	}
 
-	templateData := createTemplateDataModel(isCI, isPR, buildResults)
 	var resBuffer bytes.Buffer
 	if err := tmpl.Execute(&resBuffer, templateData); err != nil {
 		return "", err
@@ -1,13 +1,17 @@
 package bitrise
 
 import (
+	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/bitrise-io/bitrise/configs"
 	"github.com/bitrise-io/bitrise/models"
 	envmanModels "github.com/bitrise-io/envman/models"
+	stepmanModels "github.com/bitrise-io/stepman/models"
 	"github.com/stretchr/testify/require"
 )
 
@@ -406,3 +410,176 @@ mode
 	require.Equal(t, nil, err)
 	require.Equal(t, true, (strings.Contains(value, "This is") && strings.Contains(value, "value in case of not IsCI") && strings.Contains(value, "mode")))
 }
+
+func TestRunIfFunctions(t *testing.T) {
+	buildRes := models.BuildRunResultsModel{
+		SuccessSteps: []models.StepRunResultsModel{
+			{StepInfo: stepmanModels.StepInfoModel{ID: "git-clone"}, Status: models.StepRunStatusCodeSuccess, Idx: 0},
+		},
+		FailedSkippableSteps: []models.StepRunResultsModel{
+			{StepInfo: stepmanModels.StepInfoModel{ID: "xcode-test"}, Status: models.StepRunStatusCodeFailedSkippable, Idx: 1},
+		},
+		SkippedSteps: []models.StepRunResultsModel{
+			{StepInfo: stepmanModels.StepInfoModel{ID: "deploy"}, Status: models.StepRunStatusCodeSkippedWithRunIf, Idx: 2},
+		},
+		StepOutputs: map[int]map[string]string{
+			0: {"GIT_CLONE_COMMIT_INFO": `{"author": "bitrise", "files": [{"path": "ios/App.swift"}]}`},
+		},
+	}
+	envList := envmanModels.EnvsJSONListModel{
+		configs.GitBranchEnvKey:  "release/1.2",
+		configs.GitTagEnvKey:     "v1.2.0",
+		configs.GitMessageEnvKey: "Fix crash [skip tests]",
+		"APP_VERSION":            "1.2.0",
+		"EMPTY":                  "",
+	}
+
+	for expStr, expected := range map[string]bool{
+		`{{branchMatches "^release/"}}`:                            true,
+		`{{branchMatches "^master$"}}`:                             false,
+		`{{tagMatches "^v[0-9]+"}}`:                                true,
+		`{{commitMessageMatches "\\[skip tests\\]"}}`:              true,
+		`{{stepSucceeded "git-clone"}}`:                            true,
+		`{{stepFailed "git-clone"}}`:                               false,
+		`{{stepFailed "xcode-test"}}`:                              true,
+		`{{stepSkipped "deploy"}}`:                                 true,
+		`{{stepSkipped "not-run"}}`:                                false,
+		`{{semverCompare ">= 1.2, < 2.0" (getenv "APP_VERSION")}}`: true,
+		`{{semverCompare "< 1.0" "1.2.0"}}`:                        false,
+		`{{envExists "EMPTY"}}`:                                    true,
+		`{{envExists "NOT_DEFINED_RUN_IF_KEY"}}`:                   false,
+		`{{.StepFailed "xcode-test"}}`:                             true,
+	} {
+		t.Log(expStr)
+		{
+			isYes, err := EvaluateTemplateToBool(expStr, false, false, buildRes, envList)
+			require.NoError(t, err)
+			require.Equal(t, expected, isYes)
+		}
+	}
+
+	t.Log("invalid regexp")
+	{
+		_, err := EvaluateTemplateToBool(`{{branchMatches "("}}`, false, false, buildRes, envList)
+		require.Error(t, err)
+	}
+
+	t.Log("invalid version")
+	{
+		_, err := EvaluateTemplateToBool(`{{semverCompare ">= 1.0" "not-a-version"}}`, false, false, buildRes, envList)
+		require.Error(t, err)
+	}
+
+	t.Log("step outputs")
+	{
+		value, err := EvaluateTemplateToString(`{{output "git-clone" "GIT_CLONE_COMMIT_INFO" | jsonpath "$.files[0].path"}}`, false, false, buildRes, envList)
+		require.NoError(t, err)
+		require.Equal(t, "ios/App.swift", value)
+
+		isYes, err := EvaluateTemplateToBool(`{{output "git-clone" "GIT_CLONE_COMMIT_INFO" | jsonpath "$.author" | eq "bitrise"}}`, false, false, buildRes, envList)
+		require.NoError(t, err)
+		require.Equal(t, true, isYes)
+
+		value, err = EvaluateTemplateToString(`{{output "git-clone" "NOT_EXPORTED"}}`, false, false, buildRes, envList)
+		require.NoError(t, err)
+		require.Equal(t, "", value)
+
+		_, err = EvaluateTemplateToString(`{{output "xcode-archive" "BITRISE_IPA_PATH"}}`, false, false, buildRes, envList)
+		require.Error(t, err)
+	}
+
+	t.Log("changed files can not be determined")
+	{
+		notGitDir, err := ioutil.TempDir("", "changed-files")
+		require.NoError(t, err)
+
+		isChanged, err := EvaluateTemplateToBool(`{{changedFiles "apps/ios/**"}}`, false, false, buildRes, envmanModels.EnvsJSONListModel{configs.BitriseSourceDirEnvKey: notGitDir})
+		require.NoError(t, err)
+		require.Equal(t, true, isChanged)
+	}
+
+	t.Log("changed files since a base ref")
+	{
+		gitDir, err := ioutil.TempDir("", "changed-files")
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, os.RemoveAll(gitDir))
+		}()
+
+		git := func(args ...string) {
+			cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+			cmd.Dir = gitDir
+			out, err := cmd.CombinedOutput()
+			require.NoError(t, err, string(out))
+		}
+		commitFile := func(pth string) {
+			require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(gitDir, pth)), 0777))
+			require.NoError(t, ioutil.WriteFile(filepath.Join(gitDir, pth), []byte(pth), 0666))
+			git("add", "-A")
+			git("commit", "-q", "-m", pth)
+		}
+
+		git("init", "-q")
+		commitFile("README.md")
+		git("branch", "base")
+		commitFile("apps/ios/App.swift")
+		commitFile("docs/README.md")
+
+		gitEnvList := envmanModels.EnvsJSONListModel{configs.BitriseSourceDirEnvKey: gitDir}
+
+		isChanged, err := EvaluateTemplateToBool(`{{changedFilesSince "base" "apps/**"}}`, false, false, buildRes, gitEnvList)
+		require.NoError(t, err)
+		require.Equal(t, true, isChanged)
+
+		isChanged, err = EvaluateTemplateToBool(`{{changedFiles "apps/**"}}`, false, false, buildRes, gitEnvList)
+		require.NoError(t, err)
+		require.Equal(t, false, isChanged)
+
+		isChanged, err = EvaluateTemplateToBool(`{{changedFilesSince "HEAD~1" "apps/**"}}`, false, false, buildRes, gitEnvList)
+		require.NoError(t, err)
+		require.Equal(t, false, isChanged)
+
+		isChanged, err = EvaluateTemplateToBool(`{{changedFilesSince "not-existing-branch" "apps/**"}}`, false, false, buildRes, gitEnvList)
+		require.NoError(t, err)
+		require.Equal(t, true, isChanged)
+	}
+}
+
+func TestStepIDReferences(t *testing.T) {
+	buildRes := models.BuildRunResultsModel{
+		SuccessSteps: []models.StepRunResultsModel{
+			{ID: "build", StepInfo: stepmanModels.StepInfoModel{ID: "script"}, Status: models.StepRunStatusCodeSuccess, Idx: 0},
+		},
+		FailedSkippableSteps: []models.StepRunResultsModel{
+			{ID: "lint", StepInfo: stepmanModels.StepInfoModel{ID: "script"}, Status: models.StepRunStatusCodeFailedSkippable, Idx: 1},
+		},
+		SkippedSteps: []models.StepRunResultsModel{
+			{ID: "upload-app", StepInfo: stepmanModels.StepInfoModel{ID: "deploy-to-bitrise-io"}, Status: models.StepRunStatusCodeSkippedWithRunIf, Idx: 2},
+		},
+		StepOutputs: map[int]map[string]string{
+			0: {"SCRIPT_RESULT": "app.ipa"},
+			1: {"SCRIPT_RESULT": "lint.html"},
+		},
+	}
+	envList := envmanModels.EnvsJSONListModel{}
+
+	for expStr, expected := range map[string]bool{
+		`{{eq .Steps.build.Status "success"}}`:                 true,
+		`{{eq .Steps.lint.Status "failed_skippable"}}`:         true,
+		`{{eq (index .Steps "upload-app").Status "skipped"}}`:  true,
+		`{{eq .Steps.build.Outputs.SCRIPT_RESULT "app.ipa"}}`:  true,
+		`{{stepSucceeded "build"}}`:                            true,
+		`{{stepFailed "lint"}}`:                                true,
+		`{{stepFailed "script"}}`:                              true,
+		`{{eq (output "build" "SCRIPT_RESULT") "app.ipa"}}`:    true,
+		`{{eq (output "script" "SCRIPT_RESULT") "lint.html"}}`: true,
+		`{{eq (output "upload-app" "SCRIPT_RESULT") ""}}`:      true,
+	} {
+		t.Log(expStr)
+		{
+			isYes, err := EvaluateTemplateToBool(expStr, false, false, buildRes, envList)
+			require.NoError(t, err)
+			require.Equal(t, expected, isYes)
+		}
+	}
+}
@@ -91,6 +91,34 @@ func ApplyOutputAliases(onEnvs, basedOnEnvs []envmanModels.EnvironmentItemModel)
 	return onEnvs, nil
 }
 
+// NamespaceStepOutputs returns the copies of the step outputs under their keys namespaced by the step's id
+// (see models.StepOutputEnvKey), no outputs are returned for steps without id.
+// Should be called before applying the output aliases, the namespaced keys are based on the original output keys.
+func NamespaceStepOutputs(outputs []envmanModels.EnvironmentItemModel, stepID string) ([]envmanModels.EnvironmentItemModel, error) {
+	if stepID == "" {
+		return []envmanModels.EnvironmentItemModel{}, nil
+	}
+
+	namespacedOutputs := []envmanModels.EnvironmentItemModel{}
+	for _, output := range outputs {
+		key, value, err := output.GetKeyValuePair()
+		if err != nil {
+			return []envmanModels.EnvironmentItemModel{}, err
+		}
+
+		options, err := output.GetOptions()
+		if err != nil {
+			return []envmanModels.EnvironmentItemModel{}, err
+		}
+
+		namespacedOutputs = append(namespacedOutputs, envmanModels.EnvironmentItemModel{
+			models.StepOutputEnvKey(stepID, key): value,
+			envmanModels.OptionsKey:              options,
+		})
+	}
+	return namespacedOutputs, nil
+}
+
 // CollectEnvironmentsFromFile ...
 func CollectEnvironmentsFromFile(pth string) ([]envmanModels.EnvironmentItemModel, error) {
 	bytes, err := fileutil.ReadBytesFromFile(pth)
@@ -278,33 +306,33 @@ func ReadBitriseConfig(pth string) (models.BitriseDataModel, []string, error) {
 }
 
 // ReadSpecStep ...
-func ReadSpecStep(pth string) (stepmanModels.StepModel, error) {
+func ReadSpecStep(pth string) (models.StepModel, error) {
 	if isExists, err := pathutil.IsPathExists(pth); err != nil {
-		return stepmanModels.StepModel{}, err
+		return models.StepModel{}, err
 	} else if !isExists {
-		return stepmanModels.StepModel{}, fmt.Errorf("No file found at path: %s", pth)
+		return models.StepModel{}, fmt.Errorf("No file found at path: %s", pth)
 	}
 
 	bytes, err := fileutil.ReadBytesFromFile(pth)
 	if err != nil {
-		return stepmanModels.StepModel{}, err
+		return models.StepModel{}, err
 	}
 
-	var stepModel stepmanModels.StepModel
+	var stepModel models.StepModel
 	if err := yaml.Unmarshal(bytes, &stepModel); err != nil {
-		return stepmanModels.StepModel{}, err
+		return models.StepModel{}, err
 	}
 
 	if err := stepModel.Normalize(); err != nil {
-		return stepmanModels.StepModel{}, err
+		return models.StepModel{}, err
 	}
 
 	if err := stepModel.ValidateInputAndOutputEnvs(false); err != nil {
-		return stepmanModels.StepModel{}, err
+		return models.StepModel{}, err
 	}
 
 	if err := stepModel.FillMissingDefaults(); err != nil {
-		return stepmanModels.StepModel{}, err
+		return models.StepModel{}, err
 	}
 
 	return stepModel, nil
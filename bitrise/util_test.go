@@ -7,6 +7,7 @@ import (
 
 	"github.com/bitrise-io/bitrise/configs"
 	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/pointers"
 	stepmanModels "github.com/bitrise-io/stepman/models"
 	"github.com/stretchr/testify/require"
 )
@@ -86,6 +87,44 @@ func TestApplyOutputAliases(t *testing.T) {
 	}
 }
 
+func TestNamespaceStepOutputs(t *testing.T) {
+	t.Log("step with id")
+	{
+		envs := []envmanModels.EnvironmentItemModel{
+			envmanModels.EnvironmentItemModel{"BITRISE_IPA_PATH": "app.ipa", envmanModels.OptionsKey: envmanModels.EnvironmentItemOptionsModel{IsExpand: pointers.NewBoolPtr(false)}},
+		}
+
+		namespacedEnvs, err := NamespaceStepOutputs(envs, "build-app")
+		require.NoError(t, err)
+		require.Equal(t, 1, len(namespacedEnvs))
+
+		key, value, err := namespacedEnvs[0].GetKeyValuePair()
+		require.NoError(t, err)
+		require.Equal(t, "STEPS_BUILD_APP_OUTPUT_BITRISE_IPA_PATH", key)
+		require.Equal(t, "app.ipa", value)
+
+		options, err := namespacedEnvs[0].GetOptions()
+		require.NoError(t, err)
+		require.Equal(t, false, *options.IsExpand)
+
+		// the original outputs are kept, to apply the aliases on them
+		key, _, err = envs[0].GetKeyValuePair()
+		require.NoError(t, err)
+		require.Equal(t, "BITRISE_IPA_PATH", key)
+	}
+
+	t.Log("step without id")
+	{
+		envs := []envmanModels.EnvironmentItemModel{
+			envmanModels.EnvironmentItemModel{"BITRISE_IPA_PATH": "app.ipa"},
+		}
+
+		namespacedEnvs, err := NamespaceStepOutputs(envs, "")
+		require.NoError(t, err)
+		require.Equal(t, 0, len(namespacedEnvs))
+	}
+}
+
 func TestTimeToFormattedSeconds(t *testing.T) {
 	t.Log("formatted print rounds")
 	{
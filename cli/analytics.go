@@ -27,19 +27,28 @@ func redactStepInputs(environment map[string]string, inputs []models.Environment
 			continue
 		}
 
-		src := bytes.NewReader([]byte(inputValue))
-		dstBuf := new(bytes.Buffer)
-		secretFilterDst := filterwriter.New(secrets, dstBuf)
-
-		if _, err := io.Copy(secretFilterDst, src); err != nil {
-			return map[string]string{}, fmt.Errorf("failed to redact secrets, stream copy failed: %s", err)
-		}
-		if _, err := secretFilterDst.Flush(); err != nil {
-			return map[string]string{}, fmt.Errorf("failed to redact secrets, stream flush failed: %s", err)
+		redactedValue, err := redactSecrets(inputValue, secrets)
+		if err != nil {
+			return map[string]string{}, err
 		}
-
-		redactedStepInputs[inputKey] = dstBuf.String()
+		redactedStepInputs[inputKey] = redactedValue
 	}
 
 	return redactedStepInputs, nil
 }
+
+// redactSecrets replaces the secret values in the value.
+func redactSecrets(value string, secrets []string) (string, error) {
+	src := bytes.NewReader([]byte(value))
+	dstBuf := new(bytes.Buffer)
+	secretFilterDst := filterwriter.New(secrets, dstBuf)
+
+	if _, err := io.Copy(secretFilterDst, src); err != nil {
+		return "", fmt.Errorf("failed to redact secrets, stream copy failed: %s", err)
+	}
+	if _, err := secretFilterDst.Flush(); err != nil {
+		return "", fmt.Errorf("failed to redact secrets, stream flush failed: %s", err)
+	}
+
+	return dstBuf.String(), nil
+}
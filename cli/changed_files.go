@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/bitrise-io/bitrise/utils"
+)
+
+// changedFilesLister lists the changed files of the build on the first use,
+// and reuses the list for the later changed_files checks.
+type changedFilesLister struct {
+	listFn func() ([]string, error)
+
+	isListed bool
+	files    []string
+	err      error
+}
+
+func newChangedFilesLister(listFn func() ([]string, error)) *changedFilesLister {
+	return &changedFilesLister{listFn: listFn}
+}
+
+func (lister *changedFilesLister) list() ([]string, error) {
+	if !lister.isListed {
+		lister.files, lister.err = lister.listFn()
+		lister.isListed = true
+	}
+	return lister.files, lister.err
+}
+
+// relevantFiles returns the changed files passing the filter.
+func (lister *changedFilesLister) relevantFiles(filter models.ChangedFilesFilterModel) ([]string, error) {
+	files, err := lister.list()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine the changed files, error: %s", err)
+	}
+
+	relevantFiles, err := utils.FilterPathsWithGlobs(files, filter.Include, filter.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid changed_files filter, error: %s", err)
+	}
+	return relevantFiles, nil
+}
+
+// hasRelevantChanges reports whether the build changes any file passing the filter.
+// If the changed files can not be determined (e.g. not a git repository, shallow clone) every change is considered relevant.
+func (lister *changedFilesLister) hasRelevantChanges(filter models.ChangedFilesFilterModel) bool {
+	relevantFiles, err := lister.relevantFiles(filter)
+	if err != nil {
+		log.Warnf("Ignoring changed_files filter: %s", err)
+		return true
+	}
+	return len(relevantFiles) > 0
+}
@@ -131,6 +131,13 @@ func Run() {
 
 	app.Action = func(c *cli.Context) error {
 		pluginName, pluginArgs, isPlugin := plugins.ParseArgs(c.Args())
+		if !isPlugin {
+			var err error
+			pluginName, pluginArgs, isPlugin, err = plugins.ParseCommandArgs(c.Args())
+			if err != nil {
+				return fmt.Errorf("Failed to check plugin commands, error: %s", err)
+			}
+		}
 		if isPlugin {
 			plugin, found, err := plugins.LoadPlugin(pluginName)
 			if err != nil {
@@ -144,7 +151,7 @@ func Run() {
 				log.Fatalf("Setup failed, error: %s", err)
 			}
 
-			if err := plugins.RunPluginByCommand(plugin, pluginArgs); err != nil {
+			if _, err := plugins.RunPluginByCommand(plugin, pluginArgs); err != nil {
 				return fmt.Errorf("Failed to run plugin (%s), error: %s", pluginName, err)
 			}
 		} else {
@@ -13,11 +13,31 @@ const (
 	PushBranchKey     = "push-branch"
 	PRSourceBranchKey = "pr-source-branch"
 	PRTargetBranchKey = "pr-target-branch"
+	CommitMessageKey  = "commit-message"
+	PRLabelsKey       = "pr-labels"
+	DraftPRKey        = "draft-pr"
+	ChangedFilesKey   = "changed-files"
+	ExplainKey        = "explain"
+
+	HostKey          = "host"
+	PortKey          = "port"
+	WebhookSecretKey = "secret"
+	InsecureKey      = "insecure"
+	QueueSizeKey     = "queue-size"
+
+	AtKey       = "at"
+	InputKey    = "input"
+	PipelineKey = "pipeline"
 
 	IncludeWorkflowMetaKey = "include-workflow-meta"
 	ConfigKey              = "config"
 	InventoryKey           = "inventory"
 	OuputFormatKey         = "format"
+
+	ToKey     = "to"
+	DryRunKey = "dry-run"
+
+	StepKey = "step"
 )
 
 var (
@@ -44,6 +64,9 @@ var (
 				flInventory,
 				flInventoryBase64,
 				flFormat,
+				cli.BoolFlag{Name: DeepKey, Usage: "Resolve the referenced steps and validate the step inputs and env references."},
+				cli.BoolFlag{Name: StrictKey, Usage: "Report the keys, which are not defined by the bitrise config schema, as errors."},
+				cli.StringSliceFlag{Name: EnvKeyKey, Usage: "Env defined for every workflow by --deep validation, e.g. an env of the build machine. Can be specified multiple times."},
 			},
 		},
 		updateCommand,
@@ -62,8 +85,13 @@ var (
 				cli.StringFlag{Name: PRSourceBranchKey, Usage: "Git pull request source branch name."},
 				cli.StringFlag{Name: PRTargetBranchKey, Usage: "Git pull request target branch name."},
 				cli.StringFlag{Name: TagKey, Usage: "Git tag name."},
+				flCommitMessage,
+				flPRLabels,
+				flDraftPR,
+				flChangedFiles,
 
 				cli.StringFlag{Name: OuputFormatKey, Usage: "Output format. Accepted: json, yml."},
+				cli.BoolFlag{Name: ExplainKey, Usage: "Explain why each trigger map item matches or not. Accepted formats: raw, json, yml."},
 
 				// cli params used in CI mode
 				cli.StringFlag{Name: JSONParamsKey, Usage: "Specify command flags with json string-string hash."},
@@ -89,6 +117,7 @@ var (
 				flFormat,
 				flOutputPath,
 				flPretty,
+				cli.BoolFlag{Name: SchemaKey, Usage: "Export the JSON Schema of the bitrise config, instead of the config. Printed if no output path specified."},
 			},
 		},
 		{
@@ -160,6 +189,12 @@ var (
 			},
 		},
 		pluginCommand,
+		toolkitCommand,
+		runIfCommand,
+		serveTriggersCommand,
+		configCommand,
+		migrateCommand,
+		envCommand,
 		stepmanCommand,
 		envmanCommand,
 	}
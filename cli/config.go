@@ -0,0 +1,309 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/bitrise/models"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/urfave/cli"
+)
+
+var (
+	flConfigEditConfig = cli.StringFlag{Name: ConfigKey + ", " + configShortKey, Usage: "Path where the workflow config file is located."}
+	flConfigEditAt     = cli.IntFlag{Name: AtKey, Value: -1, Usage: "Position (0 based) in the list, appended to the end by default."}
+)
+
+var configCommand = cli.Command{
+	Name:  "config",
+	Usage: "Edits the bitrise.yml, keeping its comments and formatting, and compares bitrise configs.",
+	Subcommands: []cli.Command{
+		{
+			Name:      "add-step",
+			Usage:     "Adds a step to the workflow.",
+			ArgsUsage: "<workflow> <step-id@version>",
+			Action:    configEditAction(configAddStep),
+			Flags: []cli.Flag{
+				flConfigEditConfig,
+				flConfigEditAt,
+				cli.StringSliceFlag{Name: InputKey, Usage: "Step input as key=value, can be specified multiple times."},
+			},
+		},
+		{
+			Name:      "remove-step",
+			Usage:     "Removes a step, identified by its ID or by its position, from the workflow.",
+			ArgsUsage: "<workflow> [<step-id>]",
+			Action:    configEditAction(configRemoveStep),
+			Flags: []cli.Flag{
+				flConfigEditConfig,
+				cli.IntFlag{Name: AtKey, Value: -1, Usage: "Position (0 based) of the step to remove."},
+			},
+		},
+		{
+			Name:      "set-input",
+			Usage:     "Sets inputs of a step, identified by its ID or by its position.",
+			ArgsUsage: "<workflow> [<step-id>] <key>=<value>...",
+			Action:    configEditAction(configSetInput),
+			Flags: []cli.Flag{
+				flConfigEditConfig,
+				cli.IntFlag{Name: AtKey, Value: -1, Usage: "Position (0 based) of the step."},
+			},
+		},
+		{
+			Name:      "add-env",
+			Usage:     "Sets app envs, or workflow envs if the workflow is specified.",
+			ArgsUsage: "<key>=<value>...",
+			Action:    configEditAction(configAddEnv),
+			Flags: []cli.Flag{
+				flConfigEditConfig,
+				cli.StringFlag{Name: WorkflowKey, Usage: "Workflow to set the envs of."},
+			},
+		},
+		{
+			Name:      "add-workflow",
+			Usage:     "Adds an empty workflow.",
+			ArgsUsage: "<workflow>",
+			Action:    configEditAction(configAddWorkflow),
+			Flags: []cli.Flag{
+				flConfigEditConfig,
+			},
+		},
+		{
+			Name:   "add-trigger",
+			Usage:  "Adds a trigger map item.",
+			Action: configEditAction(configAddTrigger),
+			Flags: []cli.Flag{
+				flConfigEditConfig,
+				flConfigEditAt,
+				cli.StringFlag{Name: WorkflowKey, Usage: "Workflow to trigger."},
+				cli.StringFlag{Name: PipelineKey, Usage: "Pipeline to trigger."},
+				cli.StringFlag{Name: PushBranchKey, Usage: "Git push branch pattern."},
+				cli.StringFlag{Name: PRSourceBranchKey, Usage: "Git pull request source branch pattern."},
+				cli.StringFlag{Name: PRTargetBranchKey, Usage: "Git pull request target branch pattern."},
+				cli.StringFlag{Name: TagKey, Usage: "Git tag pattern."},
+			},
+		},
+		{
+			Name:      "rename-workflow",
+			Usage:     "Renames a workflow, and updates the before_run, after_run, stage and trigger map references to it.",
+			ArgsUsage: "<workflow> <new-workflow>",
+			Action:    configEditAction(configRenameWorkflow),
+			Flags: []cli.Flag{
+				flConfigEditConfig,
+			},
+		},
+		configDiffCommand,
+	},
+}
+
+// configEditAction reads the config, applies the edit, validates the edited config and saves it,
+// keeping the comments and the formatting of the config.
+func configEditAction(editFn func(c *cli.Context, config *models.BitriseDataModel) (string, error)) func(c *cli.Context) error {
+	return func(c *cli.Context) error {
+		if err := editConfig(c, editFn); err != nil {
+			log.Errorf("Failed to edit config, error: %s", err)
+			os.Exit(1)
+		}
+		return nil
+	}
+}
+
+func editConfig(c *cli.Context, editFn func(c *cli.Context, config *models.BitriseDataModel) (string, error)) error {
+	bitriseConfigPath, err := GetBitriseConfigFilePath(c.String(ConfigKey))
+	if err != nil {
+		return err
+	}
+
+	configContent, err := fileutil.ReadBytesFromFile(bitriseConfigPath)
+	if err != nil {
+		return err
+	}
+	config, _, err := bitrise.ConfigModelFromYAMLBytesWithoutDefaults(configContent)
+	if err != nil {
+		return fmt.Errorf("invalid config (%s), error: %s", bitriseConfigPath, err)
+	}
+
+	summary, err := editFn(c, &config)
+	if err != nil {
+		return err
+	}
+
+	warnings, err := config.Validate()
+	for _, warning := range warnings {
+		log.Warnf("warning: %s", warning)
+	}
+	if err != nil {
+		return fmt.Errorf("the edited config is invalid, error: %s", err)
+	}
+
+	if err := bitrise.SaveConfigToFilePreservingFormat(bitriseConfigPath, configContent, config); err != nil {
+		return err
+	}
+
+	log.Donef("%s", summary)
+	return nil
+}
+
+func parseKeyValueArgs(args []string) ([]envmanModels.EnvironmentItemModel, error) {
+	items := []envmanModels.EnvironmentItemModel{}
+	for _, arg := range args {
+		split := strings.SplitN(arg, "=", 2)
+		if len(split) != 2 || split[0] == "" {
+			return nil, fmt.Errorf("invalid key=value argument: %s", arg)
+		}
+		items = append(items, envmanModels.EnvironmentItemModel{split[0]: split[1]})
+	}
+	return items, nil
+}
+
+// stepPositionFromArgs returns the position of the step given by the --at flag or by the first argument,
+// and the remaining arguments.
+func stepPositionFromArgs(c *cli.Context, config *models.BitriseDataModel, workflowID string, args []string) (int, []string, error) {
+	if c.IsSet(AtKey) {
+		return c.Int(AtKey), args, nil
+	}
+	if len(args) == 0 {
+		return -1, nil, errors.New("the step should be specified by its ID or by its position (--at)")
+	}
+	position, err := config.StepPosition(workflowID, args[0])
+	return position, args[1:], err
+}
+
+func configAddStep(c *cli.Context, config *models.BitriseDataModel) (string, error) {
+	if len(c.Args()) != 2 {
+		return "", errors.New("the workflow and the step should be provided, e.g. bitrise config add-step primary script@1")
+	}
+	workflowID, stepID := c.Args()[0], c.Args()[1]
+
+	inputs, err := parseKeyValueArgs(c.StringSlice(InputKey))
+	if err != nil {
+		return "", err
+	}
+	if err := config.AddStep(workflowID, stepID, c.Int(AtKey), inputs); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Step (%s) added to workflow (%s)", stepID, workflowID), nil
+}
+
+func configRemoveStep(c *cli.Context, config *models.BitriseDataModel) (string, error) {
+	if len(c.Args()) == 0 {
+		return "", errors.New("the workflow should be provided, e.g. bitrise config remove-step primary script")
+	}
+	workflowID := c.Args()[0]
+
+	position, args, err := stepPositionFromArgs(c, config, workflowID, c.Args()[1:])
+	if err != nil {
+		return "", err
+	}
+	if len(args) > 0 {
+		return "", fmt.Errorf("unexpected arguments: %s", strings.Join(args, " "))
+	}
+	if err := config.RemoveStep(workflowID, position); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Step at position %d removed from workflow (%s)", position, workflowID), nil
+}
+
+func configSetInput(c *cli.Context, config *models.BitriseDataModel) (string, error) {
+	if len(c.Args()) == 0 {
+		return "", errors.New("the workflow should be provided, e.g. bitrise config set-input primary script content='echo hi'")
+	}
+	workflowID := c.Args()[0]
+
+	position, args, err := stepPositionFromArgs(c, config, workflowID, c.Args()[1:])
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "", errors.New("no input (key=value) provided")
+	}
+	inputs, err := parseKeyValueArgs(args)
+	if err != nil {
+		return "", err
+	}
+
+	for _, input := range inputs {
+		key, value, err := input.GetKeyValuePair()
+		if err != nil {
+			return "", err
+		}
+		if err := config.SetStepInput(workflowID, position, key, value); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("Inputs of the step at position %d of workflow (%s) set", position, workflowID), nil
+}
+
+func configAddEnv(c *cli.Context, config *models.BitriseDataModel) (string, error) {
+	if len(c.Args()) == 0 {
+		return "", errors.New("no env (key=value) provided")
+	}
+	envs, err := parseKeyValueArgs(c.Args())
+	if err != nil {
+		return "", err
+	}
+
+	workflowID := c.String(WorkflowKey)
+	for _, env := range envs {
+		key, value, err := env.GetKeyValuePair()
+		if err != nil {
+			return "", err
+		}
+		if err := config.AddEnv(workflowID, key, value); err != nil {
+			return "", err
+		}
+	}
+
+	if workflowID != "" {
+		return fmt.Sprintf("Envs of workflow (%s) set", workflowID), nil
+	}
+	return "App envs set", nil
+}
+
+func configAddWorkflow(c *cli.Context, config *models.BitriseDataModel) (string, error) {
+	if len(c.Args()) != 1 {
+		return "", errors.New("the workflow should be provided as the only argument, e.g. bitrise config add-workflow deploy")
+	}
+	workflowID := c.Args()[0]
+
+	if err := config.AddWorkflow(workflowID); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Workflow (%s) added", workflowID), nil
+}
+
+func configAddTrigger(c *cli.Context, config *models.BitriseDataModel) (string, error) {
+	triggerItem := models.TriggerMapItemModel{
+		PushBranch:              c.String(PushBranchKey),
+		PullRequestSourceBranch: c.String(PRSourceBranchKey),
+		PullRequestTargetBranch: c.String(PRTargetBranchKey),
+		Tag:                     c.String(TagKey),
+		WorkflowID:              c.String(WorkflowKey),
+		PipelineID:              c.String(PipelineKey),
+	}
+	if err := triggerItem.Validate(); err != nil {
+		return "", err
+	}
+
+	if err := config.AddTriggerMapItem(triggerItem, c.Int(AtKey)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Trigger map item (%s) added", triggerItem.String(true)), nil
+}
+
+func configRenameWorkflow(c *cli.Context, config *models.BitriseDataModel) (string, error) {
+	if len(c.Args()) != 2 {
+		return "", errors.New("the workflow and its new name should be provided, e.g. bitrise config rename-workflow primary test")
+	}
+	workflowID, newWorkflowID := c.Args()[0], c.Args()[1]
+
+	if err := config.RenameWorkflow(workflowID, newWorkflowID); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Workflow (%s) renamed to (%s)", workflowID, newWorkflowID), nil
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/bitrise-io/bitrise/output"
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/urfave/cli"
+)
+
+var configDiffCommand = cli.Command{
+	Name:      "diff",
+	Usage:     "Reports the semantic changes between two bitrise configs.",
+	ArgsUsage: "<old-config> <new-config>",
+	Action: func(c *cli.Context) error {
+		if err := configDiff(c); err != nil {
+			log.Errorf("Failed to diff configs, error: %s", err)
+			os.Exit(1)
+		}
+		return nil
+	},
+	Flags: []cli.Flag{
+		cli.StringFlag{Name: OuputFormatKey, Usage: "Output format. Accepted: raw (default), json."},
+	},
+}
+
+// configChangesModel is the result of config diff.
+type configChangesModel struct {
+	Old     string                     `json:"old"`
+	New     string                     `json:"new"`
+	Changes []models.ConfigChangeModel `json:"changes"`
+}
+
+func readDiffConfig(pth string) (models.BitriseDataModel, error) {
+	config, warnings, err := bitrise.ReadBitriseConfig(pth)
+	for _, warning := range warnings {
+		log.Warnf("warning: %s: %s", pth, warning)
+	}
+	if err != nil {
+		return models.BitriseDataModel{}, fmt.Errorf("invalid config (%s), error: %s", pth, err)
+	}
+	return config, nil
+}
+
+func printRawConfigChanges(changes configChangesModel) {
+	if len(changes.Changes) == 0 {
+		log.Donef("No semantic changes between %s and %s", changes.Old, changes.New)
+		return
+	}
+
+	log.Infof("Changes between %s and %s:", changes.Old, changes.New)
+	for _, change := range changes.Changes {
+		switch change.Type {
+		case models.ConfigChangeTypeEnvAdded, models.ConfigChangeTypeWorkflowAdded, models.ConfigChangeTypeStepAdded,
+			models.ConfigChangeTypeInputAdded, models.ConfigChangeTypeTriggerAdded:
+			log.Printf("%s", colorstring.Green("+ "+change.String()))
+		case models.ConfigChangeTypeEnvRemoved, models.ConfigChangeTypeWorkflowRemoved, models.ConfigChangeTypeStepRemoved,
+			models.ConfigChangeTypeInputRemoved, models.ConfigChangeTypeTriggerRemoved:
+			log.Printf("%s", colorstring.Red("- "+change.String()))
+		default:
+			log.Printf("%s", colorstring.Yellow("~ "+change.String()))
+		}
+	}
+}
+
+func configDiff(c *cli.Context) error {
+	if len(c.Args()) != 2 {
+		return errors.New("the old and the new config should be provided, e.g. bitrise config diff bitrise.old.yml bitrise.yml")
+	}
+	format := c.String(OuputFormatKey)
+	if format == "" {
+		format = output.FormatRaw
+	}
+	if format != output.FormatRaw && format != output.FormatJSON {
+		return fmt.Errorf("invalid format: %s", format)
+	}
+
+	oldConfigPath, newConfigPath := c.Args()[0], c.Args()[1]
+	oldConfig, err := readDiffConfig(oldConfigPath)
+	if err != nil {
+		return err
+	}
+	newConfig, err := readDiffConfig(newConfigPath)
+	if err != nil {
+		return err
+	}
+
+	changes, err := models.DiffConfigs(oldConfig, newConfig)
+	if err != nil {
+		return err
+	}
+
+	configChanges := configChangesModel{Old: oldConfigPath, New: newConfigPath, Changes: changes}
+	if format == output.FormatRaw {
+		printRawConfigChanges(configChanges)
+	} else {
+		output.Print(configChanges, format)
+	}
+	return nil
+}
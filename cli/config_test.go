@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+)
+
+func newConfigEditContext(t *testing.T, commandName string, args ...string) *cli.Context {
+	for _, command := range configCommand.Subcommands {
+		if command.Name != commandName {
+			continue
+		}
+
+		set := flag.NewFlagSet(commandName, flag.ContinueOnError)
+		for _, fl := range command.Flags {
+			fl.Apply(set)
+		}
+		require.NoError(t, set.Parse(args))
+		return cli.NewContext(cli.NewApp(), set, nil)
+	}
+	require.FailNow(t, "unknown config command: "+commandName)
+	return nil
+}
+
+func TestEditConfig(t *testing.T) {
+	tmpDir, err := pathutil.NormalizedOSTempDirPath("config_edit_test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tmpDir))
+	}()
+	configPth := filepath.Join(tmpDir, "bitrise.yml")
+
+	configYAML := `format_version: "11"
+default_step_lib_source: https://github.com/bitrise-io/bitrise-steplib.git
+
+workflows:
+  primary:
+    steps:
+    - script@1: # first script
+        title: First
+    - script@1:
+        title: Second # keep
+
+  secondary:
+    steps:
+    - deploy@2: {}
+`
+	require.NoError(t, ioutil.WriteFile(configPth, []byte(configYAML), 0644))
+
+	t.Log("remove-step matches the steps of the same ID by position")
+	{
+		require.NoError(t, editConfig(newConfigEditContext(t, "remove-step", "--config", configPth, "--at", "0", "primary"), configRemoveStep))
+
+		content, err := ioutil.ReadFile(configPth)
+		require.NoError(t, err)
+		require.Equal(t, `format_version: "11"
+default_step_lib_source: https://github.com/bitrise-io/bitrise-steplib.git
+
+workflows:
+  primary:
+    steps:
+    - script@1:
+        title: Second # keep
+
+  secondary:
+    steps:
+    - deploy@2: {}
+`, string(content))
+	}
+
+	t.Log("add-step keeps the blank lines and the list indentation")
+	{
+		require.NoError(t, editConfig(newConfigEditContext(t, "add-step", "--config", configPth, "--input", "content=echo hi", "secondary", "script@1"), configAddStep))
+
+		content, err := ioutil.ReadFile(configPth)
+		require.NoError(t, err)
+		require.Equal(t, `format_version: "11"
+default_step_lib_source: https://github.com/bitrise-io/bitrise-steplib.git
+
+workflows:
+  primary:
+    steps:
+    - script@1:
+        title: Second # keep
+
+  secondary:
+    steps:
+    - deploy@2: {}
+    - script@1:
+        inputs:
+        - content: echo hi
+`, string(content))
+	}
+}
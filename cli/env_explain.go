@@ -0,0 +1,633 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/bitrise-io/bitrise/output"
+	"github.com/bitrise-io/bitrise/tools"
+	"github.com/bitrise-io/bitrise/tools/filterwriter"
+	"github.com/bitrise-io/envman/env"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pointers"
+	"github.com/bitrise-io/go-utils/sliceutil"
+	"github.com/urfave/cli"
+)
+
+const (
+	envSourceBitriseCLI = "bitrise CLI"
+	envSourceSecrets    = "secrets"
+	envSourceApp        = "app"
+	envSourceProcess    = "process environment"
+)
+
+var envCommand = cli.Command{
+	Name:  "env",
+	Usage: "Explains the environment of the workflow's steps.",
+	Subcommands: []cli.Command{
+		{
+			Name:      "explain",
+			Usage:     "Shows the final value of the envs, and every layer (secrets, app and workflow envs, step inputs and outputs) which set or overrode them, without running the workflow.",
+			ArgsUsage: "<workflow> [<key>]",
+			Action: func(c *cli.Context) error {
+				if err := envExplain(c); err != nil {
+					log.Errorf("Failed to explain envs, error: %s", err)
+					os.Exit(1)
+				}
+				return nil
+			},
+			Flags: []cli.Flag{
+				flConfig,
+				flInventory,
+				cli.IntFlag{Name: StepKey, Value: -1, Usage: "Position (0 based) of the step in the build (counting the steps of the before_run and after_run workflows too), the envs at the end of the build are explained by default."},
+				cli.BoolFlag{Name: DeepKey, Usage: "Resolve the referenced steps, to explain the step input defaults and the step outputs."},
+				cli.StringFlag{Name: OuputFormatKey, Usage: "Output format. Accepted: raw (default), json."},
+			},
+		},
+	},
+}
+
+// envExpansionModel is an env referenced by an expanded env value.
+type envExpansionModel struct {
+	Key       string              `json:"key"`
+	Value     string              `json:"value"`
+	Source    string              `json:"source"`
+	Expansion []envExpansionModel `json:"expansion,omitempty"`
+}
+
+// envLayerModel is a declaration of the env by a layer of the build's environment.
+type envLayerModel struct {
+	Source string `json:"source"`
+	// Action is set, unset, skip (the value is empty and skip_if_empty is true),
+	// or export, drop and restore (the value before the workflow) at the end of an isolated workflow (isolate_envs: true)
+	Action        string              `json:"action"`
+	Value         string              `json:"value,omitempty"`
+	ExpandedValue string              `json:"expanded_value,omitempty"`
+	Expansion     []envExpansionModel `json:"expansion,omitempty"`
+}
+
+// envExplanationModel is the final value of the env, and the layers which declared it.
+type envExplanationModel struct {
+	Key    string          `json:"key"`
+	Value  string          `json:"value"`
+	IsSet  bool            `json:"is_set"`
+	Layers []envLayerModel `json:"layers"`
+}
+
+// envExplainModel is the result of env explain.
+type envExplainModel struct {
+	Workflow string                `json:"workflow"`
+	At       string                `json:"at"`
+	Envs     []envExplanationModel `json:"envs"`
+	Warnings []string              `json:"warnings,omitempty"`
+}
+
+// envDeclaration is an env declared by a layer of the build's environment.
+type envDeclaration struct {
+	source string
+	env    envmanModels.EnvironmentItemModel
+	// isolation marks the start or the end of an isolated workflow's envs instead of declaring an env
+	isolation *envIsolation
+}
+
+type envIsolation struct {
+	isEnd      bool
+	exportKeys []string
+}
+
+type envExplainParams struct {
+	config     models.BitriseDataModel
+	inventory  []envmanModels.EnvironmentItemModel
+	workflowID string
+	// stepPosition is the position of the step in the build, -1 for the end of the build
+	stepPosition int
+	// resolveStep resolves the step definitions, nil if the step definitions are not resolved
+	resolveStep stepInfoResolver
+}
+
+func newEnvDeclarations(source string, envs []envmanModels.EnvironmentItemModel) []envDeclaration {
+	declarations := []envDeclaration{}
+	for _, env := range envs {
+		declarations = append(declarations, envDeclaration{source: source, env: env})
+	}
+	return declarations
+}
+
+// literalEnvDeclaration returns an env declaration, which value is not expanded.
+func literalEnvDeclaration(source, key, value string) envDeclaration {
+	return envDeclaration{source: source, env: envmanModels.EnvironmentItemModel{
+		key:                     value,
+		envmanModels.OptionsKey: envmanModels.EnvironmentItemOptionsModel{IsExpand: pointers.NewBoolPtr(false)},
+	}}
+}
+
+// buildEnvDeclarations returns the env declarations in the order the workflow run declares them:
+// secrets, app envs, the triggered workflow's envs (unless isolated), then for every workflow of the run chain its envs and its steps' outputs.
+// If the step position is given, the declarations end with the step's inputs.
+// Returns the explained point of the build as well.
+func buildEnvDeclarations(params envExplainParams) ([]envDeclaration, string, []string, error) {
+	config := params.config
+	workflow, found := config.Workflows[params.workflowID]
+	if !found {
+		return nil, "", nil, fmt.Errorf("workflow (%s) does not exist", params.workflowID)
+	}
+	title := workflow.Title
+	if title == "" {
+		title = params.workflowID
+	}
+
+	warnings := []string{}
+	declarations := []envDeclaration{
+		{source: envSourceBitriseCLI, env: envmanModels.EnvironmentItemModel{"BITRISE_TRIGGERED_WORKFLOW_ID": params.workflowID}},
+		{source: envSourceBitriseCLI, env: envmanModels.EnvironmentItemModel{"BITRISE_TRIGGERED_WORKFLOW_TITLE": title}},
+	}
+	declarations = append(declarations, newEnvDeclarations(envSourceSecrets, params.inventory)...)
+	declarations = append(declarations, newEnvDeclarations(envSourceApp, config.App.Environments)...)
+	declarations = append(declarations, newEnvDeclarations(fmt.Sprintf("triggered workflow (%s)", params.workflowID), triggeredWorkflowEnvironments(workflow))...)
+
+	position := 0
+	for _, chainWorkflowID := range workflowRunChain(config, params.workflowID, []string{}) {
+		chainWorkflow := config.Workflows[chainWorkflowID]
+		workflowSource := fmt.Sprintf("workflow (%s)", chainWorkflowID)
+		if chainWorkflow.IsolateEnvs {
+			declarations = append(declarations, envDeclaration{source: workflowSource, isolation: &envIsolation{}})
+		}
+		declarations = append(declarations, newEnvDeclarations(workflowSource, chainWorkflow.Environments)...)
+
+		for _, stepListItem := range chainWorkflow.Steps {
+			compositeStepID, workflowStep, err := models.GetStepIDStepDataPair(stepListItem)
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("workflow (%s): %s", chainWorkflowID, err)
+			}
+			stepSource := fmt.Sprintf("step #%d (%s)", position, compositeStepID)
+
+			mergedStep := copyStepEnvs(workflowStep)
+			if params.resolveStep != nil {
+				stepIDData, err := models.CreateStepIDDataFromString(compositeStepID, config.DefaultStepLibSource)
+				if err != nil {
+					return nil, "", nil, fmt.Errorf("workflow (%s) %s: %s", chainWorkflowID, stepSource, err)
+				}
+
+				if info, isDefined, err := params.resolveStep(stepIDData); err != nil {
+					warnings = append(warnings, fmt.Sprintf("%s: failed to resolve step definition: %s", stepSource, err))
+				} else if isDefined {
+					if mergedStep, err = models.MergeStepWith(copyStepEnvs(models.NewStepModel(info.Step)), copyStepEnvs(workflowStep)); err != nil {
+						return nil, "", nil, fmt.Errorf("workflow (%s) %s: %s", chainWorkflowID, stepSource, err)
+					}
+				}
+			}
+
+			if position == params.stepPosition {
+				declarations = append(declarations,
+					literalEnvDeclaration(envSourceBitriseCLI, "BITRISE_STEP_SOURCE_DIR", configs.BitriseWorkStepsDirPath),
+					literalEnvDeclaration(envSourceBitriseCLI, configs.BitrisePerStepTestResultDirEnvKey, "<test result dir of the step>"))
+
+				workflowInputKeys := envKeys(workflowStep.Inputs)
+				for _, input := range mergedStep.Inputs {
+					source := stepSource + " input"
+					if key, _, err := input.GetKeyValuePair(); err == nil && !sliceutil.IsStringInSlice(key, workflowInputKeys) {
+						source = stepSource + " input default"
+					}
+					declarations = append(declarations, envDeclaration{source: source, env: input})
+				}
+				return declarations, fmt.Sprintf("%s of workflow (%s)", stepSource, chainWorkflowID), warnings, nil
+			}
+
+			for _, output := range mergedStep.Outputs {
+				key, alias, err := output.GetKeyValuePair()
+				if err != nil {
+					return nil, "", nil, fmt.Errorf("workflow (%s) %s: %s", chainWorkflowID, stepSource, err)
+				}
+				source := stepSource + " output"
+				outputKey := key
+				if alias != "" {
+					source = fmt.Sprintf("%s (%s)", source, key)
+					key = alias
+				}
+				declarations = append(declarations, literalEnvDeclaration(source, key, fmt.Sprintf("<output of %s>", stepSource)))
+				if mergedStep.ID != nil {
+					declarations = append(declarations, literalEnvDeclaration(stepSource+" output", models.StepOutputEnvKey(*mergedStep.ID, outputKey), fmt.Sprintf("<output of %s>", stepSource)))
+				}
+			}
+
+			position++
+		}
+
+		if chainWorkflow.IsolateEnvs {
+			declarations = append(declarations, envDeclaration{source: workflowSource, isolation: &envIsolation{isEnd: true, exportKeys: chainWorkflow.ExportEnvs}})
+		}
+	}
+
+	if params.stepPosition >= 0 {
+		return nil, "", nil, fmt.Errorf("step #%d does not exist, the build of workflow (%s) has %d steps", params.stepPosition, params.workflowID, position)
+	}
+	return declarations, "the end of the build", warnings, nil
+}
+
+// envExplainer replays the env declarations the way envman declares them,
+// and records the layers and the referenced envs of every env.
+type envExplainer struct {
+	secrets []string
+
+	envs       map[string]string
+	sources    map[string]string
+	expansions map[string][]envExpansionModel
+
+	keys         []string
+	explanations map[string]*envExplanationModel
+
+	// isolated is the state before the isolated workflow, which is running
+	isolated *envExplainerState
+}
+
+// envExplainerState is the state of the explained envs before an isolated workflow,
+// and the envs declared since the workflow started.
+type envExplainerState struct {
+	envs         map[string]string
+	sources      map[string]string
+	expansions   map[string][]envExpansionModel
+	declaredKeys []string
+}
+
+// GetEnvironment returns the current envs, the envs are declared on it.
+func (e *envExplainer) GetEnvironment() map[string]string {
+	envs := map[string]string{}
+	for key, value := range e.envs {
+		envs[key] = value
+	}
+	return envs
+}
+
+func (e *envExplainer) redact(value string) string {
+	redacted, err := redactSecrets(value, e.secrets)
+	if err != nil {
+		return filterwriter.RedactStr
+	}
+	return redacted
+}
+
+func (e *envExplainer) source(key string) string {
+	if source, ok := e.sources[key]; ok {
+		return source
+	}
+	if _, ok := e.envs[key]; ok {
+		return envSourceProcess
+	}
+	return "not set"
+}
+
+func (e *envExplainer) expansion(value string) []envExpansionModel {
+	expansion := []envExpansionModel{}
+	referenced := map[string]bool{}
+	os.Expand(value, func(key string) string {
+		if !referenced[key] {
+			referenced[key] = true
+			expansion = append(expansion, envExpansionModel{
+				Key:       key,
+				Value:     e.redact(e.envs[key]),
+				Source:    e.source(key),
+				Expansion: e.expansions[key],
+			})
+		}
+		return ""
+	})
+	return expansion
+}
+
+func (e *envExplainer) declare(declaration envDeclaration, command env.Command) error {
+	_, value, err := declaration.env.GetKeyValuePair()
+	if err != nil {
+		return err
+	}
+	options, err := declaration.env.GetOptions()
+	if err != nil {
+		return err
+	}
+
+	key := command.Variable.Key
+	if e.isolated != nil && !sliceutil.IsStringInSlice(key, e.isolated.declaredKeys) {
+		e.isolated.declaredKeys = append(e.isolated.declaredKeys, key)
+	}
+
+	layer := envLayerModel{Source: declaration.source, Value: e.redact(value)}
+	switch command.Action {
+	case env.SetAction:
+		layer.Action = "set"
+		if options.IsExpand != nil && *options.IsExpand {
+			layer.Expansion = e.expansion(value)
+		}
+		if command.Variable.Value != value {
+			layer.ExpandedValue = e.redact(command.Variable.Value)
+		}
+
+		e.envs[key] = command.Variable.Value
+		e.sources[key] = declaration.source
+		e.expansions[key] = layer.Expansion
+	case env.UnsetAction:
+		layer.Action = "unset"
+
+		delete(e.envs, key)
+		e.sources[key] = "unset by " + declaration.source
+		delete(e.expansions, key)
+	case env.SkipAction:
+		layer.Action = "skip"
+	}
+
+	e.addLayer(key, layer)
+	return nil
+}
+
+func (e *envExplainer) addLayer(key string, layer envLayerModel) {
+	explanation, found := e.explanations[key]
+	if !found {
+		explanation = &envExplanationModel{Key: key, Layers: []envLayerModel{}}
+		e.explanations[key] = explanation
+		e.keys = append(e.keys, key)
+	}
+	explanation.Layers = append(explanation.Layers, layer)
+}
+
+// declareAll declares the envs the way envman declares them, on the current envs.
+func (e *envExplainer) declareAll(declarations []envDeclaration) error {
+	// the declarations are normalized as prepareStepEnvironment does, on copies as normalizing modifies the env items in place
+	envs := []envmanModels.EnvironmentItemModel{}
+	for idx, declaration := range declarations {
+		declaredEnv := envmanModels.EnvironmentItemModel{}
+		for key, value := range declaration.env {
+			declaredEnv[key] = value
+		}
+		if err := declaredEnv.Normalize(); err != nil {
+			return fmt.Errorf("%s: %s", declaration.source, err)
+		}
+		if err := declaredEnv.FillMissingDefaults(); err != nil {
+			return fmt.Errorf("%s: %s", declaration.source, err)
+		}
+		declarations[idx].env = declaredEnv
+		envs = append(envs, declaredEnv)
+	}
+
+	sideEffects, err := env.GetDeclarationsSideEffects(envs, e)
+	if err != nil {
+		return err
+	}
+
+	for idx, command := range sideEffects.CommandHistory {
+		if err := e.declare(declarations[idx], command); err != nil {
+			return fmt.Errorf("%s: %s", declarations[idx].source, err)
+		}
+	}
+	return nil
+}
+
+// isolate starts or ends an isolated workflow, as isolateWorkflowEnvironment does:
+// at the end of the workflow the envs declared by it are dropped or restored to their values before the workflow,
+// except the exported ones.
+func (e *envExplainer) isolate(source string, isolation envIsolation) {
+	if !isolation.isEnd {
+		e.isolated = &envExplainerState{
+			envs:         e.GetEnvironment(),
+			sources:      map[string]string{},
+			expansions:   map[string][]envExpansionModel{},
+			declaredKeys: []string{},
+		}
+		for key, value := range e.sources {
+			e.isolated.sources[key] = value
+		}
+		for key, value := range e.expansions {
+			e.isolated.expansions[key] = value
+		}
+		return
+	}
+	if e.isolated == nil {
+		return
+	}
+
+	before := e.isolated
+	e.isolated = nil
+	for _, key := range before.declaredKeys {
+		value, isSet := e.envs[key]
+		if sliceutil.IsStringInSlice(key, isolation.exportKeys) {
+			e.addLayer(key, envLayerModel{Source: source + " export_envs", Action: "export", Value: e.redact(value)})
+			continue
+		}
+
+		beforeValue, wasSet := before.envs[key]
+		if beforeValue == value && wasSet == isSet {
+			continue
+		}
+
+		if !wasSet {
+			delete(e.envs, key)
+			e.sources[key] = "dropped by " + source
+			delete(e.expansions, key)
+			e.addLayer(key, envLayerModel{Source: source + " isolate_envs", Action: "drop"})
+			continue
+		}
+
+		e.envs[key] = beforeValue
+		if beforeSource, ok := before.sources[key]; ok {
+			e.sources[key] = beforeSource
+		} else {
+			delete(e.sources, key)
+		}
+		e.expansions[key] = before.expansions[key]
+		e.addLayer(key, envLayerModel{Source: source + " isolate_envs", Action: "restore", Value: e.redact(beforeValue)})
+	}
+}
+
+// explainEnvs replays the env declarations on the initial environment,
+// and returns the final value of every declared env with the layers which declared it.
+func explainEnvs(declarations []envDeclaration, envSource env.EnvironmentSource, secrets []string) ([]envExplanationModel, map[string]string, error) {
+	explainer := envExplainer{
+		secrets:      secrets,
+		envs:         envSource.GetEnvironment(),
+		sources:      map[string]string{},
+		expansions:   map[string][]envExpansionModel{},
+		keys:         []string{},
+		explanations: map[string]*envExplanationModel{},
+	}
+
+	start := 0
+	for idx, declaration := range declarations {
+		if declaration.isolation == nil {
+			continue
+		}
+		if err := explainer.declareAll(declarations[start:idx]); err != nil {
+			return nil, nil, err
+		}
+		explainer.isolate(declaration.source, *declaration.isolation)
+		start = idx + 1
+	}
+	if err := explainer.declareAll(declarations[start:]); err != nil {
+		return nil, nil, err
+	}
+
+	explanations := []envExplanationModel{}
+	for _, key := range explainer.keys {
+		explanation := *explainer.explanations[key]
+		value, isSet := explainer.envs[key]
+		explanation.Value, explanation.IsSet = explainer.redact(value), isSet
+		explanations = append(explanations, explanation)
+	}
+	return explanations, explainer.envs, nil
+}
+
+func explainWorkflowEnvs(params envExplainParams, envSource env.EnvironmentSource, key string) (envExplainModel, error) {
+	declarations, at, warnings, err := buildEnvDeclarations(params)
+	if err != nil {
+		return envExplainModel{}, err
+	}
+
+	secrets := tools.GetSecretValues(params.inventory)
+	explanations, finalEnvs, err := explainEnvs(declarations, envSource, secrets)
+	if err != nil {
+		return envExplainModel{}, err
+	}
+
+	if key != "" {
+		filtered := []envExplanationModel{}
+		for _, explanation := range explanations {
+			if explanation.Key == key {
+				filtered = append(filtered, explanation)
+			}
+		}
+		if len(filtered) == 0 {
+			value, isSet := finalEnvs[key]
+			if !isSet {
+				return envExplainModel{}, fmt.Errorf("env (%s) is not set at %s", key, at)
+			}
+			redacted, err := redactSecrets(value, secrets)
+			if err != nil {
+				return envExplainModel{}, err
+			}
+			// set by the process environment only
+			filtered = append(filtered, envExplanationModel{Key: key, Value: redacted, IsSet: true, Layers: []envLayerModel{}})
+		}
+		explanations = filtered
+	}
+
+	return envExplainModel{Workflow: params.workflowID, At: at, Envs: explanations, Warnings: warnings}, nil
+}
+
+func printRawEnvExpansion(expansion []envExpansionModel, indent string) {
+	for _, referenced := range expansion {
+		log.Printf("%s$%s = %q (%s)", indent, referenced.Key, referenced.Value, referenced.Source)
+		printRawEnvExpansion(referenced.Expansion, indent+"  ")
+	}
+}
+
+func printRawEnvExplanation(explanation envExplainModel) {
+	for _, warning := range explanation.Warnings {
+		log.Warnf("warning: %s", warning)
+	}
+	log.Infof("Envs of workflow (%s) at %s:", explanation.Workflow, explanation.At)
+
+	for _, envExplanation := range explanation.Envs {
+		fmt.Println()
+		if envExplanation.IsSet {
+			log.Printf("%s = %q", colorstring.Green(envExplanation.Key), envExplanation.Value)
+		} else {
+			log.Printf("%s is not set", colorstring.Yellow(envExplanation.Key))
+		}
+		if len(envExplanation.Layers) == 0 {
+			log.Printf("  set by the %s", envSourceProcess)
+		}
+
+		isDeclared := false
+		for _, layer := range envExplanation.Layers {
+			verb := "set by"
+			if isDeclared {
+				verb = "overridden by"
+			}
+			switch layer.Action {
+			case "unset":
+				log.Printf("  unset by %s", layer.Source)
+				isDeclared = false
+				continue
+			case "skip":
+				log.Printf("  skipped (empty value) by %s", layer.Source)
+				continue
+			case "export":
+				log.Printf("  exported by %s: %q", layer.Source, layer.Value)
+				continue
+			case "drop":
+				log.Printf("  dropped by %s", layer.Source)
+				isDeclared = false
+				continue
+			case "restore":
+				log.Printf("  restored by %s: %q", layer.Source, layer.Value)
+				isDeclared = true
+				continue
+			}
+			isDeclared = true
+
+			if layer.ExpandedValue != "" {
+				log.Printf("  %s %s: %q -> %q", verb, layer.Source, layer.Value, layer.ExpandedValue)
+			} else {
+				log.Printf("  %s %s: %q", verb, layer.Source, layer.Value)
+			}
+			printRawEnvExpansion(layer.Expansion, "    ")
+		}
+	}
+}
+
+func envExplain(c *cli.Context) error {
+	if len(c.Args()) == 0 || len(c.Args()) > 2 {
+		return errors.New("the workflow should be provided, e.g. bitrise env explain primary BITRISE_PROJECT_PATH")
+	}
+	workflowID, key := c.Args()[0], ""
+	if len(c.Args()) == 2 {
+		key = c.Args()[1]
+	}
+	format := c.String(OuputFormatKey)
+	if format == "" {
+		format = output.FormatRaw
+	}
+	if format != output.FormatRaw && format != output.FormatJSON {
+		return fmt.Errorf("invalid format: %s", format)
+	}
+
+	inventory, err := CreateInventoryFromCLIParams("", c.String(InventoryKey))
+	if err != nil {
+		return fmt.Errorf("failed to create inventory, error: %s", err)
+	}
+
+	bitriseConfigPath, err := GetBitriseConfigFilePath(c.String(ConfigKey))
+	if err != nil {
+		return err
+	}
+	config, warnings, err := CreateBitriseConfigFromCLIParams("", bitriseConfigPath)
+	for _, warning := range warnings {
+		log.Warnf("warning: %s", warning)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create bitrise config, error: %s", err)
+	}
+
+	params := envExplainParams{
+		config:       config,
+		inventory:    inventory,
+		workflowID:   workflowID,
+		stepPosition: c.Int(StepKey),
+	}
+	if c.Bool(DeepKey) {
+		params.resolveStep = resolveStepInfo
+	}
+
+	explanation, err := explainWorkflowEnvs(params, &env.DefaultEnvironmentSource{}, key)
+	if err != nil {
+		return err
+	}
+
+	if format == output.FormatRaw {
+		printRawEnvExplanation(explanation)
+	} else {
+		output.Print(explanation, format)
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/models"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/pointers"
+	stepmanModels "github.com/bitrise-io/stepman/models"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnvironmentSource map[string]string
+
+func (s testEnvironmentSource) GetEnvironment() map[string]string {
+	envs := map[string]string{}
+	for key, value := range s {
+		envs[key] = value
+	}
+	return envs
+}
+
+func testEnvExplainConfig() models.BitriseDataModel {
+	return models.BitriseDataModel{
+		FormatVersion:        "11",
+		DefaultStepLibSource: "https://github.com/bitrise-io/bitrise-steplib.git",
+		App: models.AppModel{
+			Environments: []envmanModels.EnvironmentItemModel{
+				{"CONFIGURATION": "Debug"},
+				{"API_URL": "https://$API_HOST/v1"},
+			},
+		},
+		Workflows: map[string]models.WorkflowModel{
+			"_setup": models.WorkflowModel{
+				Environments: []envmanModels.EnvironmentItemModel{{"SCHEME": "App-$CONFIGURATION"}},
+				Steps: []models.StepListItemModel{
+					{"git-clone@6": models.NewStepModel(stepmanModels.StepModel{})},
+				},
+			},
+			"deploy": models.WorkflowModel{
+				BeforeRun:    []string{"_setup"},
+				Environments: []envmanModels.EnvironmentItemModel{{"CONFIGURATION": "Release"}},
+				Steps: []models.StepListItemModel{
+					{"xcode-archive@4": models.StepModel{
+						StepModel: stepmanModels.StepModel{
+							Inputs: []envmanModels.EnvironmentItemModel{{"scheme": "$SCHEME"}},
+							Outputs: []envmanModels.EnvironmentItemModel{
+								{"BITRISE_IPA_PATH": "APP_IPA_PATH"},
+							},
+						},
+						ID: pointers.NewStringPtr("archive"),
+					}},
+				},
+			},
+		},
+	}
+}
+
+func TestExplainWorkflowEnvs(t *testing.T) {
+	envSource := testEnvironmentSource{"HOME": "/home/bitrise", "API_HOST": "api.bitrise.io"}
+	inventory := []envmanModels.EnvironmentItemModel{{"API_TOKEN": "secret-token"}}
+
+	t.Log("layers and overrides at the end of the build")
+	{
+		params := envExplainParams{config: testEnvExplainConfig(), inventory: inventory, workflowID: "deploy", stepPosition: -1}
+		explanation, err := explainWorkflowEnvs(params, envSource, "CONFIGURATION")
+		require.NoError(t, err)
+		require.Equal(t, "the end of the build", explanation.At)
+		require.Equal(t, []envExplanationModel{
+			{Key: "CONFIGURATION", Value: "Release", IsSet: true, Layers: []envLayerModel{
+				{Source: "app", Action: "set", Value: "Debug", Expansion: []envExpansionModel{}},
+				{Source: "triggered workflow (deploy)", Action: "set", Value: "Release", Expansion: []envExpansionModel{}},
+				{Source: "workflow (deploy)", Action: "set", Value: "Release", Expansion: []envExpansionModel{}},
+			}},
+		}, explanation.Envs)
+	}
+
+	t.Log("expansion chains")
+	{
+		params := envExplainParams{config: testEnvExplainConfig(), inventory: inventory, workflowID: "deploy", stepPosition: -1}
+		explanation, err := explainWorkflowEnvs(params, envSource, "SCHEME")
+		require.NoError(t, err)
+		require.Equal(t, 1, len(explanation.Envs))
+		require.Equal(t, "App-Release", explanation.Envs[0].Value)
+		require.Equal(t, []envLayerModel{
+			{Source: "workflow (_setup)", Action: "set", Value: "App-$CONFIGURATION", ExpandedValue: "App-Release", Expansion: []envExpansionModel{
+				{Key: "CONFIGURATION", Value: "Release", Source: "triggered workflow (deploy)", Expansion: []envExpansionModel{}},
+			}},
+		}, explanation.Envs[0].Layers)
+
+		explanation, err = explainWorkflowEnvs(params, envSource, "API_URL")
+		require.NoError(t, err)
+		require.Equal(t, []envExpansionModel{
+			{Key: "API_HOST", Value: "api.bitrise.io", Source: "process environment"},
+		}, explanation.Envs[0].Layers[0].Expansion)
+	}
+
+	t.Log("secrets are redacted")
+	{
+		config := testEnvExplainConfig()
+		config.App.Environments = append(config.App.Environments, envmanModels.EnvironmentItemModel{"AUTH_HEADER": "Bearer $API_TOKEN"})
+		params := envExplainParams{config: config, inventory: inventory, workflowID: "deploy", stepPosition: -1}
+		explanation, err := explainWorkflowEnvs(params, envSource, "AUTH_HEADER")
+		require.NoError(t, err)
+		require.Equal(t, "Bearer [REDACTED]", explanation.Envs[0].Value)
+		require.Equal(t, "Bearer [REDACTED]", explanation.Envs[0].Layers[0].ExpandedValue)
+		require.Equal(t, []envExpansionModel{
+			{Key: "API_TOKEN", Value: "[REDACTED]", Source: "secrets", Expansion: []envExpansionModel{}},
+		}, explanation.Envs[0].Layers[0].Expansion)
+	}
+
+	t.Log("output aliases")
+	{
+		params := envExplainParams{config: testEnvExplainConfig(), inventory: inventory, workflowID: "deploy", stepPosition: -1}
+		explanation, err := explainWorkflowEnvs(params, envSource, "APP_IPA_PATH")
+		require.NoError(t, err)
+		require.Equal(t, "<output of step #1 (xcode-archive@4)>", explanation.Envs[0].Value)
+		require.Equal(t, "step #1 (xcode-archive@4) output (BITRISE_IPA_PATH)", explanation.Envs[0].Layers[0].Source)
+	}
+
+	t.Log("step outputs namespaced by the step's id")
+	{
+		params := envExplainParams{config: testEnvExplainConfig(), inventory: inventory, workflowID: "deploy", stepPosition: -1}
+		explanation, err := explainWorkflowEnvs(params, envSource, "STEPS_ARCHIVE_OUTPUT_BITRISE_IPA_PATH")
+		require.NoError(t, err)
+		require.Equal(t, "<output of step #1 (xcode-archive@4)>", explanation.Envs[0].Value)
+		require.Equal(t, "step #1 (xcode-archive@4) output", explanation.Envs[0].Layers[0].Source)
+	}
+
+	t.Log("step inputs and input defaults")
+	{
+		resolveStep := func(stepIDData models.StepIDData) (stepmanModels.StepInfoModel, bool, error) {
+			if stepIDData.IDorURI != "xcode-archive" {
+				return stepmanModels.StepInfoModel{}, false, nil
+			}
+			return stepmanModels.StepInfoModel{Step: stepmanModels.StepModel{
+				Inputs: []envmanModels.EnvironmentItemModel{
+					{"scheme": ""},
+					{"configuration": "$CONFIGURATION", envmanModels.OptionsKey: envmanModels.EnvironmentItemOptionsModel{IsExpand: pointers.NewBoolPtr(true)}},
+				},
+			}}, true, nil
+		}
+		params := envExplainParams{config: testEnvExplainConfig(), inventory: inventory, workflowID: "deploy", stepPosition: 1, resolveStep: resolveStep}
+
+		explanation, err := explainWorkflowEnvs(params, envSource, "scheme")
+		require.NoError(t, err)
+		require.Equal(t, "step #1 (xcode-archive@4) of workflow (deploy)", explanation.At)
+		require.Equal(t, "App-Release", explanation.Envs[0].Value)
+		require.Equal(t, "step #1 (xcode-archive@4) input", explanation.Envs[0].Layers[0].Source)
+
+		explanation, err = explainWorkflowEnvs(params, envSource, "configuration")
+		require.NoError(t, err)
+		require.Equal(t, "Release", explanation.Envs[0].Value)
+		require.Equal(t, "step #1 (xcode-archive@4) input default", explanation.Envs[0].Layers[0].Source)
+
+		_, err = explainWorkflowEnvs(params, envSource, "APP_IPA_PATH")
+		require.EqualError(t, err, "env (APP_IPA_PATH) is not set at step #1 (xcode-archive@4) of workflow (deploy)")
+	}
+
+	t.Log("failing step resolution is a warning")
+	{
+		resolveStep := func(stepIDData models.StepIDData) (stepmanModels.StepInfoModel, bool, error) {
+			return stepmanModels.StepInfoModel{}, false, errors.New("network error")
+		}
+		params := envExplainParams{config: testEnvExplainConfig(), inventory: inventory, workflowID: "deploy", stepPosition: -1, resolveStep: resolveStep}
+		explanation, err := explainWorkflowEnvs(params, envSource, "")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"step #0 (git-clone@6): failed to resolve step definition: network error",
+			"step #1 (xcode-archive@4): failed to resolve step definition: network error",
+		}, explanation.Warnings)
+	}
+
+	t.Log("process environment")
+	{
+		params := envExplainParams{config: testEnvExplainConfig(), inventory: inventory, workflowID: "deploy", stepPosition: -1}
+		explanation, err := explainWorkflowEnvs(params, envSource, "HOME")
+		require.NoError(t, err)
+		require.Equal(t, []envExplanationModel{{Key: "HOME", Value: "/home/bitrise", IsSet: true, Layers: []envLayerModel{}}}, explanation.Envs)
+
+		_, err = explainWorkflowEnvs(params, envSource, "UNKNOWN")
+		require.EqualError(t, err, "env (UNKNOWN) is not set at the end of the build")
+	}
+
+	t.Log("isolated workflow")
+	{
+		config := testEnvExplainConfig()
+		setup := config.Workflows["_setup"]
+		setup.IsolateEnvs = true
+		setup.ExportEnvs = []string{"SCHEME"}
+		setup.Environments = append(setup.Environments,
+			envmanModels.EnvironmentItemModel{"CONFIGURATION": "Debug"},
+			envmanModels.EnvironmentItemModel{"SETUP_DIR": "/tmp/setup"},
+			envmanModels.EnvironmentItemModel{"HOME": "/tmp/home"},
+		)
+		setup.Steps[0]["git-clone@6"] = models.NewStepModel(stepmanModels.StepModel{Outputs: []envmanModels.EnvironmentItemModel{{"GIT_CLONE_COMMIT_HASH": ""}}})
+		config.Workflows["_setup"] = setup
+
+		params := envExplainParams{config: config, inventory: inventory, workflowID: "deploy", stepPosition: -1}
+		explanation, err := explainWorkflowEnvs(params, envSource, "")
+		require.NoError(t, err)
+
+		explanations := map[string]envExplanationModel{}
+		for _, envExplanation := range explanation.Envs {
+			explanations[envExplanation.Key] = envExplanation
+		}
+
+		scheme := explanations["SCHEME"]
+		require.Equal(t, "App-Release", scheme.Value)
+		require.Equal(t, envLayerModel{Source: "workflow (_setup) export_envs", Action: "export", Value: "App-Release"}, scheme.Layers[1])
+
+		configuration := explanations["CONFIGURATION"]
+		require.Equal(t, "Release", configuration.Value)
+		require.Equal(t, 5, len(configuration.Layers))
+		require.Equal(t, envLayerModel{Source: "workflow (_setup) isolate_envs", Action: "restore", Value: "Release"}, configuration.Layers[3])
+
+		setupDir := explanations["SETUP_DIR"]
+		require.Equal(t, false, setupDir.IsSet)
+		require.Equal(t, envLayerModel{Source: "workflow (_setup) isolate_envs", Action: "drop"}, setupDir.Layers[1])
+
+		home := explanations["HOME"]
+		require.Equal(t, "/home/bitrise", home.Value)
+		require.Equal(t, "restore", home.Layers[1].Action)
+
+		commitHash := explanations["GIT_CLONE_COMMIT_HASH"]
+		require.Equal(t, false, commitHash.IsSet)
+		require.Equal(t, "drop", commitHash.Layers[1].Action)
+
+		explanation, err = explainWorkflowEnvs(params, envSource, "API_URL")
+		require.NoError(t, err)
+		require.Equal(t, "https://api.bitrise.io/v1", explanation.Envs[0].Value)
+
+		params.stepPosition = 0
+		explanation, err = explainWorkflowEnvs(params, envSource, "SETUP_DIR")
+		require.NoError(t, err)
+		require.Equal(t, "/tmp/setup", explanation.Envs[0].Value)
+	}
+
+	t.Log("isolated triggered workflow")
+	{
+		config := testEnvExplainConfig()
+		deploy := config.Workflows["deploy"]
+		deploy.IsolateEnvs = true
+		config.Workflows["deploy"] = deploy
+
+		params := envExplainParams{config: config, inventory: inventory, workflowID: "deploy", stepPosition: 0}
+		explanation, err := explainWorkflowEnvs(params, envSource, "SCHEME")
+		require.NoError(t, err)
+		require.Equal(t, "App-Debug", explanation.Envs[0].Value)
+
+		params.stepPosition = -1
+		explanation, err = explainWorkflowEnvs(params, envSource, "CONFIGURATION")
+		require.NoError(t, err)
+		require.Equal(t, "Debug", explanation.Envs[0].Value)
+		require.Equal(t, "workflow (deploy)", explanation.Envs[0].Layers[1].Source)
+	}
+
+	t.Log("invalid workflow and step")
+	{
+		params := envExplainParams{config: testEnvExplainConfig(), inventory: inventory, workflowID: "missing", stepPosition: -1}
+		_, err := explainWorkflowEnvs(params, envSource, "")
+		require.EqualError(t, err, "workflow (missing) does not exist")
+
+		params = envExplainParams{config: testEnvExplainConfig(), inventory: inventory, workflowID: "deploy", stepPosition: 2}
+		_, err = explainWorkflowEnvs(params, envSource, "")
+		require.EqualError(t, err, "step #2 does not exist, the build of workflow (deploy) has 2 steps")
+	}
+}
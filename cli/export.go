@@ -2,15 +2,47 @@ package cli
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"gopkg.in/yaml.v2"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/bitrise-io/bitrise/models"
 	"github.com/bitrise-io/bitrise/output"
 	"github.com/bitrise-io/go-utils/fileutil"
 	"github.com/urfave/cli"
 )
 
+// exportSchema writes the JSON Schema of the bitrise config to the output path, or prints it if no path specified.
+func exportSchema(outfilePth, outFormat string, prettyFormat bool) error {
+	if outFormat != "" && outFormat != output.FormatJSON {
+		return fmt.Errorf("invalid output format (%s), the schema is exported in %s format", outFormat, output.FormatJSON)
+	}
+
+	schema := models.BitriseDataModelJSONSchema()
+	var schemaBytes []byte
+	var err error
+	if prettyFormat {
+		schemaBytes, err = json.MarshalIndent(schema, "", "\t")
+	} else {
+		schemaBytes, err = json.Marshal(schema)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate schema JSON, error: %s", err)
+	}
+
+	if outfilePth == "" {
+		fmt.Println(string(schemaBytes))
+		return nil
+	}
+
+	if err := fileutil.WriteBytesToFile(outfilePth, schemaBytes); err != nil {
+		return fmt.Errorf("failed to write file (%s), error: %s", outfilePth, err)
+	}
+	log.Infof("Done, saved to path: %s", outfilePth)
+	return nil
+}
+
 func export(c *cli.Context) error {
 	// Expand cli.Context
 	bitriseConfigBase64Data := c.String(ConfigBase64Key)
@@ -27,6 +59,13 @@ func export(c *cli.Context) error {
 	prettyFormat := c.Bool(PrettyFormatKey)
 	//
 
+	if c.Bool(SchemaKey) {
+		if err := exportSchema(outfilePth, outFormat, prettyFormat); err != nil {
+			log.Fatalf("Failed to export schema, error: %s", err)
+		}
+		return nil
+	}
+
 	if outfilePth == "" {
 		showSubcommandHelp(c)
 		log.Fatal("No output file path specified!")
@@ -65,6 +65,15 @@ const (
 	// StepYMLKey ...
 	StepYMLKey = "step-yml"
 
+	// DeepKey ...
+	DeepKey = "deep"
+	// StrictKey ...
+	StrictKey = "strict"
+	// EnvKeyKey ...
+	EnvKeyKey = "env-key"
+	// SchemaKey ...
+	SchemaKey = "schema"
+
 	//
 	// Stepman share
 
@@ -173,6 +182,22 @@ var (
 		Name:  GitKey,
 		Usage: "Git clone url of the step repository.",
 	}
+	flCommitMessage = cli.StringFlag{
+		Name:  CommitMessageKey,
+		Usage: "Git commit message.",
+	}
+	flPRLabels = cli.StringFlag{
+		Name:  PRLabelsKey,
+		Usage: "Comma separated list of the git pull request labels.",
+	}
+	flDraftPR = cli.BoolFlag{
+		Name:  DraftPRKey,
+		Usage: "The git pull request is a draft.",
+	}
+	flChangedFiles = cli.StringFlag{
+		Name:  ChangedFilesKey,
+		Usage: "Comma separated list of the changed files, instead of listing them with git diff.",
+	}
 	flStepID = cli.StringFlag{
 		Name:  StepIDKey,
 		Usage: "ID of the step.",
@@ -63,7 +63,7 @@ func initConfig(c *cli.Context) error {
 	if minimal {
 		pluginArgs = []string{"--minimal"}
 	}
-	if err := plugins.RunPluginByCommand(plugin, pluginArgs); err != nil {
+	if _, err := plugins.RunPluginByCommand(plugin, pluginArgs); err != nil {
 		return fmt.Errorf("Failed to run plugin (%s), error: %s", pluginName, err)
 	}
 
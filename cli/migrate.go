@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/urfave/cli"
+)
+
+var migrateCommand = cli.Command{
+	Name:  "migrate",
+	Usage: "Upgrades the bitrise.yml to a newer format version, rewriting the deprecated config constructs.",
+	Action: func(c *cli.Context) error {
+		if err := migrate(c); err != nil {
+			log.Errorf("Failed to migrate config, error: %s", err)
+			os.Exit(1)
+		}
+		return nil
+	},
+	Flags: []cli.Flag{
+		flConfigEditConfig,
+		cli.StringFlag{Name: ToKey, Value: models.Version, Usage: "Format version to migrate the config to."},
+		cli.BoolFlag{Name: DryRunKey, Usage: "Print the changes without saving the migrated config."},
+	},
+}
+
+func diffLines(content []byte) []string {
+	if len(content) == 0 {
+		return []string{}
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	// the diff lines are expected to end with a newline
+	lines[len(lines)-1] += "\n"
+	return lines
+}
+
+// unifiedDiff returns the line based diff of the original and the updated content, in unified diff format.
+func unifiedDiff(name string, original, updated []byte) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        diffLines(original),
+		B:        diffLines(updated),
+		FromFile: name,
+		ToFile:   name,
+		Context:  3,
+	})
+}
+
+// colorizeUnifiedDiff colors the added lines green and the removed lines red.
+func colorizeUnifiedDiff(diff string) string {
+	var buffer bytes.Buffer
+	for _, content := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(content, "---") || strings.HasPrefix(content, "+++"):
+		case strings.HasPrefix(content, "+"):
+			content = colorstring.Green(content)
+		case strings.HasPrefix(content, "-"):
+			content = colorstring.Red(content)
+		}
+		buffer.WriteString(content + "\n")
+	}
+	return buffer.String()
+}
+
+// migrateConfigContent returns the config migrated to the format version, in the format of the config,
+// and the migrations run.
+func migrateConfigContent(configContent []byte, toVersion string) ([]byte, []models.ConfigMigration, error) {
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytesWithoutDefaults(configContent)
+	for _, warning := range warnings {
+		log.Warnf("warning: %s", warning)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrations, err := config.Migrate(toVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migratedContent, err := bitrise.ConfigYAMLPreservingFormat(configContent, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return migratedContent, migrations, nil
+}
+
+func migrate(c *cli.Context) error {
+	bitriseConfigPath, err := GetBitriseConfigFilePath(c.String(ConfigKey))
+	if err != nil {
+		return err
+	}
+
+	configContent, err := fileutil.ReadBytesFromFile(bitriseConfigPath)
+	if err != nil {
+		return err
+	}
+
+	toVersion := c.String(ToKey)
+	migratedContent, migrations, err := migrateConfigContent(configContent, toVersion)
+	if err != nil {
+		return fmt.Errorf("failed to migrate config (%s), error: %s", bitriseConfigPath, err)
+	}
+	if bytes.Equal(configContent, migratedContent) {
+		log.Donef("The config is up to date (format version: %s)", toVersion)
+		return nil
+	}
+
+	diff, err := unifiedDiff(bitriseConfigPath, configContent, migratedContent)
+	if err != nil {
+		return err
+	}
+	fmt.Print(colorizeUnifiedDiff(diff))
+
+	for _, migration := range migrations {
+		log.Printf("- %s: %s", migration.FormatVersion, migration.Description)
+	}
+
+	if c.Bool(DryRunKey) {
+		log.Donef("Dry run, the config is not migrated to format version %s", toVersion)
+		return nil
+	}
+
+	if err := fileutil.WriteBytesToFile(bitriseConfigPath, migratedContent); err != nil {
+		return err
+	}
+	log.Donef("Config migrated to format version %s", toVersion)
+	return nil
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	t.Log("changed line")
+	{
+		diff, err := unifiedDiff("bitrise.yml", []byte("format_version: 1.2.0\nworkflows: {}\n"), []byte("format_version: \"11\"\nworkflows: {}\n"))
+		require.NoError(t, err)
+		require.Equal(t, `--- bitrise.yml
++++ bitrise.yml
+@@ -1,2 +1,2 @@
+-format_version: 1.2.0
++format_version: "11"
+ workflows: {}
+`, diff)
+	}
+
+	t.Log("missing trailing newline")
+	{
+		diff, err := unifiedDiff("bitrise.yml", []byte("a\nb"), []byte("a\nc\n"))
+		require.NoError(t, err)
+		require.Equal(t, `--- bitrise.yml
++++ bitrise.yml
+@@ -1,2 +1,2 @@
+ a
+-b
++c
+`, diff)
+	}
+
+	t.Log("no changes")
+	{
+		diff, err := unifiedDiff("bitrise.yml", []byte("a\n"), []byte("a\n"))
+		require.NoError(t, err)
+		require.Equal(t, "", diff)
+	}
+}
+
+func TestMigrateConfigContent(t *testing.T) {
+	configYAML := `format_version: 1.1.0
+default_step_lib_source: https://github.com/bitrise-io/bitrise-steplib.git
+
+trigger_map:
+# pushes and pull requests of master
+- pattern: master
+  is_pull_request_allowed: true
+  workflow: primary
+
+workflows:
+  primary:
+    steps:
+    - script@1:
+        inputs:
+        - content: echo hi
+`
+
+	migratedYAML, migrations, err := migrateConfigContent([]byte(configYAML), "1.3.0")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(migrations))
+	require.Equal(t, "1.3.0", migrations[0].FormatVersion)
+
+	diff, err := unifiedDiff("bitrise.yml", []byte(configYAML), migratedYAML)
+	require.NoError(t, err)
+	require.Equal(t, `--- bitrise.yml
++++ bitrise.yml
+@@ -1,10 +1,11 @@
+-format_version: 1.1.0
++format_version: 1.3.0
+ default_step_lib_source: https://github.com/bitrise-io/bitrise-steplib.git
+ 
+ trigger_map:
+ # pushes and pull requests of master
+-- pattern: master
+-  is_pull_request_allowed: true
++- push_branch: master
++  workflow: primary
++- pull_request_source_branch: master
+   workflow: primary
+ 
+ workflows:
+`, diff)
+}
@@ -1,11 +1,22 @@
 package cli
 
 import (
+	"encoding/base64"
+
 	log "github.com/Sirupsen/logrus"
 	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/go-utils/fileutil"
 	"github.com/urfave/cli"
 )
 
+// readBitriseConfigContent returns the raw config, the base of the format preserving config rewrites.
+func readBitriseConfigContent(bitriseConfigBase64Data, bitriseConfigPath string) ([]byte, error) {
+	if bitriseConfigBase64Data != "" {
+		return base64.StdEncoding.DecodeString(bitriseConfigBase64Data)
+	}
+	return fileutil.ReadBytesFromFile(bitriseConfigPath)
+}
+
 func normalize(c *cli.Context) error {
 	// Expand cli.Context
 	bitriseConfigBase64Data := c.String(ConfigBase64Key)
@@ -40,7 +51,11 @@ func normalize(c *cli.Context) error {
 	if err := bitrise.RemoveConfigRedundantFieldsAndFillStepOutputs(&bitriseConfig); err != nil {
 		log.Fatalf("Failed to remove redundant fields, error: %s", err)
 	}
-	if err := bitrise.SaveConfigToFile(bitriseConfigPath, bitriseConfig); err != nil {
+	originalConfigContent, err := readBitriseConfigContent(bitriseConfigBase64Data, bitriseConfigPath)
+	if err != nil {
+		log.Fatalf("Failed to read bitrise config, error: %s", err)
+	}
+	if err := bitrise.SaveConfigToFilePreservingFormat(bitriseConfigPath, originalConfigContent, bitriseConfig); err != nil {
 		log.Fatalf("Failed to save config to file, error: %s", err)
 	}
 
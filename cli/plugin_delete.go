@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/bitrise-io/bitrise/plugins"
 	"github.com/bitrise-io/go-utils/log"
@@ -46,6 +47,12 @@ func pluginDelete(c *cli.Context) error {
 		return nil
 	}
 
+	if dependents, err := plugins.DependentPlugins(name); err != nil {
+		return fmt.Errorf("failed to check plugin dependents, error: %s", err)
+	} else if len(dependents) > 0 {
+		return fmt.Errorf("plugin is required by the installed plugins: %s", strings.Join(dependents, ", "))
+	}
+
 	log.Infof("Deleting plugin")
 	if err := plugins.DeletePlugin(name); err != nil {
 		return fmt.Errorf("failed to delete plugin, error: %s", err)
@@ -25,6 +25,10 @@ var pluginListCommand = cli.Command{
 			Name:  output.FormatKey,
 			Usage: "Output format. Accepted: raw, json.",
 		},
+		cli.BoolFlag{
+			Name:  "tree",
+			Usage: "Print the dependency graph of the installed plugins.",
+		},
 	},
 	ArgsUsage: "",
 }
@@ -58,6 +62,16 @@ func pluginList(c *cli.Context) error {
 		return nil
 	}
 
+	if c.Bool("tree") {
+		tree, err := plugins.InstalledPluginDependencyTree()
+		if err != nil {
+			return fmt.Errorf("failed to create plugin dependency tree, error: %s", err)
+		}
+
+		logger.Print(tree)
+		return nil
+	}
+
 	plugins.SortByName(installedPlugins)
 
 	pluginInfos := plugins.PluginInfos{}
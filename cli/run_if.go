@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/goinp/goinp"
+	"github.com/urfave/cli"
+)
+
+var runIfCommand = cli.Command{
+	Name:  "run-if",
+	Usage: "run_if expression utilities.",
+	Subcommands: []cli.Command{
+		runIfEvalCommand,
+	},
+}
+
+var runIfEvalCommand = cli.Command{
+	Name:  "eval",
+	Usage: "Evaluates a run_if expression with the current environment, and prints the result.",
+	Action: func(c *cli.Context) error {
+		if err := runIfEval(c); err != nil {
+			log.Errorf("Failed to evaluate expression, error: %s", err)
+			os.Exit(1)
+		}
+		return nil
+	},
+	ArgsUsage: "<expression>",
+}
+
+func runIfEval(c *cli.Context) error {
+	if len(c.Args()) != 1 {
+		return errors.New("the expression to evaluate should be provided as the only argument, e.g. bitrise run-if eval '{{branchMatches \"^release/\"}}'")
+	}
+	expression := c.Args().First()
+
+	// the expression is evaluated before any step run
+	buildRunResults := models.BuildRunResultsModel{}
+
+	resStr, err := bitrise.EvaluateTemplateToString(expression, configs.IsCIMode, configs.IsPullRequestMode, buildRunResults, envmanModels.EnvsJSONListModel{})
+	if err != nil {
+		return err
+	}
+
+	isRun, err := goinp.ParseBool(resStr)
+	if err != nil {
+		return fmt.Errorf("the expression evaluates to (%s), which is not a bool, error: %s", resStr, err)
+	}
+
+	fmt.Println(isRun)
+	return nil
+}
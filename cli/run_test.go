@@ -262,6 +262,31 @@ workflows:
 	require.Equal(t, 0, len(buildRunResults.SkippedSteps))
 }
 
+func TestFailedStepActivationID(t *testing.T) {
+	configStr := `
+format_version: 1.3.0
+default_step_lib_source: "https://github.com/bitrise-io/bitrise-steplib.git"
+
+workflows:
+  test:
+    steps:
+    - path::./this/step/does/not/exist:
+        id: missing_step
+        title: "Missing step"
+`
+	require.NoError(t, configs.InitPaths())
+
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	buildRunResults, err := runWorkflowWithConfiguration(time.Now(), "test", config, []envmanModels.EnvironmentItemModel{})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(buildRunResults.SuccessSteps))
+	require.Equal(t, 1, len(buildRunResults.FailedSteps))
+	require.Equal(t, "missing_step", buildRunResults.FailedSteps[0].ID)
+}
+
 func TestBitriseSourceDir(t *testing.T) {
 	currPth, err := pathutil.NormalizedOSTempDirPath("bitrise_source_dir_test")
 	require.NoError(t, err)
@@ -1237,6 +1262,57 @@ workflows:
 	require.Equal(t, "0", os.Getenv("STEPLIB_BUILD_STATUS"))
 }
 
+// Test - Bitrise Environments
+// Test for isolated triggered workflow, its envs should not be available in the after_run workflows
+func TestIsolatedTriggeredWorkflowEnvironments(t *testing.T) {
+	configStr := `
+format_version: 1.3.0
+default_step_lib_source: "https://github.com/bitrise-io/bitrise-steplib.git"
+
+workflows:
+  target:
+    title: target
+    isolate_envs: true
+    after_run:
+    - after
+    envs:
+    - ISOLATED_ENV: isolatedenv
+    steps:
+    - script:
+        inputs:
+        - content: |
+            #!/bin/bash
+            set -v
+            if [[ "$ISOLATED_ENV" != "isolatedenv" ]] ; then
+              exit 1
+            fi
+
+  after:
+    steps:
+    - script:
+        inputs:
+        - content: |
+            #!/bin/bash
+            set -v
+            if [[ -n "$ISOLATED_ENV" ]] ; then
+              exit 1
+            fi
+    `
+
+	require.NoError(t, configs.InitPaths())
+
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	buildRunResults, err := runWorkflowWithConfiguration(time.Now(), "target", config, []envmanModels.EnvironmentItemModel{})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(buildRunResults.SuccessSteps))
+	require.Equal(t, 0, len(buildRunResults.FailedSteps))
+	require.Equal(t, 0, len(buildRunResults.FailedSkippableSteps))
+	require.Equal(t, 0, len(buildRunResults.SkippedSteps))
+}
+
 // Test - Bitrise Environments
 // Test for same env in before and target workflow, actual workflow should overwrite environemnt and use own value
 func TestWorkflowEnvironmentOverWrite(t *testing.T) {
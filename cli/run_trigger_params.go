@@ -3,6 +3,9 @@ package cli
 import (
 	"encoding/base64"
 	"encoding/json"
+	"strings"
+
+	"github.com/bitrise-io/bitrise/models"
 )
 
 // --------------------
@@ -22,6 +25,11 @@ type RunAndTriggerParamsModel struct {
 	PRTargetBranch string `json:"pr-target-branch"`
 	Tag            string `json:"tag"`
 
+	CommitMessage string   `json:"commit-message"`
+	PRLabels      []string `json:"pr-labels"`
+	IsDraftPR     bool     `json:"draft-pr"`
+	ChangedFiles  []string `json:"changed-files"`
+
 	// Trigger Check Params
 	Format string `json:"format"`
 
@@ -136,3 +144,43 @@ func parseTriggerCheckParams(
 	jsonParams, base64JSONParams string) (RunAndTriggerParamsModel, error) {
 	return parseRunAndTriggerParams("", triggerPattern, pushBranch, prSourceBranch, prTargetBranch, tag, format, bitriseConfigPath, bitriseConfigBase64Data, inventoryPath, inventoryBase64Data, jsonParams, base64JSONParams)
 }
+
+// overrideTriggerConditionParams overrides the params with the trigger conditions given by command flags,
+// prLabels and changedFiles are comma separated lists.
+func overrideTriggerConditionParams(params RunAndTriggerParamsModel, commitMessage, prLabels string, isDraftPR bool, changedFiles string) RunAndTriggerParamsModel {
+	if commitMessage != "" {
+		params.CommitMessage = commitMessage
+	}
+	if prLabels != "" {
+		params.PRLabels = splitCommaSeparatedList(prLabels)
+	}
+	if isDraftPR {
+		params.IsDraftPR = isDraftPR
+	}
+	if changedFiles != "" {
+		params.ChangedFiles = splitCommaSeparatedList(changedFiles)
+	}
+	return params
+}
+
+func splitCommaSeparatedList(list string) []string {
+	items := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (params RunAndTriggerParamsModel) triggerParams() models.TriggerParamsModel {
+	return models.TriggerParamsModel{
+		PushBranch:     params.PushBranch,
+		PRSourceBranch: params.PRSourceBranch,
+		PRTargetBranch: params.PRTargetBranch,
+		Tag:            params.Tag,
+		CommitMessage:  params.CommitMessage,
+		PRLabels:       params.PRLabels,
+		IsDraftPR:      params.IsDraftPR,
+	}
+}
@@ -481,3 +481,30 @@ func TestParseTriggerCheckParams(t *testing.T) {
 		require.Equal(t, inventoryBase64Data, params.InventoryBase64Data)
 	}
 }
+
+func TestTriggerConditionParams(t *testing.T) {
+	t.Log("it parses trigger conditions from json params")
+	{
+		jsonParams := `{"push-branch":"master","commit-message":"Fix [deploy]","pr-labels":["ui"],"draft-pr":true,"changed-files":["apps/ios/App.swift"]}`
+
+		params, err := parseTriggerParams("", "", "", "", "", "", "", "", "", jsonParams, "")
+		require.NoError(t, err)
+
+		require.Equal(t, "Fix [deploy]", params.CommitMessage)
+		require.Equal(t, []string{"ui"}, params.PRLabels)
+		require.Equal(t, true, params.IsDraftPR)
+		require.Equal(t, []string{"apps/ios/App.swift"}, params.ChangedFiles)
+	}
+
+	t.Log("it overrides trigger conditions with command flags")
+	{
+		params := RunAndTriggerParamsModel{CommitMessage: "Fix", PRLabels: []string{"ui"}}
+
+		params = overrideTriggerConditionParams(params, "", "bug, ui-change", false, "apps/ios/App.swift,README.md")
+
+		require.Equal(t, "Fix", params.CommitMessage)
+		require.Equal(t, []string{"bug", "ui-change"}, params.PRLabels)
+		require.Equal(t, false, params.IsDraftPR)
+		require.Equal(t, []string{"apps/ios/App.swift", "README.md"}, params.ChangedFiles)
+	}
+}
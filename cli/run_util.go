@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -305,7 +306,7 @@ func getCurrentBitriseSourceDir(envlist []envmanModels.EnvironmentItemModel) (st
 	return bitriseSourceDir, nil
 }
 
-func checkAndInstallStepDependencies(step stepmanModels.StepModel) error {
+func checkAndInstallStepDependencies(step models.StepModel) error {
 	if len(step.Dependencies) > 0 {
 		log.Warnf("step.dependencies is deprecated... Use step.deps instead.")
 	}
@@ -380,13 +381,18 @@ func checkAndInstallStepDependencies(step stepmanModels.StepModel) error {
 }
 
 func executeStep(
-	step stepmanModels.StepModel, sIDData models.StepIDData,
+	step models.StepModel, sIDData models.StepIDData,
 	stepAbsDirPath, bitriseSourceDir string,
-	secrets []envmanModels.EnvironmentItemModel) (int, error) {
-	toolkitForStep := toolkits.ToolkitForStep(step)
+	secrets []envmanModels.EnvironmentItemModel,
+	container *models.ContainerModel) (int, error) {
+	toolkitForStep := toolkits.ToolkitForWorkflowStep(step, container, bitriseSourceDir)
 	toolkitName := toolkitForStep.ToolkitName()
 
-	if err := toolkitForStep.PrepareForStepRun(step, sIDData, stepAbsDirPath); err != nil {
+	lockKey := toolkitPrepareLockKey(toolkitForStep)
+	toolkitPrepareLocks.lockForeground(lockKey)
+	err := toolkitForStep.PrepareForStepRun(step, sIDData, stepAbsDirPath)
+	toolkitPrepareLocks.unlock(lockKey)
+	if err != nil {
 		return 1, fmt.Errorf("Failed to prepare the step for execution through the required toolkit (%s), error: %s",
 			toolkitName, err)
 	}
@@ -403,13 +409,17 @@ func executeStep(
 		timeout = time.Duration(timeoutSeconds) * time.Second
 	}
 
-	return tools.EnvmanRun(configs.InputEnvstorePath, bitriseSourceDir, cmd, timeout, secrets, nil)
+	exit, err := tools.EnvmanRun(configs.InputEnvstorePath, bitriseSourceDir, cmd, timeout, secrets, nil)
+	if containerToolkit, ok := toolkitForStep.(toolkits.ContainerToolkit); ok {
+		err = containerToolkit.StepRunError(exit, err)
+	}
+	return exit, err
 }
 
 func runStep(
-	step stepmanModels.StepModel, stepIDData models.StepIDData, stepDir string,
+	step models.StepModel, stepIDData models.StepIDData, stepDir string,
 	environments []envmanModels.EnvironmentItemModel, secrets []envmanModels.EnvironmentItemModel,
-	buildRunResults models.BuildRunResultsModel) (int, []envmanModels.EnvironmentItemModel, error) {
+	buildRunResults models.BuildRunResultsModel, container *models.ContainerModel) (int, []envmanModels.EnvironmentItemModel, map[string]string, error) {
 	log.Debugf("[BITRISE_CLI] - Try running step: %s (%s)", stepIDData.IDorURI, stepIDData.Version)
 
 	// Check & Install Step Dependencies
@@ -425,54 +435,80 @@ func runStep(
 
 		return checkAndInstallStepDependencies(step)
 	}); err != nil {
-		return 1, []envmanModels.EnvironmentItemModel{},
+		return 1, []envmanModels.EnvironmentItemModel{}, map[string]string{},
 			fmt.Errorf("Failed to install Step dependency, error: %s", err)
 	}
 
 	if err := tools.EnvmanInitAtPath(configs.InputEnvstorePath); err != nil {
-		return 1, []envmanModels.EnvironmentItemModel{}, err
+		return 1, []envmanModels.EnvironmentItemModel{}, map[string]string{}, err
 	}
 
 	if err := tools.ExportEnvironmentsList(configs.InputEnvstorePath, environments); err != nil {
-		return 1, []envmanModels.EnvironmentItemModel{}, err
+		return 1, []envmanModels.EnvironmentItemModel{}, map[string]string{}, err
 	}
 
 	// Run step
 	bitriseSourceDir, err := getCurrentBitriseSourceDir(environments)
 	if err != nil {
-		return 1, []envmanModels.EnvironmentItemModel{}, err
+		return 1, []envmanModels.EnvironmentItemModel{}, map[string]string{}, err
 	}
 	if bitriseSourceDir == "" {
 		bitriseSourceDir = configs.CurrentDir
 	}
 
-	if exit, err := executeStep(step, stepIDData, stepDir, bitriseSourceDir, secrets); err != nil {
+	if exit, err := executeStep(step, stepIDData, stepDir, bitriseSourceDir, secrets, container); err != nil {
 		stepOutputs, envErr := bitrise.CollectEnvironmentsFromFile(configs.OutputEnvstorePath)
 		if envErr != nil {
-			return 1, []envmanModels.EnvironmentItemModel{}, envErr
+			return 1, []envmanModels.EnvironmentItemModel{}, map[string]string{}, envErr
+		}
+
+		outputValues, valuesErr := stepOutputValues(stepOutputs)
+		if valuesErr != nil {
+			return 1, []envmanModels.EnvironmentItemModel{}, map[string]string{}, valuesErr
+		}
+
+		namespacedStepOutputs, namespaceErr := bitrise.NamespaceStepOutputs(stepOutputs, pointers.StringWithDefault(step.ID, ""))
+		if namespaceErr != nil {
+			return 1, []envmanModels.EnvironmentItemModel{}, map[string]string{}, namespaceErr
 		}
 
 		updatedStepOutputs, updateErr := bitrise.ApplyOutputAliases(stepOutputs, step.Outputs)
 		if updateErr != nil {
-			return 1, []envmanModels.EnvironmentItemModel{}, updateErr
+			return 1, []envmanModels.EnvironmentItemModel{}, map[string]string{}, updateErr
 		}
 
-		return exit, updatedStepOutputs, err
+		return exit, append(updatedStepOutputs, namespacedStepOutputs...), outputValues, err
 	}
 
 	stepOutputs, err := bitrise.CollectEnvironmentsFromFile(configs.OutputEnvstorePath)
 	if err != nil {
-		return 1, []envmanModels.EnvironmentItemModel{}, err
+		return 1, []envmanModels.EnvironmentItemModel{}, map[string]string{}, err
+	}
+
+	// typed outputs are validated, the file and directory outputs are copied to the deploy dir
+	deployDir := os.Getenv(configs.BitriseDeployDirEnvKey)
+	outputsDir := filepath.Join(deployDir, "step_outputs", strconv.Itoa(buildRunResults.ResultsCount()))
+	outputValues, outputsErr := processStepOutputs(stepOutputs, step.Outputs, bitriseSourceDir, deployDir, outputsDir)
+
+	// the outputs are also exported under the keys namespaced by the step's id, the aliases are applied in place
+	namespacedStepOutputs, err := bitrise.NamespaceStepOutputs(stepOutputs, pointers.StringWithDefault(step.ID, ""))
+	if err != nil {
+		return 1, []envmanModels.EnvironmentItemModel{}, map[string]string{}, err
 	}
 
 	updatedStepOutputs, updateErr := bitrise.ApplyOutputAliases(stepOutputs, step.Outputs)
 	if updateErr != nil {
-		return 1, []envmanModels.EnvironmentItemModel{}, updateErr
+		return 1, []envmanModels.EnvironmentItemModel{}, map[string]string{}, updateErr
+	}
+	updatedStepOutputs = append(updatedStepOutputs, namespacedStepOutputs...)
+
+	if outputsErr != nil {
+		return 1, updatedStepOutputs, outputValues, fmt.Errorf("invalid step outputs: %s", outputsErr)
 	}
 
 	log.Debugf("[BITRISE_CLI] - Step executed: %s (%s)", stepIDData.IDorURI, stepIDData.Version)
 
-	return 0, updatedStepOutputs, nil
+	return 0, updatedStepOutputs, outputValues, nil
 }
 
 func activateStepLibStep(stepIDData models.StepIDData, destination, stepYMLCopyPth string, isStepLibUpdated bool) (stepmanModels.StepInfoModel, bool, error) {
@@ -538,8 +574,77 @@ func activateStepLibStep(stepIDData models.StepIDData, destination, stepYMLCopyP
 	return info, didStepLibUpdate, nil
 }
 
+// stepActivationResult ...
+type stepActivationResult struct {
+	// stepInfo is the info of the activated StepLib step
+	stepInfo stepmanModels.StepInfoModel
+	// origStepYMLPth is the step definition's path of a local step (path::./)
+	origStepYMLPth   string
+	didStepLibUpdate bool
+}
+
+// activateStep fetches the step's source into stepDir, and copies the step definition to stepYMLPth.
+// The notes about a failed activation are written to output.
+func activateStep(stepIDData models.StepIDData, stepDir, stepYMLPth string, isStepLibUpdated bool, output io.Writer) (stepActivationResult, error) {
+	if stepIDData.SteplibSource == "path" {
+		log.Debugf("[BITRISE_CLI] - Local step found: (path:%s)", stepIDData.IDorURI)
+		stepAbsLocalPth, err := pathutil.AbsPath(stepIDData.IDorURI)
+		if err != nil {
+			return stepActivationResult{}, err
+		}
+
+		log.Debugln("stepAbsLocalPth:", stepAbsLocalPth, "|stepDir:", stepDir)
+
+		origStepYMLPth := filepath.Join(stepAbsLocalPth, "step.yml")
+		if err := command.CopyFile(origStepYMLPth, stepYMLPth); err != nil {
+			return stepActivationResult{}, err
+		}
+
+		if err := command.CopyDir(stepAbsLocalPth, stepDir, true); err != nil {
+			return stepActivationResult{}, err
+		}
+		return stepActivationResult{origStepYMLPth: origStepYMLPth}, nil
+	} else if stepIDData.SteplibSource == "git" {
+		log.Debugf("[BITRISE_CLI] - Remote step, with direct git uri: (uri:%s) (tag-or-branch:%s)", stepIDData.IDorURI, stepIDData.Version)
+		repo, err := git.New(stepDir)
+		if err != nil {
+			return stepActivationResult{}, err
+		}
+		if err := repo.CloneTagOrBranch(stepIDData.IDorURI, stepIDData.Version).Run(); err != nil {
+			if strings.HasPrefix(stepIDData.IDorURI, "git@") {
+				fmt.Fprintln(output, colorstring.Yellow(`Note: if the step's repository is an open source one,`))
+				fmt.Fprintln(output, colorstring.Yellow(`you should probably use a "https://..." git clone URL,`))
+				fmt.Fprintln(output, colorstring.Yellow(`instead of the "git@..." git clone URL which usually requires authentication`))
+				fmt.Fprintln(output, colorstring.Yellow(`even if the repository is open source!`))
+			}
+			return stepActivationResult{}, err
+		}
+
+		if err := command.CopyFile(filepath.Join(stepDir, "step.yml"), stepYMLPth); err != nil {
+			return stepActivationResult{}, err
+		}
+		return stepActivationResult{}, nil
+	} else if stepIDData.SteplibSource == "_" {
+		log.Debugf("[BITRISE_CLI] - Steplib independent step, with direct git uri: (uri:%s) (tag-or-branch:%s)", stepIDData.IDorURI, stepIDData.Version)
+
+		repo, err := git.New(stepDir)
+		if err != nil {
+			return stepActivationResult{}, err
+		}
+		if err := repo.CloneTagOrBranch(stepIDData.IDorURI, stepIDData.Version).Run(); err != nil {
+			return stepActivationResult{}, err
+		}
+		return stepActivationResult{}, nil
+	} else if stepIDData.SteplibSource != "" {
+		stepInfo, didUpdate, err := activateStepLibStep(stepIDData, stepDir, stepYMLPth, isStepLibUpdated)
+		return stepActivationResult{stepInfo: stepInfo, didStepLibUpdate: didUpdate}, err
+	}
+
+	return stepActivationResult{}, fmt.Errorf("Invalid stepIDData: No SteplibSource or LocalPath defined (%v)", stepIDData)
+}
+
 func activateAndRunSteps(
-	workflow models.WorkflowModel,
+	workflowID string, workflow models.WorkflowModel,
 	defaultStepLibSource string,
 	buildRunResults models.BuildRunResultsModel,
 	environments *[]envmanModels.EnvironmentItemModel, secrets []envmanModels.EnvironmentItemModel,
@@ -552,7 +657,7 @@ func activateAndRunSteps(
 
 	// ------------------------------------------
 	// In function method - Registration methods, for register step run results.
-	registerStepRunResults := func(step stepmanModels.StepModel, stepInfoPtr stepmanModels.StepInfoModel,
+	registerStepRunResults := func(step models.StepModel, stepInfoPtr stepmanModels.StepInfoModel,
 		stepIdxPtr int, runIf string, resultCode, exitCode int, err error, isLastStep, printStepHeader bool,
 		redactedStepInputs map[string]string) {
 
@@ -577,6 +682,7 @@ func activateAndRunSteps(
 		}
 
 		stepResults := models.StepRunResultsModel{
+			ID:         pointers.StringWithDefault(step.ID, ""),
 			StepInfo:   stepInfoCopy,
 			StepInputs: redactedStepInputs,
 			Status:     resultCode,
@@ -623,6 +729,11 @@ func activateAndRunSteps(
 				log.Info("The Run-If expression was: ", colorstring.Blue(runIf))
 			}
 
+			buildRunResults.SkippedSteps = append(buildRunResults.SkippedSteps, stepResults)
+			break
+		case models.StepRunStatusCodeSkippedWithNoRelevantChanges:
+			log.Warnf("The step's (%s) changed_files filter matches none of the changed files - skipping", pointers.StringWithDefault(stepInfoCopy.Step.Title, "missing title"))
+
 			buildRunResults.SkippedSteps = append(buildRunResults.SkippedSteps, stepResults)
 			break
 		default:
@@ -635,6 +746,25 @@ func activateAndRunSteps(
 
 	// ------------------------------------------
 	// Main - Preparing & running the steps
+	prefetcher := newStepPrefetcher(workflow.Steps, defaultStepLibSource)
+	prefetcher.start(buildRunResults)
+	defer prefetcher.close()
+
+	// the changed files are listed with the declared environment of the first step with changed_files,
+	// e.g. BITRISE_SOURCE_DIR and BITRISE_GIT_COMMIT defined by the workflow or exported by a previous step
+	changedFiles := newChangedFilesLister(func() ([]string, error) {
+		outStr, err := tools.EnvmanJSONPrint(configs.InputEnvstorePath)
+		if err != nil {
+			return nil, fmt.Errorf("EnvmanJSONPrint failed, err: %s", err)
+		}
+
+		envList, err := envmanModels.NewEnvJSONList(outStr)
+		if err != nil {
+			return nil, fmt.Errorf("CreateFromJSON failed, err: %s", err)
+		}
+		return bitrise.ChangedFilesOfBuild(envList)
+	})
+
 	for idx, stepListItm := range workflow.Steps {
 		// Per step variables
 		stepStartTime = time.Now()
@@ -648,7 +778,7 @@ func activateAndRunSteps(
 		}
 
 		if err := bitrise.CleanupStepWorkDir(); err != nil {
-			registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
+			registerStepRunResults(models.StepModel{}, stepInfoPtr, stepIdxPtr,
 				"", models.StepRunStatusCodeFailed, 1, err, isLastStep, true, map[string]string{})
 			continue
 		}
@@ -656,13 +786,13 @@ func activateAndRunSteps(
 		//
 		// Preparing the step
 		if err := tools.EnvmanInitAtPath(configs.InputEnvstorePath); err != nil {
-			registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
+			registerStepRunResults(models.StepModel{}, stepInfoPtr, stepIdxPtr,
 				"", models.StepRunStatusCodeFailed, 1, err, isLastStep, true, map[string]string{})
 			continue
 		}
 
 		if err := tools.ExportEnvironmentsList(configs.InputEnvstorePath, *environments); err != nil {
-			registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
+			registerStepRunResults(models.StepModel{}, stepInfoPtr, stepIdxPtr,
 				"", models.StepRunStatusCodeFailed, 1, err, isLastStep, true, map[string]string{})
 			continue
 		}
@@ -670,7 +800,7 @@ func activateAndRunSteps(
 		// Get step id & version data
 		compositeStepIDStr, workflowStep, err := models.GetStepIDStepDataPair(stepListItm)
 		if err != nil {
-			registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
+			registerStepRunResults(models.StepModel{}, stepInfoPtr, stepIdxPtr,
 				"", models.StepRunStatusCodeFailed, 1, err, isLastStep, true, map[string]string{})
 			continue
 		}
@@ -683,7 +813,7 @@ func activateAndRunSteps(
 
 		stepIDData, err := models.CreateStepIDDataFromString(compositeStepIDStr, defaultStepLibSource)
 		if err != nil {
-			registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
+			registerStepRunResults(workflowStep, stepInfoPtr, stepIdxPtr,
 				"", models.StepRunStatusCodeFailed, 1, err, isLastStep, true, map[string]string{})
 			continue
 		}
@@ -698,83 +828,29 @@ func activateAndRunSteps(
 		// Activating the step
 		stepDir := configs.BitriseWorkStepsDirPath
 		stepYMLPth := filepath.Join(configs.BitriseWorkDirPath, "current_step.yml")
-		var origStepYMLPth string
-
-		if stepIDData.SteplibSource == "path" {
-			log.Debugf("[BITRISE_CLI] - Local step found: (path:%s)", stepIDData.IDorURI)
-			stepAbsLocalPth, err := pathutil.AbsPath(stepIDData.IDorURI)
-			if err != nil {
-				registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
-					"", models.StepRunStatusCodeFailed, 1, err, isLastStep, true, map[string]string{})
-				continue
-			}
-
-			log.Debugln("stepAbsLocalPth:", stepAbsLocalPth, "|stepDir:", stepDir)
-
-			origStepYMLPth = filepath.Join(stepAbsLocalPth, "step.yml")
-			if err := command.CopyFile(origStepYMLPth, stepYMLPth); err != nil {
-				registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
-					"", models.StepRunStatusCodeFailed, 1, err, isLastStep, true, map[string]string{})
-				continue
-			}
-
-			if err := command.CopyDir(stepAbsLocalPth, stepDir, true); err != nil {
-				registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
-					"", models.StepRunStatusCodeFailed, 1, err, isLastStep, true, map[string]string{})
-				continue
-			}
-		} else if stepIDData.SteplibSource == "git" {
-			log.Debugf("[BITRISE_CLI] - Remote step, with direct git uri: (uri:%s) (tag-or-branch:%s)", stepIDData.IDorURI, stepIDData.Version)
-			repo, err := git.New(stepDir)
-			if err != nil {
-				registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
-					"", models.StepRunStatusCodeFailed, 1, err, isLastStep, true, map[string]string{})
-			}
-			if err := repo.CloneTagOrBranch(stepIDData.IDorURI, stepIDData.Version).Run(); err != nil {
-				if strings.HasPrefix(stepIDData.IDorURI, "git@") {
-					fmt.Println(colorstring.Yellow(`Note: if the step's repository is an open source one,`))
-					fmt.Println(colorstring.Yellow(`you should probably use a "https://..." git clone URL,`))
-					fmt.Println(colorstring.Yellow(`instead of the "git@..." git clone URL which usually requires authentication`))
-					fmt.Println(colorstring.Yellow(`even if the repository is open source!`))
-				}
-				registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
-					"", models.StepRunStatusCodeFailed, 1, err, isLastStep, true, map[string]string{})
-				continue
-			}
-
-			if err := command.CopyFile(filepath.Join(stepDir, "step.yml"), stepYMLPth); err != nil {
-				registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
-					"", models.StepRunStatusCodeFailed, 1, err, isLastStep, true, map[string]string{})
-				continue
-			}
-		} else if stepIDData.SteplibSource == "_" {
-			log.Debugf("[BITRISE_CLI] - Steplib independent step, with direct git uri: (uri:%s) (tag-or-branch:%s)", stepIDData.IDorURI, stepIDData.Version)
 
+		if stepIDData.SteplibSource == "_" {
 			// Steplib independent steps are completly defined in workflow
 			stepYMLPth = ""
 			if err := workflowStep.FillMissingDefaults(); err != nil {
-				registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
+				registerStepRunResults(workflowStep, stepInfoPtr, stepIdxPtr,
 					"", models.StepRunStatusCodeFailed, 1, err, isLastStep, true, map[string]string{})
 				continue
 			}
+		}
 
-			repo, err := git.New(stepDir)
-			if err != nil {
-				registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
-					"", models.StepRunStatusCodeFailed, 1, err, isLastStep, true, map[string]string{})
-			}
-			if err := repo.CloneTagOrBranch(stepIDData.IDorURI, stepIDData.Version).Run(); err != nil {
-				registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
-					"", models.StepRunStatusCodeFailed, 1, err, isLastStep, true, map[string]string{})
-				continue
-			}
-		} else if stepIDData.SteplibSource != "" {
-			isUpdated := buildRunResults.IsStepLibUpdated(stepIDData.SteplibSource)
-			stepInfo, didUpdate, err := activateStepLibStep(stepIDData, stepDir, stepYMLPth, isUpdated)
-			if didUpdate {
-				buildRunResults.StepmanUpdates[stepIDData.SteplibSource]++
-			}
+		activation, isPrefetched, err := prefetcher.moveActivatedStep(idx, stepDir, stepYMLPth)
+		if err != nil {
+			log.Warnf("Failed to use the prefetched step, activating it again, error: %s", err)
+		}
+		if !isPrefetched || err != nil {
+			activation, err = activateStep(stepIDData, stepDir, stepYMLPth, buildRunResults.IsStepLibUpdated(stepIDData.SteplibSource), os.Stdout)
+		}
+		if activation.didStepLibUpdate {
+			buildRunResults.StepmanUpdates[stepIDData.SteplibSource]++
+		}
 
+		if stepInfo := activation.stepInfo; stepInfo.ID != "" {
 			stepInfoPtr.ID = stepInfo.ID
 			if stepInfoPtr.Step.Title == nil || *stepInfoPtr.Step.Title == "" {
 				stepInfoPtr.Step.Title = pointers.NewStringPtr(stepInfo.ID)
@@ -783,18 +859,14 @@ func activateAndRunSteps(
 			stepInfoPtr.LatestVersion = stepInfo.LatestVersion
 			stepInfoPtr.OriginalVersion = stepInfo.OriginalVersion
 			stepInfoPtr.GroupInfo = stepInfo.GroupInfo
+		}
 
-			if err != nil {
-				registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
-					"", models.StepRunStatusCodeFailed, 1, err, isLastStep, true, map[string]string{})
-				continue
-			}
-		} else {
-			registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
-				"", models.StepRunStatusCodeFailed, 1, fmt.Errorf("Invalid stepIDData: No SteplibSource or LocalPath defined (%v)", stepIDData),
-				isLastStep, true, map[string]string{})
+		if err != nil {
+			registerStepRunResults(workflowStep, stepInfoPtr, stepIdxPtr,
+				"", models.StepRunStatusCodeFailed, 1, err, isLastStep, true, map[string]string{})
 			continue
 		}
+		origStepYMLPth := activation.origStepYMLPth
 
 		// Fill step info with default step info, if exist
 		mergedStep := workflowStep
@@ -808,7 +880,7 @@ func activateAndRunSteps(
 					// instead of the activated step's one.
 					ymlPth = origStepYMLPth
 				}
-				registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
+				registerStepRunResults(workflowStep, stepInfoPtr, stepIdxPtr,
 					"", models.StepRunStatusCodeFailed, 1, fmt.Errorf("failed to parse step definition (%s): %s", ymlPth, err),
 					isLastStep, true, map[string]string{})
 				continue
@@ -816,7 +888,7 @@ func activateAndRunSteps(
 
 			mergedStep, err = models.MergeStepWith(specStep, workflowStep)
 			if err != nil {
-				registerStepRunResults(stepmanModels.StepModel{}, stepInfoPtr, stepIdxPtr,
+				registerStepRunResults(workflowStep, stepInfoPtr, stepIdxPtr,
 					"", models.StepRunStatusCodeFailed, 1, err, isLastStep, true, map[string]string{})
 				continue
 			}
@@ -832,6 +904,12 @@ func activateAndRunSteps(
 		//
 		// Run step
 		bitrise.PrintRunningStepHeader(stepInfoPtr, mergedStep, idx)
+		if mergedStep.ChangedFiles != nil && !changedFiles.hasRelevantChanges(*mergedStep.ChangedFiles) {
+			registerStepRunResults(mergedStep, stepInfoPtr, stepIdxPtr,
+				*mergedStep.RunIf, models.StepRunStatusCodeSkippedWithNoRelevantChanges, 0, nil, isLastStep, false, map[string]string{})
+			continue
+		}
+
 		if mergedStep.RunIf != nil && *mergedStep.RunIf != "" {
 			outStr, err := tools.EnvmanJSONPrint(configs.InputEnvstorePath)
 			if err != nil {
@@ -906,6 +984,19 @@ func activateAndRunSteps(
 					*mergedStep.RunIf, models.StepRunStatusCodeFailed, 1,
 					fmt.Errorf("failed to prepare step environment variables: %s", err),
 					isLastStep, false, map[string]string{})
+				continue
+			}
+
+			if err := models.ValidateStepInputs(mergedStep.Inputs, expandedStepEnvironment); err != nil {
+				status := models.StepRunStatusCodeFailed
+				if *mergedStep.IsSkippable {
+					status = models.StepRunStatusCodeFailedSkippable
+				}
+				registerStepRunResults(mergedStep, stepInfoPtr, stepIdxPtr,
+					*mergedStep.RunIf, status, 1,
+					fmt.Errorf("workflow (%s) step (%d) (%s): invalid step inputs: %s", workflowID, idx, stepIDData.IDorURI, err),
+					isLastStep, false, map[string]string{})
+				continue
 			}
 
 			redactedStepInputs, err := redactStepInputs(expandedStepEnvironment, mergedStep.Inputs, tools.GetSecretValues(secrets))
@@ -916,7 +1007,13 @@ func activateAndRunSteps(
 					isLastStep, false, map[string]string{})
 			}
 
-			exit, outEnvironments, err := runStep(mergedStep, stepIDData, stepDir, stepDeclaredEnvironments, secrets, buildRunResults)
+			exit, outEnvironments, outputValues, err := runStep(mergedStep, stepIDData, stepDir, stepDeclaredEnvironments, secrets, buildRunResults, workflow.Container)
+
+			if buildRunResults.StepOutputs == nil {
+				buildRunResults.StepOutputs = map[int]map[string]string{}
+			}
+			// the step run result is registered with this Idx below
+			buildRunResults.StepOutputs[buildRunResults.ResultsCount()] = outputValues
 
 			if testDirPath != "" {
 				if err := addTestMetadata(testDirPath, models.TestResultStepInfo{Number: idx, Title: *mergedStep.Title, ID: stepIDData.IDorURI, Version: stepIDData.Version}); err != nil {
@@ -948,15 +1045,26 @@ func activateAndRunSteps(
 }
 
 func runWorkflow(
-	workflow models.WorkflowModel,
+	workflowID string, workflow models.WorkflowModel,
 	steplibSource string,
 	buildRunResults models.BuildRunResultsModel,
 	environments *[]envmanModels.EnvironmentItemModel, secrets []envmanModels.EnvironmentItemModel,
 	isLastWorkflow bool) models.BuildRunResultsModel {
 	bitrise.PrintRunningWorkflow(workflow.Title)
 
+	workflowStart := len(*environments)
 	*environments = append(*environments, workflow.Environments...)
-	return activateAndRunSteps(workflow, steplibSource, buildRunResults, environments, secrets, isLastWorkflow)
+	buildRunResults = activateAndRunSteps(workflowID, workflow, steplibSource, buildRunResults, environments, secrets, isLastWorkflow)
+
+	if workflow.IsolateEnvs {
+		isolated, err := isolateWorkflowEnvironment(*environments, workflowStart, workflow.ExportEnvs, &env.DefaultEnvironmentSource{})
+		if err != nil {
+			log.Errorf("Failed to drop the envs of workflow (%s), error: %s", workflowID, err)
+		} else {
+			*environments = isolated
+		}
+	}
+	return buildRunResults
 }
 
 func activateAndRunWorkflow(
@@ -987,7 +1095,7 @@ func activateAndRunWorkflow(
 	// Run the target workflow
 	isLastWorkflow := (workflowID == lastWorkflowID)
 	buildRunResults = runWorkflow(
-		workflow, bitriseConfig.DefaultStepLibSource,
+		workflowID, workflow, bitriseConfig.DefaultStepLibSource,
 		buildRunResults,
 		environments, secrets,
 		isLastWorkflow)
@@ -1070,7 +1178,7 @@ func runWorkflowWithConfiguration(
 		return models.BuildRunResultsModel{}, fmt.Errorf("Failed to set BITRISE_TRIGGERED_WORKFLOW_TITLE env: %s", err)
 	}
 
-	environments = append(environments, workflowToRun.Environments...)
+	environments = append(environments, triggeredWorkflowEnvironments(workflowToRun)...)
 
 	lastWorkflowID, err := lastWorkflowIDInConfig(workflowToRunID, bitriseConfig)
 	if err != nil {
@@ -1097,9 +1205,16 @@ func runWorkflowWithConfiguration(
 		StartTime:   startTime,
 		ProjectType: bitriseConfig.ProjectType,
 	}
-	if err := plugins.TriggerEvent(plugins.WillStartRun, buildRunStartModel); err != nil {
+	pluginResults, err := plugins.TriggerEvent(plugins.WillStartRun, buildRunStartModel)
+	if err != nil {
 		log.Warnf("Failed to trigger WillStartRun, error: %s", err)
 	}
+	for _, pluginResult := range pluginResults {
+		if pluginResult.AbortBuild {
+			return models.BuildRunResultsModel{}, fmt.Errorf("Build aborted by plugin (%s): %s", pluginResult.PluginName, pluginResult.AbortReason)
+		}
+		environments = append(environments, pluginResult.EnvironmentItems()...)
+	}
 
 	//
 	buildRunResults := models.BuildRunResultsModel{
@@ -1122,13 +1237,22 @@ func runWorkflowWithConfiguration(
 
 	// Trigger WorkflowRunDidFinish
 	buildRunResults.EventName = string(plugins.DidFinishRun)
-	if err := plugins.TriggerEvent(plugins.DidFinishRun, buildRunResults); err != nil {
+	if _, err := plugins.TriggerEvent(plugins.DidFinishRun, buildRunResults); err != nil {
 		log.Warnf("Failed to trigger WorkflowRunDidFinish, error: %s", err)
 	}
 
 	return buildRunResults, nil
 }
 
+// triggeredWorkflowEnvironments returns the envs of the triggered workflow, which are declared for its before_run workflows too:
+// every env of the workflow, unless its envs are isolated, those are declared when it starts, so that they are dropped when it finishes.
+func triggeredWorkflowEnvironments(workflow models.WorkflowModel) []envmanModels.EnvironmentItemModel {
+	if workflow.IsolateEnvs {
+		return []envmanModels.EnvironmentItemModel{}
+	}
+	return workflow.Environments
+}
+
 func addTestMetadata(testDirPath string, testResultStepInfo models.TestResultStepInfo) error {
 	// check if the test dir is empty
 	if empty, err := isDirEmpty(testDirPath); err != nil {
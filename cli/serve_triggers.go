@@ -0,0 +1,345 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/utils"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/urfave/cli"
+)
+
+const (
+	defaultServeTriggersHost      = "127.0.0.1"
+	defaultServeTriggersPort      = 8080
+	defaultServeTriggersQueueSize = 10
+
+	// maxWebhookPayloadSize is the limit of the accepted webhook payload size (GitHub's limit is 25MB)
+	maxWebhookPayloadSize = 25 * 1024 * 1024
+
+	webhookSecretEnvKey = "BITRISE_WEBHOOK_SECRET"
+)
+
+var serveTriggersCommand = cli.Command{
+	Name:  "serve-triggers",
+	Usage: "Receives GitHub, GitLab and Bitbucket push, pull request and tag webhooks, and triggers the matching workflow.",
+	Action: func(c *cli.Context) error {
+		if err := serveTriggers(c); err != nil {
+			log.Errorf("Failed to serve triggers, error: %s", err)
+			os.Exit(1)
+		}
+		return nil
+	},
+	Flags: []cli.Flag{
+		cli.StringFlag{Name: HostKey, Value: defaultServeTriggersHost, Usage: "The address of the webhook endpoint, use 0.0.0.0 to listen on every network interface."},
+		cli.IntFlag{Name: PortKey, Value: defaultServeTriggersPort, Usage: "The port of the webhook endpoint."},
+		cli.StringFlag{Name: WebhookSecretKey, EnvVar: webhookSecretEnvKey, Usage: "The webhook secret (GitHub, Bitbucket) or token (GitLab) to verify the webhooks with."},
+		cli.BoolFlag{Name: InsecureKey, Usage: "Accept unsigned webhooks, if no webhook secret is set."},
+		cli.IntFlag{Name: QueueSizeKey, Value: defaultServeTriggersQueueSize, Usage: "The maximum number of builds waiting to run."},
+		cli.StringFlag{Name: ConfigKey + ", " + configShortKey, Usage: "Path where the workflow config file is located."},
+		cli.StringFlag{Name: InventoryKey + ", " + inventoryShortKey, Usage: "Path of the inventory file."},
+	},
+}
+
+// triggerQueue holds the builds to run, the builds run one by one as they share the source dir.
+type triggerQueue struct {
+	mu      sync.Mutex
+	pending chan webhookTrigger
+}
+
+func newTriggerQueue(size int) *triggerQueue {
+	return &triggerQueue{pending: make(chan webhookTrigger, size)}
+}
+
+// enqueue adds all of the triggers to the queue, or none of them if the queue has no room for all.
+func (queue *triggerQueue) enqueue(triggers ...webhookTrigger) bool {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if len(queue.pending)+len(triggers) > cap(queue.pending) {
+		return false
+	}
+	for _, trigger := range triggers {
+		queue.pending <- trigger
+	}
+	return true
+}
+
+// process runs the queued triggers, it returns only if the queue is closed.
+func (queue *triggerQueue) process(runFn func(webhookTrigger) error) {
+	for trigger := range queue.pending {
+		if err := runFn(trigger); err != nil {
+			log.Errorf("Triggered build failed, error: %s", err)
+		}
+	}
+}
+
+type serveTriggersResponse struct {
+	Status   string           `json:"status"`
+	Triggers []webhookTrigger `json:"triggers,omitempty"`
+}
+
+func writeServeTriggersResponse(w http.ResponseWriter, statusCode int, response serveTriggersResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Warnf("Failed to write response, error: %s", err)
+	}
+}
+
+func newServeTriggersHandler(secret string, queue *triggerQueue) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST requests are accepted", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookPayloadSize))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read payload, error: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		provider, err := webhookProvider(r.Header)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if secret != "" {
+			if err := verifyWebhookSecret(provider, r.Header, body, secret); err != nil {
+				log.Warnf("Rejected %s webhook: %s", provider, err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		triggers, err := parseWebhook(provider, r.Header, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(triggers) == 0 {
+			writeServeTriggersResponse(w, http.StatusOK, serveTriggersResponse{Status: "ignored"})
+			return
+		}
+
+		if !queue.enqueue(triggers...) {
+			log.Warnf("Rejected %s webhook: the build queue is full", provider)
+			http.Error(w, "the build queue is full", http.StatusServiceUnavailable)
+			return
+		}
+
+		for _, trigger := range triggers {
+			log.Infof("Queued %s build: %s", provider, triggerParamsString(trigger.Params))
+		}
+		writeServeTriggersResponse(w, http.StatusAccepted, serveTriggersResponse{Status: "queued", Triggers: triggers})
+	})
+}
+
+func triggerParamsString(params RunAndTriggerParamsModel) string {
+	if params.PushBranch != "" {
+		return fmt.Sprintf("push-branch: %s", params.PushBranch)
+	}
+	if params.Tag != "" {
+		return fmt.Sprintf("tag: %s", params.Tag)
+	}
+	return fmt.Sprintf("pr-source-branch: %s, pr-target-branch: %s", params.PRSourceBranch, params.PRTargetBranch)
+}
+
+// checkWebhookSecret fails if no webhook secret is set, unless unsigned webhooks are accepted explicitly.
+func checkWebhookSecret(secret string, insecure bool) error {
+	if secret != "" {
+		return nil
+	}
+	if !insecure {
+		return fmt.Errorf("no webhook secret defined, set it with --%s or %s, or accept unsigned webhooks with --%s", WebhookSecretKey, webhookSecretEnvKey, InsecureKey)
+	}
+	log.Warnf("No webhook secret defined (--%s or %s), every webhook is accepted", WebhookSecretKey, webhookSecretEnvKey)
+	return nil
+}
+
+func runGit(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if outBytes, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s failed, output: %s, error: %s", strings.Join(args, " "), strings.TrimSpace(string(outBytes)), err)
+	}
+	return nil
+}
+
+// checkServeTriggersSourceDir checks that the builds' source dir is a git clone with an origin remote,
+// the webhooks' commits are fetched from it.
+func checkServeTriggersSourceDir(sourceDir string) error {
+	if err := runGit(sourceDir, "remote", "get-url", "origin"); err != nil {
+		return fmt.Errorf("%s is not a git clone of the repository sending the webhooks, %s", sourceDir, err)
+	}
+	return nil
+}
+
+// checkoutWebhookCommit checks out the webhook's commit in the source dir, and lists the changed files of the build
+// if the webhook does not list them (Bitbucket pushes, pull requests): the files changed since the commit before the push,
+// or the files changed compared to the pull request's target branch.
+// Otherwise the triggered build compares the commit to its previous commit.
+func checkoutWebhookCommit(sourceDir string, trigger *webhookTrigger) error {
+	commit := trigger.Envs[configs.GitCommitEnvKey]
+	if commit == "" {
+		return errors.New("the webhook has no commit to check out")
+	}
+	if err := validateGitCommitHash(commit); err != nil {
+		return err
+	}
+
+	// the webhook's values are passed after --end-of-options, so git never parses them as options
+	fetchArgs := []string{"fetch", "--quiet", "--end-of-options", "origin", commit}
+	targetBranch := trigger.Params.PRTargetBranch
+	if targetBranch != "" {
+		if err := validateGitBranchName(targetBranch); err != nil {
+			return err
+		}
+		fetchArgs = append(fetchArgs, fmt.Sprintf("+%s%s:refs/remotes/origin/%s", gitBranchRefPrefix, targetBranch, targetBranch))
+	}
+	if err := runGit(sourceDir, fetchArgs...); err != nil {
+		return err
+	}
+	if err := runGit(sourceDir, "switch", "--quiet", "--discard-changes", "--detach", "--end-of-options", commit); err != nil {
+		return err
+	}
+
+	if len(trigger.Params.ChangedFiles) > 0 {
+		return nil
+	}
+	baseRef := trigger.BaseCommit
+	if baseRef != "" {
+		if err := validateGitCommitHash(baseRef); err != nil {
+			return err
+		}
+	}
+	if targetBranch != "" {
+		baseRef = utils.ChangedFilesBaseRef(targetBranch)
+	}
+	if baseRef == "" {
+		return nil
+	}
+	changedFiles, err := utils.GitChangedFiles(sourceDir, baseRef, commit)
+	if err != nil {
+		log.Warnf("Failed to list the changed files, the build compares the commit to its previous commit: %s", err)
+		return nil
+	}
+	trigger.Params.ChangedFiles = changedFiles
+	return nil
+}
+
+// webhookTriggerCmd returns the bitrise trigger command of the webhook trigger,
+// the command runs the matching workflow with the git info envs of the webhook.
+func webhookTriggerCmd(bitrisePth, sourceDir, bitriseConfigPath, inventoryPath string, trigger webhookTrigger) (*exec.Cmd, error) {
+	params := trigger.Params
+	params.BitriseConfigPath = bitriseConfigPath
+	params.InventoryPath = inventoryPath
+
+	paramsBytes, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(trigger.Envs))
+	for key := range trigger.Envs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	envs := os.Environ()
+	for _, key := range keys {
+		envs = append(envs, key+"="+trigger.Envs[key])
+	}
+
+	cmd := exec.Command(bitrisePth, "trigger", "--"+JSONParamsKey, string(paramsBytes))
+	cmd.Dir = sourceDir
+	cmd.Env = envs
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd, nil
+}
+
+func serveTriggers(c *cli.Context) error {
+	address := net.JoinHostPort(c.String(HostKey), strconv.Itoa(c.Int(PortKey)))
+	secret := c.String(WebhookSecretKey)
+	queueSize := c.Int(QueueSizeKey)
+	if queueSize < 1 {
+		return fmt.Errorf("invalid queue size: %d", queueSize)
+	}
+	if err := checkWebhookSecret(secret, c.Bool(InsecureKey)); err != nil {
+		return err
+	}
+
+	// the builds run in the current dir, the webhook's commit is checked out before each build
+	sourceDir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := checkServeTriggersSourceDir(sourceDir); err != nil {
+		return err
+	}
+
+	// the config and the inventory is read by every triggered build, the paths are checked upfront
+	bitriseConfigPath, err := GetBitriseConfigFilePath(c.String(ConfigKey))
+	if err != nil {
+		return err
+	}
+	if bitriseConfigPath, err = pathutil.AbsPath(bitriseConfigPath); err != nil {
+		return err
+	}
+	bitriseConfig, warnings, err := CreateBitriseConfigFromCLIParams("", bitriseConfigPath)
+	for _, warning := range warnings {
+		log.Warnf("warning: %s", warning)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create bitrise config, error: %s", err)
+	}
+	if len(bitriseConfig.TriggerMap) == 0 {
+		return fmt.Errorf("no trigger_map defined in %s", bitriseConfigPath)
+	}
+
+	inventoryPath, err := GetInventoryFilePath(c.String(InventoryKey))
+	if err != nil {
+		return err
+	}
+	if inventoryPath != "" {
+		if inventoryPath, err = pathutil.AbsPath(inventoryPath); err != nil {
+			return err
+		}
+	}
+
+	bitrisePth, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get the path of bitrise, error: %s", err)
+	}
+
+	queue := newTriggerQueue(queueSize)
+	go queue.process(func(trigger webhookTrigger) error {
+		log.Infof("Triggering %s build: %s", trigger.Provider, triggerParamsString(trigger.Params))
+		if err := checkoutWebhookCommit(sourceDir, &trigger); err != nil {
+			return fmt.Errorf("failed to check out the commit of the build, error: %s", err)
+		}
+		cmd, err := webhookTriggerCmd(bitrisePth, sourceDir, bitriseConfigPath, inventoryPath, trigger)
+		if err != nil {
+			return err
+		}
+		return cmd.Run()
+	})
+
+	log.Infof("Listening for webhooks on %s", address)
+	return http.ListenAndServe(address, newServeTriggersHandler(secret, queue))
+}
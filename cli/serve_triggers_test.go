@@ -0,0 +1,420 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWebhook(t *testing.T) {
+	t.Log("GitHub push")
+	{
+		header := http.Header{"X-Github-Event": []string{"push"}}
+		body := `{"ref": "refs/heads/master", "before": "999aaa0000000000000000000000000000000000", "after": "abc1230000000000000000000000000000000000", "deleted": false,
+			"head_commit": {"id": "abc1230000000000000000000000000000000000", "message": "Fix crash"},
+			"commits": [
+				{"id": "0aa", "added": ["apps/ios/App.swift"], "modified": ["README.md"]},
+				{"id": "abc1230000000000000000000000000000000000", "modified": ["README.md"], "removed": ["old.txt"]}
+			]}`
+
+		triggers, err := parseWebhook(webhookProviderGitHub, header, []byte(body))
+		require.NoError(t, err)
+		require.Equal(t, 1, len(triggers))
+		require.Equal(t, RunAndTriggerParamsModel{
+			PushBranch:    "master",
+			CommitMessage: "Fix crash",
+			ChangedFiles:  []string{"apps/ios/App.swift", "README.md", "old.txt"},
+		}, triggers[0].Params)
+		require.Equal(t, map[string]string{
+			"BITRISE_GIT_BRANCH":  "master",
+			"BITRISE_GIT_COMMIT":  "abc1230000000000000000000000000000000000",
+			"BITRISE_GIT_MESSAGE": "Fix crash",
+		}, triggers[0].Envs)
+		require.Equal(t, "999aaa0000000000000000000000000000000000", triggers[0].BaseCommit)
+	}
+
+	t.Log("GitHub tag push")
+	{
+		header := http.Header{"X-Github-Event": []string{"push"}}
+		body := `{"ref": "refs/tags/v1.0.0", "after": "abc1230000000000000000000000000000000000", "head_commit": {"id": "abc1230000000000000000000000000000000000", "message": "Release"}}`
+
+		triggers, err := parseWebhook(webhookProviderGitHub, header, []byte(body))
+		require.NoError(t, err)
+		require.Equal(t, 1, len(triggers))
+		require.Equal(t, "v1.0.0", triggers[0].Params.Tag)
+		require.Equal(t, "", triggers[0].Params.PushBranch)
+		require.Equal(t, "v1.0.0", triggers[0].Envs["BITRISE_GIT_TAG"])
+	}
+
+	t.Log("GitHub branch deletion")
+	{
+		header := http.Header{"X-Github-Event": []string{"push"}}
+		triggers, err := parseWebhook(webhookProviderGitHub, header, []byte(`{"ref": "refs/heads/feature", "deleted": true}`))
+		require.NoError(t, err)
+		require.Equal(t, 0, len(triggers))
+	}
+
+	t.Log("GitHub pull request")
+	{
+		header := http.Header{"X-Github-Event": []string{"pull_request"}}
+		body := `{"action": "opened", "number": 42, "pull_request": {"title": "Add login", "draft": true,
+			"labels": [{"name": "ui"}, {"name": "bug"}],
+			"head": {"ref": "feature/login", "sha": "def4560000000000000000000000000000000000"}, "base": {"ref": "master"}}}`
+
+		triggers, err := parseWebhook(webhookProviderGitHub, header, []byte(body))
+		require.NoError(t, err)
+		require.Equal(t, 1, len(triggers))
+		require.Equal(t, RunAndTriggerParamsModel{
+			PRSourceBranch: "feature/login",
+			PRTargetBranch: "master",
+			CommitMessage:  "Add login",
+			PRLabels:       []string{"ui", "bug"},
+			IsDraftPR:      true,
+		}, triggers[0].Params)
+		require.Equal(t, "42", triggers[0].Envs["PULL_REQUEST_ID"])
+		require.Equal(t, "master", triggers[0].Envs["BITRISEIO_GIT_BRANCH_DEST"])
+		require.Equal(t, "feature/login", triggers[0].Envs["BITRISE_GIT_BRANCH"])
+	}
+
+	t.Log("GitHub closed pull request and ping")
+	{
+		triggers, err := parseWebhook(webhookProviderGitHub, http.Header{"X-Github-Event": []string{"pull_request"}}, []byte(`{"action": "closed"}`))
+		require.NoError(t, err)
+		require.Equal(t, 0, len(triggers))
+
+		triggers, err = parseWebhook(webhookProviderGitHub, http.Header{"X-Github-Event": []string{"ping"}}, []byte(`{"zen": "Keep it simple."}`))
+		require.NoError(t, err)
+		require.Equal(t, 0, len(triggers))
+	}
+
+	t.Log("GitLab push")
+	{
+		header := http.Header{"X-Gitlab-Event": []string{"Push Hook"}}
+		body := `{"ref": "refs/heads/develop", "after": "abc1230000000000000000000000000000000000", "checkout_sha": "abc1230000000000000000000000000000000000",
+			"commits": [{"id": "abc1230000000000000000000000000000000000", "message": "Update deps", "modified": ["go.mod"]}]}`
+
+		triggers, err := parseWebhook(webhookProviderGitLab, header, []byte(body))
+		require.NoError(t, err)
+		require.Equal(t, 1, len(triggers))
+		require.Equal(t, RunAndTriggerParamsModel{
+			PushBranch:    "develop",
+			CommitMessage: "Update deps",
+			ChangedFiles:  []string{"go.mod"},
+		}, triggers[0].Params)
+		require.Equal(t, "abc1230000000000000000000000000000000000", triggers[0].Envs["BITRISE_GIT_COMMIT"])
+	}
+
+	t.Log("GitLab tag deletion")
+	{
+		header := http.Header{"X-Gitlab-Event": []string{"Tag Push Hook"}}
+		body := `{"ref": "refs/tags/v1.0.0", "after": "0000000000000000000000000000000000000000", "checkout_sha": null}`
+
+		triggers, err := parseWebhook(webhookProviderGitLab, header, []byte(body))
+		require.NoError(t, err)
+		require.Equal(t, 0, len(triggers))
+	}
+
+	t.Log("GitLab merge request")
+	{
+		header := http.Header{"X-Gitlab-Event": []string{"Merge Request Hook"}}
+		body := `{"object_attributes": {"iid": 7, "action": "update", "source_branch": "fix", "target_branch": "main",
+			"work_in_progress": true, "last_commit": {"id": "fed3210000000000000000000000000000000000", "message": "Fix typo"}},
+			"labels": [{"title": "docs"}]}`
+
+		triggers, err := parseWebhook(webhookProviderGitLab, header, []byte(body))
+		require.NoError(t, err)
+		require.Equal(t, 1, len(triggers))
+		require.Equal(t, RunAndTriggerParamsModel{
+			PRSourceBranch: "fix",
+			PRTargetBranch: "main",
+			CommitMessage:  "Fix typo",
+			PRLabels:       []string{"docs"},
+			IsDraftPR:      true,
+		}, triggers[0].Params)
+		require.Equal(t, "7", triggers[0].Envs["PULL_REQUEST_ID"])
+		require.Equal(t, "fed3210000000000000000000000000000000000", triggers[0].Envs["BITRISE_GIT_COMMIT"])
+	}
+
+	t.Log("Bitbucket push of a branch and a tag")
+	{
+		header := http.Header{"X-Event-Key": []string{"repo:push"}}
+		body := `{"push": {"changes": [
+			{"old": {"target": {"hash": "999aaa0000000000000000000000000000000000"}}, "new": {"type": "branch", "name": "master", "target": {"hash": "abc1230000000000000000000000000000000000", "message": "Merge fix\n"}}},
+			{"new": null},
+			{"old": null, "new": {"type": "tag", "name": "v2.0.0", "target": {"hash": "abc1230000000000000000000000000000000000", "message": "Merge fix\n"}}}
+		]}}`
+
+		triggers, err := parseWebhook(webhookProviderBitbucket, header, []byte(body))
+		require.NoError(t, err)
+		require.Equal(t, 2, len(triggers))
+		require.Equal(t, "master", triggers[0].Params.PushBranch)
+		require.Equal(t, "Merge fix\n", triggers[0].Params.CommitMessage)
+		require.Equal(t, "999aaa0000000000000000000000000000000000", triggers[0].BaseCommit)
+		require.Equal(t, "v2.0.0", triggers[1].Params.Tag)
+		require.Equal(t, "", triggers[1].BaseCommit)
+		require.Equal(t, "abc1230000000000000000000000000000000000", triggers[1].Envs["BITRISE_GIT_COMMIT"])
+	}
+
+	t.Log("Bitbucket pull request")
+	{
+		header := http.Header{"X-Event-Key": []string{"pullrequest:created"}}
+		body := `{"pullrequest": {"id": 3, "title": "Add feature",
+			"source": {"branch": {"name": "feature"}, "commit": {"hash": "aaa1110000000000000000000000000000000000"}},
+			"destination": {"branch": {"name": "master"}}}}`
+
+		triggers, err := parseWebhook(webhookProviderBitbucket, header, []byte(body))
+		require.NoError(t, err)
+		require.Equal(t, 1, len(triggers))
+		require.Equal(t, RunAndTriggerParamsModel{
+			PRSourceBranch: "feature",
+			PRTargetBranch: "master",
+			CommitMessage:  "Add feature",
+		}, triggers[0].Params)
+		require.Equal(t, "3", triggers[0].Envs["PULL_REQUEST_ID"])
+	}
+
+	t.Log("option as commit or target branch")
+	{
+		header := http.Header{"X-Github-Event": []string{"push"}}
+		_, err := parseWebhook(webhookProviderGitHub, header, []byte(`{"ref": "refs/heads/master", "after": "--upload-pack=touch pwned"}`))
+		require.EqualError(t, err, `invalid commit hash: "--upload-pack=touch pwned"`)
+
+		_, err = parseWebhook(webhookProviderGitHub, header, []byte(`{"ref": "refs/heads/master", "before": "--output=pwned", "after": "abc1230000000000000000000000000000000000"}`))
+		require.EqualError(t, err, `invalid base commit, error: invalid commit hash: "--output=pwned"`)
+
+		header = http.Header{"X-Github-Event": []string{"pull_request"}}
+		_, err = parseWebhook(webhookProviderGitHub, header, []byte(`{"action": "opened", "number": 42, "pull_request": {
+			"head": {"ref": "feature", "sha": "def4560000000000000000000000000000000000"}, "base": {"ref": "--upload-pack=touch pwned"}}}`))
+		require.EqualError(t, err, `invalid branch name: "--upload-pack=touch pwned"`)
+	}
+
+	t.Log("invalid payload")
+	{
+		_, err := parseWebhook(webhookProviderGitHub, http.Header{"X-Github-Event": []string{"push"}}, []byte(`not json`))
+		require.Error(t, err)
+	}
+}
+
+func TestVerifyWebhookSecret(t *testing.T) {
+	body := []byte(`{"ref": "refs/heads/master"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	_, err := mac.Write(body)
+	require.NoError(t, err)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	t.Log("GitHub signature")
+	{
+		require.NoError(t, verifyWebhookSecret(webhookProviderGitHub, http.Header{"X-Hub-Signature-256": []string{signature}}, body, "secret"))
+		require.EqualError(t, verifyWebhookSecret(webhookProviderGitHub, http.Header{"X-Hub-Signature-256": []string{signature}}, body, "other"), "invalid X-Hub-Signature-256")
+		require.EqualError(t, verifyWebhookSecret(webhookProviderGitHub, http.Header{}, body, "secret"), "missing or not sha256 X-Hub-Signature")
+	}
+
+	t.Log("Bitbucket signature")
+	{
+		require.NoError(t, verifyWebhookSecret(webhookProviderBitbucket, http.Header{"X-Hub-Signature": []string{signature}}, body, "secret"))
+	}
+
+	t.Log("GitLab token")
+	{
+		require.NoError(t, verifyWebhookSecret(webhookProviderGitLab, http.Header{"X-Gitlab-Token": []string{"secret"}}, body, "secret"))
+		require.EqualError(t, verifyWebhookSecret(webhookProviderGitLab, http.Header{"X-Gitlab-Token": []string{"wrong"}}, body, "secret"), "invalid X-Gitlab-Token")
+	}
+}
+
+func TestServeTriggersHandler(t *testing.T) {
+	pushBody := `{"ref": "refs/heads/master", "after": "abc1230000000000000000000000000000000000", "head_commit": {"id": "abc1230000000000000000000000000000000000", "message": "Fix"}}`
+	post := func(handler http.Handler, event, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+		req.Header.Set("X-Gitlab-Event", event)
+		req.Header.Set("X-Gitlab-Token", token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	gitLabPushBody := strings.Replace(pushBody, `"after"`, `"checkout_sha": "abc1230000000000000000000000000000000000", "after"`, 1)
+
+	queue := newTriggerQueue(1)
+	handler := newServeTriggersHandler("secret", queue)
+
+	t.Log("queued")
+	{
+		rec := post(handler, "Push Hook", "secret", gitLabPushBody)
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		require.Contains(t, rec.Body.String(), `"status":"queued"`)
+		require.Equal(t, 1, len(queue.pending))
+	}
+
+	t.Log("queue is full")
+	{
+		rec := post(handler, "Push Hook", "secret", gitLabPushBody)
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		require.Equal(t, 1, len(queue.pending))
+	}
+
+	t.Log("ignored event")
+	{
+		rec := post(handler, "Issue Hook", "secret", `{}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), `"status":"ignored"`)
+	}
+
+	t.Log("invalid token")
+	{
+		rec := post(handler, "Push Hook", "wrong", gitLabPushBody)
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+
+	t.Log("unknown provider and method")
+	{
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(pushBody)))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	}
+
+	t.Log("processing the queue")
+	{
+		processed := []webhookTrigger{}
+		close(queue.pending)
+		queue.process(func(trigger webhookTrigger) error {
+			processed = append(processed, trigger)
+			return nil
+		})
+		require.Equal(t, 1, len(processed))
+		require.Equal(t, "master", processed[0].Params.PushBranch)
+	}
+}
+
+func TestWebhookTriggerCmd(t *testing.T) {
+	trigger, err := newWebhookTrigger(webhookProviderGitHub, RunAndTriggerParamsModel{PushBranch: "master", CommitMessage: "Fix"}, "8d5d2f6b7c3a8e4f1b0c9a7d6e5f4a3b2c1d0e9f", "")
+	require.NoError(t, err)
+
+	cmd, err := webhookTriggerCmd("/usr/local/bin/bitrise", "/src", "/src/bitrise.yml", "", trigger)
+	require.NoError(t, err)
+	require.Equal(t, "/usr/local/bin/bitrise", cmd.Path)
+	require.Equal(t, "/src", cmd.Dir)
+	require.Equal(t, "trigger", cmd.Args[1])
+	require.Equal(t, "--json-params", cmd.Args[2])
+	require.Contains(t, cmd.Args[3], `"push-branch":"master"`)
+	require.Contains(t, cmd.Args[3], `"config":"/src/bitrise.yml"`)
+	require.Contains(t, cmd.Args[3], `"commit-message":"Fix"`)
+
+	envs := cmd.Env[len(cmd.Env)-3:]
+	require.Equal(t, []string{"BITRISE_GIT_BRANCH=master", "BITRISE_GIT_COMMIT=8d5d2f6b7c3a8e4f1b0c9a7d6e5f4a3b2c1d0e9f", "BITRISE_GIT_MESSAGE=Fix"}, envs)
+}
+
+func TestCheckWebhookSecret(t *testing.T) {
+	require.NoError(t, checkWebhookSecret("secret", false))
+	require.NoError(t, checkWebhookSecret("", true))
+	require.EqualError(t, checkWebhookSecret("", false), "no webhook secret defined, set it with --secret or BITRISE_WEBHOOK_SECRET, or accept unsigned webhooks with --insecure")
+}
+
+func TestCheckoutWebhookCommit(t *testing.T) {
+	tmpDir, err := pathutil.NormalizedOSTempDirPath("serve_triggers_test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tmpDir))
+	}()
+
+	git := func(dir string, args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Bitrise", "-c", "user.email=bitrise@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+	commit := func(dir, file string) string {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, file), []byte(file), 0644))
+		git(dir, "add", file)
+		git(dir, "commit", "-m", file)
+		return git(dir, "rev-parse", "HEAD")
+	}
+
+	originDir := filepath.Join(tmpDir, "origin")
+	require.NoError(t, os.MkdirAll(originDir, 0755))
+	git(originDir, "init", "--quiet")
+	git(originDir, "checkout", "--quiet", "-b", "master")
+	commit(originDir, "README.md")
+
+	sourceDir := filepath.Join(tmpDir, "source")
+	git(tmpDir, "clone", "--quiet", originDir, sourceDir)
+	require.NoError(t, checkServeTriggersSourceDir(sourceDir))
+	require.Error(t, checkServeTriggersSourceDir(tmpDir))
+
+	// commits pushed after the clone
+	baseCommit := git(originDir, "rev-parse", "HEAD")
+	commit(originDir, "a.txt")
+	pushedCommit := commit(originDir, "b.txt")
+	git(originDir, "checkout", "--quiet", "-b", "feature")
+	featureCommit := commit(originDir, "c.txt")
+	git(originDir, "checkout", "--quiet", "master")
+	commit(originDir, "d.txt")
+
+	t.Log("push without changed files")
+	{
+		trigger, err := newWebhookTrigger(webhookProviderBitbucket, RunAndTriggerParamsModel{PushBranch: "master"}, pushedCommit, "")
+		require.NoError(t, err)
+		trigger.BaseCommit = baseCommit
+
+		require.NoError(t, checkoutWebhookCommit(sourceDir, &trigger))
+		require.Equal(t, pushedCommit, git(sourceDir, "rev-parse", "HEAD"))
+		require.Equal(t, []string{"a.txt", "b.txt"}, trigger.Params.ChangedFiles)
+	}
+
+	t.Log("push with changed files")
+	{
+		trigger, err := newWebhookTrigger(webhookProviderGitHub, RunAndTriggerParamsModel{PushBranch: "master", ChangedFiles: []string{"b.txt"}}, pushedCommit, "")
+		require.NoError(t, err)
+		trigger.BaseCommit = baseCommit
+
+		require.NoError(t, checkoutWebhookCommit(sourceDir, &trigger))
+		require.Equal(t, []string{"b.txt"}, trigger.Params.ChangedFiles)
+	}
+
+	t.Log("pull request")
+	{
+		trigger, err := newWebhookTrigger(webhookProviderBitbucket, RunAndTriggerParamsModel{PRSourceBranch: "feature", PRTargetBranch: "master"}, featureCommit, "3")
+		require.NoError(t, err)
+
+		require.NoError(t, checkoutWebhookCommit(sourceDir, &trigger))
+		require.Equal(t, featureCommit, git(sourceDir, "rev-parse", "HEAD"))
+		require.Equal(t, []string{"c.txt"}, trigger.Params.ChangedFiles)
+	}
+
+	t.Log("unknown commit")
+	{
+		trigger, err := newWebhookTrigger(webhookProviderGitHub, RunAndTriggerParamsModel{PushBranch: "master"}, gitZeroSHA, "")
+		require.NoError(t, err)
+		require.Error(t, checkoutWebhookCommit(sourceDir, &trigger))
+
+		require.EqualError(t, checkoutWebhookCommit(sourceDir, &webhookTrigger{}), "the webhook has no commit to check out")
+	}
+
+	t.Log("option as commit, base commit or target branch")
+	{
+		trigger := webhookTrigger{Envs: map[string]string{configs.GitCommitEnvKey: "--upload-pack=touch pwned"}}
+		require.EqualError(t, checkoutWebhookCommit(sourceDir, &trigger), `invalid commit hash: "--upload-pack=touch pwned"`)
+
+		trigger = webhookTrigger{Envs: map[string]string{configs.GitCommitEnvKey: featureCommit}, BaseCommit: "--output=pwned"}
+		require.EqualError(t, checkoutWebhookCommit(sourceDir, &trigger), `invalid commit hash: "--output=pwned"`)
+
+		trigger = webhookTrigger{Envs: map[string]string{configs.GitCommitEnvKey: featureCommit}, Params: RunAndTriggerParamsModel{PRTargetBranch: "--upload-pack=touch pwned"}}
+		require.EqualError(t, checkoutWebhookCommit(sourceDir, &trigger), `invalid branch name: "--upload-pack=touch pwned"`)
+	}
+}
@@ -0,0 +1,496 @@
+package cli
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/bitrise/configs"
+)
+
+const (
+	gitZeroSHA = "0000000000000000000000000000000000000000"
+
+	webhookProviderGitHub    = "github"
+	webhookProviderGitLab    = "gitlab"
+	webhookProviderBitbucket = "bitbucket"
+
+	gitBranchRefPrefix = "refs/heads/"
+	gitTagRefPrefix    = "refs/tags/"
+)
+
+// gitCommitHashRegexp matches a full SHA-1 or SHA-256 commit hash,
+// the commits of a webhook are passed to git, so they must not be parsed as an option or a revision expression.
+var gitCommitHashRegexp = regexp.MustCompile(`^[0-9a-f]{40}([0-9a-f]{24})?$`)
+
+// validateGitCommitHash returns an error if the commit of the webhook is not a full commit hash.
+func validateGitCommitHash(commit string) error {
+	if !gitCommitHashRegexp.MatchString(commit) {
+		return fmt.Errorf("invalid commit hash: %q", commit)
+	}
+	return nil
+}
+
+// validateGitBranchName returns an error if the branch of the webhook is not a valid branch name (see git check-ref-format),
+// the pull request target branch is passed to git in a refspec.
+func validateGitBranchName(branch string) error {
+	isValid := branch != "" &&
+		!strings.HasPrefix(branch, "-") && !strings.HasPrefix(branch, "/") &&
+		!strings.HasSuffix(branch, "/") && !strings.HasSuffix(branch, ".") && !strings.HasSuffix(branch, ".lock") &&
+		!strings.Contains(branch, "..") && !strings.Contains(branch, "//") && !strings.Contains(branch, "/.") &&
+		!strings.Contains(branch, "@{") && branch != "@" && !strings.HasPrefix(branch, ".") &&
+		!strings.ContainsAny(branch, " ~^:?*[\\\x7f")
+	for _, r := range branch {
+		if r < 0x20 {
+			isValid = false
+		}
+	}
+	if !isValid {
+		return fmt.Errorf("invalid branch name: %q", branch)
+	}
+	return nil
+}
+
+// webhookTrigger is a build to trigger, parsed from a git provider's webhook payload.
+type webhookTrigger struct {
+	Provider string                   `json:"provider"`
+	Params   RunAndTriggerParamsModel `json:"params"`
+	// Envs are the git info envs of the build, e.g. BITRISE_GIT_COMMIT
+	Envs map[string]string `json:"envs"`
+	// BaseCommit is the commit the pushed branch pointed to before the push, if known
+	BaseCommit string `json:"base_commit,omitempty"`
+}
+
+func newWebhookTrigger(provider string, params RunAndTriggerParamsModel, commitHash, pullRequestID string) (webhookTrigger, error) {
+	if err := validateGitCommitHash(commitHash); err != nil {
+		return webhookTrigger{}, err
+	}
+	if params.PRTargetBranch != "" {
+		if err := validateGitBranchName(params.PRTargetBranch); err != nil {
+			return webhookTrigger{}, err
+		}
+	}
+
+	envs := map[string]string{}
+	addEnv := func(key, value string) {
+		if value != "" {
+			envs[key] = value
+		}
+	}
+
+	if params.PushBranch != "" {
+		addEnv(configs.GitBranchEnvKey, params.PushBranch)
+	} else {
+		addEnv(configs.GitBranchEnvKey, params.PRSourceBranch)
+	}
+	addEnv(configs.GitBranchDestEnvKey, params.PRTargetBranch)
+	addEnv(configs.GitTagEnvKey, params.Tag)
+	addEnv(configs.GitCommitEnvKey, commitHash)
+	addEnv(configs.GitMessageEnvKey, params.CommitMessage)
+	addEnv(configs.PullRequestIDEnvKey, pullRequestID)
+
+	return webhookTrigger{
+		Provider: provider,
+		Params:   params,
+		Envs:     envs,
+	}, nil
+}
+
+// webhookProvider returns the git provider sending the webhook, based on its event header.
+func webhookProvider(header http.Header) (string, error) {
+	switch {
+	case header.Get("X-GitHub-Event") != "":
+		return webhookProviderGitHub, nil
+	case header.Get("X-Gitlab-Event") != "":
+		return webhookProviderGitLab, nil
+	case header.Get("X-Event-Key") != "":
+		return webhookProviderBitbucket, nil
+	}
+	return "", errors.New("unsupported webhook: none of the X-GitHub-Event, X-Gitlab-Event and X-Event-Key headers found")
+}
+
+// verifyWebhookSecret checks the webhook's signature (GitHub, Bitbucket) or token (GitLab) against the secret.
+func verifyWebhookSecret(provider string, header http.Header, body []byte, secret string) error {
+	if provider == webhookProviderGitLab {
+		token := header.Get("X-Gitlab-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			return errors.New("invalid X-Gitlab-Token")
+		}
+		return nil
+	}
+
+	signatureHeader := "X-Hub-Signature-256"
+	signature := header.Get(signatureHeader)
+	if signature == "" {
+		// Bitbucket sends the sha256 signature in the X-Hub-Signature header
+		signatureHeader = "X-Hub-Signature"
+		signature = header.Get(signatureHeader)
+	}
+	if !strings.HasPrefix(signature, "sha256=") {
+		return fmt.Errorf("missing or not sha256 %s", signatureHeader)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	if _, err := mac.Write(body); err != nil {
+		return err
+	}
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return fmt.Errorf("invalid %s", signatureHeader)
+	}
+	return nil
+}
+
+// parseWebhook returns the builds to trigger by a GitHub, GitLab or Bitbucket push, pull request or tag webhook.
+// Other events (e.g. ping, branch deletion, closed pull request) trigger no build.
+func parseWebhook(provider string, header http.Header, body []byte) ([]webhookTrigger, error) {
+	switch provider {
+	case webhookProviderGitHub:
+		return parseGitHubWebhook(header.Get("X-GitHub-Event"), body)
+	case webhookProviderGitLab:
+		return parseGitLabWebhook(header.Get("X-Gitlab-Event"), body)
+	case webhookProviderBitbucket:
+		return parseBitbucketWebhook(header.Get("X-Event-Key"), body)
+	}
+	return nil, fmt.Errorf("unsupported webhook provider: %s", provider)
+}
+
+// paramsOfGitRef returns the push branch or the tag of a git ref (refs/heads/<branch> or refs/tags/<tag>).
+func paramsOfGitRef(ref string) (RunAndTriggerParamsModel, bool) {
+	if strings.HasPrefix(ref, gitBranchRefPrefix) {
+		return RunAndTriggerParamsModel{PushBranch: strings.TrimPrefix(ref, gitBranchRefPrefix)}, true
+	}
+	if strings.HasPrefix(ref, gitTagRefPrefix) {
+		return RunAndTriggerParamsModel{Tag: strings.TrimPrefix(ref, gitTagRefPrefix)}, true
+	}
+	return RunAndTriggerParamsModel{}, false
+}
+
+// baseCommitOfPush returns the commit the pushed branch pointed to before the push,
+// or an empty string for tags and new branches (GitHub and GitLab send the zero SHA as before).
+func baseCommitOfPush(params RunAndTriggerParamsModel, before string) (string, error) {
+	if params.PushBranch == "" || before == "" || before == gitZeroSHA {
+		return "", nil
+	}
+	if err := validateGitCommitHash(before); err != nil {
+		return "", fmt.Errorf("invalid base commit, error: %s", err)
+	}
+	return before, nil
+}
+
+type webhookCommitModel struct {
+	ID       string   `json:"id"`
+	Message  string   `json:"message"`
+	Added    []string `json:"added"`
+	Modified []string `json:"modified"`
+	Removed  []string `json:"removed"`
+}
+
+// changedFilesOfCommits returns the files changed by the pushed commits, in order, without duplicates.
+func changedFilesOfCommits(commits []webhookCommitModel) []string {
+	changedFiles := []string{}
+	seen := map[string]bool{}
+	for _, commit := range commits {
+		for _, files := range [][]string{commit.Added, commit.Modified, commit.Removed} {
+			for _, file := range files {
+				if !seen[file] {
+					seen[file] = true
+					changedFiles = append(changedFiles, file)
+				}
+			}
+		}
+	}
+	return changedFiles
+}
+
+// --------------------
+// GitHub
+// --------------------
+
+type gitHubPushModel struct {
+	Ref        string               `json:"ref"`
+	Before     string               `json:"before"`
+	After      string               `json:"after"`
+	Deleted    bool                 `json:"deleted"`
+	HeadCommit *webhookCommitModel  `json:"head_commit"`
+	Commits    []webhookCommitModel `json:"commits"`
+}
+
+type gitHubPullRequestModel struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Title  string `json:"title"`
+		Draft  bool   `json:"draft"`
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+		Head struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		} `json:"head"`
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+	} `json:"pull_request"`
+}
+
+func parseGitHubWebhook(event string, body []byte) ([]webhookTrigger, error) {
+	switch event {
+	case "push":
+		var push gitHubPushModel
+		if err := json.Unmarshal(body, &push); err != nil {
+			return nil, fmt.Errorf("failed to parse push payload, error: %s", err)
+		}
+		if push.Deleted {
+			return nil, nil
+		}
+
+		params, ok := paramsOfGitRef(push.Ref)
+		if !ok {
+			return nil, nil
+		}
+		if push.HeadCommit != nil {
+			params.CommitMessage = push.HeadCommit.Message
+		}
+		if params.PushBranch != "" {
+			params.ChangedFiles = changedFilesOfCommits(push.Commits)
+		}
+		trigger, err := newWebhookTrigger(webhookProviderGitHub, params, push.After, "")
+		if err != nil {
+			return nil, err
+		}
+		if trigger.BaseCommit, err = baseCommitOfPush(params, push.Before); err != nil {
+			return nil, err
+		}
+		return []webhookTrigger{trigger}, nil
+	case "pull_request":
+		var pr gitHubPullRequestModel
+		if err := json.Unmarshal(body, &pr); err != nil {
+			return nil, fmt.Errorf("failed to parse pull_request payload, error: %s", err)
+		}
+		switch pr.Action {
+		case "opened", "reopened", "synchronize", "ready_for_review":
+		default:
+			return nil, nil
+		}
+
+		params := RunAndTriggerParamsModel{
+			PRSourceBranch: pr.PullRequest.Head.Ref,
+			PRTargetBranch: pr.PullRequest.Base.Ref,
+			CommitMessage:  pr.PullRequest.Title,
+			IsDraftPR:      pr.PullRequest.Draft,
+		}
+		for _, label := range pr.PullRequest.Labels {
+			params.PRLabels = append(params.PRLabels, label.Name)
+		}
+		trigger, err := newWebhookTrigger(webhookProviderGitHub, params, pr.PullRequest.Head.SHA, fmt.Sprintf("%d", pr.Number))
+		if err != nil {
+			return nil, err
+		}
+		return []webhookTrigger{trigger}, nil
+	}
+	return nil, nil
+}
+
+// --------------------
+// GitLab
+// --------------------
+
+type gitLabPushModel struct {
+	Ref         string               `json:"ref"`
+	Before      string               `json:"before"`
+	After       string               `json:"after"`
+	CheckoutSHA string               `json:"checkout_sha"`
+	Commits     []webhookCommitModel `json:"commits"`
+}
+
+type gitLabMergeRequestModel struct {
+	ObjectAttributes struct {
+		IID            int    `json:"iid"`
+		Action         string `json:"action"`
+		Title          string `json:"title"`
+		SourceBranch   string `json:"source_branch"`
+		TargetBranch   string `json:"target_branch"`
+		Draft          bool   `json:"draft"`
+		WorkInProgress bool   `json:"work_in_progress"`
+		LastCommit     struct {
+			ID      string `json:"id"`
+			Message string `json:"message"`
+		} `json:"last_commit"`
+	} `json:"object_attributes"`
+	Labels []struct {
+		Title string `json:"title"`
+	} `json:"labels"`
+}
+
+func parseGitLabWebhook(event string, body []byte) ([]webhookTrigger, error) {
+	switch event {
+	case "Push Hook", "Tag Push Hook":
+		var push gitLabPushModel
+		if err := json.Unmarshal(body, &push); err != nil {
+			return nil, fmt.Errorf("failed to parse %s payload, error: %s", event, err)
+		}
+		if push.CheckoutSHA == "" || push.After == gitZeroSHA {
+			// the branch or the tag is deleted
+			return nil, nil
+		}
+
+		params, ok := paramsOfGitRef(push.Ref)
+		if !ok {
+			return nil, nil
+		}
+		for _, commit := range push.Commits {
+			if commit.ID == push.CheckoutSHA {
+				params.CommitMessage = commit.Message
+			}
+		}
+		if params.PushBranch != "" {
+			params.ChangedFiles = changedFilesOfCommits(push.Commits)
+		}
+		trigger, err := newWebhookTrigger(webhookProviderGitLab, params, push.CheckoutSHA, "")
+		if err != nil {
+			return nil, err
+		}
+		if trigger.BaseCommit, err = baseCommitOfPush(params, push.Before); err != nil {
+			return nil, err
+		}
+		return []webhookTrigger{trigger}, nil
+	case "Merge Request Hook":
+		var mr gitLabMergeRequestModel
+		if err := json.Unmarshal(body, &mr); err != nil {
+			return nil, fmt.Errorf("failed to parse %s payload, error: %s", event, err)
+		}
+		attributes := mr.ObjectAttributes
+		switch attributes.Action {
+		case "open", "reopen", "update":
+		default:
+			return nil, nil
+		}
+
+		params := RunAndTriggerParamsModel{
+			PRSourceBranch: attributes.SourceBranch,
+			PRTargetBranch: attributes.TargetBranch,
+			CommitMessage:  attributes.LastCommit.Message,
+			IsDraftPR:      attributes.Draft || attributes.WorkInProgress,
+		}
+		for _, label := range mr.Labels {
+			params.PRLabels = append(params.PRLabels, label.Title)
+		}
+		trigger, err := newWebhookTrigger(webhookProviderGitLab, params, attributes.LastCommit.ID, fmt.Sprintf("%d", attributes.IID))
+		if err != nil {
+			return nil, err
+		}
+		return []webhookTrigger{trigger}, nil
+	}
+	return nil, nil
+}
+
+// --------------------
+// Bitbucket
+// --------------------
+
+type bitbucketCommitModel struct {
+	Hash    string `json:"hash"`
+	Message string `json:"message"`
+}
+
+type bitbucketPushModel struct {
+	Push struct {
+		Changes []struct {
+			Old *struct {
+				Target bitbucketCommitModel `json:"target"`
+			} `json:"old"`
+			New *struct {
+				Type   string               `json:"type"`
+				Name   string               `json:"name"`
+				Target bitbucketCommitModel `json:"target"`
+			} `json:"new"`
+		} `json:"changes"`
+	} `json:"push"`
+}
+
+type bitbucketPullRequestModel struct {
+	PullRequest struct {
+		ID     int    `json:"id"`
+		Title  string `json:"title"`
+		Draft  bool   `json:"draft"`
+		Source struct {
+			Branch struct {
+				Name string `json:"name"`
+			} `json:"branch"`
+			Commit bitbucketCommitModel `json:"commit"`
+		} `json:"source"`
+		Destination struct {
+			Branch struct {
+				Name string `json:"name"`
+			} `json:"branch"`
+		} `json:"destination"`
+	} `json:"pullrequest"`
+}
+
+func parseBitbucketWebhook(event string, body []byte) ([]webhookTrigger, error) {
+	switch event {
+	case "repo:push":
+		var push bitbucketPushModel
+		if err := json.Unmarshal(body, &push); err != nil {
+			return nil, fmt.Errorf("failed to parse %s payload, error: %s", event, err)
+		}
+
+		// a push can update multiple branches and tags
+		triggers := []webhookTrigger{}
+		for _, change := range push.Push.Changes {
+			if change.New == nil {
+				// the branch or the tag is deleted
+				continue
+			}
+
+			params := RunAndTriggerParamsModel{CommitMessage: change.New.Target.Message}
+			switch change.New.Type {
+			case "branch", "named_branch":
+				params.PushBranch = change.New.Name
+			case "tag", "annotated_tag":
+				params.Tag = change.New.Name
+			default:
+				continue
+			}
+			trigger, err := newWebhookTrigger(webhookProviderBitbucket, params, change.New.Target.Hash, "")
+			if err != nil {
+				return nil, err
+			}
+			if change.Old != nil {
+				// old is null if the push created the branch
+				if trigger.BaseCommit, err = baseCommitOfPush(params, change.Old.Target.Hash); err != nil {
+					return nil, err
+				}
+			}
+			triggers = append(triggers, trigger)
+		}
+		return triggers, nil
+	case "pullrequest:created", "pullrequest:updated":
+		var pr bitbucketPullRequestModel
+		if err := json.Unmarshal(body, &pr); err != nil {
+			return nil, fmt.Errorf("failed to parse %s payload, error: %s", event, err)
+		}
+
+		params := RunAndTriggerParamsModel{
+			PRSourceBranch: pr.PullRequest.Source.Branch.Name,
+			PRTargetBranch: pr.PullRequest.Destination.Branch.Name,
+			CommitMessage:  pr.PullRequest.Title,
+			IsDraftPR:      pr.PullRequest.Draft,
+		}
+		trigger, err := newWebhookTrigger(webhookProviderBitbucket, params, pr.PullRequest.Source.Commit.Hash, fmt.Sprintf("%d", pr.PullRequest.ID))
+		if err != nil {
+			return nil, err
+		}
+		return []webhookTrigger{trigger}, nil
+	}
+	return nil, nil
+}
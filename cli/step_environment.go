@@ -7,6 +7,7 @@ import (
 	"github.com/bitrise-io/bitrise/models"
 	"github.com/bitrise-io/envman/env"
 	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/pointers"
 )
 
 type prepareStepInputParams struct {
@@ -78,3 +79,53 @@ func prepareStepEnvironment(params prepareStepInputParams, envSource env.Environ
 
 	return stepEnvironment, declarationSideEffects.ResultEnvironment, nil
 }
+
+// isolateWorkflowEnvironment drops the envs declared since the isolated workflow started (its envs and its steps' outputs),
+// the exported ones are redeclared with their values at the end of the workflow.
+func isolateWorkflowEnvironment(environment []envmanModels.EnvironmentItemModel, workflowStart int, exportKeys []string, envSource env.EnvironmentSource) ([]envmanModels.EnvironmentItemModel, error) {
+	for _, envVar := range environment {
+		if err := envVar.Normalize(); err != nil {
+			return []envmanModels.EnvironmentItemModel{}, fmt.Errorf("failed to normalize declared environment variable: %s", err)
+		}
+
+		if err := envVar.FillMissingDefaults(); err != nil {
+			return []envmanModels.EnvironmentItemModel{}, fmt.Errorf("failed to fill missing declared environment variable defaults: %s", err)
+		}
+	}
+
+	declarationSideEffects, err := env.GetDeclarationsSideEffects(environment, envSource)
+	if err != nil {
+		return []envmanModels.EnvironmentItemModel{}, fmt.Errorf("failed to get environment variable declaration results: %s", err)
+	}
+
+	declaredKeys := map[string]bool{}
+	for _, envVar := range environment[workflowStart:] {
+		key, _, err := envVar.GetKeyValuePair()
+		if err != nil {
+			return []envmanModels.EnvironmentItemModel{}, err
+		}
+		declaredKeys[key] = true
+	}
+
+	isolated := append([]envmanModels.EnvironmentItemModel{}, environment[:workflowStart]...)
+	for _, key := range exportKeys {
+		if !declaredKeys[key] {
+			continue
+		}
+		value, isSet := declarationSideEffects.ResultEnvironment[key]
+		isolated = append(isolated, exportedEnvDeclaration(key, value, isSet))
+	}
+	return isolated, nil
+}
+
+// exportedEnvDeclaration redeclares the final value of an env exported by an isolated workflow, without expanding it again.
+func exportedEnvDeclaration(key, value string, isSet bool) envmanModels.EnvironmentItemModel {
+	return envmanModels.EnvironmentItemModel{
+		key: value,
+		envmanModels.OptionsKey: envmanModels.EnvironmentItemOptionsModel{
+			IsExpand:    pointers.NewBoolPtr(false),
+			SkipIfEmpty: pointers.NewBoolPtr(false),
+			Unset:       pointers.NewBoolPtr(!isSet),
+		},
+	}
+}
@@ -86,3 +86,40 @@ func Test_prepareStepEnvironment(t *testing.T) {
 		})
 	}
 }
+
+func Test_isolateWorkflowEnvironment(t *testing.T) {
+	environment := []envmanModels.EnvironmentItemModel{
+		{"CONFIGURATION": "Release"},
+		{"OUTER": "outer"},
+		// the isolated workflow's envs and its steps' outputs
+		{"CONFIGURATION": "Debug"},
+		{"SCHEME": "App-$CONFIGURATION"},
+		{"TMP_DIR": "/tmp/setup"},
+		{"OUTER": "", "opts": models.EnvironmentItemOptionsModel{Unset: newBool(true)}},
+		{"BUILD_DIR": "$TMP_DIR/build"},
+	}
+
+	isolated, err := isolateWorkflowEnvironment(environment, 2, []string{"SCHEME", "BUILD_DIR", "NOT_DECLARED"}, &EmptyEnvironment{})
+	require.NoError(t, err)
+	require.Equal(t, 4, len(isolated))
+	require.Equal(t, environment[:2], isolated[:2])
+
+	_, got, err := prepareStepEnvironment(prepareStepInputParams{environment: isolated}, &EmptyEnvironment{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"CONFIGURATION": "Release",
+		"OUTER":         "outer",
+		"SCHEME":        "App-Debug",
+		"BUILD_DIR":     "/tmp/setup/build",
+	}, got)
+
+	t.Log("exported unset env")
+	{
+		isolated, err := isolateWorkflowEnvironment(environment, 2, []string{"OUTER"}, &EmptyEnvironment{})
+		require.NoError(t, err)
+
+		_, got, err := prepareStepEnvironment(prepareStepInputParams{environment: isolated}, &EmptyEnvironment{})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"CONFIGURATION": "Release"}, got)
+	}
+}
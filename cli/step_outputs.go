@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/bitrise/models"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/command"
+)
+
+// stepOutputValues returns the values of the outputs exported by the step, by output key.
+func stepOutputValues(outputs []envmanModels.EnvironmentItemModel) (map[string]string, error) {
+	values := map[string]string{}
+	for _, output := range outputs {
+		key, value, err := output.GetKeyValuePair()
+		if err != nil {
+			return map[string]string{}, err
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// isPathInDir reports whether the path is inside the dir.
+func isPathInDir(pth, dir string) bool {
+	rel, err := filepath.Rel(dir, pth)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// processStepOutputs validates the outputs exported by the step against the step's output declarations,
+// and copies the file and directory typed outputs into the outputsDir (unless they are already in the deployDir).
+// The relative file and directory paths are relative to the step's working dir (workDir).
+// The exported outputs are updated in place with the absolute or the copied paths.
+// Returns the output values by output key.
+func processStepOutputs(outputs, declarations []envmanModels.EnvironmentItemModel, workDir, deployDir, outputsDir string) (map[string]string, error) {
+	outputTypes := map[string]models.StepOutputType{}
+	for _, declaration := range declarations {
+		key, _, err := declaration.GetKeyValuePair()
+		if err != nil {
+			return map[string]string{}, err
+		}
+		options, err := declaration.GetOptions()
+		if err != nil {
+			return map[string]string{}, err
+		}
+		if outputTypes[key], err = models.StepOutputTypeOf(options); err != nil {
+			return map[string]string{}, err
+		}
+	}
+
+	isPathOutput := func(key, value string) bool {
+		outputType := outputTypes[key]
+		return value != "" && (outputType == models.FileStepOutputType || outputType == models.DirectoryStepOutputType)
+	}
+	setOutput := func(idx int, key, value string) error {
+		options, err := outputs[idx].GetOptions()
+		if err != nil {
+			return err
+		}
+		outputs[idx] = envmanModels.EnvironmentItemModel{key: value, envmanModels.OptionsKey: options}
+		return nil
+	}
+
+	for idx, output := range outputs {
+		key, value, err := output.GetKeyValuePair()
+		if err != nil {
+			return map[string]string{}, err
+		}
+		if isPathOutput(key, value) && !filepath.IsAbs(value) {
+			if err := setOutput(idx, key, filepath.Join(workDir, value)); err != nil {
+				return map[string]string{}, err
+			}
+		}
+	}
+
+	values, err := stepOutputValues(outputs)
+	if err != nil {
+		return map[string]string{}, err
+	}
+
+	if err := models.ValidateStepOutputs(declarations, values); err != nil {
+		return values, err
+	}
+
+	for idx, output := range outputs {
+		key, value, err := output.GetKeyValuePair()
+		if err != nil {
+			return values, err
+		}
+		if !isPathOutput(key, value) {
+			continue
+		}
+
+		absPth, err := filepath.Abs(value)
+		if err != nil {
+			return values, fmt.Errorf("output (%s): %s", key, err)
+		}
+		if deployDir != "" && isPathInDir(absPth, deployDir) {
+			continue
+		}
+
+		destinationDir := filepath.Join(outputsDir, key)
+		if err := os.MkdirAll(destinationDir, 0755); err != nil {
+			return values, fmt.Errorf("output (%s): failed to create dir (%s), error: %s", key, destinationDir, err)
+		}
+		destination := filepath.Join(destinationDir, filepath.Base(absPth))
+		if outputTypes[key] == models.FileStepOutputType {
+			err = command.CopyFile(absPth, destination)
+		} else {
+			err = command.CopyDir(absPth, destination, true)
+		}
+		if err != nil {
+			return values, fmt.Errorf("output (%s): failed to copy (%s) to (%s), error: %s", key, absPth, destination, err)
+		}
+
+		if err := setOutput(idx, key, destination); err != nil {
+			return values, err
+		}
+		values[key] = destination
+	}
+
+	return values, nil
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/models"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/stretchr/testify/require"
+)
+
+func typedOutput(key string, outputType models.StepOutputType) envmanModels.EnvironmentItemModel {
+	return envmanModels.EnvironmentItemModel{
+		key: "",
+		envmanModels.OptionsKey: envmanModels.EnvironmentItemOptionsModel{
+			Meta: map[string]interface{}{models.StepOutputTypeMetaKey: string(outputType)},
+		},
+	}
+}
+
+func TestProcessStepOutputs(t *testing.T) {
+	deployDir, err := ioutil.TempDir("", "deploy_dir")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(deployDir))
+	}()
+	ipaPth := filepath.Join(deployDir, "App.ipa")
+	require.NoError(t, ioutil.WriteFile(ipaPth, []byte("ipa"), 0600))
+	outputsDir := filepath.Join(deployDir, "step_outputs", "0")
+
+	declarations := []envmanModels.EnvironmentItemModel{
+		typedOutput("BITRISE_IPA_PATH", models.FileStepOutputType),
+		typedOutput("BITRISE_BUILD_INFO", models.JSONStepOutputType),
+		{"BITRISE_MESSAGE": ""},
+	}
+
+	t.Log("valid outputs")
+	{
+		outputs := []envmanModels.EnvironmentItemModel{
+			{"BITRISE_IPA_PATH": ipaPth},
+			{"BITRISE_BUILD_INFO": `{"number": 1}`},
+			{"BITRISE_MESSAGE": "{"},
+		}
+		values, err := processStepOutputs(outputs, declarations, deployDir, deployDir, outputsDir)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"BITRISE_IPA_PATH": ipaPth, "BITRISE_BUILD_INFO": `{"number": 1}`, "BITRISE_MESSAGE": "{"}, values)
+		// the file is already in the deploy dir
+		require.Equal(t, envmanModels.EnvironmentItemModel{"BITRISE_IPA_PATH": ipaPth}, outputs[0])
+	}
+
+	t.Log("invalid outputs")
+	{
+		outputs := []envmanModels.EnvironmentItemModel{
+			{"BITRISE_IPA_PATH": filepath.Join(deployDir, "missing.ipa")},
+			{"BITRISE_BUILD_INFO": `{"number":`},
+		}
+		_, err := processStepOutputs(outputs, declarations, deployDir, deployDir, outputsDir)
+		require.EqualError(t, err, "output (BITRISE_IPA_PATH) path ("+filepath.Join(deployDir, "missing.ipa")+") does not exist, output (BITRISE_BUILD_INFO) value is not a valid JSON")
+	}
+
+	t.Log("relative paths are relative to the step's working dir")
+	{
+		workDir := filepath.Join(deployDir, "src")
+		ipaPth := filepath.Join(workDir, "build", "App.ipa")
+		require.NoError(t, os.MkdirAll(filepath.Dir(ipaPth), 0755))
+		require.NoError(t, ioutil.WriteFile(ipaPth, []byte("ipa"), 0600))
+
+		outputs := []envmanModels.EnvironmentItemModel{{"BITRISE_IPA_PATH": "build/App.ipa"}}
+		values, err := processStepOutputs(outputs, declarations, workDir, deployDir, outputsDir)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"BITRISE_IPA_PATH": ipaPth}, values)
+		_, value, err := outputs[0].GetKeyValuePair()
+		require.NoError(t, err)
+		require.Equal(t, ipaPth, value)
+
+		outputs = []envmanModels.EnvironmentItemModel{{"BITRISE_IPA_PATH": "build/App.ipa"}}
+		_, err = processStepOutputs(outputs, declarations, deployDir, deployDir, outputsDir)
+		require.EqualError(t, err, "output (BITRISE_IPA_PATH) path ("+filepath.Join(deployDir, "build", "App.ipa")+") does not exist")
+	}
+}
+
+func TestIsPathInDir(t *testing.T) {
+	require.Equal(t, true, isPathInDir("/deploy/app.ipa", "/deploy"))
+	require.Equal(t, true, isPathInDir("/deploy/step_outputs/0/app.ipa", "/deploy"))
+	require.Equal(t, false, isPathInDir("/deploy", "/deploy/step_outputs"))
+	require.Equal(t, false, isPathInDir("/deploy_dir/app.ipa", "/deploy"))
+	require.Equal(t, false, isPathInDir("/tmp/..app.ipa", "/deploy"))
+}
@@ -0,0 +1,398 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/bitrise-io/bitrise/toolkits"
+	stepmanModels "github.com/bitrise-io/stepman/models"
+	"github.com/bitrise-io/stepman/stepman"
+)
+
+// stepPrefetchConcurrency is the number of steps activated at the same time.
+const stepPrefetchConcurrency = 4
+
+// toolkitPrepareLocks serializes the preparations of the same toolkit version (e.g. Go step compilation),
+// as the background compilation of the next steps runs next to the preparation of the current step.
+var toolkitPrepareLocks = newToolkitLocks()
+
+// errPrefetchCanceled is the error of the prefetches not started before the prefetcher is closed.
+var errPrefetchCanceled = errors.New("step prefetch canceled")
+
+// toolkitLocks are the locks of the toolkit preparations by toolkit version.
+// The preparation of the current step (foreground) has priority:
+// while it waits for or holds a lock, the background compilations of the same toolkit version do not start.
+type toolkitLocks struct {
+	mutex sync.Mutex
+	cond  *sync.Cond
+
+	isLocked          map[string]bool
+	foregroundWaiting map[string]int
+}
+
+func newToolkitLocks() *toolkitLocks {
+	locks := &toolkitLocks{
+		isLocked:          map[string]bool{},
+		foregroundWaiting: map[string]int{},
+	}
+	locks.cond = sync.NewCond(&locks.mutex)
+	return locks
+}
+
+// toolkitPrepareLockKey returns the key of the toolkit's lock: the toolkit's name and version constraint.
+func toolkitPrepareLockKey(toolkit toolkits.Toolkit) string {
+	if goToolkit, ok := toolkit.(toolkits.GoToolkit); ok {
+		return goToolkit.ToolkitName() + "@" + goToolkit.VersionConstraint
+	}
+	return toolkit.ToolkitName()
+}
+
+func (locks *toolkitLocks) lockForeground(key string) {
+	locks.mutex.Lock()
+	defer locks.mutex.Unlock()
+
+	locks.foregroundWaiting[key]++
+	for locks.isLocked[key] {
+		locks.cond.Wait()
+	}
+	locks.foregroundWaiting[key]--
+	locks.isLocked[key] = true
+}
+
+// lockBackground waits for the lock, while no foreground preparation waits for it.
+// Returns false without locking, if canceled is closed meanwhile.
+func (locks *toolkitLocks) lockBackground(key string, canceled <-chan struct{}) bool {
+	locks.mutex.Lock()
+	defer locks.mutex.Unlock()
+
+	for {
+		select {
+		case <-canceled:
+			return false
+		default:
+		}
+
+		if !locks.isLocked[key] && locks.foregroundWaiting[key] == 0 {
+			locks.isLocked[key] = true
+			return true
+		}
+		locks.cond.Wait()
+	}
+}
+
+func (locks *toolkitLocks) unlock(key string) {
+	locks.mutex.Lock()
+	defer locks.mutex.Unlock()
+
+	locks.isLocked[key] = false
+	locks.cond.Broadcast()
+}
+
+// wakeUp wakes the waiting background preparations, to check whether they are canceled.
+func (locks *toolkitLocks) wakeUp() {
+	locks.mutex.Lock()
+	defer locks.mutex.Unlock()
+
+	locks.cond.Broadcast()
+}
+
+// stepPrefetch is the activation of a step ahead of its execution.
+type stepPrefetch struct {
+	stepIDData models.StepIDData
+	stepDir    string
+	stepYMLPth string
+
+	result stepActivationResult
+	err    error
+	// output is the output of the background activation, printed when the step is used
+	output bytes.Buffer
+	done   chan struct{}
+}
+
+// stepPrefetcher activates the steps of a workflow at the workflow start, concurrently with a bounded pool,
+// and compiles the Go toolkit steps in the background, so that the next step is ready when the previous one finishes.
+// Local steps (path::) and steps whose ID references envs are activated lazily, right before their execution,
+// as their source can be created or changed by the previous steps.
+type stepPrefetcher struct {
+	prefetches map[int]*stepPrefetch
+	order      []int
+	stagingDir string
+
+	stepLocksMutex sync.Mutex
+	stepLocks      map[string]*sync.Mutex
+
+	// canceled is closed when the prefetcher is closed, to skip the not yet started background work
+	canceled chan struct{}
+	wg       sync.WaitGroup
+}
+
+func isStepPrefetchable(compositeStepIDStr string, stepIDData models.StepIDData) bool {
+	if strings.Contains(compositeStepIDStr, "$") {
+		return false
+	}
+	return stepIDData.SteplibSource != "path" && stepIDData.SteplibSource != ""
+}
+
+func newStepPrefetcher(steps []models.StepListItemModel, defaultStepLibSource string) *stepPrefetcher {
+	prefetcher := &stepPrefetcher{
+		prefetches: map[int]*stepPrefetch{},
+		stepLocks:  map[string]*sync.Mutex{},
+		canceled:   make(chan struct{}),
+	}
+
+	for idx, stepListItm := range steps {
+		compositeStepIDStr, _, err := models.GetStepIDStepDataPair(stepListItm)
+		if err != nil {
+			continue
+		}
+		stepIDData, err := models.CreateStepIDDataFromString(compositeStepIDStr, defaultStepLibSource)
+		if err != nil {
+			continue
+		}
+		if !isStepPrefetchable(compositeStepIDStr, stepIDData) {
+			continue
+		}
+
+		prefetcher.prefetches[idx] = &stepPrefetch{stepIDData: stepIDData, done: make(chan struct{})}
+		prefetcher.order = append(prefetcher.order, idx)
+	}
+
+	return prefetcher
+}
+
+func isStepLibUpdateNeeded(version string) bool {
+	versionConstraint, err := stepmanModels.ParseRequiredVersion(version)
+	if err != nil {
+		return false
+	}
+	return versionConstraint.VersionLockType == stepmanModels.Latest ||
+		versionConstraint.VersionLockType == stepmanModels.MinorLocked ||
+		versionConstraint.VersionLockType == stepmanModels.MajorLocked
+}
+
+// prepareStepLibs sets up and updates (if required) the StepLibs of the prefetched steps,
+// so that the concurrent activations only read the StepLibs.
+// The steps of a StepLib, which can not be set up, are activated lazily.
+func (prefetcher *stepPrefetcher) prepareStepLibs(buildRunResults models.BuildRunResultsModel) {
+	isUpdateNeeded := map[string]bool{}
+	libraries := []string{}
+	for _, idx := range prefetcher.order {
+		stepIDData := prefetcher.prefetches[idx].stepIDData
+		if stepIDData.SteplibSource == "git" || stepIDData.SteplibSource == "_" {
+			continue
+		}
+
+		if _, ok := isUpdateNeeded[stepIDData.SteplibSource]; !ok {
+			libraries = append(libraries, stepIDData.SteplibSource)
+		}
+		isUpdateNeeded[stepIDData.SteplibSource] = isUpdateNeeded[stepIDData.SteplibSource] || isStepLibUpdateNeeded(stepIDData.Version)
+	}
+
+	for _, library := range libraries {
+		if err := stepman.SetupLibrary(library); err != nil {
+			log.Debugf("Failed to setup StepLib (%s), activating its steps lazily, error: %s", library, err)
+			prefetcher.dropStepLibSteps(library)
+			continue
+		}
+
+		if isUpdateNeeded[library] && !buildRunResults.IsStepLibUpdated(library) {
+			log.Infof("Step uses latest version -- Updating StepLib ...")
+			if _, err := stepman.UpdateLibrary(library); err != nil {
+				log.Warnf("Step version constraint is latest or version locked, but failed to update StepLib, err: %s", err)
+			} else {
+				buildRunResults.StepmanUpdates[library]++
+			}
+		}
+	}
+}
+
+func (prefetcher *stepPrefetcher) dropStepLibSteps(library string) {
+	order := []int{}
+	for _, idx := range prefetcher.order {
+		if prefetcher.prefetches[idx].stepIDData.SteplibSource == library {
+			delete(prefetcher.prefetches, idx)
+		} else {
+			order = append(order, idx)
+		}
+	}
+	prefetcher.order = order
+}
+
+func (prefetcher *stepPrefetcher) stepLock(stepIDData models.StepIDData) *sync.Mutex {
+	prefetcher.stepLocksMutex.Lock()
+	defer prefetcher.stepLocksMutex.Unlock()
+
+	key := stepIDData.SteplibSource + "|" + stepIDData.IDorURI + "|" + stepIDData.Version
+	lock, ok := prefetcher.stepLocks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		prefetcher.stepLocks[key] = lock
+	}
+	return lock
+}
+
+// start activates the steps in the background, in the order of the steps.
+func (prefetcher *stepPrefetcher) start(buildRunResults models.BuildRunResultsModel) {
+	if len(prefetcher.order) == 0 {
+		return
+	}
+
+	stagingDir, err := ioutil.TempDir(configs.BitriseWorkDirPath, "prefetched_steps")
+	if err != nil {
+		log.Warnf("Failed to create prefetched steps dir, activating steps lazily, error: %s", err)
+		prefetcher.prefetches = map[int]*stepPrefetch{}
+		prefetcher.order = nil
+		return
+	}
+	prefetcher.stagingDir = stagingDir
+
+	prefetcher.prepareStepLibs(buildRunResults)
+
+	queue := make(chan int, len(prefetcher.order))
+	for _, idx := range prefetcher.order {
+		queue <- idx
+	}
+	close(queue)
+
+	for i := 0; i < stepPrefetchConcurrency; i++ {
+		prefetcher.wg.Add(1)
+		go func() {
+			defer prefetcher.wg.Done()
+			for idx := range queue {
+				prefetcher.prefetch(idx)
+			}
+		}()
+	}
+}
+
+func (prefetcher *stepPrefetcher) isCanceled() bool {
+	select {
+	case <-prefetcher.canceled:
+		return true
+	default:
+		return false
+	}
+}
+
+func (prefetcher *stepPrefetcher) prefetch(idx int) {
+	prefetch := prefetcher.prefetches[idx]
+	defer close(prefetch.done)
+
+	if prefetcher.isCanceled() {
+		prefetch.err = errPrefetchCanceled
+		return
+	}
+
+	prefetch.stepDir = filepath.Join(prefetcher.stagingDir, strconv.Itoa(idx))
+	prefetch.stepYMLPth = filepath.Join(prefetcher.stagingDir, strconv.Itoa(idx)+".yml")
+
+	lock := prefetcher.stepLock(prefetch.stepIDData)
+	lock.Lock()
+	// the StepLibs are already updated in prepareStepLibs
+	prefetch.result, prefetch.err = activateStep(prefetch.stepIDData, prefetch.stepDir, prefetch.stepYMLPth, true, &prefetch.output)
+	lock.Unlock()
+	if prefetch.err != nil {
+		log.Debugf("Failed to prefetch step (%s), error: %s", prefetch.stepIDData.IDorURI, prefetch.err)
+		return
+	}
+
+	if err := precompileStep(prefetch.stepIDData, prefetch.stepDir, prefetch.stepYMLPth, prefetcher.canceled); err != nil {
+		log.Debugf("Failed to precompile step (%s), error: %s", prefetch.stepIDData.IDorURI, err)
+	}
+}
+
+// precompileStep compiles the Go toolkit steps, the compiled binary is cached by the toolkit,
+// so the step's preparation before its execution finds it.
+// The compilation is skipped if it would require a Go install, which is left to the step's preparation,
+// and if canceled is closed while it waits for the preparation of the current step.
+func precompileStep(stepIDData models.StepIDData, stepDir, stepYMLPth string, canceled <-chan struct{}) error {
+	if !stepIDData.IsUniqueResourceID() {
+		// the binary of a not unique step is not cached
+		return nil
+	}
+
+	specStep, err := bitrise.ReadSpecStep(stepYMLPth)
+	if err != nil {
+		return err
+	}
+
+	goToolkit, ok := toolkits.ToolkitForStep(specStep).(toolkits.GoToolkit)
+	if !ok {
+		return nil
+	}
+
+	if isInstallRequired, _, err := goToolkit.Check(); err != nil {
+		return err
+	} else if isInstallRequired {
+		return nil
+	}
+
+	lockKey := toolkitPrepareLockKey(goToolkit)
+	if !toolkitPrepareLocks.lockBackground(lockKey, canceled) {
+		return nil
+	}
+	defer toolkitPrepareLocks.unlock(lockKey)
+
+	return goToolkit.PrepareForStepRun(specStep, stepIDData, stepDir)
+}
+
+// moveActivatedStep waits for the step's prefetch, and moves the prefetched step to stepDir and stepYMLPth.
+// Returns false if the step is not prefetched, or its prefetch failed, so that it needs to be activated lazily.
+func (prefetcher *stepPrefetcher) moveActivatedStep(idx int, stepDir, stepYMLPth string) (stepActivationResult, bool, error) {
+	prefetch, ok := prefetcher.prefetches[idx]
+	if !ok {
+		return stepActivationResult{}, false, nil
+	}
+
+	<-prefetch.done
+	if prefetch.err != nil {
+		// the lazy activation prints the notes of the failure again
+		return stepActivationResult{}, false, nil
+	}
+
+	if _, err := prefetch.output.WriteTo(os.Stdout); err != nil {
+		log.Warnf("Failed to print the output of the step's prefetch, error: %s", err)
+	}
+
+	if err := os.RemoveAll(stepDir); err != nil {
+		return stepActivationResult{}, true, err
+	}
+	if err := os.Rename(prefetch.stepDir, stepDir); err != nil {
+		return stepActivationResult{}, true, fmt.Errorf("failed to move prefetched step (%s) to (%s), error: %s", prefetch.stepDir, stepDir, err)
+	}
+
+	// Steplib independent steps do not have step definition
+	if stepYMLPth != "" {
+		if err := os.Rename(prefetch.stepYMLPth, stepYMLPth); err != nil {
+			return stepActivationResult{}, true, fmt.Errorf("failed to move prefetched step definition (%s) to (%s), error: %s", prefetch.stepYMLPth, stepYMLPth, err)
+		}
+	}
+
+	return prefetch.result, true, nil
+}
+
+// close cancels the not yet started background work, waits for the running activations
+// and removes the not used prefetched steps.
+func (prefetcher *stepPrefetcher) close() {
+	close(prefetcher.canceled)
+	toolkitPrepareLocks.wakeUp()
+	prefetcher.wg.Wait()
+
+	if prefetcher.stagingDir == "" {
+		return
+	}
+	if err := os.RemoveAll(prefetcher.stagingDir); err != nil {
+		log.Warnf("Failed to remove prefetched steps dir, error: %s", err)
+	}
+}
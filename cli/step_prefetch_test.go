@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStepPrefetcher(t *testing.T) {
+	configStr := `format_version: 11
+default_step_lib_source: https://github.com/bitrise-io/bitrise-steplib.git
+
+workflows:
+  test:
+    steps:
+    - script@1: {}
+    - path::./my-step: {}
+    - git::https://github.com/bitrise-steplib/steps-script.git@master: {}
+    - path::$STEP_DIR: {}
+    - https://github.com/bitrise-io/bitrise-steplib.git::$STEP_ID@1: {}
+    - _::https://github.com/bitrise-steplib/steps-script.git@master: {}
+`
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	prefetcher := newStepPrefetcher(config.Workflows["test"].Steps, config.DefaultStepLibSource)
+	require.Equal(t, []int{0, 2, 5}, prefetcher.order)
+	require.Equal(t, "script", prefetcher.prefetches[0].stepIDData.IDorURI)
+	require.Equal(t, "git", prefetcher.prefetches[2].stepIDData.SteplibSource)
+	require.Equal(t, "_", prefetcher.prefetches[5].stepIDData.SteplibSource)
+
+	prefetcher.dropStepLibSteps("https://github.com/bitrise-io/bitrise-steplib.git")
+	require.Equal(t, []int{2, 5}, prefetcher.order)
+}
+
+func TestStepPrefetcherMoveActivatedStep(t *testing.T) {
+	tmpDir, err := pathutil.NormalizedOSTempDirPath("step-prefetch")
+	require.NoError(t, err)
+
+	stagedStepDir := filepath.Join(tmpDir, "prefetched", "0")
+	require.NoError(t, pathutil.EnsureDirExist(stagedStepDir))
+	require.NoError(t, fileutil.WriteStringToFile(filepath.Join(stagedStepDir, "step.sh"), "echo hello"))
+	require.NoError(t, fileutil.WriteStringToFile(filepath.Join(tmpDir, "prefetched", "0.yml"), "title: hello"))
+
+	prefetcher := &stepPrefetcher{prefetches: map[int]*stepPrefetch{
+		0: {stepDir: stagedStepDir, stepYMLPth: filepath.Join(tmpDir, "prefetched", "0.yml"), done: make(chan struct{})},
+		1: {err: errors.New("clone failed"), done: make(chan struct{})},
+	}}
+	close(prefetcher.prefetches[0].done)
+	close(prefetcher.prefetches[1].done)
+
+	stepDir := filepath.Join(tmpDir, "step_src")
+	stepYMLPth := filepath.Join(tmpDir, "current_step.yml")
+
+	t.Log("prefetched step")
+	{
+		_, isPrefetched, err := prefetcher.moveActivatedStep(0, stepDir, stepYMLPth)
+		require.NoError(t, err)
+		require.True(t, isPrefetched)
+
+		content, err := fileutil.ReadStringFromFile(filepath.Join(stepDir, "step.sh"))
+		require.NoError(t, err)
+		require.Equal(t, "echo hello", content)
+
+		content, err = fileutil.ReadStringFromFile(stepYMLPth)
+		require.NoError(t, err)
+		require.Equal(t, "title: hello", content)
+	}
+
+	t.Log("failed prefetch")
+	{
+		_, isPrefetched, err := prefetcher.moveActivatedStep(1, stepDir, stepYMLPth)
+		require.NoError(t, err)
+		require.False(t, isPrefetched)
+	}
+
+	t.Log("not prefetched step")
+	{
+		_, isPrefetched, err := prefetcher.moveActivatedStep(2, stepDir, stepYMLPth)
+		require.NoError(t, err)
+		require.False(t, isPrefetched)
+	}
+}
+
+func TestToolkitLocks(t *testing.T) {
+	t.Log("the foreground preparation has priority over the waiting background ones")
+	{
+		locks := newToolkitLocks()
+		canceled := make(chan struct{})
+		require.True(t, locks.lockBackground("go@", canceled))
+
+		order := make(chan string, 2)
+		backgroundLocked := make(chan bool)
+		foregroundLocked := make(chan bool)
+		go func() {
+			locks.lockForeground("go@")
+			order <- "foreground"
+			locks.unlock("go@")
+			foregroundLocked <- true
+		}()
+		// wait for the foreground preparation to start waiting
+		for {
+			locks.mutex.Lock()
+			waiting := locks.foregroundWaiting["go@"]
+			locks.mutex.Unlock()
+			if waiting > 0 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		go func() {
+			isLocked := locks.lockBackground("go@", canceled)
+			order <- "background"
+			locks.unlock("go@")
+			backgroundLocked <- isLocked
+		}()
+
+		locks.unlock("go@")
+		require.True(t, <-foregroundLocked)
+		require.True(t, <-backgroundLocked)
+		require.Equal(t, "foreground", <-order)
+		require.Equal(t, "background", <-order)
+	}
+
+	t.Log("different toolkit versions do not wait for each other")
+	{
+		locks := newToolkitLocks()
+		require.True(t, locks.lockBackground("go@>= 1.17", make(chan struct{})))
+		locks.lockForeground("go@")
+		locks.unlock("go@")
+		locks.unlock("go@>= 1.17")
+	}
+
+	t.Log("canceled background preparation")
+	{
+		locks := newToolkitLocks()
+		locks.lockForeground("go@")
+
+		canceled := make(chan struct{})
+		backgroundLocked := make(chan bool)
+		go func() {
+			backgroundLocked <- locks.lockBackground("go@", canceled)
+		}()
+
+		close(canceled)
+		locks.wakeUp()
+		require.False(t, <-backgroundLocked)
+		locks.unlock("go@")
+	}
+}
+
+func TestStepPrefetcherCanceledPrefetch(t *testing.T) {
+	prefetcher := &stepPrefetcher{
+		prefetches: map[int]*stepPrefetch{0: {done: make(chan struct{})}},
+		canceled:   make(chan struct{}),
+	}
+	close(prefetcher.canceled)
+
+	prefetcher.prefetch(0)
+	require.Equal(t, errPrefetchCanceled, prefetcher.prefetches[0].err)
+
+	_, isPrefetched, err := prefetcher.moveActivatedStep(0, "", "")
+	require.NoError(t, err)
+	require.False(t, isPrefetched)
+}
@@ -0,0 +1,15 @@
+package cli
+
+import (
+	"github.com/urfave/cli"
+)
+
+var toolkitCommand = cli.Command{
+	Name:  "toolkit",
+	Usage: "Step toolkit handling.",
+	Subcommands: []cli.Command{
+		toolkitListCommand,
+		toolkitInstallCommand,
+		toolkitPruneCommand,
+	},
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bitrise-io/bitrise/toolkits"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/urfave/cli"
+)
+
+var toolkitInstallCommand = cli.Command{
+	Name:  "install",
+	Usage: "Install a toolkit, for the go toolkit the newest Go release matching the version constraint.",
+	Action: func(c *cli.Context) error {
+		if err := toolkitInstall(c); err != nil {
+			log.Errorf("Toolkit install failed, error: %s", err)
+			os.Exit(1)
+		}
+		return nil
+	},
+	ArgsUsage: "<toolkit_name> [<go_version_constraint>]",
+}
+
+func toolkitInstall(c *cli.Context) error {
+	// Input validation
+	args := c.Args()
+	if len(args) == 0 || args[0] == "" {
+		showSubcommandHelp(c)
+		return errors.New("toolkit_name not defined")
+	}
+
+	name := args[0]
+	var toolkit toolkits.Toolkit
+	for _, aToolkit := range toolkits.AllSupportedToolkits() {
+		if aToolkit.ToolkitName() == name {
+			toolkit = aToolkit
+		}
+	}
+	if toolkit == nil {
+		return fmt.Errorf("unknown toolkit: %s", name)
+	}
+
+	if len(args) > 1 {
+		if _, ok := toolkit.(toolkits.GoToolkit); !ok {
+			return fmt.Errorf("version constraint is only supported by the go toolkit")
+		}
+		toolkit = toolkits.GoToolkit{VersionConstraint: args[1]}
+	}
+	// ---
+
+	if err := toolkit.Install(); err != nil {
+		return err
+	}
+	if !toolkits.IsInstalledByToolkit(toolkit) {
+		if isInstallRequired, _, err := toolkit.Check(); err != nil {
+			return err
+		} else if isInstallRequired {
+			return fmt.Errorf("toolkit (%s) is not installed", name)
+		}
+	}
+
+	log.Donef("Toolkit (%s) installed", name)
+
+	return nil
+}
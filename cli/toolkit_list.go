@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bitrise-io/bitrise/toolkits"
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/urfave/cli"
+)
+
+var toolkitListCommand = cli.Command{
+	Name:  "list",
+	Usage: "List the step toolkits and the installed Go versions.",
+	Action: func(c *cli.Context) error {
+		if err := toolkitList(c); err != nil {
+			log.Errorf("Toolkit list failed, error: %s", err)
+			os.Exit(1)
+		}
+		return nil
+	},
+	ArgsUsage: "",
+}
+
+func toolkitList(c *cli.Context) error {
+	for _, toolkit := range toolkits.AllSupportedToolkits() {
+		isInstallRequired, checkResult, err := toolkit.Check()
+		if err != nil {
+			if toolkits.IsInstalledByToolkit(toolkit) {
+				return fmt.Errorf("failed to check toolkit (%s), error: %s", toolkit.ToolkitName(), err)
+			}
+			log.Printf("%s %s: %s", colorstring.Yellow("[-]"), toolkit.ToolkitName(), err)
+			continue
+		}
+
+		if isInstallRequired {
+			log.Printf("%s %s: %s", colorstring.Yellow("[-]"), toolkit.ToolkitName(), "no installed/suitable version found")
+		} else {
+			log.Printf("%s %s (%s): %s", colorstring.Green("[OK]"), toolkit.ToolkitName(), checkResult.Version, checkResult.Path)
+		}
+	}
+
+	installedGoVersions, err := toolkits.InstalledGoVersions()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	log.Infof("Go versions:")
+	if systemGoVersion, ok := toolkits.SystemGoVersion(); ok {
+		log.Printf("- %s (system)", systemGoVersion)
+	}
+	for _, version := range installedGoVersions {
+		log.Printf("- %s", version)
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"os"
+	"strings"
+
+	"github.com/bitrise-io/bitrise/toolkits"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/urfave/cli"
+)
+
+var toolkitPruneCommand = cli.Command{
+	Name:  "prune",
+	Usage: "Remove the Go versions installed by the go toolkit, but the one used by default, and the step binaries compiled with them.",
+	Action: func(c *cli.Context) error {
+		if err := toolkitPrune(c); err != nil {
+			log.Errorf("Toolkit prune failed, error: %s", err)
+			os.Exit(1)
+		}
+		return nil
+	},
+	ArgsUsage: "",
+}
+
+func toolkitPrune(c *cli.Context) error {
+	keepVersions := []string{}
+	if systemGoVersion, ok := toolkits.SystemGoVersion(); ok {
+		keepVersions = append(keepVersions, systemGoVersion)
+	}
+	if isInstallRequired, checkResult, err := (toolkits.GoToolkit{}).Check(); err != nil {
+		return err
+	} else if !isInstallRequired {
+		keepVersions = append(keepVersions, checkResult.Version)
+	}
+
+	pruned, err := toolkits.PruneGoToolkit(keepVersions)
+	if err != nil {
+		return err
+	}
+
+	if len(pruned) == 0 {
+		log.Donef("No Go version to prune")
+		return nil
+	}
+
+	log.Donef("Pruned Go versions: %s", strings.Join(pruned, ", "))
+
+	return nil
+}
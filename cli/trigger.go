@@ -29,6 +29,10 @@ var triggerCommand = cli.Command{
 		cli.StringFlag{Name: PRSourceBranchKey, Usage: "Git pull request source branch name."},
 		cli.StringFlag{Name: PRTargetBranchKey, Usage: "Git pull request target branch name."},
 		cli.StringFlag{Name: TagKey, Usage: "Git tag name."},
+		flCommitMessage,
+		flPRLabels,
+		flDraftPR,
+		flChangedFiles,
 
 		// cli params used in CI mode
 		cli.StringFlag{Name: JSONParamsKey, Usage: "Specify command flags with json string-string hash."},
@@ -123,6 +127,7 @@ func trigger(c *cli.Context) error {
 	if err != nil {
 		return fmt.Errorf("Failed to parse trigger command params, error: %s", err)
 	}
+	triggerParams = overrideTriggerConditionParams(triggerParams, c.String(CommitMessageKey), c.String(PRLabelsKey), c.Bool(DraftPRKey), c.String(ChangedFilesKey))
 
 	// Inventory validation
 	inventoryEnvironments, err := CreateInventoryFromCLIParams(triggerParams.InventoryBase64Data, triggerParams.InventoryPath)
@@ -176,6 +181,11 @@ func trigger(c *cli.Context) error {
 		log.Fatalf("Failed to register  CI mode, error: %s", err)
 	}
 
+	if models.IsSkipCICommitMessage(triggerParams.CommitMessage) {
+		log.Infof("The commit message contains [skip ci] or [ci skip], skipping the build")
+		os.Exit(0)
+	}
+
 	_, workflowToRunID, err := getPipelineAndWorkflowIDByParamsInCompatibleMode(bitriseConfig.TriggerMap, triggerParams, isPRMode)
 	if err != nil {
 		log.Errorf("Failed to get workflow id by pattern, error: %s", err)
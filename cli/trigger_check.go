@@ -2,12 +2,16 @@ package cli
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/bitrise-io/bitrise/bitrise"
 	"github.com/bitrise-io/bitrise/models"
 	"github.com/bitrise-io/bitrise/output"
+	envmanModels "github.com/bitrise-io/envman/models"
 	"github.com/bitrise-io/go-utils/colorstring"
 	"github.com/bitrise-io/go-utils/pointers"
 	"github.com/urfave/cli"
@@ -59,17 +63,57 @@ func migratePatternToParams(params RunAndTriggerParamsModel, isPullRequestMode b
 }
 
 func getPipelineAndWorkflowIDByParams(triggerMap models.TriggerMapModel, params RunAndTriggerParamsModel) (string, string, error) {
+	return getPipelineAndWorkflowIDByParamsAndChanges(triggerMap, params, newBuildChangedFilesLister(params))
+}
+
+// newBuildChangedFilesLister lists the changed files given by the params,
+// or the changes compared to the pull request's target branch or to the previous commit.
+func newBuildChangedFilesLister(params RunAndTriggerParamsModel) *changedFilesLister {
+	return newChangedFilesLister(func() ([]string, error) {
+		if len(params.ChangedFiles) > 0 {
+			return params.ChangedFiles, nil
+		}
+		return bitrise.ChangedFilesOfBuildWithBaseBranch(params.PRTargetBranch, envmanModels.EnvsJSONListModel{})
+	})
+}
+
+// getPipelineAndWorkflowIDByParamsAndChanges returns the first trigger map item matching the params,
+// the items with changed_files filter only match if the build changes relevant files.
+func getPipelineAndWorkflowIDByParamsAndChanges(triggerMap models.TriggerMapModel, params RunAndTriggerParamsModel, changedFiles *changedFilesLister) (string, string, error) {
+	if models.IsSkipCICommitMessage(params.CommitMessage) {
+		return "", "", errors.New("trigger skipped: the commit message contains [skip ci] or [ci skip]")
+	}
+
+	skippedTargets := []string{}
 	for _, item := range triggerMap {
-		match, err := item.MatchWithParams(params.PushBranch, params.PRSourceBranch, params.PRTargetBranch, params.Tag)
+		match, err := item.MatchWithTriggerParams(params.triggerParams())
 		if err != nil {
 			return "", "", err
 		}
-		if match {
-			return item.PipelineID, item.WorkflowID, nil
+		if !match {
+			continue
 		}
+
+		if item.ChangedFiles != nil && !changedFiles.hasRelevantChanges(*item.ChangedFiles) {
+			skippedTargets = append(skippedTargets, triggerItemTarget(item))
+			continue
+		}
+
+		return item.PipelineID, item.WorkflowID, nil
 	}
 
-	return "", "", fmt.Errorf("no matching pipeline & workflow found with trigger params: push-branch: %s, pr-source-branch: %s, pr-target-branch: %s, tag: %s", params.PushBranch, params.PRSourceBranch, params.PRTargetBranch, params.Tag)
+	errorMsg := fmt.Sprintf("no matching pipeline & workflow found with trigger params: push-branch: %s, pr-source-branch: %s, pr-target-branch: %s, tag: %s", params.PushBranch, params.PRSourceBranch, params.PRTargetBranch, params.Tag)
+	if len(skippedTargets) > 0 {
+		errorMsg += fmt.Sprintf(", matching items skipped: no relevant changes (%s)", strings.Join(skippedTargets, ", "))
+	}
+	return "", "", errors.New(errorMsg)
+}
+
+func triggerItemTarget(item models.TriggerMapItemModel) string {
+	if item.PipelineID != "" {
+		return "pipeline: " + item.PipelineID
+	}
+	return "workflow: " + item.WorkflowID
 }
 
 // migrates deprecated params.TriggerPattern to params.PushBranch or params.PRSourceBranch based on isPullRequestMode
@@ -132,6 +176,7 @@ func triggerCheck(c *cli.Context) error {
 	if err != nil {
 		registerFatal(fmt.Sprintf("Failed to parse trigger check params, err: %s", err), warnings, triggerParams.Format)
 	}
+	triggerParams = overrideTriggerConditionParams(triggerParams, c.String(CommitMessageKey), c.String(PRLabelsKey), c.Bool(DraftPRKey), c.String(ChangedFilesKey))
 	//
 
 	// Inventory validation
@@ -148,9 +193,13 @@ func triggerCheck(c *cli.Context) error {
 	}
 
 	// Format validation
+	isExplain := c.Bool(ExplainKey)
 	if triggerParams.Format == "" {
 		triggerParams.Format = output.FormatRaw
-	} else if !(triggerParams.Format == output.FormatRaw || triggerParams.Format == output.FormatJSON) {
+	}
+	isValidFormat := triggerParams.Format == output.FormatRaw || triggerParams.Format == output.FormatJSON ||
+		(isExplain && triggerParams.Format == output.FormatYML)
+	if !isValidFormat {
 		registerFatal(fmt.Sprintf("Invalid format: %s", triggerParams.Format), warnings, output.FormatJSON)
 	}
 
@@ -168,6 +217,22 @@ func triggerCheck(c *cli.Context) error {
 		registerFatal(fmt.Sprintf("Failed to check  PR mode, err: %s", err), warnings, triggerParams.Format)
 	}
 
+	if isExplain {
+		explainParams := triggerParams
+		if explainParams.TriggerPattern != "" {
+			explainParams = migratePatternToParams(explainParams, isPRMode)
+		}
+
+		explanation := explainTrigger(bitriseConfig.TriggerMap, explainParams, newBuildChangedFilesLister(explainParams))
+		explanation.Warnings = append(warnings, explanation.Warnings...)
+		if triggerParams.Format == output.FormatRaw {
+			printRawTriggerExplanation(explanation)
+		} else {
+			output.Print(explanation, triggerParams.Format)
+		}
+		return nil
+	}
+
 	pipelineToRunID, workflowToRunID, err := getPipelineAndWorkflowIDByParamsInCompatibleMode(bitriseConfig.TriggerMap, triggerParams, isPRMode)
 	if err != nil {
 		registerFatal(err.Error(), warnings, triggerParams.Format)
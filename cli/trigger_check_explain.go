@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/bitrise-io/go-utils/colorstring"
+)
+
+// triggerItemExplanation describes why a trigger map item matched the git event, or why not.
+type triggerItemExplanation struct {
+	Index int    `json:"index" yaml:"index"`
+	Item  string `json:"item" yaml:"item"`
+
+	EventType        models.TriggerEventType         `json:"event_type" yaml:"event_type"`
+	IsEventTypeMatch bool                            `json:"event_type_match" yaml:"event_type_match"`
+	Fields           []models.TriggerFieldMatchModel `json:"fields,omitempty" yaml:"fields,omitempty"`
+	Match            bool                            `json:"match" yaml:"match"`
+	IsSelected       bool                            `json:"selected" yaml:"selected"`
+	Error            string                          `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// triggerExplanation is the result of trigger-check --explain.
+type triggerExplanation struct {
+	EventType models.TriggerEventType  `json:"event_type" yaml:"event_type"`
+	Items     []triggerItemExplanation `json:"items" yaml:"items"`
+	Pipeline  string                   `json:"pipeline,omitempty" yaml:"pipeline,omitempty"`
+	Workflow  string                   `json:"workflow,omitempty" yaml:"workflow,omitempty"`
+	Warnings  []string                 `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+}
+
+func changedFilesFilterString(item models.TriggerMapItemModel) string {
+	return fmt.Sprintf("include: %s; exclude: %s", strings.Join(item.ChangedFiles.Include, ", "), strings.Join(item.ChangedFiles.Exclude, ", "))
+}
+
+// explainTrigger evaluates every trigger map item in order, the first matching item is selected.
+func explainTrigger(triggerMap models.TriggerMapModel, params RunAndTriggerParamsModel, changedFiles *changedFilesLister) triggerExplanation {
+	explanation := triggerExplanation{
+		Items: []triggerItemExplanation{},
+	}
+
+	eventType, err := models.TriggerParamsEventType(params.triggerParams())
+	if err != nil {
+		explanation.Warnings = append(explanation.Warnings, err.Error())
+	}
+	explanation.EventType = eventType
+
+	isSkipCI := models.IsSkipCICommitMessage(params.CommitMessage)
+	if isSkipCI {
+		explanation.Warnings = append(explanation.Warnings, "the commit message contains [skip ci] or [ci skip], no workflow is triggered")
+	}
+
+	isSelected := false
+	for idx, item := range triggerMap {
+		itemExplanation := triggerItemExplanation{
+			Index: idx,
+			Item:  item.String(true),
+		}
+
+		itemMatch, err := item.ExplainMatchWithTriggerParams(params.triggerParams())
+		if err != nil {
+			itemExplanation.Error = err.Error()
+			explanation.Items = append(explanation.Items, itemExplanation)
+			continue
+		}
+
+		itemExplanation.EventType = itemMatch.EventType
+		itemExplanation.IsEventTypeMatch = itemMatch.IsEventTypeMatch
+		itemExplanation.Fields = itemMatch.Fields
+		itemExplanation.Match = itemMatch.Match
+
+		if itemMatch.IsEventTypeMatch && item.ChangedFiles != nil {
+			fieldMatch := models.TriggerFieldMatchModel{
+				Field:   "changed_files",
+				Pattern: changedFilesFilterString(item),
+			}
+
+			relevantFiles, err := changedFiles.relevantFiles(*item.ChangedFiles)
+			if err != nil {
+				// every change is considered relevant
+				fieldMatch.Value = err.Error()
+				fieldMatch.Match = true
+			} else {
+				fieldMatch.Value = strings.Join(relevantFiles, ", ")
+				fieldMatch.Match = len(relevantFiles) > 0
+			}
+
+			itemExplanation.Fields = append(itemExplanation.Fields, fieldMatch)
+			itemExplanation.Match = itemExplanation.Match && fieldMatch.Match
+		}
+
+		if itemExplanation.Match && !isSelected && !isSkipCI {
+			isSelected = true
+			itemExplanation.IsSelected = true
+			explanation.Pipeline = item.PipelineID
+			explanation.Workflow = item.WorkflowID
+		}
+
+		explanation.Items = append(explanation.Items, itemExplanation)
+	}
+
+	shadowed := triggerMap.ShadowedItems()
+	for idx := range triggerMap {
+		if shadowingIdx, ok := shadowed[idx]; ok {
+			explanation.Warnings = append(explanation.Warnings, fmt.Sprintf("item [%d] (%s) is never selected, the earlier item [%d] (%s) matches every event it matches", idx, triggerMap[idx].String(true), shadowingIdx, triggerMap[shadowingIdx].String(true)))
+		}
+	}
+
+	return explanation
+}
+
+func matchMark(match bool) string {
+	if match {
+		return colorstring.Green("match")
+	}
+	return colorstring.Red("no match")
+}
+
+func printRawTriggerExplanation(explanation triggerExplanation) {
+	fmt.Printf("Trigger event: %s\n", explanation.EventType)
+	fmt.Println()
+
+	for _, item := range explanation.Items {
+		selected := ""
+		if item.IsSelected {
+			selected = " " + colorstring.Green("<- selected")
+		}
+		fmt.Printf("[%d] %s%s\n", item.Index, item.Item, selected)
+
+		if item.Error != "" {
+			fmt.Printf("    error: %s\n", colorstring.Red(item.Error))
+			continue
+		}
+
+		fmt.Printf("    event type: %s (%s)\n", item.EventType, matchMark(item.IsEventTypeMatch))
+		for _, field := range item.Fields {
+			fmt.Printf("    %s: %s vs %s (%s)\n", field.Field, field.Pattern, field.Value, matchMark(field.Match))
+		}
+		fmt.Printf("    => %s\n", matchMark(item.Match))
+	}
+
+	fmt.Println()
+	if explanation.Pipeline != "" {
+		fmt.Printf("-> pipeline: %s\n", colorstring.Blue(explanation.Pipeline))
+	} else if explanation.Workflow != "" {
+		fmt.Printf("-> workflow: %s\n", colorstring.Blue(explanation.Workflow))
+	} else {
+		fmt.Println(colorstring.Red("no matching pipeline & workflow found"))
+	}
+
+	if len(explanation.Warnings) > 0 {
+		fmt.Println()
+		fmt.Println("Warnings:")
+		for _, warning := range explanation.Warnings {
+			fmt.Printf("- %s\n", colorstring.Yellow(warning))
+		}
+	}
+}
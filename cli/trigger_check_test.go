@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/bitrise-io/bitrise/bitrise"
@@ -717,3 +718,163 @@ workflows:
 		require.Equal(t, "", workflowID)
 	}
 }
+
+func TestGetPipelineAndWorkflowIDByParamsAndChanges(t *testing.T) {
+	configStr := `format_version: 11
+
+trigger_map:
+- push_branch: master
+  workflow: ios
+  changed_files:
+    include:
+    - apps/ios/**
+    - shared/**
+    exclude:
+    - "**/*.md"
+- push_branch: master
+  workflow: android
+  changed_files:
+    include:
+    - apps/android/**
+- push_branch: release
+  workflow: release
+  changed_files:
+    include:
+    - apps/**
+
+workflows:
+  ios:
+  android:
+  release:
+`
+
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	listerWithFiles := func(files ...string) *changedFilesLister {
+		return newChangedFilesLister(func() ([]string, error) {
+			return files, nil
+		})
+	}
+
+	t.Log("relevant change of the first item")
+	{
+		_, workflowID, err := getPipelineAndWorkflowIDByParamsAndChanges(config.TriggerMap, RunAndTriggerParamsModel{PushBranch: "master"}, listerWithFiles("shared/Model.swift"))
+		require.NoError(t, err)
+		require.Equal(t, "ios", workflowID)
+	}
+
+	t.Log("excluded changes of the first item")
+	{
+		_, workflowID, err := getPipelineAndWorkflowIDByParamsAndChanges(config.TriggerMap, RunAndTriggerParamsModel{PushBranch: "master"}, listerWithFiles("apps/ios/README.md", "apps/android/build.gradle"))
+		require.NoError(t, err)
+		require.Equal(t, "android", workflowID)
+	}
+
+	t.Log("no relevant changes")
+	{
+		_, _, err := getPipelineAndWorkflowIDByParamsAndChanges(config.TriggerMap, RunAndTriggerParamsModel{PushBranch: "release"}, listerWithFiles("README.md"))
+		require.EqualError(t, err, "no matching pipeline & workflow found with trigger params: push-branch: release, pr-source-branch: , pr-target-branch: , tag: , matching items skipped: no relevant changes (workflow: release)")
+	}
+
+	t.Log("changed files can not be determined - every change is relevant")
+	{
+		lister := newChangedFilesLister(func() ([]string, error) {
+			return nil, errors.New("not a git repository")
+		})
+		_, workflowID, err := getPipelineAndWorkflowIDByParamsAndChanges(config.TriggerMap, RunAndTriggerParamsModel{PushBranch: "master"}, lister)
+		require.NoError(t, err)
+		require.Equal(t, "ios", workflowID)
+	}
+	t.Log("commit message with [skip ci]")
+	{
+		_, _, err := getPipelineAndWorkflowIDByParamsAndChanges(config.TriggerMap, RunAndTriggerParamsModel{PushBranch: "master", CommitMessage: "Update docs [skip ci]"}, listerWithFiles("apps/ios/App.swift"))
+		require.EqualError(t, err, "trigger skipped: the commit message contains [skip ci] or [ci skip]")
+	}
+}
+
+func TestExplainTrigger(t *testing.T) {
+	configStr := `format_version: 11
+
+trigger_map:
+- push_branch: master
+  workflow: ios
+  changed_files:
+    include:
+    - apps/ios/**
+- push_branch: "*"
+  workflow: primary
+- push_branch: feature/*
+  workflow: feature
+- pull_request_target_branch: master
+  workflow: pr
+
+workflows:
+  ios:
+  primary:
+  feature:
+  pr:
+`
+
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configStr))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	listerWithFiles := func(files ...string) *changedFilesLister {
+		return newChangedFilesLister(func() ([]string, error) {
+			return files, nil
+		})
+	}
+
+	t.Log("first item skipped: no relevant changes")
+	{
+		explanation := explainTrigger(config.TriggerMap, RunAndTriggerParamsModel{PushBranch: "master"}, listerWithFiles("apps/android/App.kt"))
+		require.Equal(t, "code-push", string(explanation.EventType))
+		require.Equal(t, "primary", explanation.Workflow)
+		require.Equal(t, 4, len(explanation.Items))
+
+		ios := explanation.Items[0]
+		require.True(t, ios.IsEventTypeMatch)
+		require.False(t, ios.Match)
+		require.False(t, ios.IsSelected)
+		require.Equal(t, 2, len(ios.Fields))
+		require.Equal(t, "push_branch", ios.Fields[0].Field)
+		require.True(t, ios.Fields[0].Match)
+		require.Equal(t, "changed_files", ios.Fields[1].Field)
+		require.False(t, ios.Fields[1].Match)
+
+		require.True(t, explanation.Items[1].Match)
+		require.True(t, explanation.Items[1].IsSelected)
+
+		require.True(t, explanation.Items[2].IsEventTypeMatch)
+		require.False(t, explanation.Items[2].Match)
+
+		require.False(t, explanation.Items[3].IsEventTypeMatch)
+		require.False(t, explanation.Items[3].Match)
+
+		require.Equal(t, 1, len(explanation.Warnings))
+		require.Contains(t, explanation.Warnings[0], "item [2] (push_branch: feature/* -> workflow: feature) is never selected, the earlier item [1]")
+	}
+
+	t.Log("relevant changes")
+	{
+		explanation := explainTrigger(config.TriggerMap, RunAndTriggerParamsModel{PushBranch: "master"}, listerWithFiles("apps/ios/App.swift"))
+		require.Equal(t, "ios", explanation.Workflow)
+		require.True(t, explanation.Items[0].IsSelected)
+		require.Equal(t, "apps/ios/App.swift", explanation.Items[0].Fields[1].Value)
+
+		// matches too, but an earlier item is already selected
+		require.True(t, explanation.Items[1].Match)
+		require.False(t, explanation.Items[1].IsSelected)
+	}
+
+	t.Log("commit message with [skip ci]")
+	{
+		explanation := explainTrigger(config.TriggerMap, RunAndTriggerParamsModel{PushBranch: "master", CommitMessage: "[ci skip]"}, listerWithFiles("apps/ios/App.swift"))
+		require.Equal(t, "", explanation.Workflow)
+		require.True(t, explanation.Items[0].Match)
+		require.False(t, explanation.Items[0].IsSelected)
+		require.Contains(t, explanation.Warnings, "the commit message contains [skip ci] or [ci skip], no workflow is triggered")
+	}
+}
@@ -1,24 +1,70 @@
 package cli
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 
 	"os"
+	"regexp"
+	"strconv"
 
 	"strings"
 
+	"github.com/bitrise-io/bitrise/models"
 	"github.com/bitrise-io/bitrise/output"
+	envmanModels "github.com/bitrise-io/envman/models"
 	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/fileutil"
 	flog "github.com/bitrise-io/go-utils/log"
 	"github.com/urfave/cli"
+	yaml "gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
 )
 
+// yamlErrorLineRegexp matches the line numbers reported by the yaml parser, e.g. line 3: cannot unmarshal ...
+var yamlErrorLineRegexp = regexp.MustCompile(`(?m)line (\d+): (.+)$`)
+
+// ValidationIssueModel is a validation error or warning, located in the validated file.
+type ValidationIssueModel struct {
+	Message string `json:"message" yaml:"message"`
+	File    string `json:"file,omitempty" yaml:"file,omitempty"`
+	Line    int    `json:"line,omitempty" yaml:"line,omitempty"`
+	Column  int    `json:"column,omitempty" yaml:"column,omitempty"`
+}
+
+// String formats the issue as file:line:column: message.
+func (issue ValidationIssueModel) String() string {
+	position := issue.File
+	if issue.Line > 0 {
+		position += fmt.Sprintf(":%d", issue.Line)
+		if issue.Column > 0 {
+			position += fmt.Sprintf(":%d", issue.Column)
+		}
+	}
+
+	if position == "" {
+		return issue.Message
+	}
+	return position + ": " + issue.Message
+}
+
 // ValidationItemModel ...
 type ValidationItemModel struct {
 	IsValid  bool     `json:"is_valid" yaml:"is_valid"`
 	Error    string   `json:"error,omitempty" yaml:"error,omitempty"`
 	Warnings []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+	// ErrorDetails and WarningDetails hold the errors and warnings, with their position in the validated file.
+	ErrorDetails   []ValidationIssueModel `json:"error_details,omitempty" yaml:"error_details,omitempty"`
+	WarningDetails []ValidationIssueModel `json:"warning_details,omitempty" yaml:"warning_details,omitempty"`
+}
+
+func validationIssueStrings(issues []ValidationIssueModel) []string {
+	strs := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		strs = append(strs, issue.String())
+	}
+	return strs
 }
 
 // ValidationModel ...
@@ -117,15 +163,23 @@ func (v ValidationModel) String() string {
 		if config.IsValid {
 			msg += fmt.Sprintf("Config is valid: %s", colorstring.Greenf("%v", true))
 		} else {
+			configError := config.Error
+			if len(config.ErrorDetails) > 0 {
+				configError = strings.Join(validationIssueStrings(config.ErrorDetails), "\n")
+			}
 			msg += fmt.Sprintf("Config is valid: %s", colorstring.Redf("%v", false))
-			msg += fmt.Sprintf("\nError: %s", colorstring.Red(config.Error))
+			msg += fmt.Sprintf("\nError: %s", colorstring.Red(configError))
 		}
 
-		if len(config.Warnings) > 0 {
+		warnings := config.Warnings
+		if len(config.WarningDetails) > 0 {
+			warnings = validationIssueStrings(config.WarningDetails)
+		}
+		if len(warnings) > 0 {
 			msg += "\nWarning(s):\n"
-			for i, warning := range config.Warnings {
+			for i, warning := range warnings {
 				msg += fmt.Sprintf("- %s", warning)
-				if i != len(config.Warnings)-1 {
+				if i != len(warnings)-1 {
 					msg += "\n"
 				}
 			}
@@ -149,7 +203,139 @@ func (v ValidationModel) String() string {
 	return msg
 }
 
-func validateBitriseYML(bitriseConfigPath string, bitriseConfigBase64Data string) (*ValidationItemModel, error) {
+func readBitriseConfigBytes(bitriseConfigPath string, bitriseConfigBase64Data string) (string, []byte, error) {
+	if bitriseConfigBase64Data != "" {
+		bytes, err := base64.StdEncoding.DecodeString(bitriseConfigBase64Data)
+		if err != nil {
+			return "", nil, fmt.Errorf("Failed to decode base 64 string, error: %s", err)
+		}
+		return DefaultBitriseConfigFileName, bytes, nil
+	}
+
+	pth, err := GetBitriseConfigFilePath(bitriseConfigPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	bytes, err := fileutil.ReadBytesFromFile(pth)
+	if err != nil {
+		return "", nil, err
+	}
+	return pth, bytes, nil
+}
+
+func deepValidateBitriseYML(configValidation *ValidationItemModel, config models.BitriseDataModel, bitriseConfigPath, bitriseConfigBase64Data, inventoryPath, inventoryBase64Data string, extraEnvKeys []string) error {
+	configName, configBytes, err := readBitriseConfigBytes(bitriseConfigPath, bitriseConfigBase64Data)
+	if err != nil {
+		return fmt.Errorf("Failed to read config, err: %s", err)
+	}
+
+	// invalid inventory is reported by the inventory validation
+	inventory, err := CreateInventoryFromCLIParams(inventoryBase64Data, inventoryPath)
+	if err != nil {
+		inventory = []envmanModels.EnvironmentItemModel{}
+	}
+
+	errs, warns, err := deepValidateConfig(configName, configBytes, config, inventory, extraEnvKeys, resolveStepInfo)
+	if err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		configValidation.IsValid = false
+		configValidation.Error = strings.Join(validationIssueStrings(errs), "\n")
+		configValidation.ErrorDetails = append(configValidation.ErrorDetails, errs...)
+	}
+	configValidation.Warnings = append(configValidation.Warnings, validationIssueStrings(warns)...)
+	configValidation.WarningDetails = append(configValidation.WarningDetails, warns...)
+
+	return nil
+}
+
+// yamlErrorIssues returns the issues of a yaml parser error, located by the line numbers reported by the parser,
+// the column is the column of the first node in the line.
+func yamlErrorIssues(configName string, root *yamlv3.Node, err error) []ValidationIssueModel {
+	issues := []ValidationIssueModel{}
+	for _, match := range yamlErrorLineRegexp.FindAllStringSubmatch(err.Error(), -1) {
+		line, convErr := strconv.Atoi(match[1])
+		if convErr != nil {
+			continue
+		}
+
+		issue := yamlIssue(configName, yamlFirstNodeOfLine(root, line), match[2])
+		// the line is reported by the parser, the node is only used for its column
+		issue.Line = line
+		issues = append(issues, issue)
+	}
+
+	if len(issues) == 0 {
+		issues = append(issues, ValidationIssueModel{Message: err.Error(), File: configName})
+	}
+	return issues
+}
+
+// configPathIssue locates the config validation error or warning in the config file.
+func configPathIssue(configName string, root *yamlv3.Node, err error) ValidationIssueModel {
+	pathErr, ok := err.(models.ConfigPathError)
+	if !ok {
+		return yamlIssue(configName, nil, err.Error())
+	}
+	return yamlIssue(configName, yamlConfigPathNode(root, pathErr.Path), pathErr.Message)
+}
+
+// configValidationIssues validates the config and returns its errors and warnings, located in the config file:
+// parser errors by the line reported by the parser, validation errors and warnings by the offending node.
+func configValidationIssues(configName string, configBytes []byte) ([]ValidationIssueModel, []ValidationIssueModel) {
+	var document yamlv3.Node
+	root := &document
+	if err := yamlv3.Unmarshal(configBytes, &document); err != nil {
+		root = nil
+	}
+
+	var config models.BitriseDataModel
+	var err error
+	if strings.HasSuffix(configName, ".json") {
+		err = json.Unmarshal(configBytes, &config)
+	} else {
+		err = yaml.Unmarshal(configBytes, &config)
+	}
+	if err != nil {
+		return yamlErrorIssues(configName, root, err), []ValidationIssueModel{}
+	}
+
+	if err := config.Normalize(); err != nil {
+		return []ValidationIssueModel{{Message: err.Error(), File: configName}}, []ValidationIssueModel{}
+	}
+
+	warnings, err := config.ValidateWithPaths()
+	warningIssues := []ValidationIssueModel{}
+	for _, warning := range warnings {
+		warningIssues = append(warningIssues, configPathIssue(configName, root, warning))
+	}
+	if err != nil {
+		return []ValidationIssueModel{configPathIssue(configName, root, err)}, warningIssues
+	}
+	return []ValidationIssueModel{}, warningIssues
+}
+
+// locateConfigValidation fills the error and warning details of the config validation.
+func locateConfigValidation(configValidation *ValidationItemModel, bitriseConfigPath, bitriseConfigBase64Data string) {
+	configName, configBytes, err := readBitriseConfigBytes(bitriseConfigPath, bitriseConfigBase64Data)
+	if err != nil {
+		configName = ""
+	} else {
+		configValidation.ErrorDetails, configValidation.WarningDetails = configValidationIssues(configName, configBytes)
+	}
+
+	if configValidation.IsValid {
+		configValidation.ErrorDetails = nil
+	} else if len(configValidation.ErrorDetails) == 0 {
+		// e.g. the format version of the config is not supported
+		configValidation.ErrorDetails = []ValidationIssueModel{{Message: configValidation.Error, File: configName}}
+	}
+}
+
+func validateBitriseYML(bitriseConfigPath, bitriseConfigBase64Data, inventoryPath, inventoryBase64Data string, isDeep, isStrict bool, extraEnvKeys []string) (*ValidationItemModel, error) {
 	pth, err := GetBitriseConfigFilePath(bitriseConfigPath)
 	if err != nil && !strings.Contains(err.Error(), "bitrise.yml path not defined and not found on it's default path:") {
 		return nil, fmt.Errorf("Failed to get config path, err: %s", err)
@@ -157,7 +343,7 @@ func validateBitriseYML(bitriseConfigPath string, bitriseConfigBase64Data string
 
 	if pth != "" || (pth == "" && bitriseConfigBase64Data != "") {
 		// Config validation
-		_, warns, err := CreateBitriseConfigFromCLIParams(bitriseConfigBase64Data, bitriseConfigPath)
+		config, warns, err := CreateBitriseConfigFromCLIParams(bitriseConfigBase64Data, bitriseConfigPath)
 		configValidation := ValidationItemModel{
 			IsValid:  true,
 			Warnings: warns,
@@ -166,6 +352,19 @@ func validateBitriseYML(bitriseConfigPath string, bitriseConfigBase64Data string
 			configValidation.IsValid = false
 			configValidation.Error = err.Error()
 		}
+		locateConfigValidation(&configValidation, bitriseConfigPath, bitriseConfigBase64Data)
+
+		if isStrict {
+			if err := strictValidateBitriseYML(&configValidation, bitriseConfigPath, bitriseConfigBase64Data); err != nil {
+				return nil, err
+			}
+		}
+
+		if err == nil && isDeep {
+			if err := deepValidateBitriseYML(&configValidation, config, bitriseConfigPath, bitriseConfigBase64Data, inventoryPath, inventoryBase64Data, extraEnvKeys); err != nil {
+				return nil, err
+			}
+		}
 
 		return &configValidation, nil
 	}
@@ -196,7 +395,7 @@ func validateInventory(inventoryPath string, inventoryBase64Data string) (*Valid
 	return nil, nil
 }
 
-func runValidate(bitriseConfigPath string, deprecatedBitriseConfigPath string, bitriseConfigBase64Data string, inventoryPath string, inventoryBase64Data string) (*ValidationModel, []string, error) {
+func runValidate(bitriseConfigPath string, deprecatedBitriseConfigPath string, bitriseConfigBase64Data string, inventoryPath string, inventoryBase64Data string, isDeep, isStrict bool, extraEnvKeys []string) (*ValidationModel, []string, error) {
 	warnings := []string{}
 
 	if bitriseConfigPath == "" && deprecatedBitriseConfigPath != "" {
@@ -206,7 +405,7 @@ func runValidate(bitriseConfigPath string, deprecatedBitriseConfigPath string, b
 
 	validation := ValidationModel{}
 
-	result, err := validateBitriseYML(bitriseConfigPath, bitriseConfigBase64Data)
+	result, err := validateBitriseYML(bitriseConfigPath, bitriseConfigBase64Data, inventoryPath, inventoryBase64Data, isDeep, isStrict, extraEnvKeys)
 	validation.Config = result
 	if err != nil {
 		return &validation, warnings, err
@@ -234,6 +433,10 @@ func validate(c *cli.Context) error {
 	inventoryBase64Data := c.String(InventoryBase64Key)
 	inventoryPath := c.String(InventoryKey)
 
+	isDeep := c.Bool(DeepKey)
+	isStrict := c.Bool(StrictKey)
+	extraEnvKeys := c.StringSlice(EnvKeyKey)
+
 	format := c.String(OuputFormatKey)
 	if format == "" {
 		format = output.FormatRaw
@@ -250,7 +453,7 @@ func validate(c *cli.Context) error {
 		os.Exit(1)
 	}
 
-	validation, warnings, err := runValidate(bitriseConfigPath, deprecatedBitriseConfigPath, bitriseConfigBase64Data, inventoryPath, inventoryBase64Data)
+	validation, warnings, err := runValidate(bitriseConfigPath, deprecatedBitriseConfigPath, bitriseConfigBase64Data, inventoryPath, inventoryBase64Data, isDeep, isStrict, extraEnvKeys)
 	if err != nil {
 		log.Print(NewValidationError(err.Error(), warnings...))
 		os.Exit(1)
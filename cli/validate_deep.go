@@ -0,0 +1,437 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/command/git"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-utils/sliceutil"
+	stepmanCLI "github.com/bitrise-io/stepman/cli"
+	stepmanModels "github.com/bitrise-io/stepman/models"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// envReferenceRegexp matches $KEY and ${KEY} env references.
+var envReferenceRegexp = regexp.MustCompile(`\$(?:\{([A-Za-z_][A-Za-z0-9_]*)\}|([A-Za-z_][A-Za-z0-9_]*))`)
+
+// builtInEnvKeys are the envs exposed by the bitrise CLI for every step.
+var builtInEnvKeys = []string{
+	configs.CIModeEnvKey,
+	configs.PRModeEnvKey,
+	configs.PullRequestIDEnvKey,
+	configs.DebugModeEnvKey,
+	configs.LogLevelEnvKey,
+	configs.EnvstorePathEnvKey,
+	configs.FormattedOutputPathEnvKey,
+	configs.BitriseSourceDirEnvKey,
+	configs.BitriseDeployDirEnvKey,
+	configs.BitriseTestDeployDirEnvKey,
+	configs.BitrisePerStepTestResultDirEnvKey,
+	configs.BitriseTmpDirEnvKey,
+	"BITRISE_STEP_SOURCE_DIR",
+	"BITRISE_TRIGGERED_WORKFLOW_ID",
+	"BITRISE_TRIGGERED_WORKFLOW_TITLE",
+	"BITRISE_BUILD_STATUS",
+	"STEPLIB_BUILD_STATUS",
+}
+
+// stepInfoResolver returns the step definition (step.yml) of the referenced step,
+// isDefined is false for steps without step definition (steplib independent steps).
+type stepInfoResolver func(stepIDData models.StepIDData) (info stepmanModels.StepInfoModel, isDefined bool, err error)
+
+func readStepInfoFromDir(stepIDData models.StepIDData, dir string) (stepmanModels.StepInfoModel, error) {
+	step, err := bitrise.ReadSpecStep(filepath.Join(dir, "step.yml"))
+	if err != nil {
+		return stepmanModels.StepInfoModel{}, err
+	}
+	return stepmanModels.StepInfoModel{ID: stepIDData.IDorURI, Version: stepIDData.Version, Step: step.StepModel}, nil
+}
+
+func resolveStepInfo(stepIDData models.StepIDData) (stepmanModels.StepInfoModel, bool, error) {
+	switch stepIDData.SteplibSource {
+	case "_":
+		return stepmanModels.StepInfoModel{}, false, nil
+	case "path":
+		stepDir, err := pathutil.AbsPath(stepIDData.IDorURI)
+		if err != nil {
+			return stepmanModels.StepInfoModel{}, false, err
+		}
+
+		info, err := readStepInfoFromDir(stepIDData, stepDir)
+		return info, true, err
+	case "git":
+		cloneDir, err := pathutil.NormalizedOSTempDirPath("step_clone")
+		if err != nil {
+			return stepmanModels.StepInfoModel{}, false, err
+		}
+		defer func() {
+			if err := os.RemoveAll(cloneDir); err != nil {
+				log.Warnf("Failed to remove step clone dir, error: %s", err)
+			}
+		}()
+
+		repo, err := git.New(cloneDir)
+		if err != nil {
+			return stepmanModels.StepInfoModel{}, false, err
+		}
+		if err := repo.CloneTagOrBranch(stepIDData.IDorURI, stepIDData.Version).Run(); err != nil {
+			return stepmanModels.StepInfoModel{}, false, fmt.Errorf("failed to clone step, error: %s", err)
+		}
+
+		info, err := readStepInfoFromDir(stepIDData, cloneDir)
+		return info, true, err
+	default:
+		info, err := stepmanCLI.QueryStepInfoFromLibrary(stepIDData.SteplibSource, stepIDData.IDorURI, stepIDData.Version)
+		if err != nil {
+			return stepmanModels.StepInfoModel{}, false, err
+		}
+
+		if err := info.Step.Normalize(); err != nil {
+			return stepmanModels.StepInfoModel{}, false, err
+		}
+		if err := info.Step.FillMissingDefaults(); err != nil {
+			return stepmanModels.StepInfoModel{}, false, err
+		}
+		return info, true, nil
+	}
+}
+
+type resolvedStepInfo struct {
+	info      stepmanModels.StepInfoModel
+	isDefined bool
+	err       error
+}
+
+// deepValidator collects the findings of the deep config validation,
+// every finding is prefixed with the position of the related node in the config file.
+type deepValidator struct {
+	configName   string
+	root         *yamlv3.Node
+	config       models.BitriseDataModel
+	baseEnvKeys  []string
+	resolveStep  stepInfoResolver
+	resolvedStep map[string]resolvedStepInfo
+
+	errors   []ValidationIssueModel
+	warnings []ValidationIssueModel
+	reported map[string]bool
+}
+
+// newDeepValidator returns the validator of the config, the envs defined for every workflow are the envs exposed by the bitrise CLI,
+// the inventory, the app envs and the extra env keys (e.g. the envs of the build machine), but not the envs of the validating process.
+func newDeepValidator(configName string, configBytes []byte, config models.BitriseDataModel, inventory []envmanModels.EnvironmentItemModel, extraEnvKeys []string, resolveStep stepInfoResolver) (*deepValidator, error) {
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(configBytes, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config, error: %s", err)
+	}
+
+	baseEnvKeys := append(append([]string{}, builtInEnvKeys...), extraEnvKeys...)
+	for _, env := range append(append([]envmanModels.EnvironmentItemModel{}, inventory...), config.App.Environments...) {
+		key, _, err := env.GetKeyValuePair()
+		if err != nil {
+			return nil, err
+		}
+		baseEnvKeys = append(baseEnvKeys, key)
+	}
+
+	return &deepValidator{
+		configName:   configName,
+		root:         &root,
+		config:       config,
+		baseEnvKeys:  baseEnvKeys,
+		resolveStep:  resolveStep,
+		resolvedStep: map[string]resolvedStepInfo{},
+		reported:     map[string]bool{},
+	}, nil
+}
+
+func (v *deepValidator) report(isError bool, node *yamlv3.Node, format string, args ...interface{}) {
+	finding := yamlIssue(v.configName, node, fmt.Sprintf(format, args...))
+	if v.reported[finding.String()] {
+		return
+	}
+	v.reported[finding.String()] = true
+
+	if isError {
+		v.errors = append(v.errors, finding)
+	} else {
+		v.warnings = append(v.warnings, finding)
+	}
+}
+
+// workflowRunChain returns the workflows in run order, when running the given workflow.
+func workflowRunChain(config models.BitriseDataModel, workflowID string, stack []string) []string {
+	if sliceutil.IsStringInSlice(workflowID, stack) {
+		return []string{}
+	}
+	workflow, found := config.Workflows[workflowID]
+	if !found {
+		return []string{}
+	}
+
+	stack = append(append([]string{}, stack...), workflowID)
+
+	chain := []string{}
+	for _, beforeWorkflowID := range workflow.BeforeRun {
+		chain = append(chain, workflowRunChain(config, beforeWorkflowID, stack)...)
+	}
+	chain = append(chain, workflowID)
+	for _, afterWorkflowID := range workflow.AfterRun {
+		chain = append(chain, workflowRunChain(config, afterWorkflowID, stack)...)
+	}
+	return chain
+}
+
+func (v *deepValidator) stepInfo(compositeStepID string, stepIDData models.StepIDData) resolvedStepInfo {
+	if resolved, found := v.resolvedStep[compositeStepID]; found {
+		return resolved
+	}
+
+	info, isDefined, err := v.resolveStep(stepIDData)
+	resolved := resolvedStepInfo{info: info, isDefined: isDefined, err: err}
+	v.resolvedStep[compositeStepID] = resolved
+	return resolved
+}
+
+func envKeys(envs []envmanModels.EnvironmentItemModel) []string {
+	keys := []string{}
+	for _, env := range envs {
+		if key, _, err := env.GetKeyValuePair(); err == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// copyStepEnvs copies the step's inputs and outputs, as merging steps modifies the env items in place.
+func copyStepEnvs(step models.StepModel) models.StepModel {
+	copyEnvs := func(envs []envmanModels.EnvironmentItemModel) []envmanModels.EnvironmentItemModel {
+		copied := []envmanModels.EnvironmentItemModel{}
+		for _, env := range envs {
+			copiedEnv := envmanModels.EnvironmentItemModel{}
+			for key, value := range env {
+				copiedEnv[key] = value
+			}
+			copied = append(copied, copiedEnv)
+		}
+		return copied
+	}
+
+	step.Inputs = copyEnvs(step.Inputs)
+	step.Outputs = copyEnvs(step.Outputs)
+	return step
+}
+
+// validateStep validates the step against its definition and returns the keys of the envs exported by the step.
+func (v *deepValidator) validateStep(workflowID string, idx int, stepListItem models.StepListItemModel, definedEnvKeys map[string]bool) []string {
+	stepNode := yamlNodeAt(v.root, "workflows", workflowID, "steps", idx)
+
+	compositeStepID, workflowStep, err := models.GetStepIDStepDataPair(stepListItem)
+	if err != nil {
+		v.report(true, stepNode, "workflow (%s) step (%d): %s", workflowID, idx, err)
+		return []string{}
+	}
+	prefix := fmt.Sprintf("workflow (%s) step (%d) (%s)", workflowID, idx, compositeStepID)
+
+	stepIDData, err := models.CreateStepIDDataFromString(compositeStepID, v.config.DefaultStepLibSource)
+	if err != nil {
+		v.report(true, stepNode, "%s: %s", prefix, err)
+		return []string{}
+	}
+
+	resolved := v.stepInfo(compositeStepID, stepIDData)
+	if resolved.err != nil {
+		v.report(true, stepNode, "%s: failed to resolve step definition: %s", prefix, resolved.err)
+		return envKeys(workflowStep.Outputs)
+	}
+
+	mergedStep := workflowStep
+	if resolved.isDefined {
+		if resolved.info.GroupInfo.RemovalDate != "" {
+			v.report(true, stepNode, "%s: step is removed from the StepLib (removal date: %s)", prefix, resolved.info.GroupInfo.RemovalDate)
+		} else if resolved.info.GroupInfo.DeprecateNotes != "" {
+			v.report(false, stepNode, "%s: step is deprecated: %s", prefix, resolved.info.GroupInfo.DeprecateNotes)
+		}
+
+		specInputKeys := envKeys(resolved.info.Step.Inputs)
+		for inputIdx, input := range workflowStep.Inputs {
+			key, _, err := input.GetKeyValuePair()
+			if err != nil {
+				continue
+			}
+			if !sliceutil.IsStringInSlice(key, specInputKeys) {
+				inputNode := yamlNodeAt(v.root, "workflows", workflowID, "steps", idx, compositeStepID, "inputs", inputIdx)
+				v.report(false, inputNode, "%s: unknown input (%s)", prefix, key)
+			}
+		}
+
+		mergedStep, err = models.MergeStepWith(copyStepEnvs(models.NewStepModel(resolved.info.Step)), workflowStep)
+		if err != nil {
+			v.report(true, stepNode, "%s: %s", prefix, err)
+			return []string{}
+		}
+	}
+
+	for _, input := range mergedStep.Inputs {
+		key, value, err := input.GetKeyValuePair()
+		if err != nil {
+			v.report(true, stepNode, "%s: %s", prefix, err)
+			continue
+		}
+
+		options, err := input.GetOptions()
+		if err != nil {
+			v.report(true, stepNode, "%s: input (%s): %s", prefix, key, err)
+			continue
+		}
+
+		inputNode := stepNode
+		for inputIdx, workflowInput := range workflowStep.Inputs {
+			if workflowKey, _, err := workflowInput.GetKeyValuePair(); err == nil && workflowKey == key {
+				inputNode = yamlNodeAt(v.root, "workflows", workflowID, "steps", idx, compositeStepID, "inputs", inputIdx)
+			}
+		}
+
+		isExpand := options.IsExpand == nil || *options.IsExpand
+		isTemplate := options.IsTemplate != nil && *options.IsTemplate
+		if isExpand {
+			for _, match := range envReferenceRegexp.FindAllStringSubmatch(value, -1) {
+				envKey := match[1] + match[2]
+				if !definedEnvKeys[envKey] {
+					v.report(false, inputNode, "%s: input (%s) references undefined env ($%s)", prefix, key, envKey)
+				}
+			}
+		}
+
+		if (isExpand && strings.Contains(value, "$")) || isTemplate {
+			// the value is only known at step run
+			continue
+		}
+
+		if inputType, err := models.StepInputTypeOf(options); err == nil && inputType == models.PathStepInputType {
+			// paths are relative to the build's working directory, only checked at step run
+			options.Meta = map[string]interface{}{}
+		}
+
+		if err := models.ValidateStepInputValue(value, options); err != nil {
+			v.report(true, inputNode, "%s: %s", prefix, models.StepInputValidationError{Key: key, Message: err.Error()})
+		}
+	}
+
+	outputKeys := []string{}
+	for _, output := range mergedStep.Outputs {
+		key, alias, err := output.GetKeyValuePair()
+		if err != nil {
+			continue
+		}
+		if options, err := output.GetOptions(); err != nil {
+			v.report(true, stepNode, "%s: output (%s): %s", prefix, key, err)
+		} else if _, err := models.StepOutputTypeOf(options); err != nil {
+			v.report(true, stepNode, "%s: output (%s): %s", prefix, key, err)
+		}
+		if mergedStep.ID != nil {
+			outputKeys = append(outputKeys, models.StepOutputEnvKey(*mergedStep.ID, key))
+		}
+		if alias != "" {
+			key = alias
+		}
+		outputKeys = append(outputKeys, key)
+	}
+	return outputKeys
+}
+
+// validateWorkflow validates every step of the workflow's run chain (including before and after run workflows),
+// returns the validated workflows.
+// The envs are defined in the order the workflow run declares them (see buildEnvDeclarations):
+// the triggered workflow's envs (unless isolated), then for every workflow of the run chain its envs and its steps' outputs,
+// the envs declared by an isolated workflow are dropped when it finishes, except the exported ones.
+func (v *deepValidator) validateWorkflow(workflowID string) []string {
+	definedEnvKeys := map[string]bool{}
+	for _, key := range v.baseEnvKeys {
+		definedEnvKeys[key] = true
+	}
+	for _, key := range envKeys(triggeredWorkflowEnvironments(v.config.Workflows[workflowID])) {
+		definedEnvKeys[key] = true
+	}
+
+	chain := workflowRunChain(v.config, workflowID, []string{})
+	for _, chainWorkflowID := range chain {
+		workflow := v.config.Workflows[chainWorkflowID]
+
+		var envKeysBefore map[string]bool
+		if workflow.IsolateEnvs {
+			envKeysBefore = map[string]bool{}
+			for key := range definedEnvKeys {
+				envKeysBefore[key] = true
+			}
+		}
+
+		for _, key := range envKeys(workflow.Environments) {
+			definedEnvKeys[key] = true
+		}
+
+		for idx, stepListItem := range workflow.Steps {
+			for _, key := range v.validateStep(chainWorkflowID, idx, stepListItem, definedEnvKeys) {
+				definedEnvKeys[key] = true
+			}
+		}
+
+		if workflow.IsolateEnvs {
+			for _, key := range workflow.ExportEnvs {
+				if definedEnvKeys[key] {
+					envKeysBefore[key] = true
+				}
+			}
+			definedEnvKeys = envKeysBefore
+		}
+	}
+	return chain
+}
+
+func (v *deepValidator) validate() {
+	workflowIDs := []string{}
+	for workflowID := range v.config.Workflows {
+		workflowIDs = append(workflowIDs, workflowID)
+	}
+	sort.Strings(workflowIDs)
+
+	// utility workflows can not be run directly, those are validated as part of the run chains
+	isValidated := map[string]bool{}
+	for _, workflowID := range workflowIDs {
+		if strings.HasPrefix(workflowID, "_") {
+			continue
+		}
+		for _, chainWorkflowID := range v.validateWorkflow(workflowID) {
+			isValidated[chainWorkflowID] = true
+		}
+	}
+
+	for _, workflowID := range workflowIDs {
+		if !isValidated[workflowID] {
+			v.validateWorkflow(workflowID)
+		}
+	}
+}
+
+// deepValidateConfig resolves the definition of every referenced step and validates the step inputs
+// and the env references, returns the errors and warnings found.
+func deepValidateConfig(configName string, configBytes []byte, config models.BitriseDataModel, inventory []envmanModels.EnvironmentItemModel, extraEnvKeys []string, resolveStep stepInfoResolver) ([]ValidationIssueModel, []ValidationIssueModel, error) {
+	validator, err := newDeepValidator(configName, configBytes, config, inventory, extraEnvKeys, resolveStep)
+	if err != nil {
+		return []ValidationIssueModel{}, []ValidationIssueModel{}, err
+	}
+
+	validator.validate()
+
+	return validator.errors, validator.warnings, nil
+}
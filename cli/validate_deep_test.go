@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/bitrise"
+	"github.com/bitrise-io/bitrise/models"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/pathutil"
+	stepmanModels "github.com/bitrise-io/stepman/models"
+	"github.com/stretchr/testify/require"
+)
+
+const deepValidateTestStepYML = `title: Test step
+inputs:
+- configuration: debug
+  opts:
+    value_options: ["debug", "release"]
+- project_path:
+  opts:
+    is_required: true
+- retry_count: "1"
+  opts:
+    meta:
+      type: int
+outputs:
+- TEST_STEP_RESULT:
+`
+
+func TestDeepValidateConfig(t *testing.T) {
+	stepDir, err := pathutil.NormalizedOSTempDirPath("deep-validate-step")
+	require.NoError(t, err)
+	require.NoError(t, fileutil.WriteStringToFile(filepath.Join(stepDir, "step.yml"), deepValidateTestStepYML))
+
+	configYML := `format_version: 11
+default_step_lib_source: https://github.com/bitrise-io/bitrise-steplib.git
+
+app:
+  envs:
+  - PROJECT_PATH: ./app
+
+workflows:
+  primary:
+    before_run:
+    - _setup
+    steps:
+    - path::` + stepDir + `:
+        inputs:
+        - configuration: relase
+        - project_path: $PROJECT_PATH
+        - retry_count: $RETRY_COUNT
+        - unknown_input: value
+    - path::` + stepDir + `:
+        inputs:
+        - project_path: $TEST_STEP_RESULT/$SETUP_DIR
+    - deprecated-step@1: {}
+    - path::` + stepDir + `: {}
+  _setup:
+    envs:
+    - SETUP_DIR: ./setup
+    steps:
+    - script@1: {}
+`
+
+	config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configYML))
+	require.NoError(t, err)
+	require.Equal(t, 0, len(warnings))
+
+	resolveStep := func(stepIDData models.StepIDData) (stepmanModels.StepInfoModel, bool, error) {
+		switch stepIDData.IDorURI {
+		case "deprecated-step":
+			return stepmanModels.StepInfoModel{GroupInfo: stepmanModels.StepGroupInfoModel{DeprecateNotes: "use new-step instead"}}, true, nil
+		case "script":
+			outputs := []envmanModels.EnvironmentItemModel{
+				{"SCRIPT_RESULT": "", envmanModels.OptionsKey: envmanModels.EnvironmentItemOptionsModel{Meta: map[string]interface{}{"type": "float"}}},
+			}
+			return stepmanModels.StepInfoModel{Step: stepmanModels.StepModel{Outputs: outputs}}, true, nil
+		}
+		return resolveStepInfo(stepIDData)
+	}
+
+	errs, warns, err := deepValidateConfig("bitrise.yml", []byte(configYML), config, []envmanModels.EnvironmentItemModel{}, nil, resolveStep)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"bitrise.yml:28:7: workflow (_setup) step (0) (script@1): output (SCRIPT_RESULT): unknown output type (float)",
+		"bitrise.yml:15:11: workflow (primary) step (0) (path::" + stepDir + "): input (configuration) value is not one of the value_options: debug, release",
+		"bitrise.yml:23:7: workflow (primary) step (3) (path::" + stepDir + "): input (project_path) is required",
+	}, validationIssueStrings(errs))
+	require.Equal(t, ValidationIssueModel{Message: "workflow (primary) step (3) (path::" + stepDir + "): input (project_path) is required", File: "bitrise.yml", Line: 23, Column: 7}, errs[2])
+	require.Equal(t, []string{
+		"bitrise.yml:18:11: workflow (primary) step (0) (path::" + stepDir + "): unknown input (unknown_input)",
+		"bitrise.yml:17:11: workflow (primary) step (0) (path::" + stepDir + "): input (retry_count) references undefined env ($RETRY_COUNT)",
+		"bitrise.yml:22:7: workflow (primary) step (2) (deprecated-step@1): step is deprecated: use new-step instead",
+	}, validationIssueStrings(warns))
+}
+
+func TestYAMLNodeAt(t *testing.T) {
+	configName, configBytes := "bitrise.yml", []byte(`workflows:
+  primary:
+    steps:
+    - script: {}
+`)
+	validator, err := newDeepValidator(configName, configBytes, models.BitriseDataModel{}, nil, nil, resolveStepInfo)
+	require.NoError(t, err)
+
+	require.Equal(t, "bitrise.yml:4:7: msg", yamlIssue(configName, yamlNodeAt(validator.root, "workflows", "primary", "steps", 0), "msg").String())
+	require.Nil(t, yamlNodeAt(validator.root, "workflows", "primary", "steps", 1))
+	require.Nil(t, yamlNodeAt(validator.root, "workflows", "secondary"))
+	require.Equal(t, "bitrise.yml: msg", yamlIssue(configName, nil, "msg").String())
+}
+
+func TestDeepValidateEnvOrder(t *testing.T) {
+	resolveStep := func(stepIDData models.StepIDData) (stepmanModels.StepInfoModel, bool, error) {
+		inputs := []envmanModels.EnvironmentItemModel{{"content": ""}}
+		return stepmanModels.StepInfoModel{Step: stepmanModels.StepModel{Inputs: inputs}}, true, nil
+	}
+	deepValidate := func(configYML string, extraEnvKeys []string) []string {
+		config, warnings, err := bitrise.ConfigModelFromYAMLBytes([]byte(configYML))
+		require.NoError(t, err)
+		require.Equal(t, 0, len(warnings))
+
+		errs, warns, err := deepValidateConfig("bitrise.yml", []byte(configYML), config, []envmanModels.EnvironmentItemModel{}, extraEnvKeys, resolveStep)
+		require.NoError(t, err)
+		require.Equal(t, []string{}, validationIssueStrings(errs))
+		return validationIssueStrings(warns)
+	}
+
+	t.Log("the envs of the validating process are not defined")
+	{
+		require.NoError(t, os.Setenv("DEEP_VALIDATE_PROCESS_ENV", "value"))
+		defer func() {
+			require.NoError(t, os.Unsetenv("DEEP_VALIDATE_PROCESS_ENV"))
+		}()
+
+		configYML := `format_version: 11
+default_step_lib_source: https://github.com/bitrise-io/bitrise-steplib.git
+workflows:
+  primary:
+    steps:
+    - script@1:
+        inputs:
+        - content: $DEEP_VALIDATE_PROCESS_ENV
+`
+		require.Equal(t, []string{
+			"bitrise.yml:8:11: workflow (primary) step (0) (script@1): input (content) references undefined env ($DEEP_VALIDATE_PROCESS_ENV)",
+		}, deepValidate(configYML, nil))
+		require.Equal(t, []string{}, deepValidate(configYML, []string{"DEEP_VALIDATE_PROCESS_ENV"}))
+	}
+
+	t.Log("the triggered workflow's envs are defined for its before_run workflows")
+	{
+		configYML := `format_version: 11
+default_step_lib_source: https://github.com/bitrise-io/bitrise-steplib.git
+workflows:
+  primary:
+    before_run:
+    - _setup
+    envs:
+    - SCHEME: App
+  _setup:
+    steps:
+    - script@1:
+        inputs:
+        - content: $SCHEME
+`
+		require.Equal(t, []string{}, deepValidate(configYML, nil))
+	}
+
+	t.Log("the envs of an isolated workflow are defined only while it runs, except the exported ones")
+	{
+		configYML := `format_version: 11
+default_step_lib_source: https://github.com/bitrise-io/bitrise-steplib.git
+workflows:
+  primary:
+    before_run:
+    - _setup
+    after_run:
+    - _deploy
+    isolate_envs: true
+    export_envs:
+    - IPA_PATH
+    envs:
+    - SCHEME: App
+    - IPA_PATH: ./App.ipa
+    steps:
+    - script@1:
+        inputs:
+        - content: $SCHEME
+  _setup:
+    steps:
+    - script@1:
+        inputs:
+        - content: $SCHEME
+  _deploy:
+    steps:
+    - script@1:
+        inputs:
+        - content: $IPA_PATH/$SCHEME
+`
+		require.Equal(t, []string{
+			"bitrise.yml:23:11: workflow (_setup) step (0) (script@1): input (content) references undefined env ($SCHEME)",
+			"bitrise.yml:28:11: workflow (_deploy) step (0) (script@1): input (content) references undefined env ($SCHEME)",
+		}, deepValidate(configYML, nil))
+	}
+}
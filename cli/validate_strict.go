@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/bitrise/models"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// unknownKeyWalker walks the config along the config schema, and collects the keys which are not defined by the schema:
+// the yaml decoder silently ignores these keys, e.g. a misspelled step property.
+type unknownKeyWalker struct {
+	configName string
+	schema     models.JSONSchemaModel
+	reported   map[*yamlv3.Node]bool
+	issues     []ValidationIssueModel
+}
+
+func (w *unknownKeyWalker) walk(node *yamlv3.Node, schema models.JSONSchemaModel) {
+	if node == nil {
+		return
+	}
+	schema = schema.ResolveRef(w.schema)
+
+	switch node.Kind {
+	case yamlv3.DocumentNode:
+		for _, child := range node.Content {
+			w.walk(child, schema)
+		}
+	case yamlv3.AliasNode:
+		w.walk(node.Alias, schema)
+	case yamlv3.SequenceNode:
+		if schema.Items == nil {
+			return
+		}
+		for _, item := range node.Content {
+			w.walk(item, *schema.Items)
+		}
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+
+			if key.Tag == "!!merge" {
+				// the keys of the merged mappings belong to this mapping
+				if value.Kind == yamlv3.SequenceNode {
+					for _, merged := range value.Content {
+						w.walk(merged, schema)
+					}
+				} else {
+					w.walk(value, schema)
+				}
+				continue
+			}
+
+			if property, ok := schema.Properties[key.Value]; ok {
+				w.walk(value, *property)
+				continue
+			}
+
+			switch additionalProperties := schema.AdditionalProperties.(type) {
+			case *models.JSONSchemaModel:
+				w.walk(value, *additionalProperties)
+			case bool:
+				if !additionalProperties && !w.reported[key] {
+					w.reported[key] = true
+					w.issues = append(w.issues, yamlIssue(w.configName, key, fmt.Sprintf("unknown key (%s)", key.Value)))
+				}
+			}
+		}
+	}
+}
+
+// unknownKeyIssues returns the keys of the config, which are not defined by the config schema.
+func unknownKeyIssues(configName string, root *yamlv3.Node, schema models.JSONSchemaModel) []ValidationIssueModel {
+	walker := unknownKeyWalker{
+		configName: configName,
+		schema:     schema,
+		reported:   map[*yamlv3.Node]bool{},
+		issues:     []ValidationIssueModel{},
+	}
+	walker.walk(root, schema)
+	return walker.issues
+}
+
+func strictValidateBitriseYML(configValidation *ValidationItemModel, bitriseConfigPath, bitriseConfigBase64Data string) error {
+	configName, configBytes, err := readBitriseConfigBytes(bitriseConfigPath, bitriseConfigBase64Data)
+	if err != nil {
+		return fmt.Errorf("Failed to read config, err: %s", err)
+	}
+
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(configBytes, &root); err != nil {
+		// the syntax error is reported by the config validation
+		return nil
+	}
+
+	issues := unknownKeyIssues(configName, &root, models.BitriseDataModelJSONSchema())
+	if len(issues) == 0 {
+		return nil
+	}
+
+	errs := validationIssueStrings(issues)
+	if configValidation.Error != "" {
+		errs = append([]string{configValidation.Error}, errs...)
+	}
+	configValidation.IsValid = false
+	configValidation.Error = strings.Join(errs, "\n")
+	configValidation.ErrorDetails = append(configValidation.ErrorDetails, issues...)
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/stretchr/testify/require"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+func TestUnknownKeyIssues(t *testing.T) {
+	configYML := `format_version: 11
+projet_type: ios
+meta:
+  anything: goes
+app:
+  envs:
+  - KEY: value
+    opts:
+      is_expnd: true
+step_defaults: &defaults
+  is_always_run: true
+  is_skipable: true
+trigger_map:
+- push_branch: master
+  workflow: primary
+  changed_files:
+    includes: ["src/**"]
+workflows:
+  primary:
+    titel: Primary
+    steps:
+    - script@1:
+        <<: *defaults
+        inputs:
+        - content: echo hi
+    - script@1:
+        <<: *defaults
+`
+
+	var root yamlv3.Node
+	require.NoError(t, yamlv3.Unmarshal([]byte(configYML), &root))
+
+	issues := unknownKeyIssues("bitrise.yml", &root, models.BitriseDataModelJSONSchema())
+	require.Equal(t, []string{
+		"bitrise.yml:2:1: unknown key (projet_type)",
+		"bitrise.yml:9:7: unknown key (is_expnd)",
+		"bitrise.yml:10:1: unknown key (step_defaults)",
+		"bitrise.yml:17:5: unknown key (includes)",
+		"bitrise.yml:20:5: unknown key (titel)",
+		"bitrise.yml:12:3: unknown key (is_skipable)",
+	}, validationIssueStrings(issues))
+}
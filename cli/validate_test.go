@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigValidationIssues(t *testing.T) {
+	t.Log("validation error and warnings are located at the offending node")
+	{
+		configYML := `format_version: "11"
+trigger_map:
+- push_branch: master
+  workflow: _setup
+- tag: "*"
+  workflow: deploy
+workflows:
+  _setup:
+    steps:
+    - script@1:
+        inputs:
+        - content: a
+        - content: b
+`
+
+		errs, warns := configValidationIssues("bitrise.yml", []byte(configYML))
+		require.Equal(t, []string{
+			"bitrise.yml:6:3: workflow (deploy) defined in trigger item (tag: * -> workflow: deploy), but does not exist",
+		}, validationIssueStrings(errs))
+		require.Equal(t, []string{
+			"bitrise.yml:4:3: workflow (_setup) defined in trigger item (push_branch: master -> workflow: _setup), but utility workflows can't be triggered directly",
+		}, validationIssueStrings(warns))
+	}
+
+	t.Log("the deepest node is used, if the path points into an alias")
+	{
+		configYML := `format_version: "11"
+workflows:
+  _base:
+    steps: &steps
+    - script@1:
+        inputs:
+        - content: a
+        - content: b
+  primary:
+    steps: *steps
+`
+
+		errs, warns := configValidationIssues("bitrise.yml", []byte(configYML))
+		require.Equal(t, 0, len(errs))
+		require.Equal(t, []string{
+			"bitrise.yml:8:11: invalid step: duplicated input found: (content)",
+			"bitrise.yml:10:12: invalid step: duplicated input found: (content)",
+		}, validationIssueStrings(warns))
+	}
+
+	t.Log("parser errors are located at the reported line")
+	{
+		configYML := `format_version: "11"
+workflows:
+  primary:
+    steps: abc
+    before_run: x
+`
+
+		errs, warns := configValidationIssues("bitrise.yml", []byte(configYML))
+		require.Equal(t, []ValidationIssueModel{
+			ValidationIssueModel{Message: "cannot unmarshal !!str `abc` into []models.StepListItemModel", File: "bitrise.yml", Line: 4, Column: 5},
+			ValidationIssueModel{Message: "cannot unmarshal !!str `x` into []string", File: "bitrise.yml", Line: 5, Column: 5},
+		}, errs)
+		require.Equal(t, 0, len(warns))
+	}
+
+	t.Log("errors without position are located at the file")
+	{
+		errs, _ := configValidationIssues("bitrise.yml", []byte(`title: missing format version`))
+		require.Equal(t, []string{"bitrise.yml:1:1: missing format_version"}, validationIssueStrings(errs))
+	}
+}
+
+func TestValidationIssueString(t *testing.T) {
+	require.Equal(t, "bitrise.yml:42:7: msg", ValidationIssueModel{Message: "msg", File: "bitrise.yml", Line: 42, Column: 7}.String())
+	require.Equal(t, "bitrise.yml:42: msg", ValidationIssueModel{Message: "msg", File: "bitrise.yml", Line: 42}.String())
+	require.Equal(t, "bitrise.yml: msg", ValidationIssueModel{Message: "msg", File: "bitrise.yml"}.String())
+	require.Equal(t, "msg", ValidationIssueModel{Message: "msg"}.String())
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// yamlMappingPair returns the key and the value node of the given key in a mapping node.
+func yamlMappingPair(node *yamlv3.Node, key string) (*yamlv3.Node, *yamlv3.Node) {
+	if node == nil || node.Kind != yamlv3.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// yamlMappingValue returns the value node of the given key in a mapping node.
+func yamlMappingValue(node *yamlv3.Node, key string) *yamlv3.Node {
+	_, value := yamlMappingPair(node, key)
+	return value
+}
+
+// yamlNodeAt walks the yaml document along the given path,
+// string path elements are mapping keys, int path elements are sequence indexes.
+func yamlNodeAt(root *yamlv3.Node, path ...interface{}) *yamlv3.Node {
+	node := root
+	if node != nil && node.Kind == yamlv3.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	for _, element := range path {
+		if node == nil {
+			return nil
+		}
+
+		switch e := element.(type) {
+		case string:
+			node = yamlMappingValue(node, e)
+		case int:
+			if node.Kind != yamlv3.SequenceNode || e < 0 || e >= len(node.Content) {
+				return nil
+			}
+			node = node.Content[e]
+		default:
+			return nil
+		}
+	}
+	return node
+}
+
+// yamlConfigPathNode returns the node of the config path (see models.ConfigPathError):
+// the key node if the path ends with a mapping key, otherwise the value node.
+// If the path can not be walked to its end (e.g. the value is an alias), the deepest node found is returned.
+func yamlConfigPathNode(root *yamlv3.Node, path []interface{}) *yamlv3.Node {
+	node := root
+	if node != nil && node.Kind == yamlv3.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node == nil {
+		return nil
+	}
+
+	for i, element := range path {
+		var next *yamlv3.Node
+		switch e := element.(type) {
+		case string:
+			key, value := yamlMappingPair(node, e)
+			if key != nil && i == len(path)-1 {
+				return key
+			}
+			next = value
+		case int:
+			if node.Kind == yamlv3.SequenceNode && e >= 0 && e < len(node.Content) {
+				next = node.Content[e]
+			}
+		}
+
+		if next == nil {
+			return node
+		}
+		node = next
+	}
+	return node
+}
+
+// yamlFirstNodeOfLine returns the first node starting in the given line.
+func yamlFirstNodeOfLine(node *yamlv3.Node, line int) *yamlv3.Node {
+	if node == nil {
+		return nil
+	}
+
+	var first *yamlv3.Node
+	if node.Kind != yamlv3.DocumentNode && node.Line == line {
+		first = node
+	}
+	for _, child := range node.Content {
+		if found := yamlFirstNodeOfLine(child, line); found != nil && (first == nil || found.Column < first.Column) {
+			first = found
+		}
+	}
+	return first
+}
+
+// yamlIssue returns the validation issue located at the node, or at the file if the node is unknown.
+func yamlIssue(file string, node *yamlv3.Node, message string) ValidationIssueModel {
+	issue := ValidationIssueModel{Message: message, File: file}
+	if node != nil {
+		issue.Line = node.Line
+		issue.Column = node.Column
+	}
+	return issue
+}
@@ -16,6 +16,8 @@ type ConfigModel struct {
 	SetupVersion           string               `json:"setup_version"`
 	LastCLIUpdateCheck     time.Time            `json:"last_cli_update_check"`
 	LastPluginUpdateChecks map[string]time.Time `json:"last_plugin_update_checks"`
+	// DownloadMirror is the base URL or local directory, which mirrors the toolkit and core tool downloads
+	DownloadMirror string `json:"download_mirror,omitempty"`
 }
 
 // ---------------------------
@@ -50,6 +52,19 @@ const (
 	// IsSecretFilteringKey ...
 	IsSecretFilteringKey = "BITRISE_SECRET_FILTERING"
 
+	// --- Git info of the build
+
+	// GitBranchEnvKey ...
+	GitBranchEnvKey = "BITRISE_GIT_BRANCH"
+	// GitBranchDestEnvKey is the target branch of the pull request
+	GitBranchDestEnvKey = "BITRISEIO_GIT_BRANCH_DEST"
+	// GitTagEnvKey ...
+	GitTagEnvKey = "BITRISE_GIT_TAG"
+	// GitCommitEnvKey ...
+	GitCommitEnvKey = "BITRISE_GIT_COMMIT"
+	// GitMessageEnvKey ...
+	GitMessageEnvKey = "BITRISE_GIT_MESSAGE"
+
 	// --- Debug Options
 
 	// DebugUseSystemTools ...
@@ -194,3 +209,13 @@ func SaveSetupSuccessForVersion(ver string) error {
 
 	return saveBitriseConfig(config)
 }
+
+// DownloadMirror returns the configured mirror of the toolkit and core tool downloads,
+// empty if downloads should be fetched from their original location.
+func DownloadMirror() string {
+	config, err := loadBitriseConfig()
+	if err != nil {
+		return ""
+	}
+	return config.DownloadMirror
+}
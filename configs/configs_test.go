@@ -28,3 +28,27 @@ func TestSetupForVersionChecks(t *testing.T) {
 
 	require.Equal(t, false, CheckIsSetupWasDoneForVersion("0.9.8"))
 }
+
+func TestDownloadMirror(t *testing.T) {
+	fakeHomePth, err := pathutil.NormalizedOSTempDirPath("_FAKE_HOME")
+	require.NoError(t, err)
+	originalHome := os.Getenv("HOME")
+
+	defer func() {
+		require.NoError(t, os.Setenv("HOME", originalHome))
+		require.NoError(t, os.RemoveAll(fakeHomePth))
+	}()
+
+	require.NoError(t, os.Setenv("HOME", fakeHomePth))
+
+	require.Equal(t, "", DownloadMirror())
+
+	require.NoError(t, SaveSetupSuccessForVersion("1.0.0"))
+	config, err := loadBitriseConfig()
+	require.NoError(t, err)
+	config.DownloadMirror = "https://mirror.example.com/bitrise"
+	require.NoError(t, saveBitriseConfig(config))
+
+	require.Equal(t, "https://mirror.example.com/bitrise", DownloadMirror())
+	require.Equal(t, true, CheckIsSetupWasDoneForVersion("1.0.0"))
+}
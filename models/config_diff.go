@@ -0,0 +1,535 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/sliceutil"
+)
+
+// ConfigChangeType ...
+type ConfigChangeType string
+
+const (
+	// ConfigChangeTypeConfigChanged : a top level property (e.g. format_version, pipelines) changed
+	ConfigChangeTypeConfigChanged ConfigChangeType = "config_changed"
+	// ConfigChangeTypeEnvAdded ...
+	ConfigChangeTypeEnvAdded ConfigChangeType = "env_added"
+	// ConfigChangeTypeEnvRemoved ...
+	ConfigChangeTypeEnvRemoved ConfigChangeType = "env_removed"
+	// ConfigChangeTypeEnvChanged ...
+	ConfigChangeTypeEnvChanged ConfigChangeType = "env_changed"
+	// ConfigChangeTypeWorkflowAdded ...
+	ConfigChangeTypeWorkflowAdded ConfigChangeType = "workflow_added"
+	// ConfigChangeTypeWorkflowRemoved ...
+	ConfigChangeTypeWorkflowRemoved ConfigChangeType = "workflow_removed"
+	// ConfigChangeTypeWorkflowChanged : a workflow property (e.g. before_run, container) changed
+	ConfigChangeTypeWorkflowChanged ConfigChangeType = "workflow_changed"
+	// ConfigChangeTypeStepAdded ...
+	ConfigChangeTypeStepAdded ConfigChangeType = "step_added"
+	// ConfigChangeTypeStepRemoved ...
+	ConfigChangeTypeStepRemoved ConfigChangeType = "step_removed"
+	// ConfigChangeTypeStepMoved : the step's position changed relative to the other steps of the workflow
+	ConfigChangeTypeStepMoved ConfigChangeType = "step_moved"
+	// ConfigChangeTypeStepVersionChanged ...
+	ConfigChangeTypeStepVersionChanged ConfigChangeType = "step_version_changed"
+	// ConfigChangeTypeStepChanged : a step property (e.g. run_if, is_always_run) changed
+	ConfigChangeTypeStepChanged ConfigChangeType = "step_changed"
+	// ConfigChangeTypeInputAdded ...
+	ConfigChangeTypeInputAdded ConfigChangeType = "input_added"
+	// ConfigChangeTypeInputRemoved ...
+	ConfigChangeTypeInputRemoved ConfigChangeType = "input_removed"
+	// ConfigChangeTypeInputChanged ...
+	ConfigChangeTypeInputChanged ConfigChangeType = "input_changed"
+	// ConfigChangeTypeTriggerAdded ...
+	ConfigChangeTypeTriggerAdded ConfigChangeType = "trigger_added"
+	// ConfigChangeTypeTriggerRemoved ...
+	ConfigChangeTypeTriggerRemoved ConfigChangeType = "trigger_removed"
+	// ConfigChangeTypeTriggerRouteChanged : the git event triggers a different workflow or pipeline
+	ConfigChangeTypeTriggerRouteChanged ConfigChangeType = "trigger_route_changed"
+)
+
+// ConfigChangeModel is a semantic change between two configs.
+type ConfigChangeModel struct {
+	Type     ConfigChangeType `json:"type" yaml:"type"`
+	Workflow string           `json:"workflow,omitempty" yaml:"workflow,omitempty"`
+	// Step is the step's ID as it is defined in the workflow, e.g. script@1
+	Step string `json:"step,omitempty" yaml:"step,omitempty"`
+	// StepPosition is the step's position (0 based) in the new workflow, or in the old one if the step was removed
+	StepPosition *int `json:"step_position,omitempty" yaml:"step_position,omitempty"`
+	// Key is the changed property, input or env key, or the git event of the trigger route change
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+	Old string `json:"old,omitempty" yaml:"old,omitempty"`
+	New string `json:"new,omitempty" yaml:"new,omitempty"`
+}
+
+func (change ConfigChangeModel) String() string {
+	subject := "app"
+	if change.Workflow != "" {
+		subject = fmt.Sprintf("workflow (%s)", change.Workflow)
+	}
+	if change.StepPosition != nil {
+		subject += fmt.Sprintf(" step #%d (%s)", *change.StepPosition, change.Step)
+	}
+	notSet := func(value string) string {
+		if value == "" {
+			return "(not set)"
+		}
+		return value
+	}
+
+	switch change.Type {
+	case ConfigChangeTypeConfigChanged:
+		return fmt.Sprintf("%s: %s -> %s", change.Key, notSet(change.Old), notSet(change.New))
+	case ConfigChangeTypeEnvAdded:
+		return fmt.Sprintf("%s env (%s) added: %q", subject, change.Key, change.New)
+	case ConfigChangeTypeEnvRemoved:
+		return fmt.Sprintf("%s env (%s) removed", subject, change.Key)
+	case ConfigChangeTypeEnvChanged:
+		return fmt.Sprintf("%s env (%s) changed: %q -> %q", subject, change.Key, change.Old, change.New)
+	case ConfigChangeTypeWorkflowAdded:
+		return fmt.Sprintf("%s added", subject)
+	case ConfigChangeTypeWorkflowRemoved:
+		return fmt.Sprintf("%s removed", subject)
+	case ConfigChangeTypeWorkflowChanged, ConfigChangeTypeStepChanged:
+		return fmt.Sprintf("%s %s: %s -> %s", subject, change.Key, notSet(change.Old), notSet(change.New))
+	case ConfigChangeTypeStepAdded:
+		return fmt.Sprintf("%s added", subject)
+	case ConfigChangeTypeStepRemoved:
+		return fmt.Sprintf("%s removed", subject)
+	case ConfigChangeTypeStepMoved:
+		return fmt.Sprintf("%s moved from #%s", subject, change.Old)
+	case ConfigChangeTypeStepVersionChanged:
+		return fmt.Sprintf("%s version: %s -> %s", subject, change.Old, change.New)
+	case ConfigChangeTypeInputAdded:
+		return fmt.Sprintf("%s input (%s) added: %q", subject, change.Key, change.New)
+	case ConfigChangeTypeInputRemoved:
+		return fmt.Sprintf("%s input (%s) removed", subject, change.Key)
+	case ConfigChangeTypeInputChanged:
+		return fmt.Sprintf("%s input (%s) changed: %q -> %q", subject, change.Key, change.Old, change.New)
+	case ConfigChangeTypeTriggerAdded:
+		return fmt.Sprintf("trigger item (%s) added", change.New)
+	case ConfigChangeTypeTriggerRemoved:
+		return fmt.Sprintf("trigger item (%s) removed", change.Old)
+	case ConfigChangeTypeTriggerRouteChanged:
+		return fmt.Sprintf("%s now triggers %s instead of %s", change.Key, change.New, change.Old)
+	}
+	return string(change.Type)
+}
+
+// propertyValues returns the model's JSON properties, except for the ignored ones:
+// string values as they are, other values JSON encoded.
+func propertyValues(model interface{}, ignoredKeys ...string) (map[string]string, error) {
+	modelBytes, err := json.Marshal(model)
+	if err != nil {
+		return nil, err
+	}
+	var properties map[string]json.RawMessage
+	if err := json.Unmarshal(modelBytes, &properties); err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for key, value := range properties {
+		var str string
+		if err := json.Unmarshal(value, &str); err == nil {
+			values[key] = str
+		} else {
+			values[key] = string(value)
+		}
+	}
+	for _, key := range ignoredKeys {
+		delete(values, key)
+	}
+	return values, nil
+}
+
+// diffProperties returns the changes of the models' properties, in key order.
+func diffProperties(changeType ConfigChangeType, workflowID string, step *diffStep, oldModel, newModel interface{}, ignoredKeys ...string) ([]ConfigChangeModel, error) {
+	oldValues, err := propertyValues(oldModel, ignoredKeys...)
+	if err != nil {
+		return nil, err
+	}
+	newValues, err := propertyValues(newModel, ignoredKeys...)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for key := range oldValues {
+		keys = append(keys, key)
+	}
+	for key := range newValues {
+		if _, ok := oldValues[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := []ConfigChangeModel{}
+	for _, key := range keys {
+		oldValue, newValue := oldValues[key], newValues[key]
+		if oldValue == newValue {
+			continue
+		}
+		changes = append(changes, step.change(ConfigChangeModel{Type: changeType, Workflow: workflowID, Key: key, Old: oldValue, New: newValue}))
+	}
+	return changes, nil
+}
+
+// envValues returns the env keys in order of definition, and their values: the last definition of a key wins.
+func envValues(envs []envmanModels.EnvironmentItemModel) ([]string, map[string]string, error) {
+	keys := []string{}
+	values := map[string]string{}
+	for _, env := range envs {
+		key, value, err := env.GetKeyValuePair()
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = value
+	}
+	return keys, values, nil
+}
+
+// diffEnvs returns the added, removed and changed envs (or step inputs).
+func diffEnvs(addedType, removedType, changedType ConfigChangeType, workflowID string, step *diffStep, oldEnvs, newEnvs []envmanModels.EnvironmentItemModel) ([]ConfigChangeModel, error) {
+	oldKeys, oldValues, err := envValues(oldEnvs)
+	if err != nil {
+		return nil, err
+	}
+	newKeys, newValues, err := envValues(newEnvs)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []ConfigChangeModel{}
+	for _, key := range oldKeys {
+		if _, ok := newValues[key]; !ok {
+			changes = append(changes, step.change(ConfigChangeModel{Type: removedType, Workflow: workflowID, Key: key, Old: oldValues[key]}))
+		}
+	}
+	for _, key := range newKeys {
+		oldValue, ok := oldValues[key]
+		if !ok {
+			changes = append(changes, step.change(ConfigChangeModel{Type: addedType, Workflow: workflowID, Key: key, New: newValues[key]}))
+		} else if oldValue != newValues[key] {
+			changes = append(changes, step.change(ConfigChangeModel{Type: changedType, Workflow: workflowID, Key: key, Old: oldValue, New: newValues[key]}))
+		}
+	}
+	return changes, nil
+}
+
+// diffStep is a step of a workflow, identified by its step ID without the version
+// and by its occurrence among the steps with the same ID.
+type diffStep struct {
+	position    int
+	compositeID string
+	identity    string
+	version     string
+	step        StepModel
+}
+
+// change sets the step of the change, if the change belongs to a step.
+func (step *diffStep) change(change ConfigChangeModel) ConfigChangeModel {
+	if step != nil {
+		position := step.position
+		change.Step = step.compositeID
+		change.StepPosition = &position
+	}
+	return change
+}
+
+func workflowDiffSteps(workflow WorkflowModel, defaultStepLibSource string) ([]diffStep, error) {
+	steps := []diffStep{}
+	occurrences := map[string]int{}
+	for idx, stepListItem := range workflow.Steps {
+		compositeID, step, err := GetStepIDStepDataPair(stepListItem)
+		if err != nil {
+			return nil, err
+		}
+
+		identity, version := getStepID(compositeID), getStepVersion(compositeID)
+		if stepIDData, err := CreateStepIDDataFromString(compositeID, defaultStepLibSource); err == nil {
+			identity, version = stepIDData.SteplibSource+"::"+stepIDData.IDorURI, stepIDData.Version
+		}
+		occurrences[identity]++
+
+		steps = append(steps, diffStep{
+			position:    idx,
+			compositeID: compositeID,
+			identity:    fmt.Sprintf("%s#%d", identity, occurrences[identity]),
+			version:     version,
+			step:        step,
+		})
+	}
+	return steps, nil
+}
+
+// longestCommonSubsequence returns the identities kept in the same relative order.
+func longestCommonSubsequence(a, b []string) map[string]bool {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	common := map[string]bool{}
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			common[a[i]] = true
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return common
+}
+
+func diffWorkflowSteps(workflowID string, oldWorkflow, newWorkflow WorkflowModel, oldDefaultStepLibSource, newDefaultStepLibSource string) ([]ConfigChangeModel, error) {
+	oldSteps, err := workflowDiffSteps(oldWorkflow, oldDefaultStepLibSource)
+	if err != nil {
+		return nil, err
+	}
+	newSteps, err := workflowDiffSteps(newWorkflow, newDefaultStepLibSource)
+	if err != nil {
+		return nil, err
+	}
+
+	oldStepsByIdentity := map[string]diffStep{}
+	for _, step := range oldSteps {
+		oldStepsByIdentity[step.identity] = step
+	}
+	newStepsByIdentity := map[string]diffStep{}
+	for _, step := range newSteps {
+		newStepsByIdentity[step.identity] = step
+	}
+
+	changes := []ConfigChangeModel{}
+	oldOrder := []string{}
+	for _, oldStep := range oldSteps {
+		if _, ok := newStepsByIdentity[oldStep.identity]; !ok {
+			changes = append(changes, oldStep.change(ConfigChangeModel{Type: ConfigChangeTypeStepRemoved, Workflow: workflowID}))
+			continue
+		}
+		oldOrder = append(oldOrder, oldStep.identity)
+	}
+	newOrder := []string{}
+	for _, newStep := range newSteps {
+		if _, ok := oldStepsByIdentity[newStep.identity]; ok {
+			newOrder = append(newOrder, newStep.identity)
+		}
+	}
+	inOrder := longestCommonSubsequence(oldOrder, newOrder)
+
+	for _, newStep := range newSteps {
+		oldStep, ok := oldStepsByIdentity[newStep.identity]
+		if !ok {
+			changes = append(changes, newStep.change(ConfigChangeModel{Type: ConfigChangeTypeStepAdded, Workflow: workflowID}))
+			continue
+		}
+
+		if !inOrder[newStep.identity] {
+			changes = append(changes, newStep.change(ConfigChangeModel{Type: ConfigChangeTypeStepMoved, Workflow: workflowID, Old: fmt.Sprintf("%d", oldStep.position), New: fmt.Sprintf("%d", newStep.position)}))
+		}
+		if oldStep.version != newStep.version {
+			changes = append(changes, newStep.change(ConfigChangeModel{Type: ConfigChangeTypeStepVersionChanged, Workflow: workflowID, Old: oldStep.version, New: newStep.version}))
+		}
+
+		propertyChanges, err := diffProperties(ConfigChangeTypeStepChanged, workflowID, &newStep, oldStep.step, newStep.step, "inputs")
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, propertyChanges...)
+
+		inputChanges, err := diffEnvs(ConfigChangeTypeInputAdded, ConfigChangeTypeInputRemoved, ConfigChangeTypeInputChanged, workflowID, &newStep, oldStep.step.Inputs, newStep.step.Inputs)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, inputChanges...)
+	}
+	return changes, nil
+}
+
+// triggerTarget returns the workflow or pipeline the first matching trigger map item triggers.
+// The items with changed files filter are skipped, matching them requires the git repository of the build.
+func (triggerMap TriggerMapModel) triggerTarget(params TriggerParamsModel) string {
+	for _, item := range triggerMap {
+		if item.ChangedFiles != nil {
+			continue
+		}
+		if match, err := item.MatchWithTriggerParams(params); err != nil || !match {
+			continue
+		}
+		if item.PipelineID != "" {
+			return fmt.Sprintf("pipeline (%s)", item.PipelineID)
+		}
+		return fmt.Sprintf("workflow (%s)", item.WorkflowID)
+	}
+	return "nothing"
+}
+
+// triggerProbes returns the git events named by the trigger map items' patterns.
+func (triggerMap TriggerMapModel) triggerProbes() []TriggerMapItemModel {
+	isLiteral := func(pattern string) bool {
+		return !strings.HasPrefix(pattern, TriggerRegexPatternPrefix)
+	}
+
+	probes := []TriggerMapItemModel{}
+	for _, item := range triggerMap {
+		items := []TriggerMapItemModel{item}
+		if item.Pattern != "" {
+			items = migrateDeprecatedTriggerItem(item)
+		}
+
+		for _, item := range items {
+			switch {
+			case item.PushBranch != "":
+				if isLiteral(item.PushBranch) {
+					probes = append(probes, TriggerMapItemModel{PushBranch: item.PushBranch})
+				}
+			case item.PullRequestSourceBranch != "" || item.PullRequestTargetBranch != "":
+				if isLiteral(item.PullRequestSourceBranch) && isLiteral(item.PullRequestTargetBranch) {
+					probes = append(probes, TriggerMapItemModel{PullRequestSourceBranch: item.PullRequestSourceBranch, PullRequestTargetBranch: item.PullRequestTargetBranch})
+				}
+			case item.Tag != "":
+				if isLiteral(item.Tag) {
+					probes = append(probes, TriggerMapItemModel{Tag: item.Tag})
+				}
+			}
+		}
+	}
+	return probes
+}
+
+// diffTriggerMaps returns the added and removed trigger map items, and the git events, which trigger a different target.
+// The git events are the branches and tags named by the trigger map items, without commit message, labels and changed files.
+func diffTriggerMaps(oldTriggerMap, newTriggerMap TriggerMapModel) []ConfigChangeModel {
+	changes := []ConfigChangeModel{}
+
+	newItems := map[string]int{}
+	for _, item := range newTriggerMap {
+		newItems[item.String(true)]++
+	}
+	oldItems := map[string]int{}
+	for _, item := range oldTriggerMap {
+		key := item.String(true)
+		oldItems[key]++
+		if oldItems[key] > newItems[key] {
+			changes = append(changes, ConfigChangeModel{Type: ConfigChangeTypeTriggerRemoved, Old: key})
+		}
+	}
+	addedItems := map[string]int{}
+	for _, item := range newTriggerMap {
+		key := item.String(true)
+		addedItems[key]++
+		if addedItems[key] > oldItems[key] {
+			changes = append(changes, ConfigChangeModel{Type: ConfigChangeTypeTriggerAdded, New: key})
+		}
+	}
+
+	probed := map[string]bool{}
+	for _, probe := range append(oldTriggerMap.triggerProbes(), newTriggerMap.triggerProbes()...) {
+		event := probe.String(false)
+		if probed[event] {
+			continue
+		}
+		probed[event] = true
+
+		params := TriggerParamsModel{
+			PushBranch:     probe.PushBranch,
+			PRSourceBranch: probe.PullRequestSourceBranch,
+			PRTargetBranch: probe.PullRequestTargetBranch,
+			Tag:            probe.Tag,
+		}
+		oldTarget, newTarget := oldTriggerMap.triggerTarget(params), newTriggerMap.triggerTarget(params)
+		if oldTarget != newTarget {
+			changes = append(changes, ConfigChangeModel{Type: ConfigChangeTypeTriggerRouteChanged, Key: event, Old: oldTarget, New: newTarget})
+		}
+	}
+	return changes
+}
+
+func sortedWorkflowIDs(workflows ...map[string]WorkflowModel) []string {
+	IDs := []string{}
+	for _, workflowMap := range workflows {
+		for ID := range workflowMap {
+			if !sliceutil.IsStringInSlice(ID, IDs) {
+				IDs = append(IDs, ID)
+			}
+		}
+	}
+	sort.Strings(IDs)
+	return IDs
+}
+
+// DiffConfigs returns the semantic changes between the configs: the changed top level properties, app envs,
+// workflows, steps, step inputs and trigger map items, and the git events which trigger a different workflow or pipeline.
+func DiffConfigs(oldConfig, newConfig BitriseDataModel) ([]ConfigChangeModel, error) {
+	changes, err := diffProperties(ConfigChangeTypeConfigChanged, "", nil, oldConfig, newConfig, "app", "trigger_map", "workflows")
+	if err != nil {
+		return nil, err
+	}
+
+	envChanges, err := diffEnvs(ConfigChangeTypeEnvAdded, ConfigChangeTypeEnvRemoved, ConfigChangeTypeEnvChanged, "", nil, oldConfig.App.Environments, newConfig.App.Environments)
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, envChanges...)
+
+	for _, workflowID := range sortedWorkflowIDs(oldConfig.Workflows, newConfig.Workflows) {
+		oldWorkflow, isOld := oldConfig.Workflows[workflowID]
+		newWorkflow, isNew := newConfig.Workflows[workflowID]
+		if !isNew {
+			changes = append(changes, ConfigChangeModel{Type: ConfigChangeTypeWorkflowRemoved, Workflow: workflowID})
+			continue
+		}
+		if !isOld {
+			changes = append(changes, ConfigChangeModel{Type: ConfigChangeTypeWorkflowAdded, Workflow: workflowID})
+			continue
+		}
+		if reflect.DeepEqual(oldWorkflow, newWorkflow) && oldConfig.DefaultStepLibSource == newConfig.DefaultStepLibSource {
+			continue
+		}
+
+		propertyChanges, err := diffProperties(ConfigChangeTypeWorkflowChanged, workflowID, nil, oldWorkflow, newWorkflow, "envs", "steps")
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, propertyChanges...)
+
+		envChanges, err := diffEnvs(ConfigChangeTypeEnvAdded, ConfigChangeTypeEnvRemoved, ConfigChangeTypeEnvChanged, workflowID, nil, oldWorkflow.Environments, newWorkflow.Environments)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, envChanges...)
+
+		stepChanges, err := diffWorkflowSteps(workflowID, oldWorkflow, newWorkflow, oldConfig.DefaultStepLibSource, newConfig.DefaultStepLibSource)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, stepChanges...)
+	}
+
+	return append(changes, diffTriggerMaps(oldConfig.TriggerMap, newConfig.TriggerMap)...), nil
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"testing"
+
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/pointers"
+	stepmanModels "github.com/bitrise-io/stepman/models"
+	"github.com/stretchr/testify/require"
+)
+
+func testDiffConfig() BitriseDataModel {
+	return BitriseDataModel{
+		FormatVersion:        "11",
+		DefaultStepLibSource: "https://github.com/bitrise-io/bitrise-steplib.git",
+		App: AppModel{
+			Environments: []envmanModels.EnvironmentItemModel{{"PROJECT": "app.xcodeproj"}, {"SCHEME": "App"}},
+		},
+		TriggerMap: TriggerMapModel{
+			TriggerMapItemModel{PushBranch: "master", WorkflowID: "deploy"},
+			TriggerMapItemModel{PushBranch: "*", WorkflowID: "primary"},
+		},
+		Workflows: map[string]WorkflowModel{
+			"primary": WorkflowModel{
+				Steps: []StepListItemModel{
+					{"git-clone@6": NewStepModel(stepmanModels.StepModel{})},
+					{"cache-pull@2": NewStepModel(stepmanModels.StepModel{})},
+					{"script@1": NewStepModel(stepmanModels.StepModel{Inputs: []envmanModels.EnvironmentItemModel{{"content": "echo build"}}})},
+					{"script@1": NewStepModel(stepmanModels.StepModel{})},
+				},
+			},
+			"deploy": WorkflowModel{BeforeRun: []string{"primary"}},
+		},
+	}
+}
+
+func TestDiffConfigs(t *testing.T) {
+	t.Log("same configs")
+	{
+		changes, err := DiffConfigs(testDiffConfig(), testDiffConfig())
+		require.NoError(t, err)
+		require.Equal(t, []ConfigChangeModel{}, changes)
+	}
+
+	t.Log("top level properties, app envs and workflows")
+	{
+		newConfig := testDiffConfig()
+		newConfig.FormatVersion = "12"
+		newConfig.App.Environments = []envmanModels.EnvironmentItemModel{{"PROJECT": "App.xcworkspace"}, {"CONFIGURATION": "Release"}}
+		newConfig.Workflows["deploy"] = WorkflowModel{BeforeRun: []string{"primary", "_setup"}}
+		newConfig.Workflows["_setup"] = WorkflowModel{}
+		delete(newConfig.Workflows, "primary")
+		newConfig.TriggerMap = nil
+
+		changes, err := DiffConfigs(testDiffConfig(), newConfig)
+		require.NoError(t, err)
+		require.Equal(t, []ConfigChangeModel{
+			{Type: ConfigChangeTypeConfigChanged, Key: "format_version", Old: "11", New: "12"},
+			{Type: ConfigChangeTypeEnvRemoved, Key: "SCHEME", Old: "App"},
+			{Type: ConfigChangeTypeEnvChanged, Key: "PROJECT", Old: "app.xcodeproj", New: "App.xcworkspace"},
+			{Type: ConfigChangeTypeEnvAdded, Key: "CONFIGURATION", New: "Release"},
+			{Type: ConfigChangeTypeWorkflowAdded, Workflow: "_setup"},
+			{Type: ConfigChangeTypeWorkflowChanged, Workflow: "deploy", Key: "before_run", Old: `["primary"]`, New: `["primary","_setup"]`},
+			{Type: ConfigChangeTypeWorkflowRemoved, Workflow: "primary"},
+			{Type: ConfigChangeTypeTriggerRemoved, Old: "push_branch: master -> workflow: deploy"},
+			{Type: ConfigChangeTypeTriggerRemoved, Old: "push_branch: * -> workflow: primary"},
+			{Type: ConfigChangeTypeTriggerRouteChanged, Key: "push_branch: master", Old: "workflow (deploy)", New: "nothing"},
+			{Type: ConfigChangeTypeTriggerRouteChanged, Key: "push_branch: *", Old: "workflow (primary)", New: "nothing"},
+		}, changes)
+	}
+
+	t.Log("steps")
+	{
+		newConfig := testDiffConfig()
+		newConfig.Workflows["primary"] = WorkflowModel{
+			Steps: []StepListItemModel{
+				{"cache-pull@2": NewStepModel(stepmanModels.StepModel{})},
+				{"git-clone@8": NewStepModel(stepmanModels.StepModel{})},
+				{"script@1": NewStepModel(stepmanModels.StepModel{Inputs: []envmanModels.EnvironmentItemModel{{"content": "echo test"}, {"working_dir": "src"}}})},
+				{"deploy-to-bitrise-io@2": NewStepModel(stepmanModels.StepModel{})},
+			},
+		}
+
+		changes, err := DiffConfigs(testDiffConfig(), newConfig)
+		require.NoError(t, err)
+		require.Equal(t, []ConfigChangeModel{
+			{Type: ConfigChangeTypeStepRemoved, Workflow: "primary", Step: "script@1", StepPosition: pointers.NewIntPtr(3)},
+			{Type: ConfigChangeTypeStepMoved, Workflow: "primary", Step: "git-clone@8", StepPosition: pointers.NewIntPtr(1), Old: "0", New: "1"},
+			{Type: ConfigChangeTypeStepVersionChanged, Workflow: "primary", Step: "git-clone@8", StepPosition: pointers.NewIntPtr(1), Old: "6", New: "8"},
+			{Type: ConfigChangeTypeInputChanged, Workflow: "primary", Step: "script@1", StepPosition: pointers.NewIntPtr(2), Key: "content", Old: "echo build", New: "echo test"},
+			{Type: ConfigChangeTypeInputAdded, Workflow: "primary", Step: "script@1", StepPosition: pointers.NewIntPtr(2), Key: "working_dir", New: "src"},
+			{Type: ConfigChangeTypeStepAdded, Workflow: "primary", Step: "deploy-to-bitrise-io@2", StepPosition: pointers.NewIntPtr(3)},
+		}, changes)
+
+		require.Equal(t, "workflow (primary) step #1 (git-clone@8) moved from #0", changes[1].String())
+		require.Equal(t, `workflow (primary) step #2 (script@1) input (content) changed: "echo build" -> "echo test"`, changes[3].String())
+	}
+
+	t.Log("step properties")
+	{
+		newConfig := testDiffConfig()
+		newConfig.Workflows["primary"].Steps[3]["script@1"] = NewStepModel(stepmanModels.StepModel{IsAlwaysRun: pointers.NewBoolPtr(true)})
+
+		changes, err := DiffConfigs(testDiffConfig(), newConfig)
+		require.NoError(t, err)
+		require.Equal(t, []ConfigChangeModel{
+			{Type: ConfigChangeTypeStepChanged, Workflow: "primary", Step: "script@1", StepPosition: pointers.NewIntPtr(3), Key: "is_always_run", New: "true"},
+		}, changes)
+		require.Equal(t, "workflow (primary) step #3 (script@1) is_always_run: (not set) -> true", changes[0].String())
+	}
+
+	t.Log("trigger routes")
+	{
+		newConfig := testDiffConfig()
+		newConfig.TriggerMap = TriggerMapModel{
+			TriggerMapItemModel{PushBranch: "*", WorkflowID: "primary"},
+			TriggerMapItemModel{PushBranch: "master", WorkflowID: "deploy"},
+			TriggerMapItemModel{Tag: "v*", PipelineID: "release"},
+		}
+
+		changes, err := DiffConfigs(testDiffConfig(), newConfig)
+		require.NoError(t, err)
+		require.Equal(t, []ConfigChangeModel{
+			{Type: ConfigChangeTypeTriggerAdded, New: "tag: v* -> pipeline: release"},
+			{Type: ConfigChangeTypeTriggerRouteChanged, Key: "push_branch: master", Old: "workflow (deploy)", New: "workflow (primary)"},
+			{Type: ConfigChangeTypeTriggerRouteChanged, Key: "tag: v*", Old: "nothing", New: "pipeline (release)"},
+		}, changes)
+		require.Equal(t, "push_branch: master now triggers workflow (primary) instead of workflow (deploy)", changes[1].String())
+	}
+
+	t.Log("deprecated trigger items route the same way")
+	{
+		oldConfig := testDiffConfig()
+		oldConfig.TriggerMap = TriggerMapModel{
+			TriggerMapItemModel{Pattern: "master", IsPullRequestAllowed: true, WorkflowID: "primary"},
+		}
+		newConfig := testDiffConfig()
+		newConfig.TriggerMap = TriggerMapModel{
+			TriggerMapItemModel{PushBranch: "master", WorkflowID: "primary"},
+			TriggerMapItemModel{PullRequestSourceBranch: "master", WorkflowID: "primary"},
+		}
+
+		changes, err := DiffConfigs(oldConfig, newConfig)
+		require.NoError(t, err)
+		for _, change := range changes {
+			require.NotEqual(t, ConfigChangeTypeTriggerRouteChanged, change.Type)
+		}
+	}
+}
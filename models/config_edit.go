@@ -0,0 +1,209 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	envmanModels "github.com/bitrise-io/envman/models"
+	stepmanModels "github.com/bitrise-io/stepman/models"
+)
+
+func (config *BitriseDataModel) workflow(workflowID string) (WorkflowModel, error) {
+	workflow, ok := config.Workflows[workflowID]
+	if !ok {
+		return WorkflowModel{}, fmt.Errorf("workflow (%s) does not exist", workflowID)
+	}
+	return workflow, nil
+}
+
+// AddWorkflow adds an empty workflow to the config.
+func (config *BitriseDataModel) AddWorkflow(workflowID string) error {
+	if _, ok := config.Workflows[workflowID]; ok {
+		return fmt.Errorf("workflow (%s) already exists", workflowID)
+	}
+	if config.Workflows == nil {
+		config.Workflows = map[string]WorkflowModel{}
+	}
+	config.Workflows[workflowID] = WorkflowModel{}
+	return nil
+}
+
+// RenameWorkflow renames the workflow, and updates the references to it:
+// the before_run and after_run lists, the stages and the trigger map items.
+func (config *BitriseDataModel) RenameWorkflow(workflowID, newWorkflowID string) error {
+	workflow, err := config.workflow(workflowID)
+	if err != nil {
+		return err
+	}
+	if _, ok := config.Workflows[newWorkflowID]; ok {
+		return fmt.Errorf("workflow (%s) already exists", newWorkflowID)
+	}
+
+	delete(config.Workflows, workflowID)
+	config.Workflows[newWorkflowID] = workflow
+
+	renameInList := func(workflowIDs []string) {
+		for i, aWorkflowID := range workflowIDs {
+			if aWorkflowID == workflowID {
+				workflowIDs[i] = newWorkflowID
+			}
+		}
+	}
+	for _, aWorkflow := range config.Workflows {
+		renameInList(aWorkflow.BeforeRun)
+		renameInList(aWorkflow.AfterRun)
+	}
+
+	for _, stage := range config.Stages {
+		for i, stageWorkflow := range stage.Workflows {
+			if stageWorkflowModel, ok := stageWorkflow[workflowID]; ok {
+				stage.Workflows[i] = WorkflowListItemModel{newWorkflowID: stageWorkflowModel}
+			}
+		}
+	}
+
+	for i, triggerItem := range config.TriggerMap {
+		if triggerItem.WorkflowID == workflowID {
+			config.TriggerMap[i].WorkflowID = newWorkflowID
+		}
+	}
+
+	return nil
+}
+
+// AddEnv sets the env of the app, or of the workflow if the workflow ID is not empty.
+// The env is appended if it is not defined yet, otherwise its value is updated, keeping its options.
+func (config *BitriseDataModel) AddEnv(workflowID, key, value string) error {
+	if workflowID == "" {
+		config.App.Environments = setEnvValue(config.App.Environments, key, value)
+		return nil
+	}
+
+	workflow, err := config.workflow(workflowID)
+	if err != nil {
+		return err
+	}
+	workflow.Environments = setEnvValue(workflow.Environments, key, value)
+	config.Workflows[workflowID] = workflow
+	return nil
+}
+
+func setEnvValue(envs []envmanModels.EnvironmentItemModel, key, value string) []envmanModels.EnvironmentItemModel {
+	for _, env := range envs {
+		if _, ok := env[key]; ok {
+			env[key] = value
+			return envs
+		}
+	}
+	return append(envs, envmanModels.EnvironmentItemModel{key: value})
+}
+
+// AddStep inserts the step into the workflow at the given position, or appends it if the position is negative.
+func (config *BitriseDataModel) AddStep(workflowID, compositeStepID string, position int, inputs []envmanModels.EnvironmentItemModel) error {
+	workflow, err := config.workflow(workflowID)
+	if err != nil {
+		return err
+	}
+	if compositeStepID == "" {
+		return errors.New("empty step ID")
+	}
+	if position > len(workflow.Steps) {
+		return fmt.Errorf("invalid position (%d), workflow (%s) has %d steps", position, workflowID, len(workflow.Steps))
+	}
+
+	stepListItem := StepListItemModel{compositeStepID: NewStepModel(stepmanModels.StepModel{Inputs: inputs})}
+	if position < 0 {
+		workflow.Steps = append(workflow.Steps, stepListItem)
+	} else {
+		workflow.Steps = append(workflow.Steps[:position], append([]StepListItemModel{stepListItem}, workflow.Steps[position:]...)...)
+	}
+	config.Workflows[workflowID] = workflow
+	return nil
+}
+
+// StepPosition returns the position of the step in the workflow.
+// The step is identified by its composite ID (e.g. script@1) or by its ID (e.g. script),
+// which has to match exactly one step of the workflow.
+func (config *BitriseDataModel) StepPosition(workflowID, stepID string) (int, error) {
+	workflow, err := config.workflow(workflowID)
+	if err != nil {
+		return -1, err
+	}
+
+	position := -1
+	for idx, stepListItem := range workflow.Steps {
+		compositeStepID, _, err := GetStepIDStepDataPair(stepListItem)
+		if err != nil {
+			return -1, err
+		}
+		if compositeStepID != stepID && getStepID(compositeStepID) != stepID {
+			continue
+		}
+
+		if position != -1 {
+			return -1, fmt.Errorf("multiple steps (%s) found in workflow (%s), at position %d and %d", stepID, workflowID, position, idx)
+		}
+		position = idx
+	}
+
+	if position == -1 {
+		return -1, fmt.Errorf("step (%s) not found in workflow (%s)", stepID, workflowID)
+	}
+	return position, nil
+}
+
+func (workflow WorkflowModel) checkStepPosition(workflowID string, position int) error {
+	if position < 0 || position >= len(workflow.Steps) {
+		return fmt.Errorf("invalid position (%d), workflow (%s) has %d steps", position, workflowID, len(workflow.Steps))
+	}
+	return nil
+}
+
+// RemoveStep removes the step at the given position of the workflow.
+func (config *BitriseDataModel) RemoveStep(workflowID string, position int) error {
+	workflow, err := config.workflow(workflowID)
+	if err != nil {
+		return err
+	}
+	if err := workflow.checkStepPosition(workflowID, position); err != nil {
+		return err
+	}
+
+	workflow.Steps = append(workflow.Steps[:position], workflow.Steps[position+1:]...)
+	config.Workflows[workflowID] = workflow
+	return nil
+}
+
+// SetStepInput sets the input of the step at the given position of the workflow.
+// The input is appended if the step does not define it yet, otherwise its value is updated, keeping its options.
+func (config *BitriseDataModel) SetStepInput(workflowID string, position int, key, value string) error {
+	workflow, err := config.workflow(workflowID)
+	if err != nil {
+		return err
+	}
+	if err := workflow.checkStepPosition(workflowID, position); err != nil {
+		return err
+	}
+
+	compositeStepID, step, err := GetStepIDStepDataPair(workflow.Steps[position])
+	if err != nil {
+		return err
+	}
+	step.Inputs = setEnvValue(step.Inputs, key, value)
+	workflow.Steps[position] = StepListItemModel{compositeStepID: step}
+	return nil
+}
+
+// AddTriggerMapItem inserts the trigger map item at the given position, or appends it if the position is negative.
+func (config *BitriseDataModel) AddTriggerMapItem(triggerItem TriggerMapItemModel, position int) error {
+	if position > len(config.TriggerMap) {
+		return fmt.Errorf("invalid position (%d), the trigger map has %d items", position, len(config.TriggerMap))
+	}
+
+	if position < 0 {
+		config.TriggerMap = append(config.TriggerMap, triggerItem)
+	} else {
+		config.TriggerMap = append(config.TriggerMap[:position], append([]TriggerMapItemModel{triggerItem}, config.TriggerMap[position:]...)...)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"testing"
+
+	envmanModels "github.com/bitrise-io/envman/models"
+	stepmanModels "github.com/bitrise-io/stepman/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameWorkflow(t *testing.T) {
+	config := BitriseDataModel{
+		TriggerMap: TriggerMapModel{
+			TriggerMapItemModel{PushBranch: "master", WorkflowID: "primary"},
+			TriggerMapItemModel{Tag: "*", WorkflowID: "deploy"},
+		},
+		Stages: map[string]StageModel{
+			"build": StageModel{Workflows: []WorkflowListItemModel{{"primary": WorkflowModel{}}, {"deploy": WorkflowModel{}}}},
+		},
+		Workflows: map[string]WorkflowModel{
+			"_setup":  WorkflowModel{},
+			"primary": WorkflowModel{BeforeRun: []string{"_setup"}, Title: "Primary"},
+			"deploy":  WorkflowModel{BeforeRun: []string{"primary"}, AfterRun: []string{"_setup", "primary"}},
+		},
+	}
+
+	require.NoError(t, config.RenameWorkflow("primary", "build"))
+
+	_, ok := config.Workflows["primary"]
+	require.False(t, ok)
+	require.Equal(t, "Primary", config.Workflows["build"].Title)
+	require.Equal(t, []string{"build"}, config.Workflows["deploy"].BeforeRun)
+	require.Equal(t, []string{"_setup", "build"}, config.Workflows["deploy"].AfterRun)
+	require.Equal(t, []WorkflowListItemModel{{"build": WorkflowModel{}}, {"deploy": WorkflowModel{}}}, config.Stages["build"].Workflows)
+	require.Equal(t, "build", config.TriggerMap[0].WorkflowID)
+	require.Equal(t, "deploy", config.TriggerMap[1].WorkflowID)
+
+	require.EqualError(t, config.RenameWorkflow("build", "deploy"), "workflow (deploy) already exists")
+	require.EqualError(t, config.RenameWorkflow("primary", "test"), "workflow (primary) does not exist")
+}
+
+func TestEditSteps(t *testing.T) {
+	config := BitriseDataModel{
+		Workflows: map[string]WorkflowModel{
+			"primary": WorkflowModel{
+				Steps: []StepListItemModel{
+					{"script@1": NewStepModel(stepmanModels.StepModel{Inputs: []envmanModels.EnvironmentItemModel{{"content": "echo build", "opts": map[string]interface{}{"is_expand": false}}}})},
+				},
+			},
+		},
+	}
+
+	t.Log("add step")
+	{
+		require.NoError(t, config.AddStep("primary", "git-clone@8", 0, nil))
+		require.NoError(t, config.AddStep("primary", "deploy-to-bitrise-io@2", -1, []envmanModels.EnvironmentItemModel{{"notify_user_groups": "none"}}))
+		require.EqualError(t, config.AddStep("primary", "script@1", 4, nil), "invalid position (4), workflow (primary) has 3 steps")
+		require.EqualError(t, config.AddStep("deploy", "script@1", -1, nil), "workflow (deploy) does not exist")
+
+		var stepIDs []string
+		for _, stepListItem := range config.Workflows["primary"].Steps {
+			stepID, _, err := GetStepIDStepDataPair(stepListItem)
+			require.NoError(t, err)
+			stepIDs = append(stepIDs, stepID)
+		}
+		require.Equal(t, []string{"git-clone@8", "script@1", "deploy-to-bitrise-io@2"}, stepIDs)
+	}
+
+	t.Log("step position")
+	{
+		position, err := config.StepPosition("primary", "script")
+		require.NoError(t, err)
+		require.Equal(t, 1, position)
+
+		position, err = config.StepPosition("primary", "deploy-to-bitrise-io@2")
+		require.NoError(t, err)
+		require.Equal(t, 2, position)
+
+		_, err = config.StepPosition("primary", "xcode-test")
+		require.EqualError(t, err, "step (xcode-test) not found in workflow (primary)")
+
+		require.NoError(t, config.AddStep("primary", "script@1", -1, nil))
+		_, err = config.StepPosition("primary", "script")
+		require.EqualError(t, err, "multiple steps (script) found in workflow (primary), at position 1 and 3")
+	}
+
+	t.Log("set step input")
+	{
+		require.NoError(t, config.SetStepInput("primary", 1, "content", "echo test"))
+		require.NoError(t, config.SetStepInput("primary", 1, "working_dir", "src"))
+
+		_, step, err := GetStepIDStepDataPair(config.Workflows["primary"].Steps[1])
+		require.NoError(t, err)
+		require.Equal(t, []envmanModels.EnvironmentItemModel{
+			{"content": "echo test", "opts": map[string]interface{}{"is_expand": false}},
+			{"working_dir": "src"},
+		}, step.Inputs)
+
+		require.EqualError(t, config.SetStepInput("primary", 4, "content", ""), "invalid position (4), workflow (primary) has 4 steps")
+	}
+
+	t.Log("remove step")
+	{
+		require.NoError(t, config.RemoveStep("primary", 3))
+		require.NoError(t, config.RemoveStep("primary", 0))
+		require.Equal(t, 2, len(config.Workflows["primary"].Steps))
+		require.EqualError(t, config.RemoveStep("primary", -1), "invalid position (-1), workflow (primary) has 2 steps")
+	}
+}
+
+func TestAddEnvWorkflowAndTrigger(t *testing.T) {
+	config := BitriseDataModel{
+		App: AppModel{Environments: []envmanModels.EnvironmentItemModel{{"PROJECT": "app", "opts": map[string]interface{}{"is_expand": false}}}},
+	}
+
+	require.NoError(t, config.AddEnv("", "PROJECT", "renamed"))
+	require.NoError(t, config.AddEnv("", "SCHEME", "App"))
+	require.Equal(t, []envmanModels.EnvironmentItemModel{
+		{"PROJECT": "renamed", "opts": map[string]interface{}{"is_expand": false}},
+		{"SCHEME": "App"},
+	}, config.App.Environments)
+
+	require.EqualError(t, config.AddEnv("primary", "KEY", "value"), "workflow (primary) does not exist")
+	require.NoError(t, config.AddWorkflow("primary"))
+	require.EqualError(t, config.AddWorkflow("primary"), "workflow (primary) already exists")
+	require.NoError(t, config.AddEnv("primary", "KEY", "value"))
+	require.Equal(t, []envmanModels.EnvironmentItemModel{{"KEY": "value"}}, config.Workflows["primary"].Environments)
+
+	require.NoError(t, config.AddTriggerMapItem(TriggerMapItemModel{PushBranch: "*", WorkflowID: "primary"}, -1))
+	require.NoError(t, config.AddTriggerMapItem(TriggerMapItemModel{PushBranch: "master", WorkflowID: "primary"}, 0))
+	require.Equal(t, "master", config.TriggerMap[0].PushBranch)
+	require.Equal(t, "*", config.TriggerMap[1].PushBranch)
+	require.EqualError(t, config.AddTriggerMapItem(TriggerMapItemModel{Tag: "*", WorkflowID: "primary"}, 3), "invalid position (3), the trigger map has 2 items")
+}
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/bitrise-io/go-utils/versions"
+	stepmanModels "github.com/bitrise-io/stepman/models"
+)
+
+const (
+	depManagerBrew     = "brew"
+	depManagerTryCheck = "_"
+)
+
+// ConfigMigration rewrites the constructs of the config, which are deprecated since its format version.
+// IsNeeded reports whether the config still uses the deprecated constructs.
+type ConfigMigration struct {
+	FormatVersion string
+	Description   string
+	IsNeeded      func(config BitriseDataModel) bool
+	Migrate       func(config *BitriseDataModel) error
+}
+
+// ConfigMigrations are the registered config migrations, in format version order.
+var ConfigMigrations = []ConfigMigration{
+	{
+		FormatVersion: "1.2.0",
+		Description:   "step dependencies are replaced with step deps",
+		IsNeeded:      hasStepDependencies,
+		Migrate:       migrateStepDependencies,
+	},
+	{
+		FormatVersion: "1.3.0",
+		Description:   "trigger map items with pattern are replaced with push_branch and pull_request_source_branch items",
+		IsNeeded:      hasTriggerPatterns,
+		Migrate:       migrateTriggerPatterns,
+	},
+}
+
+func hasStepDependencies(config BitriseDataModel) bool {
+	for _, workflow := range config.Workflows {
+		for _, stepListItem := range workflow.Steps {
+			for _, step := range stepListItem {
+				if len(step.Dependencies) > 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func migrateStepDependencies(config *BitriseDataModel) error {
+	for workflowID, workflow := range config.Workflows {
+		for _, stepListItem := range workflow.Steps {
+			stepID, step, err := GetStepIDStepDataPair(stepListItem)
+			if err != nil {
+				return err
+			}
+			if len(step.Dependencies) == 0 {
+				continue
+			}
+
+			// the dependencies are not used if deps are defined
+			if step.Deps == nil || (len(step.Deps.Brew) == 0 && len(step.Deps.AptGet) == 0 && len(step.Deps.CheckOnly) == 0) {
+				deps := stepmanModels.DepsModel{}
+				for _, dep := range step.Dependencies {
+					switch dep.Manager {
+					case depManagerBrew:
+						deps.Brew = append(deps.Brew, stepmanModels.BrewDepModel{Name: dep.Name})
+					case depManagerTryCheck:
+						deps.CheckOnly = append(deps.CheckOnly, stepmanModels.CheckOnlyDepModel{Name: dep.Name})
+					default:
+						return fmt.Errorf("workflow (%s) step (%s) has not supported dependency (%s) (%s)", workflowID, stepID, dep.Manager, dep.Name)
+					}
+				}
+				step.Deps = &deps
+			}
+
+			step.Dependencies = nil
+			stepListItem[stepID] = step
+		}
+	}
+	return nil
+}
+
+func hasTriggerPatterns(config BitriseDataModel) bool {
+	for _, triggerItem := range config.TriggerMap {
+		if triggerItem.Pattern != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func migrateTriggerPatterns(config *BitriseDataModel) error {
+	if len(config.TriggerMap) == 0 {
+		return nil
+	}
+
+	triggerMap := TriggerMapModel{}
+	for _, triggerItem := range config.TriggerMap {
+		if triggerItem.Pattern == "" {
+			triggerMap = append(triggerMap, triggerItem)
+			continue
+		}
+
+		for _, migratedItem := range migrateDeprecatedTriggerItem(triggerItem) {
+			migratedItem.PipelineID = triggerItem.PipelineID
+			triggerMap = append(triggerMap, migratedItem)
+		}
+	}
+	config.TriggerMap = triggerMap
+	return nil
+}
+
+func isFormatVersionGreater(version, otherVersion string) (bool, error) {
+	isGreaterOrEqual, err := versions.IsVersionGreaterOrEqual(otherVersion, version)
+	if err != nil {
+		return false, err
+	}
+	return !isGreaterOrEqual, nil
+}
+
+// Migrate upgrades the config to the given format version: runs the migrations registered
+// up to the given version whose deprecated constructs the config still uses, whatever the config's
+// format version is, and updates the config's format version.
+// Returns the migrations run.
+func (config *BitriseDataModel) Migrate(toVersion string) ([]ConfigMigration, error) {
+	if isNewer, err := isFormatVersionGreater(toVersion, Version); err != nil {
+		return nil, fmt.Errorf("invalid format version (%s), error: %s", toVersion, err)
+	} else if isNewer {
+		return nil, fmt.Errorf("format version (%s) is newer than the latest supported format version (%s), please upgrade your bitrise CLI", toVersion, Version)
+	}
+
+	if isNewer, err := isFormatVersionGreater(config.FormatVersion, toVersion); err != nil {
+		return nil, fmt.Errorf("invalid config format version (%s), error: %s", config.FormatVersion, err)
+	} else if isNewer {
+		return nil, fmt.Errorf("the config's format version (%s) is newer than %s, configs can not be downgraded", config.FormatVersion, toVersion)
+	}
+
+	migrations := []ConfigMigration{}
+	for _, migration := range ConfigMigrations {
+		isAfterToVersion, err := isFormatVersionGreater(migration.FormatVersion, toVersion)
+		if err != nil {
+			return nil, err
+		}
+		if isAfterToVersion || !migration.IsNeeded(*config) {
+			continue
+		}
+
+		if err := migration.Migrate(config); err != nil {
+			return nil, fmt.Errorf("failed to migrate to format version %s, error: %s", migration.FormatVersion, err)
+		}
+		migrations = append(migrations, migration)
+	}
+
+	config.FormatVersion = toVersion
+	return migrations, nil
+}
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"testing"
+
+	stepmanModels "github.com/bitrise-io/stepman/models"
+	"github.com/stretchr/testify/require"
+)
+
+func testMigrateConfig() BitriseDataModel {
+	return BitriseDataModel{
+		FormatVersion: "1.1.0",
+		TriggerMap: TriggerMapModel{
+			TriggerMapItemModel{Pattern: "master", IsPullRequestAllowed: true, WorkflowID: "primary"},
+			TriggerMapItemModel{Tag: "*", WorkflowID: "deploy"},
+			TriggerMapItemModel{Pattern: "release/*", PipelineID: "release"},
+		},
+		Workflows: map[string]WorkflowModel{
+			"primary": WorkflowModel{
+				Steps: []StepListItemModel{
+					{"script": NewStepModel(stepmanModels.StepModel{Dependencies: []stepmanModels.DependencyModel{
+						{Manager: "brew", Name: "carthage"},
+						{Manager: "_", Name: "xcode"},
+					}})},
+				},
+			},
+			"deploy": WorkflowModel{},
+		},
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	t.Log("migrates to the latest format version")
+	{
+		config := testMigrateConfig()
+
+		migrations, err := config.Migrate(Version)
+		require.NoError(t, err)
+		require.Equal(t, 2, len(migrations))
+		require.Equal(t, "1.2.0", migrations[0].FormatVersion)
+		require.Equal(t, "1.3.0", migrations[1].FormatVersion)
+
+		require.Equal(t, Version, config.FormatVersion)
+		require.Equal(t, TriggerMapModel{
+			TriggerMapItemModel{PushBranch: "master", WorkflowID: "primary"},
+			TriggerMapItemModel{PullRequestSourceBranch: "master", WorkflowID: "primary"},
+			TriggerMapItemModel{Tag: "*", WorkflowID: "deploy"},
+			TriggerMapItemModel{PushBranch: "release/*", PipelineID: "release"},
+		}, config.TriggerMap)
+
+		step := config.Workflows["primary"].Steps[0]["script"]
+		require.Equal(t, 0, len(step.Dependencies))
+		require.Equal(t, &stepmanModels.DepsModel{
+			Brew:      []stepmanModels.BrewDepModel{{Name: "carthage"}},
+			CheckOnly: []stepmanModels.CheckOnlyDepModel{{Name: "xcode"}},
+		}, step.Deps)
+	}
+
+	t.Log("only runs the migrations up to the given format version")
+	{
+		config := testMigrateConfig()
+
+		migrations, err := config.Migrate("1.2.0")
+		require.NoError(t, err)
+		require.Equal(t, 1, len(migrations))
+		require.Equal(t, "1.2.0", config.FormatVersion)
+		require.Equal(t, "master", config.TriggerMap[0].Pattern)
+		require.Equal(t, 0, len(config.Workflows["primary"].Steps[0]["script"].Dependencies))
+	}
+
+	t.Log("runs the migrations of the deprecated constructs used, whatever the config's format version")
+	{
+		config := testMigrateConfig()
+		config.FormatVersion = "1.2.0"
+
+		migrations, err := config.Migrate(Version)
+		require.NoError(t, err)
+		require.Equal(t, 2, len(migrations))
+		require.Equal(t, 0, len(config.Workflows["primary"].Steps[0]["script"].Dependencies))
+	}
+
+	t.Log("config at the latest format version with a pattern trigger")
+	{
+		config := BitriseDataModel{
+			FormatVersion: Version,
+			TriggerMap: TriggerMapModel{
+				TriggerMapItemModel{Pattern: "master", IsPullRequestAllowed: true, WorkflowID: "primary"},
+			},
+			Workflows: map[string]WorkflowModel{"primary": WorkflowModel{}},
+		}
+
+		migrations, err := config.Migrate(Version)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(migrations))
+		require.Equal(t, "1.3.0", migrations[0].FormatVersion)
+		require.Equal(t, Version, config.FormatVersion)
+		require.Equal(t, TriggerMapModel{
+			TriggerMapItemModel{PushBranch: "master", WorkflowID: "primary"},
+			TriggerMapItemModel{PullRequestSourceBranch: "master", WorkflowID: "primary"},
+		}, config.TriggerMap)
+	}
+
+	t.Log("does not run the migrations of the deprecated constructs not used")
+	{
+		config := BitriseDataModel{
+			FormatVersion: "1.1.0",
+			TriggerMap:    TriggerMapModel{TriggerMapItemModel{PushBranch: "master", WorkflowID: "primary"}},
+			Workflows:     map[string]WorkflowModel{"primary": WorkflowModel{}},
+		}
+
+		migrations, err := config.Migrate(Version)
+		require.NoError(t, err)
+		require.Equal(t, 0, len(migrations))
+		require.Equal(t, Version, config.FormatVersion)
+	}
+
+	t.Log("keeps the deps if defined")
+	{
+		config := testMigrateConfig()
+		deps := &stepmanModels.DepsModel{AptGet: []stepmanModels.AptGetDepModel{{Name: "git"}}}
+		config.Workflows["primary"].Steps[0]["script"] = NewStepModel(stepmanModels.StepModel{
+			Dependencies: []stepmanModels.DependencyModel{{Manager: "brew", Name: "git"}},
+			Deps:         deps,
+		})
+
+		_, err := config.Migrate(Version)
+		require.NoError(t, err)
+		step := config.Workflows["primary"].Steps[0]["script"]
+		require.Equal(t, 0, len(step.Dependencies))
+		require.Equal(t, deps, step.Deps)
+	}
+
+	t.Log("unknown dependency manager")
+	{
+		config := testMigrateConfig()
+		config.Workflows["primary"].Steps[0]["script"] = NewStepModel(stepmanModels.StepModel{
+			Dependencies: []stepmanModels.DependencyModel{{Manager: "apt", Name: "git"}},
+		})
+
+		_, err := config.Migrate(Version)
+		require.EqualError(t, err, "failed to migrate to format version 1.2.0, error: workflow (primary) step (script) has not supported dependency (apt) (git)")
+	}
+
+	t.Log("newer than the supported format version")
+	{
+		config := testMigrateConfig()
+
+		_, err := config.Migrate("99")
+		require.EqualError(t, err, "format version (99) is newer than the latest supported format version ("+Version+"), please upgrade your bitrise CLI")
+	}
+
+	t.Log("downgrade")
+	{
+		config := testMigrateConfig()
+		config.FormatVersion = "1.3.0"
+
+		_, err := config.Migrate("1.2.0")
+		require.EqualError(t, err, "the config's format version (1.3.0) is newer than 1.2.0, configs can not be downgraded")
+	}
+}
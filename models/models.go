@@ -18,13 +18,15 @@ const (
 	StepRunStatusCodeSkipped = 3
 	// StepRunStatusCodeSkippedWithRunIf ...
 	StepRunStatusCodeSkippedWithRunIf = 4
+	// StepRunStatusCodeSkippedWithNoRelevantChanges ...
+	StepRunStatusCodeSkippedWithNoRelevantChanges = 5
 
 	// Version ...
 	Version = "11"
 )
 
 // StepListItemModel ...
-type StepListItemModel map[string]stepmanModels.StepModel
+type StepListItemModel map[string]StepModel
 
 // PipelineModel ...
 type PipelineModel struct {
@@ -50,10 +52,25 @@ type WorkflowModel struct {
 	BeforeRun    []string                            `json:"before_run,omitempty" yaml:"before_run,omitempty"`
 	AfterRun     []string                            `json:"after_run,omitempty" yaml:"after_run,omitempty"`
 	Environments []envmanModels.EnvironmentItemModel `json:"envs,omitempty" yaml:"envs,omitempty"`
+	IsolateEnvs  bool                                `json:"isolate_envs,omitempty" yaml:"isolate_envs,omitempty"`
+	ExportEnvs   []string                            `json:"export_envs,omitempty" yaml:"export_envs,omitempty"`
 	Steps        []StepListItemModel                 `json:"steps,omitempty" yaml:"steps,omitempty"`
+	Container    *ContainerModel                     `json:"container,omitempty" yaml:"container,omitempty"`
 	Meta         map[string]interface{}              `json:"meta,omitempty" yaml:"meta,omitempty"`
 }
 
+// ContainerEngines are the supported container engine CLIs, in order of preference.
+var ContainerEngines = []string{"docker", "podman"}
+
+// ContainerModel describes the container image the workflow's steps are run in.
+type ContainerModel struct {
+	Image string `json:"image,omitempty" yaml:"image,omitempty"`
+	// Engine is the container engine CLI (docker or podman), the first one available in the PATH is used if not specified.
+	Engine string `json:"engine,omitempty" yaml:"engine,omitempty"`
+	// Options are additional arguments of the engine's run command.
+	Options []string `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
 // AppModel ...
 type AppModel struct {
 	Title        string                              `json:"title,omitempty" yaml:"title,omitempty"`
@@ -76,6 +93,10 @@ const (
 	TriggerEventTypeUnknown TriggerEventType = "unknown"
 )
 
+// TriggerRegexPatternPrefix marks the trigger map item patterns, which are regular expressions instead of globs,
+// e.g. push_branch: "regex:^release/[0-9.]+$"
+const TriggerRegexPatternPrefix = "regex:"
+
 // TriggerMapItemModel ...
 type TriggerMapItemModel struct {
 	PushBranch              string `json:"push_branch,omitempty" yaml:"push_branch,omitempty"`
@@ -84,6 +105,14 @@ type TriggerMapItemModel struct {
 	Tag                     string `json:"tag,omitempty" yaml:"tag,omitempty"`
 	PipelineID              string `json:"pipeline,omitempty" yaml:"pipeline,omitempty"`
 	WorkflowID              string `json:"workflow,omitempty" yaml:"workflow,omitempty"`
+	// CommitMessage : the item only matches if the commit message matches the pattern
+	CommitMessage string `json:"commit_message,omitempty" yaml:"commit_message,omitempty"`
+	// PullRequestLabel : the pull request item only matches if any of the pull request's labels matches the pattern
+	PullRequestLabel string `json:"pull_request_label,omitempty" yaml:"pull_request_label,omitempty"`
+	// DraftPullRequestEnabled : if false, the pull request item does not match draft pull requests (default: true)
+	DraftPullRequestEnabled *bool `json:"draft_pull_request_enabled,omitempty" yaml:"draft_pull_request_enabled,omitempty"`
+	// ChangedFiles : the item only matches if the build changes relevant files
+	ChangedFiles *ChangedFilesFilterModel `json:"changed_files,omitempty" yaml:"changed_files,omitempty"`
 
 	// deprecated
 	Pattern              string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
@@ -93,6 +122,34 @@ type TriggerMapItemModel struct {
 // TriggerMapModel ...
 type TriggerMapModel []TriggerMapItemModel
 
+// TriggerFieldMatchModel is the result of matching a trigger map item field's pattern with the git event.
+type TriggerFieldMatchModel struct {
+	Field   string `json:"field" yaml:"field"`
+	Pattern string `json:"pattern" yaml:"pattern"`
+	Value   string `json:"value" yaml:"value"`
+	Match   bool   `json:"match" yaml:"match"`
+}
+
+// TriggerItemMatchModel is the result of matching a trigger map item with the git event.
+type TriggerItemMatchModel struct {
+	EventType        TriggerEventType         `json:"event_type" yaml:"event_type"`
+	IsEventTypeMatch bool                     `json:"event_type_match" yaml:"event_type_match"`
+	Fields           []TriggerFieldMatchModel `json:"fields,omitempty" yaml:"fields,omitempty"`
+	Match            bool                     `json:"match" yaml:"match"`
+}
+
+// TriggerParamsModel is the git event the trigger map items are matched against.
+type TriggerParamsModel struct {
+	PushBranch     string
+	PRSourceBranch string
+	PRTargetBranch string
+	Tag            string
+
+	CommitMessage string
+	PRLabels      []string
+	IsDraftPR     bool
+}
+
 // BitriseDataModel ...
 type BitriseDataModel struct {
 	FormatVersion        string `json:"format_version" yaml:"format_version"`
@@ -111,6 +168,13 @@ type BitriseDataModel struct {
 	Workflows  map[string]WorkflowModel `json:"workflows,omitempty" yaml:"workflows,omitempty"`
 }
 
+// ConfigPathError is a config validation error (or warning), located by the path of the offending config node:
+// string path elements are mapping keys, int path elements are list indexes, e.g. trigger_map, 0, workflow.
+type ConfigPathError struct {
+	Path    []interface{}
+	Message string
+}
+
 // StepIDData ...
 // structured representation of a composite-step-id
 //  a composite step id is: step-lib-source::step-id@1.0.0
@@ -140,10 +204,14 @@ type BuildRunResultsModel struct {
 	FailedSteps          []StepRunResultsModel `json:"failed_steps" yaml:"failed_steps"`
 	FailedSkippableSteps []StepRunResultsModel `json:"failed_skippable_steps" yaml:"failed_skippable_steps"`
 	SkippedSteps         []StepRunResultsModel `json:"skipped_steps" yaml:"skipped_steps"`
+	// StepOutputs are the outputs of the step runs by the step run result's Idx,
+	// not serialized as the outputs are not redacted.
+	StepOutputs map[int]map[string]string `json:"-" yaml:"-"`
 }
 
 // StepRunResultsModel ...
 type StepRunResultsModel struct {
+	ID         string                      `json:"id,omitempty" yaml:"id,omitempty"`
 	StepInfo   stepmanModels.StepInfoModel `json:"step_info" yaml:"step_info"`
 	StepInputs map[string]string           `json:"step_inputs" yaml:"step_inputs"`
 	Status     int                         `json:"status" yaml:"status"`
@@ -4,10 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	envmanModels "github.com/bitrise-io/envman/models"
 	"github.com/bitrise-io/go-utils/pointers"
+	"github.com/bitrise-io/go-utils/sliceutil"
 	stepmanModels "github.com/bitrise-io/stepman/models"
 	"github.com/ryanuber/go-glob"
 )
@@ -52,6 +54,19 @@ func (triggerItem TriggerMapItemModel) String(printTarget bool) string {
 		str += fmt.Sprintf("pattern: %s && is_pull_request_allowed: %v", triggerItem.Pattern, triggerItem.IsPullRequestAllowed)
 	}
 
+	if triggerItem.CommitMessage != "" {
+		str += fmt.Sprintf(" && commit_message: %s", triggerItem.CommitMessage)
+	}
+	if triggerItem.PullRequestLabel != "" {
+		str += fmt.Sprintf(" && pull_request_label: %s", triggerItem.PullRequestLabel)
+	}
+	if triggerItem.DraftPullRequestEnabled != nil {
+		str += fmt.Sprintf(" && draft_pull_request_enabled: %v", *triggerItem.DraftPullRequestEnabled)
+	}
+	if triggerItem.ChangedFiles != nil {
+		str += fmt.Sprintf(" && changed_files: (include: %s, exclude: %s)", strings.Join(triggerItem.ChangedFiles.Include, ", "), strings.Join(triggerItem.ChangedFiles.Exclude, ", "))
+	}
+
 	if printTarget {
 		if triggerItem.PipelineID != "" {
 			str += fmt.Sprintf(" -> pipeline: %s", triggerItem.PipelineID)
@@ -93,6 +108,11 @@ func triggerEventType(pushBranch, prSourceBranch, prTargetBranch, tag string) (T
 	return TriggerEventTypeUnknown, fmt.Errorf("failed to determin trigger event from params: push-branch: %s, pr-source-branch: %s, pr-target-branch: %s, tag: %s", pushBranch, prSourceBranch, prTargetBranch, tag)
 }
 
+// TriggerParamsEventType returns the type of the git event.
+func TriggerParamsEventType(params TriggerParamsModel) (TriggerEventType, error) {
+	return triggerEventType(params.PushBranch, params.PRSourceBranch, params.PRTargetBranch, params.Tag)
+}
+
 func migrateDeprecatedTriggerItem(triggerItem TriggerMapItemModel) []TriggerMapItemModel {
 	migratedItems := []TriggerMapItemModel{
 		TriggerMapItemModel{
@@ -109,55 +129,229 @@ func migrateDeprecatedTriggerItem(triggerItem TriggerMapItemModel) []TriggerMapI
 	return migratedItems
 }
 
+// IsSkipCICommitMessage reports whether the commit message asks for skipping the CI build ([skip ci] or [ci skip]).
+func IsSkipCICommitMessage(commitMessage string) bool {
+	lowerMessage := strings.ToLower(commitMessage)
+	return strings.Contains(lowerMessage, "[skip ci]") || strings.Contains(lowerMessage, "[ci skip]")
+}
+
+// matchTriggerPattern matches the value with the glob pattern,
+// or with the regular expression if the pattern is prefixed with TriggerRegexPatternPrefix.
+func matchTriggerPattern(pattern, value string) (bool, error) {
+	if strings.HasPrefix(pattern, TriggerRegexPatternPrefix) {
+		exp, err := regexp.Compile(strings.TrimPrefix(pattern, TriggerRegexPatternPrefix))
+		if err != nil {
+			return false, fmt.Errorf("invalid regex pattern (%s), error: %s", pattern, err)
+		}
+		return exp.MatchString(value), nil
+	}
+	return glob.Glob(pattern, value), nil
+}
+
+// hasAdditionalConditions reports whether the item narrows down its git event with further conditions,
+// beside the branches and the tag.
+func (triggerItem TriggerMapItemModel) hasAdditionalConditions() bool {
+	return triggerItem.CommitMessage != "" ||
+		triggerItem.PullRequestLabel != "" ||
+		triggerItem.DraftPullRequestEnabled != nil ||
+		triggerItem.ChangedFiles != nil
+}
+
+func newTriggerFieldMatch(field, pattern, value string) (TriggerFieldMatchModel, error) {
+	match, err := matchTriggerPattern(pattern, value)
+	if err != nil {
+		return TriggerFieldMatchModel{}, err
+	}
+	return TriggerFieldMatchModel{Field: field, Pattern: pattern, Value: value, Match: match}, nil
+}
+
+func (triggerItem TriggerMapItemModel) additionalConditionMatches(params TriggerParamsModel, eventType TriggerEventType) ([]TriggerFieldMatchModel, error) {
+	fieldMatches := []TriggerFieldMatchModel{}
+
+	if triggerItem.CommitMessage != "" {
+		fieldMatch, err := newTriggerFieldMatch("commit_message", triggerItem.CommitMessage, params.CommitMessage)
+		if err != nil {
+			return nil, err
+		}
+		fieldMatches = append(fieldMatches, fieldMatch)
+	}
+
+	if eventType != TriggerEventTypePullRequest {
+		return fieldMatches, nil
+	}
+
+	if triggerItem.DraftPullRequestEnabled != nil {
+		fieldMatches = append(fieldMatches, TriggerFieldMatchModel{
+			Field:   "draft_pull_request_enabled",
+			Pattern: fmt.Sprintf("%v", *triggerItem.DraftPullRequestEnabled),
+			Value:   fmt.Sprintf("draft: %v", params.IsDraftPR),
+			Match:   *triggerItem.DraftPullRequestEnabled || !params.IsDraftPR,
+		})
+	}
+
+	if triggerItem.PullRequestLabel != "" {
+		fieldMatch := TriggerFieldMatchModel{
+			Field:   "pull_request_label",
+			Pattern: triggerItem.PullRequestLabel,
+			Value:   strings.Join(params.PRLabels, ", "),
+		}
+		for _, label := range params.PRLabels {
+			match, err := matchTriggerPattern(triggerItem.PullRequestLabel, label)
+			if err != nil {
+				return nil, err
+			}
+			fieldMatch.Match = fieldMatch.Match || match
+		}
+		fieldMatches = append(fieldMatches, fieldMatch)
+	}
+
+	return fieldMatches, nil
+}
+
 // MatchWithParams ...
 func (triggerItem TriggerMapItemModel) MatchWithParams(pushBranch, prSourceBranch, prTargetBranch, tag string) (bool, error) {
-	paramsEventType, err := triggerEventType(pushBranch, prSourceBranch, prTargetBranch, tag)
+	return triggerItem.MatchWithTriggerParams(TriggerParamsModel{
+		PushBranch:     pushBranch,
+		PRSourceBranch: prSourceBranch,
+		PRTargetBranch: prTargetBranch,
+		Tag:            tag,
+	})
+}
+
+// MatchWithTriggerParams reports whether the item matches the git event, except for the changed files,
+// which require the git repository of the build.
+func (triggerItem TriggerMapItemModel) MatchWithTriggerParams(params TriggerParamsModel) (bool, error) {
+	itemMatch, err := triggerItem.ExplainMatchWithTriggerParams(params)
 	if err != nil {
 		return false, err
 	}
+	return itemMatch.Match, nil
+}
+
+// ExplainMatchWithTriggerParams matches the item with the git event field by field, except for the changed files.
+func (triggerItem TriggerMapItemModel) ExplainMatchWithTriggerParams(params TriggerParamsModel) (TriggerItemMatchModel, error) {
+	paramsEventType, err := triggerEventType(params.PushBranch, params.PRSourceBranch, params.PRTargetBranch, params.Tag)
+	if err != nil {
+		return TriggerItemMatchModel{}, err
+	}
 
 	migratedTriggerItems := []TriggerMapItemModel{triggerItem}
 	if triggerItem.Pattern != "" {
 		migratedTriggerItems = migrateDeprecatedTriggerItem(triggerItem)
 	}
 
+	itemMatch := TriggerItemMatchModel{}
 	for _, migratedTriggerItem := range migratedTriggerItems {
 		itemEventType, err := triggerEventType(migratedTriggerItem.PushBranch, migratedTriggerItem.PullRequestSourceBranch, migratedTriggerItem.PullRequestTargetBranch, migratedTriggerItem.Tag)
 		if err != nil {
-			return false, err
+			return TriggerItemMatchModel{}, err
 		}
 
+		if itemMatch.EventType == "" {
+			itemMatch.EventType = itemEventType
+		}
 		if paramsEventType != itemEventType {
 			continue
 		}
+		itemMatch.EventType = itemEventType
+		itemMatch.IsEventTypeMatch = true
+
+		fieldMatches := []TriggerFieldMatchModel{}
+		addFieldMatch := func(field, pattern, value string) error {
+			fieldMatch, err := newTriggerFieldMatch(field, pattern, value)
+			if err != nil {
+				return err
+			}
+			fieldMatches = append(fieldMatches, fieldMatch)
+			return nil
+		}
 
 		switch itemEventType {
 		case TriggerEventTypeCodePush:
-			match := glob.Glob(migratedTriggerItem.PushBranch, pushBranch)
-			return match, nil
+			err = addFieldMatch("push_branch", migratedTriggerItem.PushBranch, params.PushBranch)
 		case TriggerEventTypePullRequest:
-			sourceMatch := false
-			if migratedTriggerItem.PullRequestSourceBranch == "" {
-				sourceMatch = true
-			} else {
-				sourceMatch = glob.Glob(migratedTriggerItem.PullRequestSourceBranch, prSourceBranch)
+			if migratedTriggerItem.PullRequestSourceBranch != "" {
+				err = addFieldMatch("pull_request_source_branch", migratedTriggerItem.PullRequestSourceBranch, params.PRSourceBranch)
 			}
-
-			targetMatch := false
-			if migratedTriggerItem.PullRequestTargetBranch == "" {
-				targetMatch = true
-			} else {
-				targetMatch = glob.Glob(migratedTriggerItem.PullRequestTargetBranch, prTargetBranch)
+			if err == nil && migratedTriggerItem.PullRequestTargetBranch != "" {
+				err = addFieldMatch("pull_request_target_branch", migratedTriggerItem.PullRequestTargetBranch, params.PRTargetBranch)
 			}
-
-			return (sourceMatch && targetMatch), nil
 		case TriggerEventTypeTag:
-			match := glob.Glob(migratedTriggerItem.Tag, tag)
-			return match, nil
+			err = addFieldMatch("tag", migratedTriggerItem.Tag, params.Tag)
+		}
+		if err != nil {
+			return TriggerItemMatchModel{}, err
+		}
+
+		additionalMatches, err := triggerItem.additionalConditionMatches(params, itemEventType)
+		if err != nil {
+			return TriggerItemMatchModel{}, err
 		}
+		itemMatch.Fields = append(fieldMatches, additionalMatches...)
+
+		itemMatch.Match = true
+		for _, fieldMatch := range itemMatch.Fields {
+			itemMatch.Match = itemMatch.Match && fieldMatch.Match
+		}
+		return itemMatch, nil
 	}
 
-	return false, nil
+	return itemMatch, nil
+}
+
+// isTriggerPatternCovering reports whether every value matching the pattern also matches the broader pattern.
+// Regex patterns are only compared as is, so the check might miss some of the covered patterns.
+func isTriggerPatternCovering(broaderPattern, pattern string) bool {
+	if broaderPattern == "" || broaderPattern == "*" || broaderPattern == pattern {
+		return true
+	}
+	if strings.HasPrefix(broaderPattern, TriggerRegexPatternPrefix) || strings.HasPrefix(pattern, TriggerRegexPatternPrefix) {
+		return false
+	}
+	// the glob's wildcards match the other pattern's wildcards as literal characters
+	return glob.Glob(broaderPattern, pattern)
+}
+
+// ShadowedItems returns the items, which can never be selected as an earlier, broader item of the same event type always matches first.
+// The returned map's keys are the shadowed item indexes, the values are the shadowing item indexes.
+func (triggerMap TriggerMapModel) ShadowedItems() map[int]int {
+	shadowed := map[int]int{}
+	for idx, item := range triggerMap {
+		if item.Pattern != "" {
+			continue
+		}
+		eventType, err := triggerEventType(item.PushBranch, item.PullRequestSourceBranch, item.PullRequestTargetBranch, item.Tag)
+		if err != nil {
+			continue
+		}
+
+		for earlierIdx, earlierItem := range triggerMap[:idx] {
+			if earlierItem.Pattern != "" || earlierItem.hasAdditionalConditions() {
+				continue
+			}
+			earlierEventType, err := triggerEventType(earlierItem.PushBranch, earlierItem.PullRequestSourceBranch, earlierItem.PullRequestTargetBranch, earlierItem.Tag)
+			if err != nil || earlierEventType != eventType {
+				continue
+			}
+
+			isCovered := false
+			switch eventType {
+			case TriggerEventTypeCodePush:
+				isCovered = isTriggerPatternCovering(earlierItem.PushBranch, item.PushBranch)
+			case TriggerEventTypePullRequest:
+				isCovered = isTriggerPatternCovering(earlierItem.PullRequestSourceBranch, item.PullRequestSourceBranch) &&
+					isTriggerPatternCovering(earlierItem.PullRequestTargetBranch, item.PullRequestTargetBranch)
+			case TriggerEventTypeTag:
+				isCovered = isTriggerPatternCovering(earlierItem.Tag, item.Tag)
+			}
+
+			if isCovered {
+				shadowed[idx] = earlierIdx
+				break
+			}
+		}
+	}
+	return shadowed
 }
 
 func containsWorkflowName(title string, workflowStack []string) bool {
@@ -186,14 +380,14 @@ func checkWorkflowReferenceCycle(workflowID string, workflow WorkflowModel, bitr
 			stackStr += aWorkflowID + " -> "
 		}
 		stackStr += workflowID
-		return fmt.Errorf("Workflow reference cycle found: %s", stackStr)
+		return configPathErrorf([]interface{}{"workflows", workflowID}, "Workflow reference cycle found: %s", stackStr)
 	}
 	workflowStack = append(workflowStack, workflowID)
 
-	for _, beforeWorkflowName := range workflow.BeforeRun {
+	for idx, beforeWorkflowName := range workflow.BeforeRun {
 		beforeWorkflow, exist := bitriseConfig.Workflows[beforeWorkflowName]
 		if !exist {
-			return errors.New("Workflow does not exist with name " + beforeWorkflowName)
+			return configPathErrorf([]interface{}{"workflows", workflowID, "before_run", idx}, "Workflow does not exist with name %s", beforeWorkflowName)
 		}
 
 		err := checkWorkflowReferenceCycle(beforeWorkflowName, beforeWorkflow, bitriseConfig, workflowStack)
@@ -202,10 +396,10 @@ func checkWorkflowReferenceCycle(workflowID string, workflow WorkflowModel, bitr
 		}
 	}
 
-	for _, afterWorkflowName := range workflow.AfterRun {
+	for idx, afterWorkflowName := range workflow.AfterRun {
 		afterWorkflow, exist := bitriseConfig.Workflows[afterWorkflowName]
 		if !exist {
-			return errors.New("Workflow does not exist with name " + afterWorkflowName)
+			return configPathErrorf([]interface{}{"workflows", workflowID, "after_run", idx}, "Workflow does not exist with name %s", afterWorkflowName)
 		}
 
 		err := checkWorkflowReferenceCycle(afterWorkflowName, afterWorkflow, bitriseConfig, workflowStack)
@@ -277,41 +471,87 @@ func (config *BitriseDataModel) Normalize() error {
 // ----------------------------
 // --- Validate
 
+// Error ...
+func (err ConfigPathError) Error() string {
+	return err.Message
+}
+
+// newConfigPathError locates the error at the given path,
+// the path of a ConfigPathError is relative to the given path.
+func newConfigPathError(err error, path ...interface{}) ConfigPathError {
+	if pathErr, ok := err.(ConfigPathError); ok {
+		return ConfigPathError{Path: append(append([]interface{}{}, path...), pathErr.Path...), Message: pathErr.Message}
+	}
+	return ConfigPathError{Path: path, Message: err.Error()}
+}
+
+func configPathErrorf(path []interface{}, format string, args ...interface{}) ConfigPathError {
+	return ConfigPathError{Path: path, Message: fmt.Sprintf(format, args...)}
+}
+
+func prefixConfigPathErrors(errs []ConfigPathError, path ...interface{}) []ConfigPathError {
+	prefixed := make([]ConfigPathError, 0, len(errs))
+	for _, err := range errs {
+		prefixed = append(prefixed, newConfigPathError(err, path...))
+	}
+	return prefixed
+}
+
+func configPathErrorMessages(errs []ConfigPathError) []string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Message)
+	}
+	return messages
+}
+
 // Validate ...
 func (workflow *WorkflowModel) Validate() ([]string, error) {
-	for _, env := range workflow.Environments {
+	warnings, err := workflow.validate()
+	return configPathErrorMessages(warnings), err
+}
+
+// validate returns the warnings and the error, located relative to the workflow.
+func (workflow *WorkflowModel) validate() ([]ConfigPathError, error) {
+	for idx, env := range workflow.Environments {
 		if err := env.Validate(); err != nil {
-			return []string{}, err
+			return []ConfigPathError{}, newConfigPathError(err, "envs", idx)
 		}
 	}
 
-	warnings := []string{}
-	for _, stepListItem := range workflow.Steps {
+	warnings := []ConfigPathError{}
+	for idx, stepListItem := range workflow.Steps {
 		stepID, step, err := GetStepIDStepDataPair(stepListItem)
 		if err != nil {
-			return warnings, err
+			return warnings, newConfigPathError(err, "steps", idx)
 		}
 
 		if ver, src := getStepVersion(stepID), getStepSource(stepID); len(ver) > 0 && isStepLibSource(src) {
 			if _, err := stepmanModels.ParseRequiredVersion(ver); err != nil {
-				return warnings, fmt.Errorf("invalid version format (%s) specified for step ID: %s", ver, stepID)
+				return warnings, configPathErrorf([]interface{}{"steps", idx}, "invalid version format (%s) specified for step ID: %s", ver, stepID)
 			}
 		}
 
 		if err := step.ValidateInputAndOutputEnvs(false); err != nil {
-			return warnings, err
+			return warnings, newConfigPathError(err, "steps", idx, stepID)
+		}
+
+		if step.ID != nil {
+			if err := ValidateStepID(*step.ID); err != nil {
+				return warnings, newConfigPathError(err, "steps", idx, stepID, "id")
+			}
 		}
 
 		stepInputMap := map[string]bool{}
-		for _, input := range step.Inputs {
+		for inputIdx, input := range step.Inputs {
 			key, _, err := input.GetKeyValuePair()
 			if err != nil {
-				return warnings, err
+				return warnings, newConfigPathError(err, "steps", idx, stepID, "inputs", inputIdx)
 			}
 
 			_, found := stepInputMap[key]
 			if found {
-				warnings = append(warnings, fmt.Sprintf("invalid step: duplicated input found: (%s)", key))
+				warnings = append(warnings, configPathErrorf([]interface{}{"steps", idx, stepID, "inputs", inputIdx}, "invalid step: duplicated input found: (%s)", key))
 			}
 			stepInputMap[key] = true
 		}
@@ -319,14 +559,40 @@ func (workflow *WorkflowModel) Validate() ([]string, error) {
 		stepListItem[stepID] = step
 	}
 
+	for idx, key := range workflow.ExportEnvs {
+		if key == "" {
+			return warnings, configPathErrorf([]interface{}{"export_envs", idx}, "invalid export_envs: empty env key")
+		}
+	}
+	if len(workflow.ExportEnvs) > 0 && !workflow.IsolateEnvs {
+		warnings = append(warnings, configPathErrorf([]interface{}{"export_envs"}, "export_envs has no effect without isolate_envs: true, every env of the workflow is kept"))
+	}
+
+	if workflow.Container != nil {
+		if err := workflow.Container.Validate(); err != nil {
+			return warnings, newConfigPathError(err, "container")
+		}
+	}
+
 	return warnings, nil
 }
 
+// Validate ...
+func (container ContainerModel) Validate() error {
+	if container.Image == "" {
+		return errors.New("invalid container: missing image")
+	}
+	if container.Engine != "" && !sliceutil.IsStringInSlice(container.Engine, ContainerEngines) {
+		return fmt.Errorf("invalid container: unknown engine (%s), supported engines: %s", container.Engine, strings.Join(ContainerEngines, ", "))
+	}
+	return nil
+}
+
 // Validate ...
 func (app *AppModel) Validate() error {
-	for _, env := range app.Environments {
+	for idx, env := range app.Environments {
 		if err := env.Validate(); err != nil {
-			return err
+			return newConfigPathError(err, "envs", idx)
 		}
 	}
 	return nil
@@ -349,6 +615,21 @@ func (triggerItem TriggerMapItemModel) Validate() error {
 	} else if triggerItem.PushBranch != "" ||
 		triggerItem.PullRequestSourceBranch != "" || triggerItem.PullRequestTargetBranch != "" || triggerItem.Tag != "" {
 		return fmt.Errorf("deprecated trigger item (pattern defined), mixed with trigger params (push_branch: %s, pull_request_source_branch: %s, pull_request_target_branch: %s, tag: %s)", triggerItem.PushBranch, triggerItem.PullRequestSourceBranch, triggerItem.PullRequestTargetBranch, triggerItem.Tag)
+	} else if triggerItem.hasAdditionalConditions() {
+		return fmt.Errorf("deprecated trigger item (pattern defined), mixed with trigger conditions (%s)", triggerItem.String(false))
+	}
+
+	if triggerItem.PullRequestLabel != "" || triggerItem.DraftPullRequestEnabled != nil {
+		eventType, _ := triggerEventType(triggerItem.PushBranch, triggerItem.PullRequestSourceBranch, triggerItem.PullRequestTargetBranch, triggerItem.Tag)
+		if eventType != TriggerEventTypePullRequest {
+			return fmt.Errorf("trigger map item (%s) validate failed, error: pull_request_label and draft_pull_request_enabled are only available for pull request items", triggerItem.String(true))
+		}
+	}
+
+	for _, pattern := range []string{triggerItem.PushBranch, triggerItem.PullRequestSourceBranch, triggerItem.PullRequestTargetBranch, triggerItem.Tag, triggerItem.CommitMessage, triggerItem.PullRequestLabel} {
+		if _, err := matchTriggerPattern(pattern, ""); err != nil {
+			return fmt.Errorf("trigger map item (%s) validate failed, error: %s", triggerItem.String(true), err)
+		}
 	}
 
 	return nil
@@ -356,9 +637,9 @@ func (triggerItem TriggerMapItemModel) Validate() error {
 
 // Validate ...
 func (triggerMap TriggerMapModel) Validate() error {
-	for _, item := range triggerMap {
+	for idx, item := range triggerMap {
 		if err := item.Validate(); err != nil {
-			return err
+			return newConfigPathError(err, idx)
 		}
 	}
 
@@ -368,29 +649,34 @@ func (triggerMap TriggerMapModel) Validate() error {
 func checkDuplicatedTriggerMapItems(triggerMap TriggerMapModel) error {
 	triggeTypeItemMap := map[string][]TriggerMapItemModel{}
 
-	for _, triggerItem := range triggerMap {
+	for idx, triggerItem := range triggerMap {
 		if triggerItem.Pattern == "" {
 			triggerType, err := triggerEventType(triggerItem.PushBranch, triggerItem.PullRequestSourceBranch, triggerItem.PullRequestTargetBranch, triggerItem.Tag)
 			if err != nil {
-				return fmt.Errorf("trigger map item (%v) validate failed, error: %s", triggerItem, err)
+				return configPathErrorf([]interface{}{idx}, "trigger map item (%v) validate failed, error: %s", triggerItem, err)
 			}
 
 			triggerItems := triggeTypeItemMap[string(triggerType)]
 
 			for _, item := range triggerItems {
+				if triggerItem.hasAdditionalConditions() || item.hasAdditionalConditions() {
+					// items of the same branch can be narrowed down by further conditions, e.g. the changed files per app of a monorepo
+					continue
+				}
+
 				switch triggerType {
 				case TriggerEventTypeCodePush:
 					if triggerItem.PushBranch == item.PushBranch {
-						return fmt.Errorf("duplicated trigger item found (%s)", triggerItem.String(false))
+						return configPathErrorf([]interface{}{idx}, "duplicated trigger item found (%s)", triggerItem.String(false))
 					}
 				case TriggerEventTypePullRequest:
 					if triggerItem.PullRequestSourceBranch == item.PullRequestSourceBranch &&
 						triggerItem.PullRequestTargetBranch == item.PullRequestTargetBranch {
-						return fmt.Errorf("duplicated trigger item found (%s)", triggerItem.String(false))
+						return configPathErrorf([]interface{}{idx}, "duplicated trigger item found (%s)", triggerItem.String(false))
 					}
 				case TriggerEventTypeTag:
 					if triggerItem.Tag == item.Tag {
-						return fmt.Errorf("duplicated trigger item found (%s)", triggerItem.String(false))
+						return configPathErrorf([]interface{}{idx}, "duplicated trigger item found (%s)", triggerItem.String(false))
 					}
 				}
 			}
@@ -403,7 +689,7 @@ func checkDuplicatedTriggerMapItems(triggerMap TriggerMapModel) error {
 			for _, item := range triggerItems {
 				if triggerItem.Pattern == item.Pattern &&
 					triggerItem.IsPullRequestAllowed == item.IsPullRequestAllowed {
-					return fmt.Errorf("duplicated trigger item found (%s)", triggerItem.String(false))
+					return configPathErrorf([]interface{}{idx}, "duplicated trigger item found (%s)", triggerItem.String(false))
 				}
 			}
 
@@ -417,20 +703,27 @@ func checkDuplicatedTriggerMapItems(triggerMap TriggerMapModel) error {
 
 // Validate ...
 func (config *BitriseDataModel) Validate() ([]string, error) {
-	warnings := []string{}
+	warnings, err := config.ValidateWithPaths()
+	return configPathErrorMessages(warnings), err
+}
+
+// ValidateWithPaths validates the config like Validate, but the warnings and the error (a ConfigPathError)
+// are located by the path of the offending config node.
+func (config *BitriseDataModel) ValidateWithPaths() ([]ConfigPathError, error) {
+	warnings := []ConfigPathError{}
 
 	if config.FormatVersion == "" {
-		return warnings, fmt.Errorf("missing format_version")
+		return warnings, configPathErrorf(nil, "missing format_version")
 	}
 
 	// trigger map
 	if err := config.TriggerMap.Validate(); err != nil {
-		return warnings, err
+		return warnings, newConfigPathError(err, "trigger_map")
 	}
 
-	for _, triggerMapItem := range config.TriggerMap {
+	for idx, triggerMapItem := range config.TriggerMap {
 		if strings.HasPrefix(triggerMapItem.WorkflowID, "_") {
-			warnings = append(warnings, fmt.Sprintf("workflow (%s) defined in trigger item (%s), but utility workflows can't be triggered directly", triggerMapItem.WorkflowID, triggerMapItem.String(true)))
+			warnings = append(warnings, configPathErrorf([]interface{}{"trigger_map", idx, "workflow"}, "workflow (%s) defined in trigger item (%s), but utility workflows can't be triggered directly", triggerMapItem.WorkflowID, triggerMapItem.String(true)))
 		}
 
 		found := false
@@ -443,7 +736,7 @@ func (config *BitriseDataModel) Validate() ([]string, error) {
 			}
 
 			if !found {
-				return warnings, fmt.Errorf("pipeline (%s) defined in trigger item (%s), but does not exist", triggerMapItem.PipelineID, triggerMapItem.String(true))
+				return warnings, configPathErrorf([]interface{}{"trigger_map", idx, "pipeline"}, "pipeline (%s) defined in trigger item (%s), but does not exist", triggerMapItem.PipelineID, triggerMapItem.String(true))
 			}
 		} else {
 			for workflowID := range config.Workflows {
@@ -454,19 +747,19 @@ func (config *BitriseDataModel) Validate() ([]string, error) {
 			}
 
 			if !found {
-				return warnings, fmt.Errorf("workflow (%s) defined in trigger item (%s), but does not exist", triggerMapItem.WorkflowID, triggerMapItem.String(true))
+				return warnings, configPathErrorf([]interface{}{"trigger_map", idx, "workflow"}, "workflow (%s) defined in trigger item (%s), but does not exist", triggerMapItem.WorkflowID, triggerMapItem.String(true))
 			}
 		}
 	}
 
 	if err := checkDuplicatedTriggerMapItems(config.TriggerMap); err != nil {
-		return warnings, err
+		return warnings, newConfigPathError(err, "trigger_map")
 	}
 	// ---
 
 	// app
 	if err := config.App.Validate(); err != nil {
-		return warnings, err
+		return warnings, newConfigPathError(err, "app")
 	}
 	// ---
 
@@ -497,25 +790,33 @@ func (config *BitriseDataModel) Validate() ([]string, error) {
 	return warnings, nil
 }
 
-func validatePipelines(config *BitriseDataModel) ([]string, error) {
-	pipelineWarnings := make([]string, 0)
-	for ID, pipeline := range config.Pipelines {
+func validatePipelines(config *BitriseDataModel) ([]ConfigPathError, error) {
+	IDs := []string{}
+	for ID := range config.Pipelines {
+		IDs = append(IDs, ID)
+	}
+	// validated in order, to report the same issue on every validation
+	sort.Strings(IDs)
+
+	pipelineWarnings := make([]ConfigPathError, 0)
+	for _, ID := range IDs {
+		pipeline := config.Pipelines[ID]
 		idWarning, err := validateID(ID, "pipeline")
 		if idWarning != "" {
-			pipelineWarnings = append(pipelineWarnings, idWarning)
+			pipelineWarnings = append(pipelineWarnings, configPathErrorf([]interface{}{"pipelines", ID}, "%s", idWarning))
 		}
 		if err != nil {
-			return pipelineWarnings, err
+			return pipelineWarnings, newConfigPathError(err, "pipelines", ID)
 		}
 
 		if len(pipeline.Stages) == 0 {
-			return pipelineWarnings, fmt.Errorf("pipeline (%s) should have at least 1 stage", ID)
+			return pipelineWarnings, configPathErrorf([]interface{}{"pipelines", ID}, "pipeline (%s) should have at least 1 stage", ID)
 		}
 
-		for _, pipelineStage := range pipeline.Stages {
+		for idx, pipelineStage := range pipeline.Stages {
 			pipelineStageID, err := GetStageIDFromListItemModel(pipelineStage)
 			if err != nil {
-				return pipelineWarnings, err
+				return pipelineWarnings, newConfigPathError(err, "pipelines", ID, "stages", idx)
 			}
 			found := false
 			for stageID := range config.Stages {
@@ -525,7 +826,7 @@ func validatePipelines(config *BitriseDataModel) ([]string, error) {
 				}
 			}
 			if !found {
-				return pipelineWarnings, fmt.Errorf("stage (%s) defined in pipeline (%s), but does not exist", pipelineStageID, ID)
+				return pipelineWarnings, configPathErrorf([]interface{}{"pipelines", ID, "stages", idx}, "stage (%s) defined in pipeline (%s), but does not exist", pipelineStageID, ID)
 			}
 		}
 	}
@@ -533,26 +834,33 @@ func validatePipelines(config *BitriseDataModel) ([]string, error) {
 	return pipelineWarnings, nil
 }
 
-func validateStages(config *BitriseDataModel) ([]string, error) {
-	stageWarnings := make([]string, 0)
-	for ID, stage := range config.Stages {
+func validateStages(config *BitriseDataModel) ([]ConfigPathError, error) {
+	IDs := []string{}
+	for ID := range config.Stages {
+		IDs = append(IDs, ID)
+	}
+	sort.Strings(IDs)
+
+	stageWarnings := make([]ConfigPathError, 0)
+	for _, ID := range IDs {
+		stage := config.Stages[ID]
 		idWarning, err := validateID(ID, "stage")
 		if idWarning != "" {
-			stageWarnings = append(stageWarnings, idWarning)
+			stageWarnings = append(stageWarnings, configPathErrorf([]interface{}{"stages", ID}, "%s", idWarning))
 		}
 		if err != nil {
-			return stageWarnings, err
+			return stageWarnings, newConfigPathError(err, "stages", ID)
 		}
 
 		if len(stage.Workflows) == 0 {
-			return stageWarnings, fmt.Errorf("stage (%s) should have at least 1 workflow", ID)
+			return stageWarnings, configPathErrorf([]interface{}{"stages", ID}, "stage (%s) should have at least 1 workflow", ID)
 		}
 
-		for _, stageWorkflow := range stage.Workflows {
+		for idx, stageWorkflow := range stage.Workflows {
 			found := false
 			stageWorkflowID, err := GetWorkflowIDFromListItemModel(stageWorkflow)
 			if err != nil {
-				return stageWarnings, err
+				return stageWarnings, newConfigPathError(err, "stages", ID, "workflows", idx)
 			}
 			for workflowID := range config.Workflows {
 				if workflowID == stageWorkflowID {
@@ -561,7 +869,7 @@ func validateStages(config *BitriseDataModel) ([]string, error) {
 				}
 			}
 			if !found {
-				return stageWarnings, fmt.Errorf("workflow (%s) defined in stage (%s), but does not exist", stageWorkflowID, ID)
+				return stageWarnings, configPathErrorf([]interface{}{"stages", ID, "workflows", idx}, "workflow (%s) defined in stage (%s), but does not exist", stageWorkflowID, ID)
 			}
 		}
 	}
@@ -569,26 +877,39 @@ func validateStages(config *BitriseDataModel) ([]string, error) {
 	return stageWarnings, nil
 }
 
-func validateWorkflows(config *BitriseDataModel) ([]string, error) {
-	workflowWarnings := make([]string, 0)
-	for ID, workflow := range config.Workflows {
+func validateWorkflows(config *BitriseDataModel) ([]ConfigPathError, error) {
+	IDs := []string{}
+	for ID := range config.Workflows {
+		IDs = append(IDs, ID)
+	}
+	sort.Strings(IDs)
+
+	workflowWarnings := make([]ConfigPathError, 0)
+	for _, ID := range IDs {
+		workflow := config.Workflows[ID]
 		idWarning, err := validateID(ID, "workflow")
 		if idWarning != "" {
-			workflowWarnings = append(workflowWarnings, idWarning)
+			workflowWarnings = append(workflowWarnings, configPathErrorf([]interface{}{"workflows", ID}, "%s", idWarning))
 		}
 		if err != nil {
-			return workflowWarnings, err
+			return workflowWarnings, newConfigPathError(err, "workflows", ID)
 		}
 
-		warns, err := workflow.Validate()
-		workflowWarnings = append(workflowWarnings, warns...)
+		warns, err := workflow.validate()
+		workflowWarnings = append(workflowWarnings, prefixConfigPathErrors(warns, "workflows", ID)...)
 		if err != nil {
-			return workflowWarnings, fmt.Errorf("validation error in workflow: %s: %s", ID, err)
+			pathErr := newConfigPathError(err, "workflows", ID)
+			pathErr.Message = fmt.Sprintf("validation error in workflow: %s: %s", ID, err)
+			return workflowWarnings, pathErr
 		}
 
 		if err := checkWorkflowReferenceCycle(ID, workflow, *config, []string{}); err != nil {
 			return workflowWarnings, err
 		}
+
+		if err := checkStepIDsUnique(ID, *config); err != nil {
+			return workflowWarnings, err
+		}
 	}
 
 	return workflowWarnings, nil
@@ -890,7 +1211,7 @@ func getOutputByKey(step stepmanModels.StepModel, key string) (envmanModels.Envi
 }
 
 // MergeStepWith ...
-func MergeStepWith(step, otherStep stepmanModels.StepModel) (stepmanModels.StepModel, error) {
+func MergeStepWith(step, otherStep StepModel) (StepModel, error) {
 	if otherStep.Title != nil {
 		step.Title = pointers.NewStringPtr(*otherStep.Title)
 	}
@@ -943,6 +1264,11 @@ func MergeStepWith(step, otherStep stepmanModels.StepModel) (stepmanModels.StepM
 	if otherStep.Toolkit != nil {
 		step.Toolkit = new(stepmanModels.StepToolkitModel)
 		*step.Toolkit = *otherStep.Toolkit
+		step.StepToolkit = nil
+	}
+	if otherStep.StepToolkit != nil {
+		step.StepToolkit = new(StepToolkitModel)
+		*step.StepToolkit = *otherStep.StepToolkit
 	}
 	if otherStep.Deps != nil && (len(otherStep.Deps.Brew) > 0 || len(otherStep.Deps.AptGet) > 0 || len(otherStep.Deps.CheckOnly) > 0) {
 		step.Deps = otherStep.Deps
@@ -960,6 +1286,13 @@ func MergeStepWith(step, otherStep stepmanModels.StepModel) (stepmanModels.StepM
 	if otherStep.RunIf != nil {
 		step.RunIf = pointers.NewStringPtr(*otherStep.RunIf)
 	}
+	if otherStep.ChangedFiles != nil {
+		step.ChangedFiles = new(ChangedFilesFilterModel)
+		*step.ChangedFiles = *otherStep.ChangedFiles
+	}
+	if otherStep.ID != nil {
+		step.ID = pointers.NewStringPtr(*otherStep.ID)
+	}
 	if otherStep.Timeout != nil {
 		step.Timeout = pointers.NewIntPtr(*otherStep.Timeout)
 	}
@@ -967,13 +1300,13 @@ func MergeStepWith(step, otherStep stepmanModels.StepModel) (stepmanModels.StepM
 	for _, input := range step.Inputs {
 		key, _, err := input.GetKeyValuePair()
 		if err != nil {
-			return stepmanModels.StepModel{}, err
+			return StepModel{}, err
 		}
-		otherInput, found := getInputByKey(otherStep, key)
+		otherInput, found := getInputByKey(otherStep.StepModel, key)
 		if found {
 			err := MergeEnvironmentWith(&input, otherInput)
 			if err != nil {
-				return stepmanModels.StepModel{}, err
+				return StepModel{}, err
 			}
 		}
 	}
@@ -981,13 +1314,13 @@ func MergeStepWith(step, otherStep stepmanModels.StepModel) (stepmanModels.StepM
 	for _, output := range step.Outputs {
 		key, _, err := output.GetKeyValuePair()
 		if err != nil {
-			return stepmanModels.StepModel{}, err
+			return StepModel{}, err
 		}
-		otherOutput, found := getOutputByKey(otherStep, key)
+		otherOutput, found := getOutputByKey(otherStep.StepModel, key)
 		if found {
 			err := MergeEnvironmentWith(&output, otherOutput)
 			if err != nil {
-				return stepmanModels.StepModel{}, err
+				return StepModel{}, err
 			}
 		}
 	}
@@ -1027,14 +1360,14 @@ func GetStageIDFromListItemModel(stageListItem StageListItemModel) (string, erro
 // --- StepIDData
 
 // GetStepIDStepDataPair ...
-func GetStepIDStepDataPair(stepListItem StepListItemModel) (string, stepmanModels.StepModel, error) {
+func GetStepIDStepDataPair(stepListItem StepListItemModel) (string, StepModel, error) {
 	if len(stepListItem) > 1 {
-		return "", stepmanModels.StepModel{}, errors.New("StepListItem contains more than 1 key-value pair")
+		return "", StepModel{}, errors.New("StepListItem contains more than 1 key-value pair")
 	}
 	for key, value := range stepListItem {
 		return key, value, nil
 	}
-	return "", stepmanModels.StepModel{}, errors.New("StepListItem does not contain a key-value pair")
+	return "", StepModel{}, errors.New("StepListItem does not contain a key-value pair")
 }
 
 // detaches source from the step node
@@ -29,6 +29,24 @@ func TestCheckDuplicatedTriggerMapItems(t *testing.T) {
 		require.EqualError(t, err, "duplicated trigger item found (push_branch: master)")
 	}
 
+	t.Log("same push branch narrowed down by changed files - no error")
+	{
+		err := checkDuplicatedTriggerMapItems(TriggerMapModel{
+			TriggerMapItemModel{
+				PushBranch:   "master",
+				WorkflowID:   "ios",
+				ChangedFiles: &ChangedFilesFilterModel{Include: []string{"apps/ios/**"}},
+			},
+			TriggerMapItemModel{
+				PushBranch:   "master",
+				WorkflowID:   "android",
+				ChangedFiles: &ChangedFilesFilterModel{Include: []string{"apps/android/**"}},
+			},
+		})
+
+		require.NoError(t, err)
+	}
+
 	t.Log("duplicated pull request - error")
 	{
 		err := checkDuplicatedTriggerMapItems(TriggerMapModel{
@@ -438,6 +456,46 @@ workflows:
 		require.NoError(t, item.Validate())
 	}
 
+	t.Log("it fails for invalid regex pattern")
+	{
+		item := TriggerMapItemModel{
+			PushBranch: "regex:release/(",
+			WorkflowID: "primary",
+		}
+		require.Error(t, item.Validate())
+	}
+
+	t.Log("it fails for pull request conditions of code-push trigger item")
+	{
+		item := TriggerMapItemModel{
+			PushBranch:       "*",
+			PullRequestLabel: "ui",
+			WorkflowID:       "primary",
+		}
+		require.Error(t, item.Validate())
+	}
+
+	t.Log("it validates pull request trigger item with label and draft conditions")
+	{
+		item := TriggerMapItemModel{
+			PullRequestSourceBranch: "*",
+			PullRequestLabel:        "regex:^ui-",
+			DraftPullRequestEnabled: pointers.NewBoolPtr(false),
+			WorkflowID:              "primary",
+		}
+		require.NoError(t, item.Validate())
+	}
+
+	t.Log("it fails for deprecated trigger item with trigger conditions")
+	{
+		item := TriggerMapItemModel{
+			Pattern:       "*",
+			CommitMessage: "*deploy*",
+			WorkflowID:    "primary",
+		}
+		require.Error(t, item.Validate())
+	}
+
 	t.Log("it fails for invalid code-push trigger item - missing push-branch")
 	{
 		item := TriggerMapItemModel{
@@ -855,10 +913,101 @@ func TestMatchWithParamsTagTypeItem(t *testing.T) {
 	}
 }
 
+func TestMatchWithTriggerParams(t *testing.T) {
+	t.Log("regex push branch - MATCH")
+	{
+		item := TriggerMapItemModel{
+			PushBranch: "regex:^release/[0-9.]+$",
+			WorkflowID: "release",
+		}
+		match, err := item.MatchWithTriggerParams(TriggerParamsModel{PushBranch: "release/1.2.0"})
+		require.NoError(t, err)
+		require.Equal(t, true, match)
+
+		match, err = item.MatchWithTriggerParams(TriggerParamsModel{PushBranch: "release/next"})
+		require.NoError(t, err)
+		require.Equal(t, false, match)
+	}
+
+	t.Log("commit message")
+	{
+		item := TriggerMapItemModel{
+			PushBranch:    "*",
+			CommitMessage: "regex:\\[deploy\\]",
+			WorkflowID:    "deploy",
+		}
+		match, err := item.MatchWithTriggerParams(TriggerParamsModel{PushBranch: "master", CommitMessage: "Fix crash [deploy]"})
+		require.NoError(t, err)
+		require.Equal(t, true, match)
+
+		match, err = item.MatchWithTriggerParams(TriggerParamsModel{PushBranch: "master", CommitMessage: "Fix crash"})
+		require.NoError(t, err)
+		require.Equal(t, false, match)
+	}
+
+	t.Log("pull request label")
+	{
+		item := TriggerMapItemModel{
+			PullRequestTargetBranch: "master",
+			PullRequestLabel:        "ui-*",
+			WorkflowID:              "ui-test",
+		}
+		match, err := item.MatchWithTriggerParams(TriggerParamsModel{PRSourceBranch: "feature", PRTargetBranch: "master", PRLabels: []string{"bug", "ui-change"}})
+		require.NoError(t, err)
+		require.Equal(t, true, match)
+
+		match, err = item.MatchWithTriggerParams(TriggerParamsModel{PRSourceBranch: "feature", PRTargetBranch: "master", PRLabels: []string{"bug"}})
+		require.NoError(t, err)
+		require.Equal(t, false, match)
+	}
+
+	t.Log("draft pull request")
+	{
+		item := TriggerMapItemModel{
+			PullRequestTargetBranch: "master",
+			DraftPullRequestEnabled: pointers.NewBoolPtr(false),
+			WorkflowID:              "ci",
+		}
+		match, err := item.MatchWithTriggerParams(TriggerParamsModel{PRSourceBranch: "feature", PRTargetBranch: "master", IsDraftPR: true})
+		require.NoError(t, err)
+		require.Equal(t, false, match)
+
+		match, err = item.MatchWithTriggerParams(TriggerParamsModel{PRSourceBranch: "feature", PRTargetBranch: "master"})
+		require.NoError(t, err)
+		require.Equal(t, true, match)
+
+		item.DraftPullRequestEnabled = nil
+		match, err = item.MatchWithTriggerParams(TriggerParamsModel{PRSourceBranch: "feature", PRTargetBranch: "master", IsDraftPR: true})
+		require.NoError(t, err)
+		require.Equal(t, true, match)
+	}
+}
+
+func TestIsSkipCICommitMessage(t *testing.T) {
+	require.Equal(t, true, IsSkipCICommitMessage("Update README [skip ci]"))
+	require.Equal(t, true, IsSkipCICommitMessage("[CI SKIP] Update README"))
+	require.Equal(t, false, IsSkipCICommitMessage("Skip the CI step in tests"))
+}
+
 // ----------------------------
 // --- Validate
 
 // Config
+func TestShadowedItems(t *testing.T) {
+	triggerMap := TriggerMapModel{
+		TriggerMapItemModel{PushBranch: "master", WorkflowID: "ios", ChangedFiles: &ChangedFilesFilterModel{Include: []string{"apps/ios/**"}}},
+		TriggerMapItemModel{PushBranch: "release/*", WorkflowID: "release"},
+		TriggerMapItemModel{PushBranch: "master", WorkflowID: "master"},
+		TriggerMapItemModel{PushBranch: "release/1.*", WorkflowID: "release-1"},
+		TriggerMapItemModel{PushBranch: "*", WorkflowID: "primary"},
+		TriggerMapItemModel{PushBranch: "regex:^hotfix/", WorkflowID: "hotfix"},
+		TriggerMapItemModel{PullRequestTargetBranch: "master", WorkflowID: "pr"},
+		TriggerMapItemModel{Tag: "*", WorkflowID: "tag"},
+	}
+
+	require.Equal(t, map[int]int{3: 1, 5: 4}, triggerMap.ShadowedItems())
+}
+
 func TestValidateConfig(t *testing.T) {
 	t.Log("Valid bitriseData")
 	{
@@ -1134,6 +1283,107 @@ func TestValidateConfig(t *testing.T) {
 }
 
 // Workflow
+func TestValidateConfigWithPaths(t *testing.T) {
+	t.Log("trigger map item references a missing workflow")
+	{
+		bitriseData := BitriseDataModel{
+			FormatVersion: "1.4.0",
+			TriggerMap: TriggerMapModel{
+				TriggerMapItemModel{PushBranch: "master", WorkflowID: "_setup"},
+				TriggerMapItemModel{Tag: "*", WorkflowID: "deploy"},
+			},
+			Workflows: map[string]WorkflowModel{
+				"_setup": WorkflowModel{},
+			},
+		}
+
+		warnings, err := bitriseData.ValidateWithPaths()
+		require.Equal(t, []ConfigPathError{
+			ConfigPathError{
+				Path:    []interface{}{"trigger_map", 0, "workflow"},
+				Message: "workflow (_setup) defined in trigger item (push_branch: master -> workflow: _setup), but utility workflows can't be triggered directly",
+			},
+		}, warnings)
+		require.Equal(t, ConfigPathError{
+			Path:    []interface{}{"trigger_map", 1, "workflow"},
+			Message: "workflow (deploy) defined in trigger item (tag: * -> workflow: deploy), but does not exist",
+		}, err)
+	}
+
+	t.Log("invalid trigger map item")
+	{
+		bitriseData := BitriseDataModel{
+			FormatVersion: "1.4.0",
+			TriggerMap: TriggerMapModel{
+				TriggerMapItemModel{PushBranch: "master", WorkflowID: "primary"},
+				TriggerMapItemModel{PushBranch: "master", WorkflowID: "primary"},
+			},
+			Workflows: map[string]WorkflowModel{
+				"primary": WorkflowModel{},
+			},
+		}
+
+		_, err := bitriseData.ValidateWithPaths()
+		require.Equal(t, ConfigPathError{
+			Path:    []interface{}{"trigger_map", 1},
+			Message: "duplicated trigger item found (push_branch: master)",
+		}, err)
+	}
+
+	t.Log("workflow issues are located in the workflow")
+	{
+		bitriseData := BitriseDataModel{
+			FormatVersion: "1.4.0",
+			Workflows: map[string]WorkflowModel{
+				"primary": WorkflowModel{
+					Steps: []StepListItemModel{
+						StepListItemModel{"script@1": NewStepModel(stepmanModels.StepModel{
+							Inputs: []envmanModels.EnvironmentItemModel{
+								envmanModels.EnvironmentItemModel{"content": "a"},
+								envmanModels.EnvironmentItemModel{"content": "b"},
+							},
+						})},
+					},
+					Container: &ContainerModel{},
+				},
+			},
+		}
+
+		warnings, err := bitriseData.ValidateWithPaths()
+		require.Equal(t, []ConfigPathError{
+			ConfigPathError{
+				Path:    []interface{}{"workflows", "primary", "steps", 0, "script@1", "inputs", 1},
+				Message: "invalid step: duplicated input found: (content)",
+			},
+		}, warnings)
+		require.Equal(t, ConfigPathError{
+			Path:    []interface{}{"workflows", "primary", "container"},
+			Message: "validation error in workflow: primary: invalid container: missing image",
+		}, err)
+
+		stringWarnings, stringErr := bitriseData.Validate()
+		require.Equal(t, []string{"invalid step: duplicated input found: (content)"}, stringWarnings)
+		require.EqualError(t, stringErr, "validation error in workflow: primary: invalid container: missing image")
+	}
+
+	t.Log("missing before run workflow")
+	{
+		bitriseData := BitriseDataModel{
+			FormatVersion: "1.4.0",
+			Workflows: map[string]WorkflowModel{
+				"primary": WorkflowModel{BeforeRun: []string{"_setup", "_missing"}},
+				"_setup":  WorkflowModel{},
+			},
+		}
+
+		_, err := bitriseData.ValidateWithPaths()
+		require.Equal(t, ConfigPathError{
+			Path:    []interface{}{"workflows", "primary", "before_run", 1},
+			Message: "Workflow does not exist with name _missing",
+		}, err)
+	}
+}
+
 func TestValidateWorkflow(t *testing.T) {
 	t.Log("before-after test")
 	{
@@ -1269,7 +1519,7 @@ func TestMergeStepWith(t *testing.T) {
 	fork := "fork/1"
 	published := time.Date(2012, time.January, 1, 0, 0, 0, 0, time.UTC)
 
-	stepData := stepmanModels.StepModel{
+	stepData := NewStepModel(stepmanModels.StepModel{
 		Description:         pointers.NewStringPtr(desc),
 		Summary:             pointers.NewStringPtr(summ),
 		Website:             pointers.NewStringPtr(website),
@@ -1288,12 +1538,12 @@ func TestMergeStepWith(t *testing.T) {
 			},
 		},
 		Outputs: []envmanModels.EnvironmentItemModel{},
-	}
+	})
 
 	diffTitle := "name 2"
 	newSuppURL := "supp"
 	runIfStr := ""
-	stepDiffToMerge := stepmanModels.StepModel{
+	stepDiffToMerge := NewStepModel(stepmanModels.StepModel{
 		Title:      pointers.NewStringPtr(diffTitle),
 		HostOsTags: []string{"linux"},
 		Source: &stepmanModels.StepSourceModel{
@@ -1318,7 +1568,7 @@ func TestMergeStepWith(t *testing.T) {
 				PackageName: "test",
 			},
 		},
-	}
+	})
 
 	mergedStepData, err := MergeStepWith(stepData, stepDiffToMerge)
 	require.NoError(t, err)
@@ -1497,7 +1747,7 @@ func Test_StepIDData_IsUniqueResourceID(t *testing.T) {
 }
 
 func TestGetStepIDStepDataPair(t *testing.T) {
-	stepData := stepmanModels.StepModel{}
+	stepData := NewStepModel(stepmanModels.StepModel{})
 
 	t.Log("valid steplist item")
 	{
@@ -1882,3 +2132,70 @@ workflows:
 		}
 	}
 }
+
+func TestContainerModelValidate(t *testing.T) {
+	require.NoError(t, ContainerModel{Image: "ubuntu:20.04"}.Validate())
+	require.NoError(t, ContainerModel{Image: "ubuntu:20.04", Engine: "podman"}.Validate())
+	require.EqualError(t, ContainerModel{}.Validate(), "invalid container: missing image")
+	require.EqualError(t, ContainerModel{Image: "ubuntu:20.04", Engine: "lxc"}.Validate(), "invalid container: unknown engine (lxc), supported engines: docker, podman")
+
+	t.Log("workflow container")
+	{
+		configStr := `format_version: 11
+workflows:
+  primary:
+    container:
+      image: ubuntu:20.04
+      engine: docker
+`
+		config := BitriseDataModel{}
+		require.NoError(t, yaml.Unmarshal([]byte(configStr), &config))
+		require.Equal(t, &ContainerModel{Image: "ubuntu:20.04", Engine: "docker"}, config.Workflows["primary"].Container)
+
+		workflow := config.Workflows["primary"]
+		workflow.Container.Image = ""
+		_, err := workflow.Validate()
+		require.Error(t, err)
+	}
+}
+
+func TestWorkflowModelValidateIsolateEnvs(t *testing.T) {
+	t.Log("isolated workflow")
+	{
+		configStr := `format_version: 11
+workflows:
+  _setup:
+    isolate_envs: true
+    export_envs:
+    - SCHEME
+    envs:
+    - SCHEME: App
+    - TMP_DIR: /tmp/setup
+`
+		config := BitriseDataModel{}
+		require.NoError(t, yaml.Unmarshal([]byte(configStr), &config))
+
+		workflow := config.Workflows["_setup"]
+		require.Equal(t, true, workflow.IsolateEnvs)
+		require.Equal(t, []string{"SCHEME"}, workflow.ExportEnvs)
+
+		warnings, err := workflow.Validate()
+		require.NoError(t, err)
+		require.Equal(t, 0, len(warnings))
+	}
+
+	t.Log("export_envs without isolate_envs")
+	{
+		workflow := WorkflowModel{ExportEnvs: []string{"SCHEME"}}
+		warnings, err := workflow.Validate()
+		require.NoError(t, err)
+		require.Equal(t, []string{"export_envs has no effect without isolate_envs: true, every env of the workflow is kept"}, warnings)
+	}
+
+	t.Log("empty export key")
+	{
+		workflow := WorkflowModel{IsolateEnvs: true, ExportEnvs: []string{"SCHEME", ""}}
+		_, err := workflow.Validate()
+		require.EqualError(t, err, "invalid export_envs: empty env key")
+	}
+}
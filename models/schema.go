@@ -0,0 +1,190 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	envmanModels "github.com/bitrise-io/envman/models"
+)
+
+// JSONSchemaDraft is the JSON Schema version of the generated schemas.
+const JSONSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// JSONSchemaModel is a JSON Schema (draft-07) node.
+type JSONSchemaModel struct {
+	Schema string `json:"$schema,omitempty"`
+	Ref    string `json:"$ref,omitempty"`
+	Title  string `json:"title,omitempty"`
+	// Type is a JSON type name or a list of JSON type names.
+	Type       interface{}                 `json:"type,omitempty"`
+	Format     string                      `json:"format,omitempty"`
+	Properties map[string]*JSONSchemaModel `json:"properties,omitempty"`
+	// AdditionalProperties is either false (no other keys are allowed) or the schema of the other keys' values.
+	AdditionalProperties interface{}                 `json:"additionalProperties,omitempty"`
+	MinProperties        *int                        `json:"minProperties,omitempty"`
+	MaxProperties        *int                        `json:"maxProperties,omitempty"`
+	Items                *JSONSchemaModel            `json:"items,omitempty"`
+	Definitions          map[string]*JSONSchemaModel `json:"definitions,omitempty"`
+}
+
+// jsonSchemaDefinitionRefPrefix is the prefix of the references to the schema definitions.
+const jsonSchemaDefinitionRefPrefix = "#/definitions/"
+
+// stringFieldTypes are the JSON types of the string fields:
+// the yaml decoder converts numbers and booleans to strings, e.g. format_version: 11
+var stringFieldTypes = []string{"string", "number", "boolean"}
+
+// listItemTypes are the single key maps of the config lists, e.g. - script@1: { ... }
+var listItemTypes = map[reflect.Type]bool{
+	reflect.TypeOf(StepListItemModel{}):     true,
+	reflect.TypeOf(StageListItemModel{}):    true,
+	reflect.TypeOf(WorkflowListItemModel{}): true,
+}
+
+type jsonSchemaGenerator struct {
+	definitions map[string]*JSONSchemaModel
+}
+
+func newIntPointer(value int) *int {
+	return &value
+}
+
+func (generator jsonSchemaGenerator) definitionRef(name string, generate func() *JSONSchemaModel) *JSONSchemaModel {
+	if _, ok := generator.definitions[name]; !ok {
+		// registered before generating, to stop at recursive types
+		generator.definitions[name] = &JSONSchemaModel{}
+		*generator.definitions[name] = *generate()
+	}
+	return &JSONSchemaModel{Ref: jsonSchemaDefinitionRefPrefix + name}
+}
+
+func (generator jsonSchemaGenerator) envItemSchema() *JSONSchemaModel {
+	return &JSONSchemaModel{
+		Type: "object",
+		Properties: map[string]*JSONSchemaModel{
+			"opts": generator.typeSchema(reflect.TypeOf(envmanModels.EnvironmentItemOptionsModel{})),
+		},
+		// the env's key and value
+		AdditionalProperties: &JSONSchemaModel{Type: append(append([]string{}, stringFieldTypes...), "null")},
+		MinProperties:        newIntPointer(1),
+		MaxProperties:        newIntPointer(2),
+	}
+}
+
+func (generator jsonSchemaGenerator) structSchema(t reflect.Type) *JSONSchemaModel {
+	schema := &JSONSchemaModel{
+		Type:                 "object",
+		Properties:           map[string]*JSONSchemaModel{},
+		AdditionalProperties: false,
+	}
+
+	var inlineFields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		yamlTag := strings.Split(field.Tag.Get("yaml"), ",")
+		if field.Anonymous && len(yamlTag) > 1 && yamlTag[1] == "inline" {
+			inlineFields = append(inlineFields, field)
+			continue
+		}
+
+		// a property parsed by the model's custom unmarshaler, instead of the yaml decoder
+		name := field.Tag.Get("schema")
+		if name == "" {
+			name = yamlTag[0]
+		}
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			// the default key of the yaml decoder
+			name = strings.ToLower(field.Name)
+		}
+		schema.Properties[name] = generator.typeSchema(field.Type)
+	}
+
+	// the properties of the inlined structs, unless the struct overrides them
+	for _, field := range inlineFields {
+		for name, property := range generator.structSchema(field.Type).Properties {
+			if _, ok := schema.Properties[name]; !ok {
+				schema.Properties[name] = property
+			}
+		}
+	}
+	return schema
+}
+
+func (generator jsonSchemaGenerator) typeSchema(t reflect.Type) *JSONSchemaModel {
+	switch {
+	case t == reflect.TypeOf(envmanModels.EnvironmentItemModel{}):
+		return generator.definitionRef(t.Name(), generator.envItemSchema)
+	case t == reflect.TypeOf(time.Time{}):
+		return &JSONSchemaModel{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return generator.typeSchema(t.Elem())
+	case reflect.String:
+		return &JSONSchemaModel{Type: stringFieldTypes}
+	case reflect.Bool:
+		return &JSONSchemaModel{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchemaModel{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchemaModel{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &JSONSchemaModel{Type: "array", Items: generator.typeSchema(t.Elem())}
+	case reflect.Map:
+		mapSchema := func() *JSONSchemaModel {
+			schema := &JSONSchemaModel{Type: "object"}
+			if t.Elem().Kind() != reflect.Interface {
+				schema.AdditionalProperties = generator.typeSchema(t.Elem())
+			}
+			if listItemTypes[t] {
+				schema.MinProperties = newIntPointer(1)
+				schema.MaxProperties = newIntPointer(1)
+			}
+			return schema
+		}
+		if listItemTypes[t] {
+			return generator.definitionRef(t.Name(), mapSchema)
+		}
+		return mapSchema()
+	case reflect.Struct:
+		return generator.definitionRef(t.Name(), func() *JSONSchemaModel { return generator.structSchema(t) })
+	}
+
+	// interface{}: any value
+	return &JSONSchemaModel{}
+}
+
+// BitriseDataModelJSONSchema generates the JSON Schema of the bitrise config from the config model types,
+// the schema does not allow keys which are not defined by the models.
+func BitriseDataModelJSONSchema() JSONSchemaModel {
+	generator := jsonSchemaGenerator{definitions: map[string]*JSONSchemaModel{}}
+	root := generator.structSchema(reflect.TypeOf(BitriseDataModel{}))
+
+	root.Schema = JSONSchemaDraft
+	root.Title = "bitrise.yml"
+	root.Definitions = generator.definitions
+	return *root
+}
+
+// ResolveRef returns the definition referenced by the schema, or the schema itself if it is not a reference.
+func (schema JSONSchemaModel) ResolveRef(root JSONSchemaModel) JSONSchemaModel {
+	if !strings.HasPrefix(schema.Ref, jsonSchemaDefinitionRefPrefix) {
+		return schema
+	}
+
+	definition, ok := root.Definitions[strings.TrimPrefix(schema.Ref, jsonSchemaDefinitionRefPrefix)]
+	if !ok {
+		return JSONSchemaModel{}
+	}
+	return *definition
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// schemaPath is the published JSON Schema of the config, regenerate it with:
+// bitrise export --schema --pretty --outpath _docs/bitrise-yml.schema.json
+const schemaPath = "../_docs/bitrise-yml.schema.json"
+
+func TestBitriseDataModelJSONSchemaInSync(t *testing.T) {
+	schemaBytes, err := json.MarshalIndent(BitriseDataModelJSONSchema(), "", "\t")
+	require.NoError(t, err)
+
+	publishedSchemaBytes, err := ioutil.ReadFile(schemaPath)
+	require.NoError(t, err)
+	require.Equal(t, string(publishedSchemaBytes), string(schemaBytes), "%s is out of sync with the models, regenerate it with: bitrise export --schema --pretty --outpath _docs/bitrise-yml.schema.json", schemaPath)
+}
+
+func TestBitriseDataModelJSONSchema(t *testing.T) {
+	schema := BitriseDataModelJSONSchema()
+
+	t.Log("struct fields are the properties, other keys are not allowed")
+	{
+		workflows := schema.Properties["workflows"].AdditionalProperties.(*JSONSchemaModel)
+		workflow := workflows.ResolveRef(schema)
+		require.Equal(t, false, workflow.AdditionalProperties)
+		require.Equal(t, "#/definitions/EnvironmentItemModel", workflow.Properties["envs"].Items.Ref)
+		require.Equal(t, "#/definitions/ContainerModel", workflow.Properties["container"].Ref)
+		require.Equal(t, &JSONSchemaModel{Type: "array", Items: &JSONSchemaModel{Type: stringFieldTypes}}, workflow.Properties["before_run"])
+		require.Equal(t, &JSONSchemaModel{Type: "object"}, workflow.Properties["meta"])
+	}
+
+	t.Log("list items are single key maps")
+	{
+		steps := schema.Definitions["WorkflowModel"].Properties["steps"]
+		stepListItem := steps.Items.ResolveRef(schema)
+		require.Equal(t, 1, *stepListItem.MinProperties)
+		require.Equal(t, 1, *stepListItem.MaxProperties)
+		require.Equal(t, "#/definitions/StepModel", stepListItem.AdditionalProperties.(*JSONSchemaModel).Ref)
+
+		step := stepListItem.AdditionalProperties.(*JSONSchemaModel).ResolveRef(schema)
+		require.Equal(t, &JSONSchemaModel{Type: "integer"}, step.Properties["timeout"])
+		require.Equal(t, &JSONSchemaModel{Type: "boolean"}, step.Properties["is_always_run"])
+	}
+
+	t.Log("env items have a key and options")
+	{
+		env := schema.Definitions["EnvironmentItemModel"]
+		require.Equal(t, "#/definitions/EnvironmentItemOptionsModel", env.Properties["opts"].Ref)
+		require.Equal(t, 2, *env.MaxProperties)
+		require.Equal(t, false, env.Properties["opts"].ResolveRef(schema).AdditionalProperties)
+	}
+
+	t.Log("unknown reference")
+	{
+		require.Equal(t, JSONSchemaModel{}, JSONSchemaModel{Ref: "#/definitions/Unknown"}.ResolveRef(schema))
+	}
+}
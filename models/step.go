@@ -0,0 +1,158 @@
+package models
+
+import (
+	"encoding/json"
+
+	stepmanModels "github.com/bitrise-io/stepman/models"
+	"gopkg.in/yaml.v2"
+)
+
+// StepModel is a step of a workflow or of a step.yml: the stepman step model,
+// extended with the step properties only the bitrise CLI supports.
+type StepModel struct {
+	stepmanModels.StepModel `yaml:",inline"`
+
+	// ChangedFiles : only run the step if the build changes relevant files
+	ChangedFiles *ChangedFilesFilterModel `json:"changed_files,omitempty" yaml:"changed_files,omitempty"`
+	// ID : identifies the step in the workflow, its status and outputs can be referenced by it (e.g. {{ .Steps.build.Status }})
+	ID *string `json:"id,omitempty" yaml:"id,omitempty"`
+
+	// StepToolkit is the step's toolkit, including the toolkits the stepman step model does not support.
+	// Parsed from the step's toolkit property, next to the stepman step model's Toolkit, see GetToolkit.
+	StepToolkit *StepToolkitModel `json:"-" yaml:"-" schema:"toolkit"`
+}
+
+// ChangedFilesFilterModel limits a step (or a trigger map item) to the builds changing relevant files:
+// at least one changed file has to match an Include glob (any file without Include globs) and none of the Exclude globs.
+type ChangedFilesFilterModel struct {
+	Include []string `json:"include,omitempty" yaml:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+}
+
+// GoStepToolkitModel is the stepman Go toolkit model, extended with the Go version constraint of the step.
+type GoStepToolkitModel struct {
+	// PackageName - required
+	PackageName string `json:"package_name" yaml:"package_name"`
+	// Version - optional, Go version constraint, e.g. ">= 1.17"
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+}
+
+// PythonStepToolkitModel ...
+type PythonStepToolkitModel struct {
+	EntryFile  string `json:"entry_file,omitempty" yaml:"entry_file,omitempty"`
+	MinVersion string `json:"min_version,omitempty" yaml:"min_version,omitempty"`
+}
+
+// NodeStepToolkitModel ...
+type NodeStepToolkitModel struct {
+	EntryFile  string `json:"entry_file,omitempty" yaml:"entry_file,omitempty"`
+	MinVersion string `json:"min_version,omitempty" yaml:"min_version,omitempty"`
+}
+
+// StepToolkitModel is the stepman step toolkit model, extended with the toolkits only the bitrise CLI supports.
+type StepToolkitModel struct {
+	Bash   *stepmanModels.BashStepToolkitModel `json:"bash,omitempty" yaml:"bash,omitempty"`
+	Go     *GoStepToolkitModel                 `json:"go,omitempty" yaml:"go,omitempty"`
+	Python *PythonStepToolkitModel             `json:"python,omitempty" yaml:"python,omitempty"`
+	Node   *NodeStepToolkitModel               `json:"node,omitempty" yaml:"node,omitempty"`
+}
+
+// stepToolkitProperty is the toolkit property of a step, parsed next to the stepman step model.
+type stepToolkitProperty struct {
+	Toolkit *StepToolkitModel `json:"toolkit,omitempty" yaml:"toolkit,omitempty"`
+}
+
+// NewStepModel ...
+func NewStepModel(step stepmanModels.StepModel) StepModel {
+	return StepModel{StepModel: step}
+}
+
+// UnmarshalYAML parses the stepman step model and the step properties only the bitrise CLI supports.
+func (step *StepModel) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type stepModel StepModel
+	if err := unmarshal((*stepModel)(step)); err != nil {
+		return err
+	}
+
+	var toolkit stepToolkitProperty
+	if err := unmarshal(&toolkit); err != nil {
+		return err
+	}
+	step.StepToolkit = toolkit.Toolkit
+	return nil
+}
+
+// UnmarshalJSON parses the stepman step model and the step properties only the bitrise CLI supports.
+func (step *StepModel) UnmarshalJSON(data []byte) error {
+	type stepModel StepModel
+	if err := json.Unmarshal(data, (*stepModel)(step)); err != nil {
+		return err
+	}
+
+	var toolkit stepToolkitProperty
+	if err := json.Unmarshal(data, &toolkit); err != nil {
+		return err
+	}
+	step.StepToolkit = toolkit.Toolkit
+	return nil
+}
+
+// MarshalYAML marshals the stepman step model, with the StepToolkit as the step's toolkit if set.
+func (step StepModel) MarshalYAML() (interface{}, error) {
+	type stepModel StepModel
+	bytes, err := yaml.Marshal(stepModel(step))
+	if err != nil {
+		return nil, err
+	}
+
+	var properties yaml.MapSlice
+	if err := yaml.Unmarshal(bytes, &properties); err != nil {
+		return nil, err
+	}
+	if step.StepToolkit == nil {
+		return properties, nil
+	}
+
+	for idx, property := range properties {
+		if property.Key == "toolkit" {
+			properties[idx].Value = step.StepToolkit
+			return properties, nil
+		}
+	}
+	return append(properties, yaml.MapItem{Key: "toolkit", Value: step.StepToolkit}), nil
+}
+
+// MarshalJSON marshals the stepman step model, with the StepToolkit as the step's toolkit if set.
+func (step StepModel) MarshalJSON() ([]byte, error) {
+	type stepModel StepModel
+	bytes, err := json.Marshal(stepModel(step))
+	if err != nil || step.StepToolkit == nil {
+		return bytes, err
+	}
+
+	var properties map[string]json.RawMessage
+	if err := json.Unmarshal(bytes, &properties); err != nil {
+		return nil, err
+	}
+	toolkitBytes, err := json.Marshal(step.StepToolkit)
+	if err != nil {
+		return nil, err
+	}
+	properties["toolkit"] = toolkitBytes
+	return json.Marshal(properties)
+}
+
+// GetToolkit returns the step's toolkit: the StepToolkit if set, otherwise the stepman step model's Toolkit.
+func (step StepModel) GetToolkit() *StepToolkitModel {
+	if step.StepToolkit != nil {
+		return step.StepToolkit
+	}
+	if step.Toolkit == nil {
+		return nil
+	}
+	toolkit := &StepToolkitModel{Bash: step.Toolkit.Bash}
+	if step.Toolkit.Go != nil {
+		toolkit.Go = &GoStepToolkitModel{PackageName: step.Toolkit.Go.PackageName}
+	}
+	return toolkit
+}
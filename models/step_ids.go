@@ -0,0 +1,105 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// StepOutputEnvKeyPrefix ...
+	StepOutputEnvKeyPrefix = "STEPS_"
+	// StepOutputEnvKeyInfix separates the step's id and the output key in the namespaced output env key.
+	StepOutputEnvKeyInfix = "_OUTPUT_"
+)
+
+var stepIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidateStepID validates the id of a workflow step (the step's `id` property).
+func ValidateStepID(id string) error {
+	if id == "" {
+		return errors.New("invalid step id: empty")
+	}
+	if !stepIDRegexp.MatchString(id) {
+		return fmt.Errorf("invalid step id (%s): doesn't conform to: [A-Za-z0-9-_]", id)
+	}
+	return nil
+}
+
+// stepIDEnvKeyPart returns the step id in the form used in env keys: upper cased, - replaced with _.
+func stepIDEnvKeyPart(id string) string {
+	return strings.ToUpper(strings.Replace(id, "-", "_", -1))
+}
+
+// StepOutputEnvKey returns the env key of the step output namespaced by the step's id,
+// e.g. STEPS_BUILD_OUTPUT_BITRISE_IPA_PATH for the BITRISE_IPA_PATH output of the step with id: build
+func StepOutputEnvKey(stepID, outputKey string) string {
+	return StepOutputEnvKeyPrefix + stepIDEnvKeyPart(stepID) + StepOutputEnvKeyInfix + outputKey
+}
+
+// StepRunStatusName returns the name of the step run status: success, failed, failed_skippable or skipped.
+func StepRunStatusName(status int) string {
+	switch status {
+	case StepRunStatusCodeSuccess:
+		return "success"
+	case StepRunStatusCodeFailed:
+		return "failed"
+	case StepRunStatusCodeFailedSkippable:
+		return "failed_skippable"
+	case StepRunStatusCodeSkipped, StepRunStatusCodeSkippedWithRunIf, StepRunStatusCodeSkippedWithNoRelevantChanges:
+		return "skipped"
+	default:
+		return "unknown"
+	}
+}
+
+type stepIDLocation struct {
+	workflowID string
+	stepIdx    int
+	stepID     string
+}
+
+// collectStepIDs collects the ids of the steps run by the workflow (including its before and after run workflows),
+// by the id's env key form, as the namespaced output env keys of the ids have to be unique too.
+func collectStepIDs(workflowID string, config BitriseDataModel, locations map[string]stepIDLocation) error {
+	workflow, found := config.Workflows[workflowID]
+	if !found {
+		return nil
+	}
+
+	for _, beforeWorkflowID := range workflow.BeforeRun {
+		if err := collectStepIDs(beforeWorkflowID, config, locations); err != nil {
+			return err
+		}
+	}
+
+	for idx, stepListItem := range workflow.Steps {
+		stepID, step, err := GetStepIDStepDataPair(stepListItem)
+		if err != nil || step.ID == nil {
+			continue
+		}
+
+		location := stepIDLocation{workflowID: workflowID, stepIdx: idx, stepID: stepID}
+		key := stepIDEnvKeyPart(*step.ID)
+		if other, found := locations[key]; found && (other.workflowID != workflowID || other.stepIdx != idx) {
+			return configPathErrorf([]interface{}{"workflows", workflowID, "steps", idx, stepID, "id"},
+				"step id (%s) is not unique, also used by workflow (%s) step (%d) (%s)", *step.ID, other.workflowID, other.stepIdx, other.stepID)
+		}
+		locations[key] = location
+	}
+
+	for _, afterWorkflowID := range workflow.AfterRun {
+		if err := collectStepIDs(afterWorkflowID, config, locations); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkStepIDsUnique checks that the step ids are unique in the run of the workflow (including its before and after run workflows).
+// The same workflow running more than once (e.g. a utility workflow) does not make its step ids duplicated.
+func checkStepIDsUnique(workflowID string, config BitriseDataModel) error {
+	return collectStepIDs(workflowID, config, map[string]stepIDLocation{})
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestStepOutputEnvKey(t *testing.T) {
+	require.Equal(t, "STEPS_BUILD_OUTPUT_BITRISE_IPA_PATH", StepOutputEnvKey("build", "BITRISE_IPA_PATH"))
+	require.Equal(t, "STEPS_UNIT_TEST_OUTPUT_BITRISE_XCRESULT_PATH", StepOutputEnvKey("unit-test", "BITRISE_XCRESULT_PATH"))
+}
+
+func TestValidateStepIDs(t *testing.T) {
+	t.Log("unique ids")
+	{
+		configStr := `format_version: 11
+workflows:
+  _setup:
+    steps:
+    - script:
+        id: setup
+  primary:
+    before_run:
+    - _setup
+    steps:
+    - script:
+        id: build
+    - script:
+        id: unit-test
+  deploy:
+    before_run:
+    - _setup
+    - primary
+    steps:
+    - script:
+        id: deploy
+`
+		config := BitriseDataModel{}
+		require.NoError(t, yaml.Unmarshal([]byte(configStr), &config))
+
+		script := config.Workflows["primary"].Steps[0]["script"]
+		require.Equal(t, "build", *script.ID)
+
+		_, err := config.ValidateWithPaths()
+		require.NoError(t, err)
+	}
+
+	t.Log("invalid id")
+	{
+		configStr := `format_version: 11
+workflows:
+  primary:
+    steps:
+    - script:
+        id: build.app
+`
+		config := BitriseDataModel{}
+		require.NoError(t, yaml.Unmarshal([]byte(configStr), &config))
+
+		_, err := config.ValidateWithPaths()
+		require.Error(t, err)
+		require.Equal(t, []interface{}{"workflows", "primary", "steps", 0, "script", "id"}, err.(ConfigPathError).Path)
+		require.Contains(t, err.Error(), "invalid step id (build.app): doesn't conform to: [A-Za-z0-9-_]")
+	}
+
+	t.Log("duplicated id in a workflow")
+	{
+		configStr := `format_version: 11
+workflows:
+  primary:
+    steps:
+    - script:
+        id: build
+    - script@1:
+        id: build
+`
+		config := BitriseDataModel{}
+		require.NoError(t, yaml.Unmarshal([]byte(configStr), &config))
+
+		_, err := config.ValidateWithPaths()
+		require.Error(t, err)
+		require.Equal(t, []interface{}{"workflows", "primary", "steps", 1, "script@1", "id"}, err.(ConfigPathError).Path)
+		require.EqualError(t, err, "step id (build) is not unique, also used by workflow (primary) step (0) (script)")
+	}
+
+	t.Log("duplicated id in the run chain")
+	{
+		configStr := `format_version: 11
+workflows:
+  _setup:
+    steps:
+    - script:
+        id: unit_test
+  primary:
+    after_run:
+    - _setup
+    steps:
+    - script:
+        id: unit-test
+`
+		config := BitriseDataModel{}
+		require.NoError(t, yaml.Unmarshal([]byte(configStr), &config))
+
+		_, err := config.ValidateWithPaths()
+		require.EqualError(t, err, "step id (unit_test) is not unique, also used by workflow (primary) step (0) (script)")
+	}
+}
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/sliceutil"
+)
+
+// StepInputType is the declared type of a step input, set in the step.yml as the input's `meta.type` option.
+type StepInputType string
+
+const (
+	// StepInputTypeMetaKey ...
+	StepInputTypeMetaKey = "type"
+	// StepInputPatternMetaKey is the meta key of the regular expression a regex typed input has to match.
+	StepInputPatternMetaKey = "pattern"
+
+	// BoolStepInputType ...
+	BoolStepInputType StepInputType = "bool"
+	// IntStepInputType ...
+	IntStepInputType StepInputType = "int"
+	// PathStepInputType is an input referring to an existing file system path.
+	PathStepInputType StepInputType = "path"
+	// URLStepInputType ...
+	URLStepInputType StepInputType = "url"
+	// EnumStepInputType is an input which has to be one of the input's value_options.
+	EnumStepInputType StepInputType = "enum"
+	// RegexStepInputType is an input which has to match the input's `meta.pattern` regular expression.
+	RegexStepInputType StepInputType = "regex"
+)
+
+var stepInputBoolValues = []string{"true", "false", "yes", "no"}
+
+// StepInputValidationError ...
+type StepInputValidationError struct {
+	Key     string
+	Message string
+}
+
+// Error ...
+func (e StepInputValidationError) Error() string {
+	return fmt.Sprintf("input (%s) %s", e.Key, e.Message)
+}
+
+// StepInputValidationErrors ...
+type StepInputValidationErrors []StepInputValidationError
+
+// Error ...
+func (errs StepInputValidationErrors) Error() string {
+	messages := []string{}
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, ", ")
+}
+
+// StepInputTypeOf returns the declared type of the input, empty string if the input is not typed.
+func StepInputTypeOf(options envmanModels.EnvironmentItemOptionsModel) (StepInputType, error) {
+	value, found := options.Meta[StepInputTypeMetaKey]
+	if !found || value == nil {
+		return "", nil
+	}
+
+	typeStr, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid input type (%v), should be a string", value)
+	}
+
+	inputType := StepInputType(typeStr)
+	switch inputType {
+	case BoolStepInputType, IntStepInputType, PathStepInputType, URLStepInputType, EnumStepInputType, RegexStepInputType:
+		return inputType, nil
+	default:
+		return "", fmt.Errorf("unknown input type (%s)", typeStr)
+	}
+}
+
+func stepInputPattern(options envmanModels.EnvironmentItemOptionsModel) (*regexp.Regexp, error) {
+	pattern, ok := options.Meta[StepInputPatternMetaKey].(string)
+	if !ok || pattern == "" {
+		return nil, errors.New("regex typed input requires a meta.pattern")
+	}
+
+	exp, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid meta.pattern (%s), error: %s", pattern, err)
+	}
+	return exp, nil
+}
+
+// ValidateStepInputValue validates the (env expanded) value of an input against its declaration:
+// is_required, value_options and the declared type.
+// Empty values of not required inputs are not validated against the value_options and the type.
+// The errors do not contain the value, as it might be sensitive or contain secrets.
+func ValidateStepInputValue(value string, options envmanModels.EnvironmentItemOptionsModel) error {
+	inputType, err := StepInputTypeOf(options)
+	if err != nil {
+		return err
+	}
+
+	if value == "" {
+		if options.IsRequired != nil && *options.IsRequired {
+			return errors.New("is required")
+		}
+		return nil
+	}
+
+	if len(options.ValueOptions) > 0 && !sliceutil.IsStringInSlice(value, options.ValueOptions) {
+		return fmt.Errorf("value is not one of the value_options: %s", strings.Join(options.ValueOptions, ", "))
+	}
+
+	switch inputType {
+	case BoolStepInputType:
+		if !sliceutil.IsStringInSlice(strings.ToLower(value), stepInputBoolValues) {
+			return fmt.Errorf("value is not a bool, should be one of: %s", strings.Join(stepInputBoolValues, ", "))
+		}
+	case IntStepInputType:
+		if _, err := strconv.Atoi(value); err != nil {
+			return errors.New("value is not an int")
+		}
+	case PathStepInputType:
+		if _, err := os.Stat(value); err != nil {
+			if os.IsNotExist(err) {
+				return errors.New("path does not exist")
+			}
+			if pathErr, ok := err.(*os.PathError); ok {
+				// the path error contains the path
+				err = pathErr.Err
+			}
+			return fmt.Errorf("failed to check path, error: %s", err)
+		}
+	case URLStepInputType:
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.New("value is not a valid url")
+		}
+	case EnumStepInputType:
+		if len(options.ValueOptions) == 0 {
+			return errors.New("enum typed input requires value_options")
+		}
+	case RegexStepInputType:
+		exp, err := stepInputPattern(options)
+		if err != nil {
+			return err
+		}
+		if !exp.MatchString(value) {
+			return fmt.Errorf("value does not match pattern (%s)", options.Meta[StepInputPatternMetaKey])
+		}
+	}
+
+	return nil
+}
+
+// ValidateStepInputs validates the merged step inputs against their declarations.
+// values contains the env expanded input values by input key.
+func ValidateStepInputs(inputs []envmanModels.EnvironmentItemModel, values map[string]string) error {
+	validationErrors := StepInputValidationErrors{}
+	for _, input := range inputs {
+		key, _, err := input.GetKeyValuePair()
+		if err != nil {
+			return fmt.Errorf("failed to get input key, error: %s", err)
+		}
+
+		options, err := input.GetOptions()
+		if err != nil {
+			return fmt.Errorf("failed to get input (%s) options, error: %s", key, err)
+		}
+
+		if err := ValidateStepInputValue(values[key], options); err != nil {
+			validationErrors = append(validationErrors, StepInputValidationError{Key: key, Message: err.Error()})
+		}
+	}
+
+	if len(validationErrors) > 0 {
+		return validationErrors
+	}
+	return nil
+}
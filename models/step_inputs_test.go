@@ -0,0 +1,85 @@
+package models
+
+import (
+	"os"
+	"testing"
+
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/pointers"
+	stepmanModels "github.com/bitrise-io/stepman/models"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func typedInputOptions(inputType StepInputType) envmanModels.EnvironmentItemOptionsModel {
+	return envmanModels.EnvironmentItemOptionsModel{Meta: map[string]interface{}{StepInputTypeMetaKey: string(inputType)}}
+}
+
+func TestValidateStepInputValue(t *testing.T) {
+	t.Log("required")
+	{
+		options := envmanModels.EnvironmentItemOptionsModel{IsRequired: pointers.NewBoolPtr(true)}
+		require.EqualError(t, ValidateStepInputValue("", options), "is required")
+		require.NoError(t, ValidateStepInputValue("value", options))
+		require.NoError(t, ValidateStepInputValue("", typedInputOptions(IntStepInputType)))
+	}
+
+	t.Log("value_options")
+	{
+		options := envmanModels.EnvironmentItemOptionsModel{ValueOptions: []string{"debug", "release"}}
+		require.NoError(t, ValidateStepInputValue("debug", options))
+		require.EqualError(t, ValidateStepInputValue("relase", options), "value is not one of the value_options: debug, release")
+	}
+
+	t.Log("types")
+	{
+		require.NoError(t, ValidateStepInputValue("yes", typedInputOptions(BoolStepInputType)))
+		require.NoError(t, ValidateStepInputValue("False", typedInputOptions(BoolStepInputType)))
+		require.Error(t, ValidateStepInputValue("1", typedInputOptions(BoolStepInputType)))
+
+		require.NoError(t, ValidateStepInputValue("-12", typedInputOptions(IntStepInputType)))
+		require.Error(t, ValidateStepInputValue("1.5", typedInputOptions(IntStepInputType)))
+
+		require.NoError(t, ValidateStepInputValue(os.TempDir(), typedInputOptions(PathStepInputType)))
+		require.EqualError(t, ValidateStepInputValue("/not/existing/path", typedInputOptions(PathStepInputType)), "path does not exist")
+
+		require.NoError(t, ValidateStepInputValue("https://bitrise.io/path", typedInputOptions(URLStepInputType)))
+		require.EqualError(t, ValidateStepInputValue("secret-token@bitrise.io", typedInputOptions(URLStepInputType)), "value is not a valid url")
+
+		require.Error(t, ValidateStepInputValue("debug", typedInputOptions(EnumStepInputType)))
+
+		regexOptions := typedInputOptions(RegexStepInputType)
+		regexOptions.Meta[StepInputPatternMetaKey] = `[0-9]+\.[0-9]+`
+		require.NoError(t, ValidateStepInputValue("11.2", regexOptions))
+		require.EqualError(t, ValidateStepInputValue("v11.2", regexOptions), `value does not match pattern ([0-9]+\.[0-9]+)`)
+		require.Error(t, ValidateStepInputValue("11.2", typedInputOptions(RegexStepInputType)))
+
+		require.EqualError(t, ValidateStepInputValue("value", typedInputOptions("float")), "unknown input type (float)")
+	}
+}
+
+func TestValidateStepInputs(t *testing.T) {
+	stepYML := `inputs:
+- configuration: debug
+  opts:
+    value_options: ["debug", "release"]
+- timeout:
+  opts:
+    is_required: true
+    meta:
+      type: int
+- verbose: "no"
+  opts:
+    meta:
+      type: bool
+`
+	var step stepmanModels.StepModel
+	require.NoError(t, yaml.Unmarshal([]byte(stepYML), &step))
+	require.NoError(t, step.Normalize())
+	require.NoError(t, step.FillMissingDefaults())
+
+	require.NoError(t, ValidateStepInputs(step.Inputs, map[string]string{"configuration": "release", "timeout": "10", "verbose": "yes"}))
+
+	err := ValidateStepInputs(step.Inputs, map[string]string{"configuration": "relase", "verbose": "no"})
+	require.EqualError(t, err, "input (configuration) value is not one of the value_options: debug, release, input (timeout) is required")
+}
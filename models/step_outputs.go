@@ -0,0 +1,144 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	envmanModels "github.com/bitrise-io/envman/models"
+)
+
+// StepOutputType is the declared type of a step output, set in the step.yml as the output's `meta.type` option.
+type StepOutputType string
+
+const (
+	// StepOutputTypeMetaKey ...
+	StepOutputTypeMetaKey = "type"
+	// StepOutputListSeparator separates the items of a list typed output, e.g. BITRISE_APK_PATH_LIST: a.apk|b.apk
+	StepOutputListSeparator = "|"
+
+	// StringStepOutputType ...
+	StringStepOutputType StepOutputType = "string"
+	// JSONStepOutputType is an output with a JSON document value.
+	JSONStepOutputType StepOutputType = "json"
+	// FileStepOutputType is an output referring to an existing file, copied to the build's deploy dir.
+	FileStepOutputType StepOutputType = "file"
+	// DirectoryStepOutputType is an output referring to an existing directory, copied to the build's deploy dir.
+	DirectoryStepOutputType StepOutputType = "directory"
+	// ListStepOutputType is an output with StepOutputListSeparator separated items.
+	ListStepOutputType StepOutputType = "list"
+)
+
+// StepOutputValidationError ...
+type StepOutputValidationError struct {
+	Key     string
+	Message string
+}
+
+// Error ...
+func (e StepOutputValidationError) Error() string {
+	return fmt.Sprintf("output (%s) %s", e.Key, e.Message)
+}
+
+// StepOutputValidationErrors ...
+type StepOutputValidationErrors []StepOutputValidationError
+
+// Error ...
+func (errs StepOutputValidationErrors) Error() string {
+	messages := []string{}
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, ", ")
+}
+
+// StepOutputTypeOf returns the declared type of the output, string if the output is not typed.
+func StepOutputTypeOf(options envmanModels.EnvironmentItemOptionsModel) (StepOutputType, error) {
+	value, found := options.Meta[StepOutputTypeMetaKey]
+	if !found || value == nil {
+		return StringStepOutputType, nil
+	}
+
+	typeStr, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid output type (%v), should be a string", value)
+	}
+
+	outputType := StepOutputType(typeStr)
+	switch outputType {
+	case StringStepOutputType, JSONStepOutputType, FileStepOutputType, DirectoryStepOutputType, ListStepOutputType:
+		return outputType, nil
+	default:
+		return "", fmt.Errorf("unknown output type (%s)", typeStr)
+	}
+}
+
+// ValidateStepOutputValue validates the value of an output, exported by the step, against its declared type.
+// Empty values (the output is not exported by the step) are not validated.
+func ValidateStepOutputValue(value string, options envmanModels.EnvironmentItemOptionsModel) error {
+	outputType, err := StepOutputTypeOf(options)
+	if err != nil {
+		return err
+	}
+
+	if value == "" {
+		return nil
+	}
+
+	switch outputType {
+	case JSONStepOutputType:
+		if !json.Valid([]byte(value)) {
+			return errors.New("value is not a valid JSON")
+		}
+	case FileStepOutputType, DirectoryStepOutputType:
+		info, err := os.Stat(value)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("path (%s) does not exist", value)
+			}
+			return fmt.Errorf("failed to check path (%s), error: %s", value, err)
+		}
+		if outputType == FileStepOutputType && info.IsDir() {
+			return fmt.Errorf("path (%s) is a directory, not a file", value)
+		}
+		if outputType == DirectoryStepOutputType && !info.IsDir() {
+			return fmt.Errorf("path (%s) is not a directory", value)
+		}
+	case ListStepOutputType:
+		for idx, item := range strings.Split(value, StepOutputListSeparator) {
+			if item == "" {
+				return fmt.Errorf("list item #%d is empty", idx)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateStepOutputs validates the values exported by the step against the step's output declarations.
+// values contains the exported output values by output key, before applying the output aliases.
+func ValidateStepOutputs(outputs []envmanModels.EnvironmentItemModel, values map[string]string) error {
+	validationErrors := StepOutputValidationErrors{}
+	for _, output := range outputs {
+		key, _, err := output.GetKeyValuePair()
+		if err != nil {
+			return fmt.Errorf("failed to get output key, error: %s", err)
+		}
+
+		options, err := output.GetOptions()
+		if err != nil {
+			return fmt.Errorf("failed to get output (%s) options, error: %s", key, err)
+		}
+
+		if err := ValidateStepOutputValue(values[key], options); err != nil {
+			validationErrors = append(validationErrors, StepOutputValidationError{Key: key, Message: err.Error()})
+		}
+	}
+
+	if len(validationErrors) > 0 {
+		return validationErrors
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	envmanModels "github.com/bitrise-io/envman/models"
+	stepmanModels "github.com/bitrise-io/stepman/models"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func typedOutputOptions(outputType StepOutputType) envmanModels.EnvironmentItemOptionsModel {
+	return envmanModels.EnvironmentItemOptionsModel{Meta: map[string]interface{}{StepOutputTypeMetaKey: string(outputType)}}
+}
+
+func TestValidateStepOutputValue(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "step_outputs")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tmpDir))
+	}()
+	filePth := filepath.Join(tmpDir, "app.ipa")
+	require.NoError(t, ioutil.WriteFile(filePth, []byte("ipa"), 0600))
+
+	t.Log("not typed and empty values")
+	{
+		require.NoError(t, ValidateStepOutputValue("{", envmanModels.EnvironmentItemOptionsModel{}))
+		require.NoError(t, ValidateStepOutputValue("", typedOutputOptions(FileStepOutputType)))
+	}
+
+	t.Log("types")
+	{
+		require.NoError(t, ValidateStepOutputValue(`{"a": [1, 2]}`, typedOutputOptions(JSONStepOutputType)))
+		require.NoError(t, ValidateStepOutputValue(`"a"`, typedOutputOptions(JSONStepOutputType)))
+		require.EqualError(t, ValidateStepOutputValue(`{"a":`, typedOutputOptions(JSONStepOutputType)), "value is not a valid JSON")
+
+		require.NoError(t, ValidateStepOutputValue(filePth, typedOutputOptions(FileStepOutputType)))
+		require.EqualError(t, ValidateStepOutputValue(tmpDir, typedOutputOptions(FileStepOutputType)), "path ("+tmpDir+") is a directory, not a file")
+		require.Error(t, ValidateStepOutputValue("/not/existing/path", typedOutputOptions(FileStepOutputType)))
+
+		require.NoError(t, ValidateStepOutputValue(tmpDir, typedOutputOptions(DirectoryStepOutputType)))
+		require.EqualError(t, ValidateStepOutputValue(filePth, typedOutputOptions(DirectoryStepOutputType)), "path ("+filePth+") is not a directory")
+
+		require.NoError(t, ValidateStepOutputValue("a.apk|b.apk", typedOutputOptions(ListStepOutputType)))
+		require.EqualError(t, ValidateStepOutputValue("a.apk||b.apk", typedOutputOptions(ListStepOutputType)), "list item #1 is empty")
+
+		require.EqualError(t, ValidateStepOutputValue("value", typedOutputOptions("int")), "unknown output type (int)")
+	}
+}
+
+func TestValidateStepOutputs(t *testing.T) {
+	stepYML := `outputs:
+- BITRISE_BUILD_INFO:
+  opts:
+    meta:
+      type: json
+- BITRISE_APK_PATH_LIST:
+  opts:
+    meta:
+      type: list
+- BITRISE_MESSAGE:
+`
+	var step stepmanModels.StepModel
+	require.NoError(t, yaml.Unmarshal([]byte(stepYML), &step))
+	require.NoError(t, step.Normalize())
+	require.NoError(t, step.FillMissingDefaults())
+
+	require.NoError(t, ValidateStepOutputs(step.Outputs, map[string]string{"BITRISE_BUILD_INFO": `{"id": 1}`, "BITRISE_MESSAGE": "{"}))
+
+	err := ValidateStepOutputs(step.Outputs, map[string]string{"BITRISE_BUILD_INFO": `{"id":`, "BITRISE_APK_PATH_LIST": "a.apk|"})
+	require.EqualError(t, err, "output (BITRISE_BUILD_INFO) value is not a valid JSON, output (BITRISE_APK_PATH_LIST) list item #1 is empty")
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestStepModelToolkit(t *testing.T) {
+	t.Log("toolkit supported by the stepman step model")
+	{
+		var step StepModel
+		require.NoError(t, yaml.Unmarshal([]byte("title: Build\ntoolkit:\n  go:\n    package_name: github.com/bitrise-steplib/steps-build\n"), &step))
+
+		require.Equal(t, "Build", *step.Title)
+		require.NotNil(t, step.Toolkit)
+		require.Equal(t, "github.com/bitrise-steplib/steps-build", step.GetToolkit().Go.PackageName)
+	}
+
+	t.Log("go toolkit with a version constraint")
+	{
+		var step StepModel
+		require.NoError(t, yaml.Unmarshal([]byte("toolkit:\n  go:\n    package_name: github.com/bitrise-steplib/steps-build\n    version: '>= 1.17'\n"), &step))
+
+		require.Equal(t, "github.com/bitrise-steplib/steps-build", step.Toolkit.Go.PackageName)
+		require.Equal(t, ">= 1.17", step.GetToolkit().Go.Version)
+	}
+
+	t.Log("toolkit supported only by the bitrise CLI")
+	{
+		var step StepModel
+		require.NoError(t, yaml.Unmarshal([]byte("title: Lint\ntoolkit:\n  python:\n    entry_file: src/main.py\n"), &step))
+
+		require.Equal(t, "Lint", *step.Title)
+		require.NotNil(t, step.GetToolkit().Python)
+		require.Equal(t, "src/main.py", step.GetToolkit().Python.EntryFile)
+
+		yamlBytes, err := yaml.Marshal(step)
+		require.NoError(t, err)
+		require.Equal(t, "title: Lint\ntoolkit:\n  python:\n    entry_file: src/main.py\n", string(yamlBytes))
+
+		jsonBytes, err := json.Marshal(step)
+		require.NoError(t, err)
+
+		var jsonStep StepModel
+		require.NoError(t, json.Unmarshal(jsonBytes, &jsonStep))
+		require.Equal(t, "Lint", *jsonStep.Title)
+		require.Equal(t, "src/main.py", jsonStep.GetToolkit().Python.EntryFile)
+	}
+
+	t.Log("changed_files")
+	{
+		var step StepModel
+		require.NoError(t, yaml.Unmarshal([]byte("title: Test\nchanged_files:\n  include:\n  - apps/ios/**\n"), &step))
+		require.Equal(t, []string{"apps/ios/**"}, step.ChangedFiles.Include)
+
+		yamlBytes, err := yaml.Marshal(step)
+		require.NoError(t, err)
+		require.Equal(t, "title: Test\nchanged_files:\n  include:\n  - apps/ios/**\n", string(yamlBytes))
+	}
+
+	t.Log("id")
+	{
+		var step StepModel
+		require.NoError(t, yaml.Unmarshal([]byte("title: Build\nid: build\n"), &step))
+		require.Equal(t, "build", *step.ID)
+
+		yamlBytes, err := yaml.Marshal(step)
+		require.NoError(t, err)
+		require.Equal(t, "title: Build\nid: build\n", string(yamlBytes))
+
+		jsonBytes, err := json.Marshal(step)
+		require.NoError(t, err)
+
+		var jsonStep StepModel
+		require.NoError(t, json.Unmarshal(jsonBytes, &jsonStep))
+		require.Equal(t, "build", *jsonStep.ID)
+	}
+
+	t.Log("no toolkit")
+	{
+		var step StepModel
+		require.NoError(t, yaml.Unmarshal([]byte("title: Script\n"), &step))
+		require.Nil(t, step.GetToolkit())
+	}
+}
@@ -0,0 +1,307 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/sliceutil"
+	ver "github.com/hashicorp/go-version"
+)
+
+//=======================================
+// Util
+//=======================================
+
+type dependencyConstraint struct {
+	requiredBy string
+	constraint string
+}
+
+func (c dependencyConstraint) String() string {
+	if c.constraint == "" {
+		return fmt.Sprintf("(%s) requires any version", c.requiredBy)
+	}
+	return fmt.Sprintf("(%s) requires (%s)", c.requiredBy, c.constraint)
+}
+
+func checkVersionConstraint(version *ver.Version, constraint string) (bool, error) {
+	if constraint == "" {
+		return true, nil
+	}
+
+	constraints, err := ver.NewConstraint(constraint)
+	if err != nil {
+		return false, fmt.Errorf("invalid version constraint (%s), error: %s", constraint, err)
+	}
+
+	return constraints.Check(version), nil
+}
+
+func satisfiesConstraints(version *ver.Version, constraints []dependencyConstraint) (bool, error) {
+	for _, constraint := range constraints {
+		if ok, err := checkVersionConstraint(version, constraint.constraint); err != nil {
+			return false, fmt.Errorf("plugin %s: %s", constraint.requiredBy, err)
+		} else if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func newConflictError(name, reason string, constraints []dependencyConstraint) error {
+	message := fmt.Sprintf("plugin (%s) version conflict: %s", name, reason)
+	for _, constraint := range constraints {
+		message += "\n- " + constraint.String()
+	}
+	return errors.New(message)
+}
+
+// dependentConstraints returns the version constraints declared for the given plugin
+// by the installed plugins, except the excluded ones.
+func dependentConstraints(name string, excludedPlugins []string) ([]dependencyConstraint, error) {
+	installedPlugins, err := InstalledPluginList()
+	if err != nil {
+		return []dependencyConstraint{}, err
+	}
+
+	SortByName(installedPlugins)
+
+	constraints := []dependencyConstraint{}
+	for _, plugin := range installedPlugins {
+		if sliceutil.IsStringInSlice(plugin.Name, excludedPlugins) {
+			continue
+		}
+
+		for _, dependency := range plugin.Dependencies {
+			if dependency.Name == name {
+				constraints = append(constraints, dependencyConstraint{requiredBy: plugin.Name, constraint: dependency.Version})
+			}
+		}
+	}
+
+	return constraints, nil
+}
+
+func selectDependencyVersion(name string, versions []*ver.Version, constraints []dependencyConstraint) (*ver.Version, error) {
+	for i := len(versions) - 1; i >= 0; i-- {
+		if ok, err := satisfiesConstraints(versions[i], constraints); err != nil {
+			return nil, err
+		} else if ok {
+			return versions[i], nil
+		}
+	}
+
+	return nil, newConflictError(name, "no version satisfies every constraint", constraints)
+}
+
+func validateDependencies(dependencies []PluginDependency) error {
+	for _, dependency := range dependencies {
+		if dependency.Name == "" {
+			return fmt.Errorf("plugin dependency name is required")
+		}
+		if dependency.Source == "" {
+			return fmt.Errorf("plugin dependency (%s) source is required", dependency.Name)
+		}
+		if dependency.Version != "" {
+			if _, err := ver.NewConstraint(dependency.Version); err != nil {
+				return fmt.Errorf("invalid plugin dependency (%s) version constraint (%s), error: %s", dependency.Name, dependency.Version, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateBitriseVersion(constraint string, currentVersionMap map[string]ver.Version) error {
+	if constraint == "" {
+		return nil
+	}
+
+	currentVersion := currentVersionMap["bitrise"]
+	if ok, err := checkVersionConstraint(&currentVersion, constraint); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("plugin requires bitrise version (%s), current version: %s", constraint, currentVersion.String())
+	}
+	return nil
+}
+
+// validateDependents checks if the given version of the plugin satisfies the installed plugins, depending on it.
+func validateDependents(name, version string, dependencyStack []string) error {
+	if version == "" {
+		// local plugin, no version to check
+		return nil
+	}
+
+	versionPtr, err := ver.NewVersion(version)
+	if err != nil {
+		return fmt.Errorf("failed to parse plugin version (%s), error: %s", version, err)
+	}
+
+	constraints, err := dependentConstraints(name, append(append([]string{}, dependencyStack...), name))
+	if err != nil {
+		return err
+	}
+
+	if ok, err := satisfiesConstraints(versionPtr, constraints); err != nil {
+		return err
+	} else if !ok {
+		return newConflictError(name, fmt.Sprintf("version (%s) does not satisfy the installed plugins", version), constraints)
+	}
+	return nil
+}
+
+func installPluginDependencies(plugin Plugin, dependencyStack []string) error {
+	stack := append(append([]string{}, dependencyStack...), plugin.Name)
+
+	for _, dependency := range plugin.Dependencies {
+		if sliceutil.IsStringInSlice(dependency.Name, stack) {
+			return fmt.Errorf("plugin dependency cycle found: %s -> %s", strings.Join(stack, " -> "), dependency.Name)
+		}
+
+		constraints, err := dependentConstraints(dependency.Name, stack)
+		if err != nil {
+			return err
+		}
+		constraints = append(constraints, dependencyConstraint{requiredBy: plugin.Name, constraint: dependency.Version})
+
+		_, found, err := ReadPluginRoute(dependency.Name)
+		if err != nil {
+			return err
+		}
+
+		if found {
+			installedVersion, err := GetPluginVersion(dependency.Name)
+			if err != nil {
+				return err
+			}
+
+			if installedVersion == nil {
+				log.Warnf("Dependency plugin (%s) installed from local source, skipping version check", dependency.Name)
+				continue
+			}
+
+			if ok, err := satisfiesConstraints(installedVersion, constraints); err != nil {
+				return err
+			} else if ok {
+				log.Printf("Dependency plugin (%s) with version (%s) already installed", dependency.Name, installedVersion.String())
+				continue
+			}
+
+			if isLocalURL(dependency.Source) {
+				return newConflictError(dependency.Name, fmt.Sprintf("installed version (%s) does not satisfy every constraint", installedVersion.String()), constraints)
+			}
+		}
+
+		versionTag := ""
+		if !isLocalURL(dependency.Source) {
+			versions, err := GitRemoteVersionTags(dependency.Source)
+			if err != nil {
+				return fmt.Errorf("failed to list dependency plugin (%s) versions, error: %s", dependency.Name, err)
+			}
+
+			selectedVersion, err := selectDependencyVersion(dependency.Name, versions, constraints)
+			if err != nil {
+				return err
+			}
+			versionTag = selectedVersion.String()
+
+			log.Infof("Installing dependency plugin (%s) with version (%s)", dependency.Name, versionTag)
+		} else {
+			log.Infof("Installing dependency plugin (%s) from local source", dependency.Name)
+		}
+
+		dependencyPlugin, _, err := installPlugin(dependency.Source, versionTag, stack)
+		if err != nil {
+			return fmt.Errorf("failed to install dependency plugin (%s), error: %s", dependency.Name, err)
+		}
+
+		if dependencyPlugin.Name != dependency.Name {
+			return fmt.Errorf("dependency plugin source (%s) contains plugin (%s) instead of (%s)", dependency.Source, dependencyPlugin.Name, dependency.Name)
+		}
+	}
+
+	return nil
+}
+
+//=======================================
+// Main
+//=======================================
+
+// DependentPlugins returns the name of the installed plugins, depending on the given plugin.
+func DependentPlugins(name string) ([]string, error) {
+	constraints, err := dependentConstraints(name, []string{name})
+	if err != nil {
+		return []string{}, err
+	}
+
+	names := []string{}
+	for _, constraint := range constraints {
+		names = append(names, constraint.requiredBy)
+	}
+	return names, nil
+}
+
+func dependencyNode(name, constraint string, installedPlugins map[string]Plugin, stack []string) PluginDependencyNode {
+	node := PluginDependencyNode{
+		Name:        name,
+		Constraint:  constraint,
+		IsSatisfied: true,
+	}
+
+	plugin, found := installedPlugins[name]
+	if !found {
+		node.IsSatisfied = false
+		return node
+	}
+	node.IsInstalled = true
+
+	if version, err := GetPluginVersion(name); err != nil {
+		log.Warnf("Failed to read plugin (%s) version, error: %s", name, err)
+	} else if version != nil {
+		node.Version = version.String()
+		ok, err := checkVersionConstraint(version, constraint)
+		node.IsSatisfied = (err == nil && ok)
+	}
+
+	if sliceutil.IsStringInSlice(name, stack) {
+		return node
+	}
+
+	childStack := append(append([]string{}, stack...), name)
+	for _, dependency := range plugin.Dependencies {
+		node.Dependencies = append(node.Dependencies, dependencyNode(dependency.Name, dependency.Version, installedPlugins, childStack))
+	}
+
+	return node
+}
+
+// InstalledPluginDependencyTree returns the dependency graph of the installed plugins,
+// the roots are the plugins no other plugin depends on.
+func InstalledPluginDependencyTree() (PluginDependencyTree, error) {
+	installedPlugins, err := InstalledPluginList()
+	if err != nil {
+		return PluginDependencyTree{}, err
+	}
+
+	SortByName(installedPlugins)
+
+	pluginByName := map[string]Plugin{}
+	isDependency := map[string]bool{}
+	for _, plugin := range installedPlugins {
+		pluginByName[plugin.Name] = plugin
+		for _, dependency := range plugin.Dependencies {
+			isDependency[dependency.Name] = true
+		}
+	}
+
+	tree := PluginDependencyTree{}
+	for _, plugin := range installedPlugins {
+		if !isDependency[plugin.Name] {
+			tree = append(tree, dependencyNode(plugin.Name, "", pluginByName, []string{}))
+		}
+	}
+
+	return tree, nil
+}
@@ -0,0 +1,138 @@
+package plugins
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/pathutil"
+	ver "github.com/hashicorp/go-version"
+	"github.com/stretchr/testify/require"
+)
+
+func givenVersions(t *testing.T, versions ...string) []*ver.Version {
+	versionPtrs := []*ver.Version{}
+	for _, version := range versions {
+		versionPtr, err := ver.NewVersion(version)
+		require.NoError(t, err)
+		versionPtrs = append(versionPtrs, versionPtr)
+	}
+	return versionPtrs
+}
+
+func givenInstalledPlugin(t *testing.T, name, version, definition string) {
+	require.NoError(t, os.MkdirAll(GetPluginSrcDir(name), 0777))
+	require.NoError(t, fileutil.WriteStringToFile(GetPluginDefinitionPath(name), definition))
+	require.NoError(t, AddPluginRoute(PluginRoute{Name: name, Source: "https://github.com/bitrise-io/bitrise-plugins-" + name + ".git", Version: version}))
+}
+
+func TestSelectDependencyVersion(t *testing.T) {
+	versions := givenVersions(t, "0.9.0", "1.0.0", "1.2.0", "2.0.0")
+
+	t.Log("highest version satisfying every constraint")
+	{
+		constraints := []dependencyConstraint{
+			{requiredBy: "analytics", constraint: ">= 1.0.0"},
+			{requiredBy: "init", constraint: "< 2.0.0"},
+		}
+
+		version, err := selectDependencyVersion("step", versions, constraints)
+		require.NoError(t, err)
+		require.Equal(t, "1.2.0", version.String())
+	}
+
+	t.Log("any version")
+	{
+		version, err := selectDependencyVersion("step", versions, []dependencyConstraint{{requiredBy: "analytics"}})
+		require.NoError(t, err)
+		require.Equal(t, "2.0.0", version.String())
+	}
+
+	t.Log("conflict")
+	{
+		constraints := []dependencyConstraint{
+			{requiredBy: "analytics", constraint: ">= 2.0.0"},
+			{requiredBy: "init", constraint: "< 2.0.0"},
+		}
+
+		_, err := selectDependencyVersion("step", versions, constraints)
+		require.EqualError(t, err, `plugin (step) version conflict: no version satisfies every constraint
+- (analytics) requires (>= 2.0.0)
+- (init) requires (< 2.0.0)`)
+	}
+
+	t.Log("invalid constraint")
+	{
+		_, err := selectDependencyVersion("step", versions, []dependencyConstraint{{requiredBy: "analytics", constraint: "latest"}})
+		require.Error(t, err)
+	}
+}
+
+func TestValidateBitriseVersion(t *testing.T) {
+	bitriseVersion, err := ver.NewVersion("1.46.0")
+	require.NoError(t, err)
+
+	currentVersionMap := map[string]ver.Version{"bitrise": *bitriseVersion}
+
+	require.NoError(t, validateBitriseVersion("", currentVersionMap))
+	require.NoError(t, validateBitriseVersion(">= 1.40.0, < 2.0.0", currentVersionMap))
+	require.Error(t, validateBitriseVersion(">= 2.0.0", currentVersionMap))
+	require.Error(t, validateBitriseVersion("invalid", currentVersionMap))
+}
+
+func TestValidateDependencies(t *testing.T) {
+	require.NoError(t, validateDependencies([]PluginDependency{{Name: "step", Source: "https://github.com/bitrise-io/bitrise-plugins-step.git", Version: "~> 1.0"}}))
+	require.Error(t, validateDependencies([]PluginDependency{{Source: "https://github.com/bitrise-io/bitrise-plugins-step.git"}}))
+	require.Error(t, validateDependencies([]PluginDependency{{Name: "step"}}))
+	require.Error(t, validateDependencies([]PluginDependency{{Name: "step", Source: "https://github.com/bitrise-io/bitrise-plugins-step.git", Version: "latest"}}))
+}
+
+func TestInstalledPluginDependencyTree(t *testing.T) {
+	bitriseDir, err := pathutil.NormalizedOSTempDirPath("plugin-dependency-test")
+	require.NoError(t, err)
+	ForceInitPaths(bitriseDir)
+	require.NoError(t, os.MkdirAll(pluginsDir, 0777))
+
+	givenInstalledPlugin(t, "analytics", "1.0.0", `name: analytics
+dependencies:
+- name: step
+  source: https://github.com/bitrise-io/bitrise-plugins-step.git
+  version: ">= 1.0.0"
+- name: workflow-editor
+  source: https://github.com/bitrise-io/bitrise-workflow-editor.git
+`)
+	givenInstalledPlugin(t, "init", "1.0.0", `name: init
+dependencies:
+- name: step
+  source: https://github.com/bitrise-io/bitrise-plugins-step.git
+  version: ">= 2.0.0"
+`)
+	givenInstalledPlugin(t, "step", "1.2.0", `name: step
+`)
+
+	tree, err := InstalledPluginDependencyTree()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(tree))
+
+	require.Equal(t, "analytics", tree[0].Name)
+	require.Equal(t, 2, len(tree[0].Dependencies))
+	require.Equal(t, PluginDependencyNode{Name: "step", Version: "1.2.0", Constraint: ">= 1.0.0", IsInstalled: true, IsSatisfied: true}, tree[0].Dependencies[0])
+	require.Equal(t, PluginDependencyNode{Name: "workflow-editor"}, tree[0].Dependencies[1])
+
+	require.Equal(t, "init", tree[1].Name)
+	require.Equal(t, PluginDependencyNode{Name: "step", Version: "1.2.0", Constraint: ">= 2.0.0", IsInstalled: true}, tree[1].Dependencies[0])
+
+	dependents, err := DependentPlugins("step")
+	require.NoError(t, err)
+	require.Equal(t, []string{"analytics", "init"}, dependents)
+
+	t.Log("installed plugins version conflict")
+	{
+		require.Error(t, validateDependents("step", "1.5.0", []string{}))
+		require.NoError(t, validateDependents("step", "2.1.0", []string{}))
+		require.NoError(t, validateDependents("step", "", []string{}))
+	}
+
+	require.NoError(t, os.RemoveAll(filepath.Join(bitriseDir, pluginsDirName)))
+}
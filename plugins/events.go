@@ -3,6 +3,7 @@ package plugins
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 
 	"github.com/bitrise-io/go-utils/sliceutil"
 )
@@ -18,12 +19,16 @@ const (
 	DidFinishRun TriggerEventName = "DidFinishRun"
 )
 
+func isKnownTriggerEvent(name string) bool {
+	return sliceutil.IsStringInSlice(name, []string{string(WillStartRun), string(DidFinishRun)})
+}
+
 // TriggerEvent ...
-func TriggerEvent(name TriggerEventName, payload interface{}) error {
+func TriggerEvent(name TriggerEventName, payload interface{}) ([]PluginResult, error) {
 	// Create plugin input
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		return err
+		return []PluginResult{}, err
 	}
 
 	pluginConfig := PluginConfig{
@@ -33,17 +38,20 @@ func TriggerEvent(name TriggerEventName, payload interface{}) error {
 	// Load plugins
 	plugins, err := LoadPlugins(string(name))
 	if err != nil {
-		return err
+		return []PluginResult{}, err
 	}
 
 	// Run plugins
+	results := []PluginResult{}
 	for _, plugin := range plugins {
-		if err := RunPluginByEvent(plugin, pluginConfig, payloadBytes); err != nil {
-			return err
+		result, err := RunPluginByEvent(plugin, pluginConfig, payloadBytes)
+		if err != nil {
+			return results, err
 		}
+		results = append(results, result)
 	}
 
-	return nil
+	return results, nil
 }
 
 // LoadPlugins ...
@@ -61,6 +69,8 @@ func LoadPlugins(eventName string) ([]Plugin, error) {
 		}
 	}
 
+	sort.Strings(pluginNames)
+
 	plugins := []Plugin{}
 	for _, name := range pluginNames {
 		plugin, found, err := LoadPlugin(name)
@@ -73,8 +73,12 @@ func gitRemoteTagList(cloneIntoDir string) ([]string, error) {
 	if err != nil {
 		return []string{}, err
 	}
+	return parseTagList(out), nil
+}
+
+func parseTagList(out string) []string {
 	if out == "" {
-		return []string{}, nil
+		return []string{}
 	}
 
 	var exp = regexp.MustCompile(`(^[a-z0-9]+)+.*refs/tags/([0-9.]+)`)
@@ -98,7 +102,7 @@ func gitRemoteTagList(cloneIntoDir string) ([]string, error) {
 		versions = append(versions, key)
 	}
 
-	return versions, nil
+	return versions
 }
 
 func gitInit(cloneIntoDir string) error {
@@ -189,6 +193,21 @@ func GitVersionTags(gitRepoDir string) ([]*ver.Version, error) {
 	return tags, nil
 }
 
+// GitRemoteVersionTags lists the version tags of the repository, without cloning it.
+func GitRemoteVersionTags(repositoryURL string) ([]*ver.Version, error) {
+	cmd := command.New("git", "ls-remote", "--tags", repositoryURL)
+	out, err := runForOutputAndHandle(cmd)
+	if err != nil {
+		return []*ver.Version{}, fmt.Errorf("Could not get version tag list, error: %s", err)
+	}
+
+	tags := filterVersionTags(parseTagList(out))
+
+	sort.Sort(ByVersion(tags))
+
+	return tags, nil
+}
+
 // GitCloneAndCheckoutVersionOrLatestVersion ...
 func GitCloneAndCheckoutVersionOrLatestVersion(cloneIntoDir, repositoryURL, checkoutVersion string) (string, error) {
 	if err := gitInitWithRemote(cloneIntoDir, repositoryURL); err != nil {
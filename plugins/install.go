@@ -114,7 +114,7 @@ func isSourceURIChanged(installed, new string) bool {
 	return true
 }
 
-func installLocalPlugin(pluginSourceURI, pluginLocalPth string) (Plugin, error) {
+func installLocalPlugin(pluginSourceURI, pluginLocalPth, version string, dependencyStack []string) (Plugin, error) {
 	// Parse & validate plugin
 	tmpPluginYMLPath := filepath.Join(pluginLocalPth, pluginDefinitionFileName)
 
@@ -130,6 +130,16 @@ func installLocalPlugin(pluginSourceURI, pluginLocalPth string) (Plugin, error)
 	if err := validatePlugin(newPlugin, pluginSourceURI); err != nil {
 		return Plugin{}, fmt.Errorf("plugin validation failed, error: %s", err)
 	}
+
+	if err := validateDependents(newPlugin.Name, version, dependencyStack); err != nil {
+		return Plugin{}, err
+	}
+	// ---
+
+	// Install plugin dependencies
+	if err := installPluginDependencies(newPlugin, dependencyStack); err != nil {
+		return Plugin{}, fmt.Errorf("failed to install plugin dependencies, error: %s", err)
+	}
 	// ---
 
 	// Check if plugin already installed
@@ -233,8 +243,12 @@ func isLocalURL(urlStr string) bool {
 // Main
 //=======================================
 
-// InstallPlugin ...
+// InstallPlugin installs the plugin and its dependencies.
 func InstallPlugin(pluginSourceURI, versionTag string) (Plugin, string, error) {
+	return installPlugin(pluginSourceURI, versionTag, []string{})
+}
+
+func installPlugin(pluginSourceURI, versionTag string, dependencyStack []string) (Plugin, string, error) {
 	newVersion := ""
 	pluginDir := ""
 
@@ -267,7 +281,7 @@ func InstallPlugin(pluginSourceURI, versionTag string) (Plugin, string, error) {
 		pluginDir = pluginSourceURI
 	}
 
-	newPlugin, err := installLocalPlugin(pluginSourceURI, pluginDir)
+	newPlugin, err := installLocalPlugin(pluginSourceURI, pluginDir, newVersion, dependencyStack)
 	if err != nil {
 		return Plugin{}, "", err
 	}
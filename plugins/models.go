@@ -1,5 +1,7 @@
 package plugins
 
+import "encoding/json"
+
 const (
 	// TypeGeneric ...
 	TypeGeneric = "_"
@@ -11,14 +13,15 @@ const (
 
 // PluginRoute ...
 type PluginRoute struct {
-	Name                   string   `yaml:"name"`
-	Source                 string   `yaml:"source"`
-	Version                string   `yaml:"version"`
-	CommitHash             string   `yaml:"commit_hash"`
-	Executable             string   `yaml:"executable"`
-	TriggerEvent           string   `yaml:"trigger"`
-	TriggerEvents          []string `yaml:"triggers"`
-	LatestAvailableVersion string   `yaml:"latest_available_version"`
+	Name                   string          `yaml:"name"`
+	Source                 string          `yaml:"source"`
+	Version                string          `yaml:"version"`
+	CommitHash             string          `yaml:"commit_hash"`
+	Executable             string          `yaml:"executable"`
+	TriggerEvent           string          `yaml:"trigger"`
+	TriggerEvents          []string        `yaml:"triggers"`
+	LatestAvailableVersion string          `yaml:"latest_available_version"`
+	Commands               []PluginCommand `yaml:"commands,omitempty"`
 }
 
 // PluginRouting ...
@@ -39,6 +42,22 @@ type Requirement struct {
 	MaxVersion string `yaml:"max_version"`
 }
 
+// PluginDependency ...
+type PluginDependency struct {
+	Name    string `yaml:"name" json:"name"`
+	Source  string `yaml:"source" json:"source"`
+	Version string `yaml:"version,omitempty" json:"version,omitempty"`
+}
+
+// PluginPermissions ...
+type PluginPermissions struct {
+	// Envs lists the allowed env keys of the parent environment, glob patterns are supported.
+	Envs       []string `yaml:"envs,omitempty"`
+	ReadPaths  []string `yaml:"read_paths,omitempty"`
+	WritePaths []string `yaml:"write_paths,omitempty"`
+	Network    bool     `yaml:"network,omitempty"`
+}
+
 // Plugin ...
 type Plugin struct {
 	Name          string          `yaml:"name,omitempty"`
@@ -47,6 +66,11 @@ type Plugin struct {
 	TriggerEvent  string          `yaml:"trigger,omitempty"`
 	TriggerEvents []string        `yaml:"triggers,omitempty"`
 	Requirements  []Requirement   `yaml:"requirements,omitempty"`
+	Protocol      string          `yaml:"protocol,omitempty"`
+	// BitriseVersion is a version constraint for the bitrise CLI, like: >= 1.40.0, < 2.0.0
+	BitriseVersion string             `yaml:"bitrise_version,omitempty"`
+	Dependencies   []PluginDependency `yaml:"dependencies,omitempty"`
+	Permissions    *PluginPermissions `yaml:"permissions,omitempty"`
 }
 
 // PluginInfoModel ...
@@ -60,3 +84,39 @@ type PluginInfoModel struct {
 
 // PluginInfos ...
 type PluginInfos []PluginInfoModel
+
+// PluginCommand ...
+type PluginCommand struct {
+	Name  string `json:"name" yaml:"name"`
+	Usage string `json:"usage,omitempty" yaml:"usage,omitempty"`
+}
+
+// PluginEnv ...
+type PluginEnv struct {
+	Key   string `json:"key" yaml:"key"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// PluginResult ...
+type PluginResult struct {
+	PluginName  string          `json:"plugin_name"`
+	Data        json.RawMessage `json:"data,omitempty"`
+	Envs        []PluginEnv     `json:"envs,omitempty"`
+	Commands    []PluginCommand `json:"commands,omitempty"`
+	Events      []string        `json:"events,omitempty"`
+	AbortBuild  bool            `json:"abort_build,omitempty"`
+	AbortReason string          `json:"abort_reason,omitempty"`
+}
+
+// PluginDependencyNode ...
+type PluginDependencyNode struct {
+	Name         string                 `json:"name"`
+	Version      string                 `json:"version,omitempty"`
+	Constraint   string                 `json:"constraint,omitempty"`
+	IsInstalled  bool                   `json:"is_installed"`
+	IsSatisfied  bool                   `json:"is_satisfied"`
+	Dependencies []PluginDependencyNode `json:"dependencies,omitempty"`
+}
+
+// PluginDependencyTree ...
+type PluginDependencyTree []PluginDependencyNode
@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 
 	"github.com/bitrise-io/bitrise/version"
+	envmanModels "github.com/bitrise-io/envman/models"
 	"github.com/bitrise-io/go-utils/colorstring"
 	"github.com/bitrise-io/go-utils/command"
 	"github.com/bitrise-io/go-utils/fileutil"
@@ -121,6 +123,12 @@ func validatePlugin(plugin Plugin, pluginDefinitionPth string) error {
 		linuxRemoteExecutable = true
 	}
 
+	switch PluginProtocol(plugin.Protocol) {
+	case "", EnvProtocol, JSONRPCProtocol:
+	default:
+		return fmt.Errorf("invalid protocol (%s), supported: %s, %s", plugin.Protocol, EnvProtocol, JSONRPCProtocol)
+	}
+
 	if linuxRemoteExecutable != osxRemoteExecutable {
 		return errors.New("both osx and linux executable should be defined, or non of them")
 	}
@@ -145,6 +153,18 @@ func validatePlugin(plugin Plugin, pluginDefinitionPth string) error {
 	if err := validateRequirements(plugin.Requirements, currentVersionMap); err != nil {
 		return fmt.Errorf("requirements validation failed, error: %s", err)
 	}
+
+	if err := validateBitriseVersion(plugin.BitriseVersion, currentVersionMap); err != nil {
+		return fmt.Errorf("bitrise version validation failed, error: %s", err)
+	}
+
+	if err := validateDependencies(plugin.Dependencies); err != nil {
+		return fmt.Errorf("dependencies validation failed, error: %s", err)
+	}
+
+	if err := validatePermissions(plugin.Permissions); err != nil {
+		return fmt.Errorf("permissions validation failed, error: %s", err)
+	}
 	// ---
 
 	return nil
@@ -245,6 +265,77 @@ func (s *pluginSorter) Less(i, j int) bool {
 	return s.sortBy(&s.plugins[i], &s.plugins[j])
 }
 
+//=======================================
+// PluginDependencyTree
+//=======================================
+
+func (node PluginDependencyNode) string(prefix string, isLast, isRoot bool) string {
+	line := colorstring.Green(node.Name)
+	details := []string{}
+	if node.Version != "" {
+		details = append(details, node.Version)
+	}
+	if node.Constraint != "" {
+		details = append(details, "requires "+node.Constraint)
+	}
+	if len(details) > 0 {
+		line += " (" + strings.Join(details, ", ") + ")"
+	}
+	if !node.IsInstalled {
+		line += " " + colorstring.Red("not installed")
+	} else if !node.IsSatisfied {
+		line += " " + colorstring.Red("version conflict")
+	}
+
+	str := ""
+	childPrefix := ""
+	if isRoot {
+		str = line + "\n"
+	} else if isLast {
+		str = prefix + "└── " + line + "\n"
+		childPrefix = prefix + "    "
+	} else {
+		str = prefix + "├── " + line + "\n"
+		childPrefix = prefix + "│   "
+	}
+
+	for idx, dependency := range node.Dependencies {
+		str += dependency.string(childPrefix, idx == len(node.Dependencies)-1, false)
+	}
+	return str
+}
+
+// String ...
+func (tree PluginDependencyTree) String() string {
+	str := ""
+	for _, node := range tree {
+		str += node.string("", true, true)
+	}
+	return str
+}
+
+// JSON ...
+func (tree PluginDependencyTree) JSON() string {
+	bytes, err := json.Marshal(tree)
+	if err != nil {
+		return fmt.Sprintf(`"Failed to marshal plugin dependency tree (%#v), err: %s"`, tree, err)
+	}
+	return string(bytes) + "\n"
+}
+
+//=======================================
+// PluginResult
+//=======================================
+
+// EnvironmentItems returns the envs the plugin injected into the build.
+func (result PluginResult) EnvironmentItems() []envmanModels.EnvironmentItemModel {
+	envs := []envmanModels.EnvironmentItemModel{}
+	for _, env := range result.Envs {
+		envs = append(envs, envmanModels.EnvironmentItemModel{env.Key: env.Value})
+	}
+	return envs
+}
+
 //=======================================
 // PluginRoute
 //=======================================
@@ -10,6 +10,7 @@ import (
 	"github.com/bitrise-io/go-utils/fileutil"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-utils/sliceutil"
 	ver "github.com/hashicorp/go-version"
 )
 
@@ -64,6 +65,47 @@ func DeletePluginRoute(name string) error {
 	return writeRoutingToFile(routing)
 }
 
+// UpdatePluginRouteRegistrations saves the commands and events registered by the plugin through the plugin api.
+func UpdatePluginRouteRegistrations(name string, commands []PluginCommand, events []string) error {
+	if len(commands) == 0 && len(events) == 0 {
+		return nil
+	}
+
+	routing, err := readPluginRouting()
+	if err != nil {
+		return err
+	}
+
+	route, found := routing.RouteMap[name]
+	if !found {
+		return fmt.Errorf("plugin not installed with name (%s)", name)
+	}
+
+	for _, command := range commands {
+		for otherName, otherRoute := range routing.RouteMap {
+			if otherName == name {
+				continue
+			}
+			for _, otherCommand := range otherRoute.Commands {
+				if otherCommand.Name == command.Name {
+					return fmt.Errorf("command (%s) already registered by plugin (%s)", command.Name, otherName)
+				}
+			}
+		}
+	}
+	route.Commands = commands
+
+	for _, event := range events {
+		if event != route.TriggerEvent && !sliceutil.IsStringInSlice(event, route.TriggerEvents) {
+			route.TriggerEvents = append(route.TriggerEvents, event)
+		}
+	}
+
+	routing.AddRoute(route)
+
+	return writeRoutingToFile(routing)
+}
+
 // GetPluginVersion ...
 func GetPluginVersion(name string) (*ver.Version, error) {
 	route, found, err := ReadPluginRoute(name)
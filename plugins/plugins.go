@@ -19,6 +19,8 @@ const (
 	PluginConfigDataDirKey = "BITRISE_PLUGIN_INPUT_DATA_DIR"
 	// PluginConfigFormatVersionKey ...
 	PluginConfigFormatVersionKey = "BITRISE_PLUGIN_INPUT_FORMAT_VERSION"
+	// PluginConfigRPCSocketKey ...
+	PluginConfigRPCSocketKey = "BITRISE_PLUGIN_INPUT_RPC_SOCKET"
 
 	// PluginOutputEnvKey ...
 	PluginOutputEnvKey = "BITRISE_PLUGIN_OUTPUT"
@@ -36,6 +38,16 @@ const (
 // PluginMode ...
 type PluginMode string
 
+const (
+	// EnvProtocol ...
+	EnvProtocol PluginProtocol = "env"
+	// JSONRPCProtocol ...
+	JSONRPCProtocol PluginProtocol = "jsonrpc"
+)
+
+// PluginProtocol ...
+type PluginProtocol string
+
 // PluginConfig ...
 type PluginConfig map[string]string
 
@@ -68,6 +80,29 @@ func ParseArgs(args []string) (string, []string, bool) {
 	return "", []string{}, false
 }
 
+// ParseCommandArgs checks if the first arg is a command registered by an installed plugin,
+// the command name is kept as the first plugin arg.
+func ParseCommandArgs(args []string) (string, []string, bool, error) {
+	if len(args) == 0 || args[0] == "" {
+		return "", []string{}, false, nil
+	}
+
+	routing, err := readPluginRouting()
+	if err != nil {
+		return "", []string{}, false, err
+	}
+
+	for name, route := range routing.RouteMap {
+		for _, command := range route.Commands {
+			if command.Name == args[0] {
+				return name, args, true, nil
+			}
+		}
+	}
+
+	return "", []string{}, false, nil
+}
+
 // CheckForNewVersion ...
 func CheckForNewVersion(plugin Plugin) (string, error) {
 	route, found, err := ReadPluginRoute(plugin.Name)
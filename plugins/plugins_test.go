@@ -1,8 +1,10 @@
 package plugins
 
 import (
+	"os"
 	"testing"
 
+	"github.com/bitrise-io/go-utils/pathutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -52,3 +54,44 @@ func TestParseArgs(t *testing.T) {
 		require.Equal(t, 0, len(pluginArgs))
 	}
 }
+
+func TestParseCommandArgs(t *testing.T) {
+	bitriseDir, err := pathutil.NormalizedOSTempDirPath("plugin-command-test")
+	require.NoError(t, err)
+	ForceInitPaths(bitriseDir)
+	require.NoError(t, os.MkdirAll(pluginsDir, 0777))
+
+	require.NoError(t, AddPluginRoute(PluginRoute{Name: "analytics", Source: "https://github.com/bitrise-io/bitrise-plugins-analytics.git"}))
+	require.NoError(t, AddPluginRoute(PluginRoute{Name: "init", Source: "https://github.com/bitrise-io/bitrise-plugins-init.git"}))
+	require.NoError(t, UpdatePluginRouteRegistrations("analytics", []PluginCommand{{Name: "stats"}}, []string{string(WillStartRun)}))
+
+	t.Log("registered command")
+	{
+		pluginName, pluginArgs, isPlugin, err := ParseCommandArgs([]string{"stats", "--last", "5"})
+		require.NoError(t, err)
+		require.Equal(t, true, isPlugin)
+		require.Equal(t, "analytics", pluginName)
+		require.EqualValues(t, []string{"stats", "--last", "5"}, pluginArgs)
+	}
+
+	t.Log("not registered command")
+	{
+		pluginName, _, isPlugin, err := ParseCommandArgs([]string{"hello"})
+		require.NoError(t, err)
+		require.Equal(t, false, isPlugin)
+		require.Equal(t, "", pluginName)
+	}
+
+	t.Log("subscribed events")
+	{
+		route, found, err := ReadPluginRoute("analytics")
+		require.NoError(t, err)
+		require.Equal(t, true, found)
+		require.EqualValues(t, []string{string(WillStartRun)}, route.TriggerEvents)
+	}
+
+	t.Log("command already registered by other plugin")
+	{
+		require.Error(t, UpdatePluginRouteRegistrations("init", []PluginCommand{{Name: "stats"}}, nil))
+	}
+}
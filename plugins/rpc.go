@@ -0,0 +1,216 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"sync"
+
+	"github.com/bitrise-io/go-utils/sliceutil"
+)
+
+// RPCServiceName is the name the bitrise service is registered with,
+// plugins call its methods as `Bitrise.<Method>`.
+const RPCServiceName = "Bitrise"
+
+// RPCEmptyArgs ...
+type RPCEmptyArgs struct{}
+
+// RPCEventReply ...
+type RPCEventReply struct {
+	Name    string          `json:"name"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// RPCResultArgs ...
+type RPCResultArgs struct {
+	Data json.RawMessage `json:"data"`
+}
+
+// RPCEnvsArgs ...
+type RPCEnvsArgs struct {
+	Envs []PluginEnv `json:"envs"`
+}
+
+// RPCCommandArgs ...
+type RPCCommandArgs struct {
+	Command PluginCommand `json:"command"`
+}
+
+// RPCSubscribeArgs ...
+type RPCSubscribeArgs struct {
+	Events []string `json:"events"`
+}
+
+// RPCAbortArgs ...
+type RPCAbortArgs struct {
+	Reason string `json:"reason"`
+}
+
+// RPCService is served to the plugins with jsonrpc protocol,
+// it exposes the plugin inputs and collects the plugin's result.
+type RPCService struct {
+	config  PluginConfig
+	event   RPCEventReply
+	mutex   sync.Mutex
+	result  PluginResult
+	isReady bool
+}
+
+func newRPCService(pluginName string, config PluginConfig, input []byte) *RPCService {
+	service := RPCService{
+		config: config,
+		result: PluginResult{PluginName: pluginName},
+	}
+
+	if eventName := config[PluginConfigTriggerEventKey]; eventName != "" {
+		service.event = RPCEventReply{Name: eventName}
+		if len(input) > 0 && json.Valid(input) {
+			service.event.Payload = json.RawMessage(input)
+		}
+	}
+
+	return &service
+}
+
+// Config returns the plugin inputs, the same ones the env based plugins receive as envs.
+func (s *RPCService) Config(args RPCEmptyArgs, reply *PluginConfig) error {
+	*reply = s.config
+	return nil
+}
+
+// Event returns the event which triggered the plugin, empty in command mode.
+func (s *RPCService) Event(args RPCEmptyArgs, reply *RPCEventReply) error {
+	*reply = s.event
+	return nil
+}
+
+// SetResult stores the plugin's structured result.
+func (s *RPCService) SetResult(args RPCResultArgs, reply *bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.result.Data = args.Data
+	*reply = true
+	return nil
+}
+
+// AddEnvs injects the given envs into the build, only allowed in trigger mode.
+func (s *RPCService) AddEnvs(args RPCEnvsArgs, reply *bool) error {
+	if s.event.Name == "" {
+		return fmt.Errorf("envs can only be added by event triggered plugins")
+	}
+
+	for _, env := range args.Envs {
+		if env.Key == "" {
+			return fmt.Errorf("env key is required")
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.result.Envs = append(s.result.Envs, args.Envs...)
+	*reply = true
+	return nil
+}
+
+// RegisterCommand registers an additional bitrise CLI command, handled by the plugin.
+func (s *RPCService) RegisterCommand(args RPCCommandArgs, reply *bool) error {
+	if args.Command.Name == "" {
+		return fmt.Errorf("command name is required")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, command := range s.result.Commands {
+		if command.Name == args.Command.Name {
+			return fmt.Errorf("command (%s) already registered", command.Name)
+		}
+	}
+
+	s.result.Commands = append(s.result.Commands, args.Command)
+	*reply = true
+	return nil
+}
+
+// Subscribe subscribes the plugin to the given events.
+func (s *RPCService) Subscribe(args RPCSubscribeArgs, reply *bool) error {
+	for _, event := range args.Events {
+		if !isKnownTriggerEvent(event) {
+			return fmt.Errorf("unknown event (%s)", event)
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, event := range args.Events {
+		if !sliceutil.IsStringInSlice(event, s.result.Events) {
+			s.result.Events = append(s.result.Events, event)
+		}
+	}
+	*reply = true
+	return nil
+}
+
+// AbortBuild requests the build to be aborted, only allowed in trigger mode.
+func (s *RPCService) AbortBuild(args RPCAbortArgs, reply *bool) error {
+	if s.event.Name == "" {
+		return fmt.Errorf("build abort can only be requested by event triggered plugins")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.result.AbortBuild = true
+	s.result.AbortReason = args.Reason
+	*reply = true
+	return nil
+}
+
+func (s *RPCService) pluginResult() PluginResult {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.result
+}
+
+type rpcServer struct {
+	listener net.Listener
+	service  *RPCService
+}
+
+func startRPCServer(socketPth string, service *RPCService) (*rpcServer, error) {
+	server := rpc.NewServer()
+	if err := server.RegisterName(RPCServiceName, service); err != nil {
+		return nil, fmt.Errorf("failed to register rpc service, error: %s", err)
+	}
+
+	listener, err := net.Listen("unix", socketPth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on (%s), error: %s", socketPth, err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+		}
+	}()
+
+	return &rpcServer{
+		listener: listener,
+		service:  service,
+	}, nil
+}
+
+func (s *rpcServer) Close() error {
+	return s.listener.Close()
+}
@@ -0,0 +1,88 @@
+package plugins
+
+import (
+	"net/rpc/jsonrpc"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRPCService(t *testing.T) {
+	tmpDir, err := pathutil.NormalizedOSTempDirPath("plugin-rpc-test")
+	require.NoError(t, err)
+
+	t.Log("trigger mode")
+	{
+		config := PluginConfig{
+			PluginConfigPluginModeKey:   string(TriggerMode),
+			PluginConfigTriggerEventKey: string(DidFinishRun),
+		}
+		socketPth := filepath.Join(tmpDir, "trigger.sock")
+		server, err := startRPCServer(socketPth, newRPCService("analytics", config, []byte(`{"event_name":"DidFinishRun"}`)))
+		require.NoError(t, err)
+
+		client, err := jsonrpc.Dial("unix", socketPth)
+		require.NoError(t, err)
+
+		var receivedConfig PluginConfig
+		require.NoError(t, client.Call("Bitrise.Config", RPCEmptyArgs{}, &receivedConfig))
+		require.Equal(t, config, receivedConfig)
+
+		var event RPCEventReply
+		require.NoError(t, client.Call("Bitrise.Event", RPCEmptyArgs{}, &event))
+		require.Equal(t, string(DidFinishRun), event.Name)
+		require.Equal(t, `{"event_name":"DidFinishRun"}`, string(event.Payload))
+
+		var ok bool
+		require.NoError(t, client.Call("Bitrise.SetResult", RPCResultArgs{Data: []byte(`{"sent":true}`)}, &ok))
+		require.NoError(t, client.Call("Bitrise.AddEnvs", RPCEnvsArgs{Envs: []PluginEnv{{Key: "ANALYTICS_ID", Value: "1"}}}, &ok))
+		require.NoError(t, client.Call("Bitrise.Subscribe", RPCSubscribeArgs{Events: []string{string(WillStartRun)}}, &ok))
+		require.NoError(t, client.Call("Bitrise.RegisterCommand", RPCCommandArgs{Command: PluginCommand{Name: "analytics"}}, &ok))
+		require.NoError(t, client.Call("Bitrise.AbortBuild", RPCAbortArgs{Reason: "quota exceeded"}, &ok))
+
+		require.Error(t, client.Call("Bitrise.Subscribe", RPCSubscribeArgs{Events: []string{"DidSomething"}}, &ok))
+		require.Error(t, client.Call("Bitrise.RegisterCommand", RPCCommandArgs{Command: PluginCommand{Name: "analytics"}}, &ok))
+
+		require.NoError(t, client.Close())
+		require.NoError(t, server.Close())
+
+		result := server.service.pluginResult()
+		require.Equal(t, "analytics", result.PluginName)
+		require.Equal(t, `{"sent":true}`, string(result.Data))
+		require.Equal(t, []PluginEnv{{Key: "ANALYTICS_ID", Value: "1"}}, result.Envs)
+		require.Equal(t, []string{string(WillStartRun)}, result.Events)
+		require.Equal(t, []PluginCommand{{Name: "analytics"}}, result.Commands)
+		require.Equal(t, true, result.AbortBuild)
+		require.Equal(t, "quota exceeded", result.AbortReason)
+	}
+
+	t.Log("command mode - envs and abort are not allowed")
+	{
+		config := PluginConfig{
+			PluginConfigPluginModeKey: string(CommandMode),
+		}
+		socketPth := filepath.Join(tmpDir, "command.sock")
+		server, err := startRPCServer(socketPth, newRPCService("init", config, nil))
+		require.NoError(t, err)
+
+		client, err := jsonrpc.Dial("unix", socketPth)
+		require.NoError(t, err)
+
+		var event RPCEventReply
+		require.NoError(t, client.Call("Bitrise.Event", RPCEmptyArgs{}, &event))
+		require.Equal(t, "", event.Name)
+
+		var ok bool
+		require.Error(t, client.Call("Bitrise.AddEnvs", RPCEnvsArgs{Envs: []PluginEnv{{Key: "KEY", Value: "value"}}}, &ok))
+		require.Error(t, client.Call("Bitrise.AbortBuild", RPCAbortArgs{}, &ok))
+
+		require.NoError(t, client.Close())
+		require.NoError(t, server.Close())
+
+		result := server.service.pluginResult()
+		require.Equal(t, 0, len(result.Envs))
+		require.Equal(t, false, result.AbortBuild)
+	}
+}
@@ -59,14 +59,14 @@ func strip(str string) string {
 //=======================================
 
 // RunPluginByEvent ...
-func RunPluginByEvent(plugin Plugin, pluginConfig PluginConfig, input []byte) error {
+func RunPluginByEvent(plugin Plugin, pluginConfig PluginConfig, input []byte) (PluginResult, error) {
 	pluginConfig[PluginConfigPluginModeKey] = string(TriggerMode)
 
 	return runPlugin(plugin, []string{}, pluginConfig, input)
 }
 
 // RunPluginByCommand ...
-func RunPluginByCommand(plugin Plugin, args []string) error {
+func RunPluginByCommand(plugin Plugin, args []string) (PluginResult, error) {
 	pluginConfig := PluginConfig{
 		PluginConfigPluginModeKey: string(CommandMode),
 	}
@@ -83,7 +83,7 @@ func PrintPluginUpdateInfos(newVersion string, plugin Plugin) {
 	flog.Donef("$ bitrise plugin update %s", plugin.Name)
 }
 
-func runPlugin(plugin Plugin, args []string, envs PluginConfig, input []byte) error {
+func runPlugin(plugin Plugin, args []string, envs PluginConfig, input []byte) (PluginResult, error) {
 	if !configs.IsCIMode && configs.CheckIsPluginUpdateCheckRequired(plugin.Name) {
 		// Check for new version
 		log.Infof("Checking for plugin (%s) new version...", plugin.Name)
@@ -96,7 +96,7 @@ func runPlugin(plugin Plugin, args []string, envs PluginConfig, input []byte) er
 		}
 
 		if err := configs.SavePluginUpdateCheck(plugin.Name); err != nil {
-			return err
+			return PluginResult{}, err
 		}
 
 		fmt.Println()
@@ -105,7 +105,7 @@ func runPlugin(plugin Plugin, args []string, envs PluginConfig, input []byte) er
 	// Append common data to plugin iputs
 	bitriseVersion, err := version.BitriseCliVersion()
 	if err != nil {
-		return err
+		return PluginResult{}, err
 	}
 	envs[PluginConfigBitriseVersionKey] = bitriseVersion.String()
 	envs[PluginConfigDataDirKey] = GetPluginDataDir(plugin.Name)
@@ -114,7 +114,7 @@ func runPlugin(plugin Plugin, args []string, envs PluginConfig, input []byte) er
 	// Prepare plugin envstore
 	pluginWorkDir, err := pathutil.NormalizedOSTempDirPath("plugin-work-dir")
 	if err != nil {
-		return err
+		return PluginResult{}, err
 	}
 	defer func() {
 		if err := os.RemoveAll(pluginWorkDir); err != nil {
@@ -125,24 +125,41 @@ func runPlugin(plugin Plugin, args []string, envs PluginConfig, input []byte) er
 	pluginEnvstorePath := filepath.Join(pluginWorkDir, "envstore.yml")
 
 	if err := tools.EnvmanInitAtPath(pluginEnvstorePath); err != nil {
-		return err
+		return PluginResult{}, err
 	}
 
 	if err := tools.EnvmanAdd(pluginEnvstorePath, configs.EnvstorePathEnvKey, pluginEnvstorePath, false, false); err != nil {
-		return err
+		return PluginResult{}, err
+	}
+
+	// Serve plugin api
+	var server *rpcServer
+	if PluginProtocol(plugin.Protocol) == JSONRPCProtocol {
+		socketPth := filepath.Join(pluginWorkDir, "rpc.sock")
+		envs[PluginConfigRPCSocketKey] = socketPth
+
+		server, err = startRPCServer(socketPth, newRPCService(plugin.Name, envs, input))
+		if err != nil {
+			return PluginResult{}, err
+		}
+		defer func() {
+			if err := server.Close(); err != nil {
+				log.Warnf("Failed to close plugin api server, error: %s", err)
+			}
+		}()
 	}
 
 	// Add plugin inputs
 	for key, value := range envs {
 		if err := tools.EnvmanAdd(pluginEnvstorePath, key, value, false, false); err != nil {
-			return err
+			return PluginResult{}, err
 		}
 	}
 
 	// Run plugin executable
 	pluginExecutable, isBin, err := GetPluginExecutablePath(plugin.Name)
 	if err != nil {
-		return err
+		return PluginResult{}, err
 	}
 
 	cmd := []string{}
@@ -153,9 +170,31 @@ func runPlugin(plugin Plugin, args []string, envs PluginConfig, input []byte) er
 		cmd = append([]string{"bash", pluginExecutable}, args...)
 	}
 
-	if _, err := tools.EnvmanRun(pluginEnvstorePath, "", cmd, -1, nil, input); err != nil {
-		return err
+	if envs[PluginConfigPluginModeKey] == string(TriggerMode) {
+		// event triggered plugins run with restricted environment
+		spec, err := newSandboxSpec(plugin.Name, pluginWorkDir, plugin.Permissions)
+		if err != nil {
+			return PluginResult{}, err
+		}
+
+		cmd = sandboxCommand(cmd, spec)
+		environ := sandboxEnviron(os.Environ(), plugin.Permissions)
+
+		if _, err := tools.EnvmanRunWithEnvironment(pluginEnvstorePath, "", cmd, -1, nil, input, environ); err != nil {
+			return PluginResult{}, err
+		}
+	} else if _, err := tools.EnvmanRun(pluginEnvstorePath, "", cmd, -1, nil, input); err != nil {
+		return PluginResult{}, err
+	}
+
+	if server == nil {
+		return PluginResult{PluginName: plugin.Name}, nil
+	}
+
+	result := server.service.pluginResult()
+	if err := UpdatePluginRouteRegistrations(plugin.Name, result.Commands, result.Events); err != nil {
+		return result, fmt.Errorf("failed to save plugin (%s) registrations, error: %s", plugin.Name, err)
 	}
 
-	return nil
+	return result, nil
 }
@@ -0,0 +1,128 @@
+package plugins
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/go-utils/pathutil"
+	glob "github.com/ryanuber/go-glob"
+)
+
+// sandboxBaseEnvs are the envs of the parent environment every event triggered plugin receives,
+// any other env needs to be allowed by the plugin's permissions.
+var sandboxBaseEnvs = []string{
+	"PATH",
+	"HOME",
+	"USER",
+	"LOGNAME",
+	"SHELL",
+	"TERM",
+	"TMPDIR",
+	"TZ",
+	"LANG",
+	"LC_*",
+	configs.CIModeEnvKey,
+	configs.PRModeEnvKey,
+	configs.DebugModeEnvKey,
+	configs.LogLevelEnvKey,
+}
+
+func isEnvAllowed(key string, allowedPatterns []string) bool {
+	for _, pattern := range allowedPatterns {
+		if glob.Glob(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// sandboxEnviron filters the parent environment (in os.Environ format) by the plugin's permissions.
+func sandboxEnviron(environ []string, permissions *PluginPermissions) []string {
+	allowedPatterns := append([]string{}, sandboxBaseEnvs...)
+	if permissions != nil {
+		allowedPatterns = append(allowedPatterns, permissions.Envs...)
+	}
+
+	filtered := []string{}
+	for _, env := range environ {
+		key := strings.SplitN(env, "=", 2)[0]
+		if isEnvAllowed(key, allowedPatterns) {
+			filtered = append(filtered, env)
+		}
+	}
+	return filtered
+}
+
+// sandboxPaths expands and makes the permission paths absolute.
+func sandboxPaths(pths []string) ([]string, error) {
+	absPths := []string{}
+	for _, pth := range pths {
+		absPth, err := pathutil.AbsPath(os.ExpandEnv(pth))
+		if err != nil {
+			return []string{}, fmt.Errorf("failed to expand path (%s), error: %s", pth, err)
+		}
+		absPths = append(absPths, filepath.Clean(absPth))
+	}
+	return absPths, nil
+}
+
+func validatePermissions(permissions *PluginPermissions) error {
+	if permissions == nil {
+		return nil
+	}
+
+	for _, pattern := range permissions.Envs {
+		if strings.TrimSpace(pattern) == "" {
+			return fmt.Errorf("empty env permission")
+		}
+	}
+
+	for _, pth := range append(append([]string{}, permissions.ReadPaths...), permissions.WritePaths...) {
+		if strings.TrimSpace(pth) == "" {
+			return fmt.Errorf("empty path permission")
+		}
+	}
+
+	return nil
+}
+
+// sandboxSpec describes the restrictions of an event triggered plugin run.
+type sandboxSpec struct {
+	isRestricted bool
+	readPaths    []string
+	writePaths   []string
+	network      bool
+}
+
+func newSandboxSpec(pluginName, pluginWorkDir string, permissions *PluginPermissions) (sandboxSpec, error) {
+	spec := sandboxSpec{
+		readPaths:  []string{GetPluginDir(pluginName)},
+		writePaths: []string{pluginWorkDir, GetPluginDataDir(pluginName)},
+	}
+
+	if permissions == nil {
+		// plugins without permission manifest are not restricted in file system and network access
+		spec.network = true
+		return spec, nil
+	}
+	spec.isRestricted = true
+
+	readPaths, err := sandboxPaths(permissions.ReadPaths)
+	if err != nil {
+		return sandboxSpec{}, err
+	}
+	spec.readPaths = append(spec.readPaths, readPaths...)
+
+	writePaths, err := sandboxPaths(permissions.WritePaths)
+	if err != nil {
+		return sandboxSpec{}, err
+	}
+	spec.writePaths = append(spec.writePaths, writePaths...)
+
+	spec.network = permissions.Network
+
+	return spec, nil
+}
@@ -0,0 +1,42 @@
+package plugins
+
+import (
+	"os/exec"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// sandboxSystemPaths are mounted read-only, so that the plugin's interpreter and tools are available.
+var sandboxSystemPaths = []string{"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/etc", "/opt"}
+
+// sandboxCommand wraps the plugin command with bubblewrap, which restricts the file system
+// and network access of the plugin by linux namespaces.
+func sandboxCommand(cmd []string, spec sandboxSpec) []string {
+	if !spec.isRestricted {
+		return cmd
+	}
+
+	bwrapPth, err := exec.LookPath("bwrap")
+	if err != nil {
+		log.Warnf("bubblewrap (bwrap) not found, plugin file system and network permissions are not enforced")
+		return cmd
+	}
+
+	args := []string{bwrapPth, "--die-with-parent", "--unshare-pid", "--unshare-ipc", "--unshare-uts"}
+	if !spec.network {
+		args = append(args, "--unshare-net")
+	}
+	for _, pth := range sandboxSystemPaths {
+		args = append(args, "--ro-bind-try", pth, pth)
+	}
+	args = append(args, "--dev", "/dev", "--proc", "/proc", "--tmpfs", "/tmp")
+	for _, pth := range spec.readPaths {
+		args = append(args, "--ro-bind-try", pth, pth)
+	}
+	for _, pth := range spec.writePaths {
+		args = append(args, "--bind-try", pth, pth)
+	}
+	args = append(args, "--")
+
+	return append(args, cmd...)
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package plugins
+
+import "github.com/bitrise-io/go-utils/log"
+
+// sandboxCommand returns the command as it is, file system and network restrictions
+// are only supported on linux.
+func sandboxCommand(cmd []string, spec sandboxSpec) []string {
+	if spec.isRestricted {
+		log.Warnf("Plugin file system and network permissions are only enforced on linux")
+	}
+	return cmd
+}
@@ -0,0 +1,79 @@
+package plugins
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSandboxEnviron(t *testing.T) {
+	environ := []string{
+		"PATH=/usr/bin:/bin",
+		"HOME=/home/bitrise",
+		"LC_ALL=en_US.UTF-8",
+		"BITRISE_APP_SLUG=app-slug",
+		"BITRISE_ANDROID_KEYSTORE_PASSWORD=secret",
+		"ANALYTICS_TOKEN=a=b",
+	}
+
+	t.Log("without permissions only the base envs are passed")
+	{
+		require.Equal(t, []string{
+			"PATH=/usr/bin:/bin",
+			"HOME=/home/bitrise",
+			"LC_ALL=en_US.UTF-8",
+		}, sandboxEnviron(environ, nil))
+	}
+
+	t.Log("allowed envs")
+	{
+		permissions := &PluginPermissions{Envs: []string{"ANALYTICS_*", "BITRISE_APP_SLUG"}}
+		require.Equal(t, []string{
+			"PATH=/usr/bin:/bin",
+			"HOME=/home/bitrise",
+			"LC_ALL=en_US.UTF-8",
+			"BITRISE_APP_SLUG=app-slug",
+			"ANALYTICS_TOKEN=a=b",
+		}, sandboxEnviron(environ, permissions))
+	}
+}
+
+func TestNewSandboxSpec(t *testing.T) {
+	ForceInitPaths("/bitrise")
+
+	t.Log("without permissions")
+	{
+		spec, err := newSandboxSpec("analytics", "/tmp/work", nil)
+		require.NoError(t, err)
+		require.Equal(t, sandboxSpec{
+			readPaths:  []string{"/bitrise/plugins/analytics"},
+			writePaths: []string{"/tmp/work", "/bitrise/plugins/analytics/data"},
+			network:    true,
+		}, spec)
+		require.Equal(t, []string{"bash", "plugin.sh"}, sandboxCommand([]string{"bash", "plugin.sh"}, spec))
+	}
+
+	t.Log("with permissions")
+	{
+		require.NoError(t, os.Setenv("SANDBOX_TEST_DIR", "/tmp/sandbox-test"))
+
+		spec, err := newSandboxSpec("analytics", "/tmp/work", &PluginPermissions{
+			ReadPaths:  []string{"$SANDBOX_TEST_DIR/read"},
+			WritePaths: []string{"/tmp/sandbox-test/write/"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, sandboxSpec{
+			isRestricted: true,
+			readPaths:    []string{"/bitrise/plugins/analytics", "/tmp/sandbox-test/read"},
+			writePaths:   []string{"/tmp/work", "/bitrise/plugins/analytics/data", "/tmp/sandbox-test/write"},
+		}, spec)
+	}
+}
+
+func TestValidatePermissions(t *testing.T) {
+	require.NoError(t, validatePermissions(nil))
+	require.NoError(t, validatePermissions(&PluginPermissions{Envs: []string{"BITRISE_*"}, ReadPaths: []string{"$HOME/.bitrise"}}))
+	require.Error(t, validatePermissions(&PluginPermissions{Envs: []string{""}}))
+	require.Error(t, validatePermissions(&PluginPermissions{WritePaths: []string{" "}}))
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/bitrise-io/bitrise/utils"
 	"github.com/bitrise-io/go-utils/command"
 	"github.com/bitrise-io/go-utils/stringutil"
-	stepmanModels "github.com/bitrise-io/stepman/models"
 )
 
 // BashToolkit ...
@@ -60,12 +59,12 @@ func (toolkit BashToolkit) ToolkitName() string {
 }
 
 // PrepareForStepRun ...
-func (toolkit BashToolkit) PrepareForStepRun(step stepmanModels.StepModel, sIDData models.StepIDData, stepAbsDirPath string) error {
+func (toolkit BashToolkit) PrepareForStepRun(step models.StepModel, sIDData models.StepIDData, stepAbsDirPath string) error {
 	return nil
 }
 
 // StepRunCommandArguments ...
-func (toolkit BashToolkit) StepRunCommandArguments(step stepmanModels.StepModel, sIDData models.StepIDData, stepAbsDirPath string) ([]string, error) {
+func (toolkit BashToolkit) StepRunCommandArguments(step models.StepModel, sIDData models.StepIDData, stepAbsDirPath string) ([]string, error) {
 	entryFile := "step.sh"
 	if step.Toolkit != nil && step.Toolkit.Bash != nil && step.Toolkit.Bash.EntryFile != "" {
 		entryFile = step.Toolkit.Bash.EntryFile
@@ -0,0 +1,270 @@
+package toolkits
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+	envmanModels "github.com/bitrise-io/envman/models"
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-utils/stringutil"
+	"gopkg.in/yaml.v2"
+)
+
+// containerEnvmanBinPath is the path, where the host's envman is mounted in the container,
+// so that steps can export outputs the same way as on the host.
+const containerEnvmanBinPath = "/usr/local/bin/envman"
+
+// containerPassthroughEnvKeys are the envs set by the bitrise CLI for the step process,
+// passed to the container next to the envs of the step's envstore.
+var containerPassthroughEnvKeys = []string{
+	configs.CIModeEnvKey,
+	configs.PRModeEnvKey,
+	configs.PullRequestIDEnvKey,
+	configs.DebugModeEnvKey,
+	configs.LogLevelEnvKey,
+	configs.EnvstorePathEnvKey,
+	configs.FormattedOutputPathEnvKey,
+	configs.BitriseSourceDirEnvKey,
+	configs.BitriseDeployDirEnvKey,
+	configs.BitriseTestDeployDirEnvKey,
+	configs.BitriseTmpDirEnvKey,
+	"BITRISE_TRIGGERED_WORKFLOW_ID",
+	"BITRISE_TRIGGERED_WORKFLOW_TITLE",
+	"BITRISE_BUILD_STATUS",
+	"STEPLIB_BUILD_STATUS",
+}
+
+// ContainerToolkit runs the command of the step's own toolkit inside the workflow's container image,
+// using a local Docker or Podman engine.
+type ContainerToolkit struct {
+	Container   models.ContainerModel
+	StepToolkit Toolkit
+	// SourceDir is the working directory of the step
+	SourceDir string
+}
+
+// ToolkitName ...
+func (toolkit ContainerToolkit) ToolkitName() string {
+	return "container"
+}
+
+func (toolkit ContainerToolkit) engine() (string, error) {
+	if toolkit.Container.Engine != "" {
+		if _, err := exec.LookPath(toolkit.Container.Engine); err != nil {
+			return "", fmt.Errorf("container engine (%s) not found in PATH", toolkit.Container.Engine)
+		}
+		return toolkit.Container.Engine, nil
+	}
+
+	for _, engine := range models.ContainerEngines {
+		if _, err := exec.LookPath(engine); err == nil {
+			return engine, nil
+		}
+	}
+	return "", fmt.Errorf("no container engine found in PATH, supported engines: %v", models.ContainerEngines)
+}
+
+// Check ...
+func (toolkit ContainerToolkit) Check() (bool, ToolkitCheckResult, error) {
+	engine, err := toolkit.engine()
+	if err != nil {
+		return true, ToolkitCheckResult{}, nil
+	}
+
+	binPath, err := exec.LookPath(engine)
+	if err != nil {
+		return true, ToolkitCheckResult{}, nil
+	}
+
+	verOut, err := command.RunCommandAndReturnStdout(engine, "--version")
+	if err != nil {
+		return false, ToolkitCheckResult{}, fmt.Errorf("Failed to check %s version, error: %s", engine, err)
+	}
+
+	return false, ToolkitCheckResult{
+		Path:    binPath,
+		Version: stringutil.ReadFirstLine(verOut, true),
+	}, nil
+}
+
+// IsToolAvailableInPATH ...
+func (toolkit ContainerToolkit) IsToolAvailableInPATH() bool {
+	_, err := toolkit.engine()
+	return err == nil
+}
+
+// Bootstrap ...
+func (toolkit ContainerToolkit) Bootstrap() error {
+	return nil
+}
+
+// Install ...
+func (toolkit ContainerToolkit) Install() error {
+	_, err := toolkit.engine()
+	return err
+}
+
+// PrepareForStepRun pulls the container image if it's not available locally,
+// and prepares the step with the step's own toolkit.
+func (toolkit ContainerToolkit) PrepareForStepRun(step models.StepModel, sIDData models.StepIDData, stepAbsDirPath string) error {
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("container toolkit is not supported on %s, only on linux", runtime.GOOS)
+	}
+
+	engine, err := toolkit.engine()
+	if err != nil {
+		return err
+	}
+
+	if out, err := command.RunCommandAndReturnCombinedStdoutAndStderr(engine, "image", "inspect", toolkit.Container.Image); err != nil {
+		log.Debugf("Image (%s) not found locally: %s", toolkit.Container.Image, out)
+		log.Infof("Pulling image (%s) ...", toolkit.Container.Image)
+
+		if out, err := command.RunCommandAndReturnCombinedStdoutAndStderr(engine, "pull", toolkit.Container.Image); err != nil {
+			return fmt.Errorf("failed to pull image (%s), output: %s, error: %s", toolkit.Container.Image, out, err)
+		}
+	}
+
+	return toolkit.StepToolkit.PrepareForStepRun(step, sIDData, stepAbsDirPath)
+}
+
+// envstoreKeys returns the keys of the envs exported into the given envstore.
+func envstoreKeys(envstorePth string) ([]string, error) {
+	if exist, err := pathutil.IsPathExists(envstorePth); err != nil {
+		return []string{}, err
+	} else if !exist {
+		return []string{}, nil
+	}
+
+	bytes, err := fileutil.ReadBytesFromFile(envstorePth)
+	if err != nil {
+		return []string{}, err
+	}
+
+	var envstore envmanModels.EnvsSerializeModel
+	if err := yaml.Unmarshal(bytes, &envstore); err != nil {
+		return []string{}, err
+	}
+
+	keys := []string{}
+	for _, env := range envstore.Envs {
+		key, _, err := env.GetKeyValuePair()
+		if err != nil {
+			return []string{}, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// containerMounts returns the host directories shared with the container, mounted at the same path:
+// the source dir, the deploy dirs, the bitrise work dir (with the envstores and the step sources) and the step's dir.
+func (toolkit ContainerToolkit) containerMounts(stepAbsDirPath string, stepCmd []string) []string {
+	pths := []string{
+		toolkit.SourceDir,
+		os.Getenv(configs.BitriseDeployDirEnvKey),
+		os.Getenv(configs.BitriseTestDeployDirEnvKey),
+		configs.BitriseWorkDirPath,
+		stepAbsDirPath,
+	}
+	if len(stepCmd) > 0 && filepath.IsAbs(stepCmd[0]) {
+		// e.g. the compiled binary of a go step
+		pths = append(pths, filepath.Dir(stepCmd[0]))
+	}
+
+	mounts := []string{}
+	isMounted := map[string]bool{}
+	for _, pth := range pths {
+		if pth == "" {
+			continue
+		}
+		pth = filepath.Clean(pth)
+		if isMounted[pth] {
+			continue
+		}
+		isMounted[pth] = true
+		mounts = append(mounts, pth)
+	}
+	return mounts
+}
+
+// StepRunCommandArguments returns the engine's run command, running the step's command inside the container.
+func (toolkit ContainerToolkit) StepRunCommandArguments(step models.StepModel, sIDData models.StepIDData, stepAbsDirPath string) ([]string, error) {
+	stepCmd, err := toolkit.StepToolkit.StepRunCommandArguments(step, sIDData, stepAbsDirPath)
+	if err != nil {
+		return []string{}, err
+	}
+	if len(stepCmd) == 0 {
+		return []string{}, errors.New("empty step command")
+	}
+
+	engine, err := toolkit.engine()
+	if err != nil {
+		return []string{}, err
+	}
+
+	cmd := []string{engine, "run", "--rm", "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())}
+
+	for _, mount := range toolkit.containerMounts(stepAbsDirPath, stepCmd) {
+		cmd = append(cmd, "--volume", mount+":"+mount)
+	}
+	if envmanPth, err := exec.LookPath("envman"); err == nil {
+		cmd = append(cmd, "--volume", envmanPth+":"+containerEnvmanBinPath+":ro")
+	} else {
+		log.Warnf("envman not found in PATH, step outputs can not be exported from the container")
+	}
+
+	keys, err := envstoreKeys(configs.InputEnvstorePath)
+	if err != nil {
+		return []string{}, fmt.Errorf("failed to read envstore, error: %s", err)
+	}
+	keys = append(keys, containerPassthroughEnvKeys...)
+	sort.Strings(keys)
+
+	passedKeys := map[string]bool{}
+	for _, key := range keys {
+		if passedKeys[key] {
+			continue
+		}
+		passedKeys[key] = true
+		// the value is taken from the engine CLI's environment, which is prepared by envman
+		cmd = append(cmd, "--env", key)
+	}
+
+	if toolkit.SourceDir != "" {
+		cmd = append(cmd, "--workdir", toolkit.SourceDir)
+	}
+	cmd = append(cmd, toolkit.Container.Options...)
+	cmd = append(cmd, "--entrypoint", stepCmd[0], toolkit.Container.Image)
+	cmd = append(cmd, stepCmd[1:]...)
+
+	return cmd, nil
+}
+
+// StepRunError maps the exit codes reserved by the container engine to a descriptive error,
+// any other exit code is the exit code of the step's command.
+func (toolkit ContainerToolkit) StepRunError(exitCode int, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch exitCode {
+	case 125:
+		return fmt.Errorf("container engine failed to run the step in image (%s): %s", toolkit.Container.Image, err)
+	case 126:
+		return fmt.Errorf("step command can not be invoked in image (%s): %s", toolkit.Container.Image, err)
+	case 127:
+		return fmt.Errorf("step command not found in image (%s): %s", toolkit.Container.Image, err)
+	}
+	return err
+}
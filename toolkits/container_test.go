@@ -0,0 +1,76 @@
+package toolkits
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/pathutil"
+	stepmanModels "github.com/bitrise-io/stepman/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerToolkitStepRunCommandArguments(t *testing.T) {
+	binDir, err := pathutil.NormalizedOSTempDirPath("container-toolkit-test")
+	require.NoError(t, err)
+	require.NoError(t, fileutil.WriteStringToFileWithPermission(filepath.Join(binDir, "podman"), "#!/bin/sh\n", 0755))
+
+	origPath := os.Getenv("PATH")
+	require.NoError(t, os.Setenv("PATH", binDir))
+	defer func() {
+		require.NoError(t, os.Setenv("PATH", origPath))
+	}()
+
+	origEnvstorePath, origWorkDirPath := configs.InputEnvstorePath, configs.BitriseWorkDirPath
+	configs.InputEnvstorePath = filepath.Join(binDir, "input_envstore.yml")
+	configs.BitriseWorkDirPath = "/tmp/bitrise"
+	defer func() {
+		configs.InputEnvstorePath, configs.BitriseWorkDirPath = origEnvstorePath, origWorkDirPath
+	}()
+	require.NoError(t, fileutil.WriteStringToFile(configs.InputEnvstorePath, "envs:\n- PROJECT_PATH: ./app\n- BITRISE_SOURCE_DIR: /src\n"))
+
+	require.NoError(t, os.Unsetenv(configs.BitriseDeployDirEnvKey))
+	require.NoError(t, os.Unsetenv(configs.BitriseTestDeployDirEnvKey))
+
+	toolkit := ToolkitForWorkflowStep(models.NewStepModel(stepmanModels.StepModel{}), &models.ContainerModel{Image: "ubuntu:20.04", Options: []string{"--network", "host"}}, "/src")
+	require.Equal(t, "container", toolkit.ToolkitName())
+	require.True(t, toolkit.IsToolAvailableInPATH())
+
+	cmd, err := toolkit.StepRunCommandArguments(models.NewStepModel(stepmanModels.StepModel{}), models.StepIDData{}, "/tmp/bitrise/step_src")
+	require.NoError(t, err)
+
+	expected := []string{"podman", "run", "--rm", "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+		"--volume", "/src:/src",
+		"--volume", "/tmp/bitrise:/tmp/bitrise",
+		"--volume", "/tmp/bitrise/step_src:/tmp/bitrise/step_src",
+	}
+	for _, key := range []string{"BITRISE_BUILD_STATUS", "BITRISE_DEPLOY_DIR", "BITRISE_SOURCE_DIR", "BITRISE_STEP_FORMATTED_OUTPUT_FILE_PATH",
+		"BITRISE_TEST_DEPLOY_DIR", "BITRISE_TMP_DIR", "BITRISE_TRIGGERED_WORKFLOW_ID", "BITRISE_TRIGGERED_WORKFLOW_TITLE",
+		"CI", "DEBUG", "ENVMAN_ENVSTORE_PATH", "LOGLEVEL", "PR", "PROJECT_PATH", "PULL_REQUEST_ID", "STEPLIB_BUILD_STATUS"} {
+		expected = append(expected, "--env", key)
+	}
+	expected = append(expected, "--workdir", "/src", "--network", "host", "--entrypoint", "bash", "ubuntu:20.04", "/tmp/bitrise/step_src/step.sh")
+	require.Equal(t, expected, cmd)
+
+	t.Log("not available engine")
+	{
+		toolkit := ToolkitForWorkflowStep(models.NewStepModel(stepmanModels.StepModel{}), &models.ContainerModel{Image: "ubuntu:20.04", Engine: "docker"}, "/src")
+		require.False(t, toolkit.IsToolAvailableInPATH())
+		_, err := toolkit.StepRunCommandArguments(models.NewStepModel(stepmanModels.StepModel{}), models.StepIDData{}, "/tmp/bitrise/step_src")
+		require.EqualError(t, err, "container engine (docker) not found in PATH")
+	}
+}
+
+func TestContainerToolkitStepRunError(t *testing.T) {
+	toolkit := ContainerToolkit{Container: models.ContainerModel{Image: "ubuntu:20.04"}}
+	stepErr := errors.New("exit status 125")
+
+	require.NoError(t, toolkit.StepRunError(0, nil))
+	require.EqualError(t, toolkit.StepRunError(125, stepErr), "container engine failed to run the step in image (ubuntu:20.04): exit status 125")
+	require.EqualError(t, toolkit.StepRunError(1, errors.New("exit status 1")), "exit status 1")
+}
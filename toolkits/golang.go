@@ -19,19 +19,22 @@ import (
 	"github.com/bitrise-io/go-utils/pathutil"
 	"github.com/bitrise-io/go-utils/progress"
 	"github.com/bitrise-io/go-utils/retry"
-	"github.com/bitrise-io/go-utils/versions"
 	"github.com/bitrise-io/gows/gows"
-	stepmanModels "github.com/bitrise-io/stepman/models"
+	ver "github.com/hashicorp/go-version"
 )
 
 const (
 	minGoVersionForToolkit = "1.15.2"
+	// stepBinaryGoVersionSeparator separates the step and the Go version in the step binary's filename
+	stepBinaryGoVersionSeparator = "-go"
 )
 
 // === Base Toolkit struct ===
 
 // GoToolkit ...
 type GoToolkit struct {
+	// VersionConstraint is the Go version constraint of the step (toolkit.go.version), e.g. ">= 1.17"
+	VersionConstraint string
 }
 
 // ToolkitName ...
@@ -49,7 +52,7 @@ type GoConfigurationModel struct {
 	GOROOT string
 }
 
-func checkGoConfiguration(goConfig GoConfigurationModel) (bool, ToolkitCheckResult, error) {
+func checkGoConfiguration(goConfig GoConfigurationModel, constraints ver.Constraints) (bool, ToolkitCheckResult, error) {
 	cmdEnvs := os.Environ()
 	if len(goConfig.GOROOT) > 0 {
 		cmdEnvs = append(cmdEnvs, "GOROOT="+goConfig.GOROOT)
@@ -70,18 +73,25 @@ func checkGoConfiguration(goConfig GoConfigurationModel) (bool, ToolkitCheckResu
 	}
 
 	// version check
-	isVersionOk, err := versions.IsVersionGreaterOrEqual(verStr, minGoVersionForToolkit)
+	version, err := ver.NewVersion(verStr)
 	if err != nil {
 		return false, checkRes, fmt.Errorf("Failed to validate installed go version, error: %s", err)
 	}
-	if !isVersionOk {
+	if !constraints.Check(version) {
 		return true, checkRes, nil
 	}
 
 	return false, checkRes, nil
 }
 
-func selectGoConfiguration() (bool, ToolkitCheckResult, GoConfigurationModel, error) {
+// selectGoConfiguration selects the newest Go installation matching the version constraint,
+// from the system installed Go and the Go versions installed by the toolkit.
+func selectGoConfiguration(versionConstraint string) (bool, ToolkitCheckResult, GoConfigurationModel, error) {
+	constraints, err := goVersionConstraints(versionConstraint)
+	if err != nil {
+		return false, ToolkitCheckResult{}, GoConfigurationModel{}, err
+	}
+
 	potentialGoConfigurations := []GoConfigurationModel{}
 	// from PATH
 	{
@@ -90,7 +100,7 @@ func selectGoConfiguration() (bool, ToolkitCheckResult, GoConfigurationModel, er
 			potentialGoConfigurations = append(potentialGoConfigurations, GoConfigurationModel{GoBinaryPath: binPath})
 		}
 	}
-	// from Bitrise Toolkits
+	// from Bitrise Toolkits, installed by previous CLI versions
 	{
 		binPath := goBinaryInToolkitFullPath()
 		if isExist, err := pathutil.IsPathExists(binPath); err != nil {
@@ -98,7 +108,20 @@ func selectGoConfiguration() (bool, ToolkitCheckResult, GoConfigurationModel, er
 		} else if isExist {
 			potentialGoConfigurations = append(potentialGoConfigurations, GoConfigurationModel{
 				GoBinaryPath: binPath,
-				GOROOT:       goToolkitInstallRootPath(),
+				GOROOT:       goToolkitLegacyInstallRootPath(),
+			})
+		}
+	}
+	// from Bitrise Toolkits, side-by-side installs
+	{
+		installedVersions, err := InstalledGoVersions()
+		if err != nil {
+			log.Warnf("Failed to list the Go versions inside the Bitrise Toolkit dir, error: %s", err)
+		}
+		for _, version := range installedVersions {
+			potentialGoConfigurations = append(potentialGoConfigurations, GoConfigurationModel{
+				GoBinaryPath: goBinaryInToolkitVersionFullPath(version),
+				GOROOT:       goToolkitVersionInstallRootPath(version),
 			})
 		}
 	}
@@ -106,20 +129,41 @@ func selectGoConfiguration() (bool, ToolkitCheckResult, GoConfigurationModel, er
 	isRequireInstall := true
 	checkResult := ToolkitCheckResult{}
 	goConfig := GoConfigurationModel{}
+	var selectedVersion *ver.Version
 	var checkError error
 	for _, aPotentialGoInfoToUse := range potentialGoConfigurations {
-		isInstReq, chkRes, err := checkGoConfiguration(aPotentialGoInfoToUse)
-		checkResult = chkRes
-		checkError = err
-		if !isInstReq {
-			// select this one
+		isInstReq, chkRes, err := checkGoConfiguration(aPotentialGoInfoToUse, constraints)
+		if err != nil {
+			checkError = err
+			continue
+		}
+		if isInstReq {
+			log.Debugf("Installed go found (path: %s), but not a supported version: %s", chkRes.Path, chkRes.Version)
+			if isRequireInstall {
+				checkResult = chkRes
+			}
+			continue
+		}
+
+		// select the newest matching one
+		version, err := ver.NewVersion(chkRes.Version)
+		if err != nil {
+			checkError = err
+			continue
+		}
+		if selectedVersion == nil || version.GreaterThan(selectedVersion) {
+			selectedVersion = version
+			checkResult = chkRes
 			goConfig = aPotentialGoInfoToUse
 			isRequireInstall = false
-			break
 		}
 	}
 
-	if len(potentialGoConfigurations) > 0 && isRequireInstall {
+	if !isRequireInstall {
+		return false, checkResult, goConfig, nil
+	}
+
+	if len(potentialGoConfigurations) > 0 && checkResult.Version != "" {
 		log.Warnf("Installed go found (path: %s), but not a supported version: %s", checkResult.Path, checkResult.Version)
 	}
 
@@ -128,7 +172,7 @@ func selectGoConfiguration() (bool, ToolkitCheckResult, GoConfigurationModel, er
 
 // Check ...
 func (toolkit GoToolkit) Check() (bool, ToolkitCheckResult, error) {
-	isInstallRequired, checkResult, _, err := selectGoConfiguration()
+	isInstallRequired, checkResult, _, err := selectGoConfiguration(toolkit.VersionConstraint)
 	return isInstallRequired, checkResult, err
 }
 
@@ -176,12 +220,19 @@ func (toolkit GoToolkit) Bootstrap() error {
 		return nil
 	}
 
-	pthWithGoBins := configs.GeneratePATHEnvString(os.Getenv("PATH"), goToolkitBinsPath())
+	goRoot := goToolkitLegacyInstallRootPath()
+	if isInstallRequired, _, goConfig, err := selectGoConfiguration(toolkit.VersionConstraint); err != nil {
+		log.Warnf("Failed to select Go toolkit install, error: %s", err)
+	} else if !isInstallRequired && goConfig.GOROOT != "" {
+		goRoot = goConfig.GOROOT
+	}
+
+	pthWithGoBins := configs.GeneratePATHEnvString(os.Getenv("PATH"), filepath.Join(goRoot, "bin"))
 	if err := os.Setenv("PATH", pthWithGoBins); err != nil {
 		return fmt.Errorf("Failed to set PATH to include the Go toolkit bins, error: %s", err)
 	}
 
-	if err := os.Setenv("GOROOT", goToolkitInstallRootPath()); err != nil {
+	if err := os.Setenv("GOROOT", goRoot); err != nil {
 		return fmt.Errorf("Failed to set GOROOT to Go toolkit root, error: %s", err)
 	}
 
@@ -190,8 +241,8 @@ func (toolkit GoToolkit) Bootstrap() error {
 
 // === Toolkit: Install ===
 
-func installGoTar(goTarGzPath string) error {
-	installToPath := goToolkitInstallToPath()
+func installGoTar(goTarGzPath, version string) error {
+	installToPath := filepath.Join(goToolkitInstallToPath(), version)
 
 	if err := os.RemoveAll(installToPath); err != nil {
 		return fmt.Errorf("Failed to remove previous Go toolkit install (path: %s), error: %s", installToPath, err)
@@ -209,16 +260,33 @@ func installGoTar(goTarGzPath string) error {
 	return nil
 }
 
-// Install ...
+// Install installs the newest Go release matching the version constraint, next to the already installed versions.
 func (toolkit GoToolkit) Install() error {
-	versionStr := minGoVersionForToolkit
+	versionStr, err := resolveGoVersionToInstall(toolkit.VersionConstraint)
+	if err != nil {
+		return fmt.Errorf("Failed to select Go version to install, error: %s", err)
+	}
+	if exists, err := pathutil.IsPathExists(goBinaryInToolkitVersionFullPath(versionStr)); err != nil {
+		return fmt.Errorf("Failed to check Go %s install, error: %s", versionStr, err)
+	} else if exists {
+		log.Infof("Go %s is already installed", versionStr)
+		return nil
+	}
+	log.Infof("Installing Go %s ...", versionStr)
+
 	osStr := runtime.GOOS
 	archStr := runtime.GOARCH
 	extentionStr := "tar.gz"
 	if osStr == "windows" {
 		extentionStr = "zip"
 	}
-	downloadURL := fmt.Sprintf("https://storage.googleapis.com/golang/go%s.%s-%s.%s", versionStr, osStr, archStr, extentionStr)
+	archiveName := fmt.Sprintf("go%s.%s-%s.%s", versionStr, osStr, archStr, extentionStr)
+	downloadURL := "https://storage.googleapis.com/golang/" + archiveName
+
+	archiveSHA256, err := goArchiveSHA256(archiveName)
+	if err != nil {
+		return fmt.Errorf("Failed to get the SHA-256 sum of the Go archive, error: %s", err)
+	}
 
 	goTmpDirPath := goToolkitTmpDirPath()
 	if err := pathutil.EnsureDirExist(goTmpDirPath); err != nil {
@@ -234,7 +302,7 @@ func (toolkit GoToolkit) Install() error {
 			if attempt > 0 {
 				log.Warnf("==> Download failed, retrying ...")
 			}
-			return tools.DownloadFile(downloadURL, goArchiveDownloadPath)
+			return tools.DownloadFileWithSHA256(downloadURL, goArchiveDownloadPath, archiveSHA256)
 		})
 	})
 	if downloadErr != nil {
@@ -242,7 +310,7 @@ func (toolkit GoToolkit) Install() error {
 	}
 
 	fmt.Println("=> Installing ...")
-	if err := installGoTar(goArchiveDownloadPath); err != nil {
+	if err := installGoTar(goArchiveDownloadPath, versionStr); err != nil {
 		return fmt.Errorf("Failed to install Go toolkit, error: %s", err)
 	}
 	if err := os.Remove(goArchiveDownloadPath); err != nil {
@@ -339,13 +407,54 @@ func stepBinaryFilename(sIDData models.StepIDData) string {
 	return safeStepID
 }
 
-func stepBinaryCacheFullPath(sIDData models.StepIDData) string {
-	return filepath.Join(goToolkitCacheRootPath(), stepBinaryFilename(sIDData))
+// stepBinaryCacheFullPath returns the path of the step's binary compiled with the given Go version.
+func stepBinaryCacheFullPath(sIDData models.StepIDData, goVersion string) string {
+	return filepath.Join(goToolkitCacheRootPath(), stepBinaryFilename(sIDData)+stepBinaryGoVersionSeparator+goVersion)
+}
+
+// selectGoConfigurationForStep selects the Go installation for the step,
+// the newest Go release matching the step's version constraint is installed if none of the installed ones match.
+func (toolkit GoToolkit) selectGoConfigurationForStep() (ToolkitCheckResult, GoConfigurationModel, error) {
+	isInstallRequired, checkResult, goConfig, err := selectGoConfiguration(toolkit.VersionConstraint)
+	if err != nil {
+		return ToolkitCheckResult{}, GoConfigurationModel{}, fmt.Errorf("Failed to select an appropriate Go installation for compiling the Step: %s", err)
+	}
+	if !isInstallRequired {
+		return checkResult, goConfig, nil
+	}
+
+	log.Warnf("No installed Go matches the Step's Go version requirement, installing ...")
+	if err := toolkit.Install(); err != nil {
+		return ToolkitCheckResult{}, GoConfigurationModel{}, fmt.Errorf("Failed to install Go for compiling the Step: %s", err)
+	}
+
+	isInstallRequired, checkResult, goConfig, err = selectGoConfiguration(toolkit.VersionConstraint)
+	if err != nil {
+		return ToolkitCheckResult{}, GoConfigurationModel{}, fmt.Errorf("Failed to select an appropriate Go installation for compiling the Step: %s", err)
+	}
+	if isInstallRequired {
+		return ToolkitCheckResult{}, GoConfigurationModel{}, fmt.Errorf("Failed to select an appropriate Go installation for compiling the Step: %s",
+			"installed Go does not match the Step's Go version requirement")
+	}
+	return checkResult, goConfig, nil
 }
 
 // PrepareForStepRun ...
-func (toolkit GoToolkit) PrepareForStepRun(step stepmanModels.StepModel, sIDData models.StepIDData, stepAbsDirPath string) error {
-	fullStepBinPath := stepBinaryCacheFullPath(sIDData)
+func (toolkit GoToolkit) PrepareForStepRun(step models.StepModel, sIDData models.StepIDData, stepAbsDirPath string) error {
+	stepToolkit := step.GetToolkit()
+	if stepToolkit == nil {
+		return errors.New("No Toolkit information specified in step")
+	}
+	if stepToolkit.Go == nil {
+		return errors.New("No Toolkit.Go information specified in step")
+	}
+
+	checkResult, goConfig, err := toolkit.selectGoConfigurationForStep()
+	if err != nil {
+		return err
+	}
+
+	fullStepBinPath := stepBinaryCacheFullPath(sIDData, checkResult.Version)
 
 	// try to use cached binary, if possible
 	if sIDData.IsUniqueResourceID() {
@@ -357,30 +466,22 @@ func (toolkit GoToolkit) PrepareForStepRun(step stepmanModels.StepModel, sIDData
 	}
 
 	// it's not cached, so compile it
-	if step.Toolkit == nil {
-		return errors.New("No Toolkit information specified in step")
-	}
-	if step.Toolkit.Go == nil {
-		return errors.New("No Toolkit.Go information specified in step")
-	}
+	return goBuildStep(&defaultRunner{}, goConfig, stepToolkit.Go.PackageName, stepAbsDirPath, fullStepBinPath)
+}
 
-	isInstallRequired, _, goConfig, err := selectGoConfiguration()
+// === Toolkit: Step Run ===
+
+// StepRunCommandArguments ...
+func (toolkit GoToolkit) StepRunCommandArguments(step models.StepModel, sIDData models.StepIDData, stepAbsDirPath string) ([]string, error) {
+	isInstallRequired, checkResult, _, err := selectGoConfiguration(toolkit.VersionConstraint)
 	if err != nil {
-		return fmt.Errorf("Failed to select an appropriate Go installation for compiling the Step: %s", err)
+		return []string{}, err
 	}
 	if isInstallRequired {
-		return fmt.Errorf("Failed to select an appropriate Go installation for compiling the Step: %s",
-			"Found Go version is older than required. Please run 'bitrise setup' to check and install the required version")
+		return []string{}, fmt.Errorf("no installed Go matches the Step's Go version requirement (%s)", toolkit.VersionConstraint)
 	}
 
-	return goBuildStep(&defaultRunner{}, goConfig, step.Toolkit.Go.PackageName, stepAbsDirPath, fullStepBinPath)
-}
-
-// === Toolkit: Step Run ===
-
-// StepRunCommandArguments ...
-func (toolkit GoToolkit) StepRunCommandArguments(step stepmanModels.StepModel, sIDData models.StepIDData, stepAbsDirPath string) ([]string, error) {
-	fullStepBinPath := stepBinaryCacheFullPath(sIDData)
+	fullStepBinPath := stepBinaryCacheFullPath(sIDData, checkResult.Version)
 	return []string{fullStepBinPath}, nil
 }
 
@@ -401,14 +502,19 @@ func goToolkitCacheRootPath() string {
 	return filepath.Join(goToolkitRootPath(), "cache")
 }
 
-func goToolkitInstallRootPath() string {
+// goToolkitLegacyInstallRootPath is the GOROOT of the single Go install of previous CLI versions.
+func goToolkitLegacyInstallRootPath() string {
 	return filepath.Join(goToolkitInstallToPath(), "go")
 }
 
-func goToolkitBinsPath() string {
-	return filepath.Join(goToolkitInstallRootPath(), "bin")
+func goBinaryInToolkitFullPath() string {
+	return filepath.Join(goToolkitLegacyInstallRootPath(), "bin", "go")
 }
 
-func goBinaryInToolkitFullPath() string {
-	return filepath.Join(goToolkitBinsPath(), "go")
+func goToolkitVersionInstallRootPath(version string) string {
+	return filepath.Join(goToolkitInstallToPath(), version, "go")
+}
+
+func goBinaryInToolkitVersionFullPath(version string) string {
+	return filepath.Join(goToolkitVersionInstallRootPath(version), "bin", "go")
 }
@@ -0,0 +1,288 @@
+package toolkits
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bitrise-io/bitrise/tools"
+	"github.com/bitrise-io/bitrise/utils"
+	"github.com/bitrise-io/go-utils/pathutil"
+	ver "github.com/hashicorp/go-version"
+)
+
+const (
+	goReleasesURL = "https://go.dev/dl/?mode=json&include=all"
+	// goReleasesFetchTimeout limits fetching the Go release list, which is a few megabytes.
+	goReleasesFetchTimeout = time.Minute
+)
+
+// goRelease is an item of the Go release list (goReleasesURL).
+type goRelease struct {
+	// Version is prefixed with go, e.g. go1.17.5
+	Version string          `json:"version"`
+	Stable  bool            `json:"stable"`
+	Files   []goReleaseFile `json:"files"`
+}
+
+// goReleaseFile is a downloadable file (e.g. an archive) of a Go release.
+type goReleaseFile struct {
+	// Filename is the name of the file at the download host, e.g. go1.17.5.linux-amd64.tar.gz
+	Filename string `json:"filename"`
+	SHA256   string `json:"sha256"`
+}
+
+// goVersionConstraints parses the step's toolkit.go.version constraint (e.g. ">= 1.17, < 1.19" or "~> 1.17.0"),
+// without a constraint any Go version supported by the toolkit is accepted.
+func goVersionConstraints(versionConstraint string) (ver.Constraints, error) {
+	if versionConstraint == "" {
+		versionConstraint = ">= " + minGoVersionForToolkit
+	}
+
+	constraints, err := ver.NewConstraint(versionConstraint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Go version constraint (%s), error: %s", versionConstraint, err)
+	}
+	return constraints, nil
+}
+
+// bestMatchingGoVersion returns the newest version matching the constraints.
+func bestMatchingGoVersion(versions []string, constraints ver.Constraints) (string, bool) {
+	var best *ver.Version
+	for _, versionStr := range versions {
+		version, err := ver.NewVersion(versionStr)
+		if err != nil {
+			log.Debugf("Skipping invalid Go version (%s): %s", versionStr, err)
+			continue
+		}
+		if !constraints.Check(version) {
+			continue
+		}
+		if best == nil || version.GreaterThan(best) {
+			best = version
+		}
+	}
+
+	if best == nil {
+		return "", false
+	}
+	return best.Original(), true
+}
+
+func parseGoReleases(content []byte) ([]goRelease, error) {
+	var releases []goRelease
+	if err := json.Unmarshal(content, &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse Go releases, error: %s", err)
+	}
+	return releases, nil
+}
+
+// fetchGoReleases fetches the Go release list through the download mirror if configured, otherwise from go.dev.
+func fetchGoReleases() ([]goRelease, error) {
+	content, err := tools.ReadURL(goReleasesURL, goReleasesFetchTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Go releases, error: %s", err)
+	}
+	return parseGoReleases(content)
+}
+
+func fetchGoReleaseVersions() ([]string, error) {
+	releases, err := fetchGoReleases()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []string{}
+	for _, release := range releases {
+		if release.Stable {
+			versions = append(versions, strings.TrimPrefix(release.Version, "go"))
+		}
+	}
+	return versions, nil
+}
+
+// goArchiveSHA256FromReleases returns the SHA-256 sum of the Go release file.
+func goArchiveSHA256FromReleases(releases []goRelease, archiveName string) (string, error) {
+	for _, release := range releases {
+		for _, file := range release.Files {
+			if file.Filename == archiveName && file.SHA256 != "" {
+				return file.SHA256, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no SHA-256 sum found for: %s", archiveName)
+}
+
+// goArchiveSHA256 returns the SHA-256 sum of the Go release file, from the Go release list
+// of the download mirror if configured (e.g. an offline mirror), otherwise of go.dev.
+func goArchiveSHA256(archiveName string) (string, error) {
+	releases, err := fetchGoReleases()
+	if err != nil {
+		return "", err
+	}
+	return goArchiveSHA256FromReleases(releases, archiveName)
+}
+
+// resolveGoVersionToInstall returns the newest Go release matching the constraint,
+// without a constraint the minimum Go version of the toolkit is installed.
+func resolveGoVersionToInstall(versionConstraint string) (string, error) {
+	if versionConstraint == "" {
+		return minGoVersionForToolkit, nil
+	}
+
+	constraints, err := goVersionConstraints(versionConstraint)
+	if err != nil {
+		return "", err
+	}
+
+	releases, err := fetchGoReleaseVersions()
+	if err != nil {
+		return "", err
+	}
+
+	version, found := bestMatchingGoVersion(releases, constraints)
+	if !found {
+		return "", fmt.Errorf("no Go release found matching: %s", versionConstraint)
+	}
+	return version, nil
+}
+
+// InstalledGoVersions returns the Go versions installed by the Go toolkit, in ascending order.
+func InstalledGoVersions() ([]string, error) {
+	entries, err := ioutil.ReadDir(goToolkitInstallToPath())
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to list Go toolkit installs, error: %s", err)
+	}
+
+	versions := ver.Collection{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		version, err := ver.NewVersion(entry.Name())
+		if err != nil {
+			// e.g. the single, not versioned install of previous CLI versions
+			continue
+		}
+
+		if exists, err := pathutil.IsPathExists(goBinaryInToolkitVersionFullPath(entry.Name())); err != nil {
+			return nil, err
+		} else if exists {
+			versions = append(versions, version)
+		}
+	}
+	sort.Sort(versions)
+
+	versionStrs := []string{}
+	for _, version := range versions {
+		versionStrs = append(versionStrs, version.Original())
+	}
+	return versionStrs, nil
+}
+
+// SystemGoVersion returns the version of the Go available in PATH, if any.
+func SystemGoVersion() (string, bool) {
+	binPath, err := utils.CheckProgramInstalledPath("go")
+	if err != nil {
+		return "", false
+	}
+
+	constraints, err := goVersionConstraints("")
+	if err != nil {
+		return "", false
+	}
+
+	_, checkResult, err := checkGoConfiguration(GoConfigurationModel{GoBinaryPath: binPath}, constraints)
+	if err != nil {
+		log.Warnf("Failed to check the version of the system installed Go, error: %s", err)
+		return "", false
+	}
+	return checkResult.Version, true
+}
+
+// legacyGoToolkitVersion returns the version of the Go installed by previous CLI versions, if any.
+func legacyGoToolkitVersion() (string, bool) {
+	if exists, err := pathutil.IsPathExists(goBinaryInToolkitFullPath()); err != nil || !exists {
+		return "", false
+	}
+
+	constraints, err := goVersionConstraints("")
+	if err != nil {
+		return "", false
+	}
+
+	goConfig := GoConfigurationModel{GoBinaryPath: goBinaryInToolkitFullPath(), GOROOT: goToolkitLegacyInstallRootPath()}
+	_, checkResult, err := checkGoConfiguration(goConfig, constraints)
+	if err != nil {
+		log.Debugf("Failed to check the version of the Go toolkit install (%s), error: %s", goToolkitLegacyInstallRootPath(), err)
+		return "", false
+	}
+	return checkResult.Version, true
+}
+
+// PruneGoToolkit removes every Go install of the toolkit but the ones to keep (e.g. the system Go version),
+// and the step binaries compiled with the removed Go versions.
+// Returns the removed Go versions.
+func PruneGoToolkit(keepVersions []string) ([]string, error) {
+	installed, err := InstalledGoVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	isKept := map[string]bool{}
+	for _, version := range keepVersions {
+		isKept[version] = true
+	}
+
+	pruned := []string{}
+	for _, version := range installed {
+		if isKept[version] {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(goToolkitInstallToPath(), version)); err != nil {
+			return pruned, fmt.Errorf("failed to remove Go (%s), error: %s", version, err)
+		}
+		pruned = append(pruned, version)
+	}
+
+	// the install of previous CLI versions, not kept side-by-side, unless it is the install of a version to keep
+	if legacyVersion, ok := legacyGoToolkitVersion(); !ok || !isKept[legacyVersion] {
+		if err := os.RemoveAll(goToolkitLegacyInstallRootPath()); err != nil {
+			return pruned, fmt.Errorf("failed to remove Go toolkit install (%s), error: %s", goToolkitLegacyInstallRootPath(), err)
+		}
+	}
+
+	entries, err := ioutil.ReadDir(goToolkitCacheRootPath())
+	if os.IsNotExist(err) {
+		return pruned, nil
+	} else if err != nil {
+		return pruned, fmt.Errorf("failed to list step binary cache, error: %s", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if idx := strings.LastIndex(name, stepBinaryGoVersionSeparator); idx != -1 && isKept[name[idx+len(stepBinaryGoVersionSeparator):]] {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(goToolkitCacheRootPath(), name)); err != nil {
+			return pruned, fmt.Errorf("failed to remove step binary (%s), error: %s", name, err)
+		}
+	}
+
+	if err := os.RemoveAll(goToolkitTmpDirPath()); err != nil {
+		return pruned, fmt.Errorf("failed to remove Go toolkit tmp dir, error: %s", err)
+	}
+
+	return pruned, nil
+}
@@ -0,0 +1,201 @@
+package toolkits
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_bestMatchingGoVersion(t *testing.T) {
+	versions := []string{"1.15.2", "1.16", "1.16.15", "1.17.8", "1.18", "invalid"}
+
+	t.Log("default constraint")
+	{
+		constraints, err := goVersionConstraints("")
+		require.NoError(t, err)
+
+		version, found := bestMatchingGoVersion(versions, constraints)
+		require.True(t, found)
+		require.Equal(t, "1.18", version)
+	}
+
+	t.Log("pessimistic constraint")
+	{
+		constraints, err := goVersionConstraints("~> 1.16.0")
+		require.NoError(t, err)
+
+		version, found := bestMatchingGoVersion(versions, constraints)
+		require.True(t, found)
+		require.Equal(t, "1.16.15", version)
+	}
+
+	t.Log("range constraint")
+	{
+		constraints, err := goVersionConstraints(">= 1.16, < 1.18")
+		require.NoError(t, err)
+
+		version, found := bestMatchingGoVersion(versions, constraints)
+		require.True(t, found)
+		require.Equal(t, "1.17.8", version)
+	}
+
+	t.Log("no match")
+	{
+		constraints, err := goVersionConstraints(">= 1.19")
+		require.NoError(t, err)
+
+		_, found := bestMatchingGoVersion(versions, constraints)
+		require.False(t, found)
+	}
+
+	t.Log("invalid constraint")
+	{
+		_, err := goVersionConstraints("latest")
+		require.Error(t, err)
+	}
+}
+
+func Test_goArchiveSHA256FromReleases(t *testing.T) {
+	releases, err := parseGoReleases([]byte(`[
+  {"version": "go1.18", "stable": true, "files": [
+    {"filename": "go1.18.src.tar.gz", "sha256": "38f423db4cc834883f2b52344282fa7a39fbb93650dc62a11fdf0be6409bdad6"},
+    {"filename": "go1.18.linux-amd64.tar.gz", "sha256": "e85278e98f57cdb150fe8409e6e5df5343ecb13cebf03a5d5ff12bd55a80264f"}
+  ]},
+  {"version": "go1.18rc1", "stable": false, "files": []}
+]`))
+	require.NoError(t, err)
+
+	t.Log("archive of a release")
+	{
+		sum, err := goArchiveSHA256FromReleases(releases, "go1.18.linux-amd64.tar.gz")
+		require.NoError(t, err)
+		require.Equal(t, "e85278e98f57cdb150fe8409e6e5df5343ecb13cebf03a5d5ff12bd55a80264f", sum)
+	}
+
+	t.Log("unknown archive")
+	{
+		_, err := goArchiveSHA256FromReleases(releases, "go1.18.darwin-amd64.tar.gz")
+		require.EqualError(t, err, "no SHA-256 sum found for: go1.18.darwin-amd64.tar.gz")
+	}
+}
+
+func Test_goArchiveSHA256(t *testing.T) {
+	homeDir, err := pathutil.NormalizedOSTempDirPath("go-toolkit-test")
+	require.NoError(t, err)
+
+	origHome := os.Getenv("HOME")
+	require.NoError(t, os.Setenv("HOME", homeDir))
+	defer func() {
+		require.NoError(t, os.Setenv("HOME", origHome))
+	}()
+
+	t.Log("release list of the local directory mirror")
+	{
+		mirrorDir, err := pathutil.NormalizedOSTempDirPath("go-mirror")
+		require.NoError(t, err)
+		require.NoError(t, pathutil.EnsureDirExist(filepath.Join(mirrorDir, "dl")))
+		require.NoError(t, fileutil.WriteStringToFile(filepath.Join(mirrorDir, "dl", "index.json"), `[
+  {"version": "go1.18", "stable": true, "files": [
+    {"filename": "go1.18.linux-amd64.tar.gz", "sha256": "e85278e98f57cdb150fe8409e6e5df5343ecb13cebf03a5d5ff12bd55a80264f"}
+  ]}
+]`))
+		require.NoError(t, configs.EnsureBitriseConfigDirExists())
+		require.NoError(t, fileutil.WriteStringToFile(filepath.Join(configs.GetBitriseHomeDirPath(), "config.json"), `{"download_mirror":"`+mirrorDir+`"}`))
+
+		sum, err := goArchiveSHA256("go1.18.linux-amd64.tar.gz")
+		require.NoError(t, err)
+		require.Equal(t, "e85278e98f57cdb150fe8409e6e5df5343ecb13cebf03a5d5ff12bd55a80264f", sum)
+	}
+}
+
+func TestPruneGoToolkit(t *testing.T) {
+	homeDir, err := pathutil.NormalizedOSTempDirPath("go-toolkit-test")
+	require.NoError(t, err)
+
+	origHome := os.Getenv("HOME")
+	require.NoError(t, os.Setenv("HOME", homeDir))
+	defer func() {
+		require.NoError(t, os.Setenv("HOME", origHome))
+	}()
+
+	writeFile := func(pth string) {
+		require.NoError(t, pathutil.EnsureDirExist(filepath.Dir(pth)))
+		require.NoError(t, fileutil.WriteStringToFile(pth, ""))
+	}
+
+	for _, version := range []string{"1.17.8", "1.15.2", "1.16"} {
+		writeFile(goBinaryInToolkitVersionFullPath(version))
+	}
+	writeFile(goBinaryInToolkitFullPath())
+
+	sIDData := models.StepIDData{SteplibSource: "https://github.com/bitrise-io/bitrise-steplib.git", IDorURI: "go-step", Version: "1.0.0"}
+	require.Equal(t, filepath.Join(goToolkitCacheRootPath(), "https___github.com_bitrise-io_bitrise-steplib.git-go-step-1.0.0-go1.16"), stepBinaryCacheFullPath(sIDData, "1.16"))
+	for _, version := range []string{"1.17.8", "1.16"} {
+		writeFile(stepBinaryCacheFullPath(sIDData, version))
+	}
+
+	versions, err := InstalledGoVersions()
+	require.NoError(t, err)
+	require.Equal(t, []string{"1.15.2", "1.16", "1.17.8"}, versions)
+
+	pruned, err := PruneGoToolkit([]string{"1.17.8"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"1.15.2", "1.16"}, pruned)
+
+	versions, err = InstalledGoVersions()
+	require.NoError(t, err)
+	require.Equal(t, []string{"1.17.8"}, versions)
+
+	exists, err := pathutil.IsPathExists(goBinaryInToolkitFullPath())
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	exists, err = pathutil.IsPathExists(stepBinaryCacheFullPath(sIDData, "1.17.8"))
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	exists, err = pathutil.IsPathExists(stepBinaryCacheFullPath(sIDData, "1.16"))
+	require.NoError(t, err)
+	require.False(t, exists)
+}
+
+func TestPruneGoToolkitKeepsLegacyInstall(t *testing.T) {
+	homeDir, err := pathutil.NormalizedOSTempDirPath("go-toolkit-test")
+	require.NoError(t, err)
+
+	origHome := os.Getenv("HOME")
+	require.NoError(t, os.Setenv("HOME", homeDir))
+	defer func() {
+		require.NoError(t, os.Setenv("HOME", origHome))
+	}()
+
+	require.NoError(t, pathutil.EnsureDirExist(filepath.Dir(goBinaryInToolkitFullPath())))
+	require.NoError(t, fileutil.WriteStringToFile(goBinaryInToolkitFullPath(), "#!/bin/sh\necho 'go version go1.17.8 linux/amd64'\n"))
+	require.NoError(t, os.Chmod(goBinaryInToolkitFullPath(), 0755))
+
+	t.Log("the legacy install is the install of a version to keep")
+	{
+		_, err := PruneGoToolkit([]string{"1.17.8"})
+		require.NoError(t, err)
+
+		exists, err := pathutil.IsPathExists(goBinaryInToolkitFullPath())
+		require.NoError(t, err)
+		require.True(t, exists)
+	}
+
+	t.Log("the legacy install is not kept")
+	{
+		_, err := PruneGoToolkit([]string{"1.18"})
+		require.NoError(t, err)
+
+		exists, err := pathutil.IsPathExists(goBinaryInToolkitFullPath())
+		require.NoError(t, err)
+		require.False(t, exists)
+	}
+}
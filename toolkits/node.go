@@ -0,0 +1,204 @@
+package toolkits
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/bitrise-io/bitrise/utils"
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-utils/versions"
+	"github.com/bitrise-io/gows/gows"
+)
+
+const (
+	minNodeVersionForToolkit = "12.0.0"
+	nodeBinName              = "node"
+)
+
+// NodeToolkit runs the step's Node.js entry file,
+// the step's dependencies (node_modules) are installed once per step version and linked into the step's directory.
+type NodeToolkit struct {
+	// MinVersion is the minimum Node.js version required by the step
+	MinVersion string
+}
+
+// ToolkitName ...
+func (toolkit NodeToolkit) ToolkitName() string {
+	return "node"
+}
+
+func (toolkit NodeToolkit) minVersion() string {
+	if toolkit.MinVersion != "" {
+		return toolkit.MinVersion
+	}
+	return minNodeVersionForToolkit
+}
+
+func parseNodeVersion(versionOut string) (string, error) {
+	// example versionOut: v16.13.0
+	expRes := regexp.MustCompile(`v([0-9.]+)`).FindStringSubmatch(versionOut)
+	if expRes == nil {
+		return "", fmt.Errorf("failed to find version in output: %s", versionOut)
+	}
+	return expRes[1], nil
+}
+
+// Check ...
+func (toolkit NodeToolkit) Check() (bool, ToolkitCheckResult, error) {
+	binPath, err := utils.CheckProgramInstalledPath(nodeBinName)
+	if err != nil {
+		return true, ToolkitCheckResult{}, nil
+	}
+
+	verOut, err := command.RunCommandAndReturnStdout(binPath, "--version")
+	if err != nil {
+		return false, ToolkitCheckResult{}, fmt.Errorf("Failed to check node version, error: %s", err)
+	}
+
+	verStr, err := parseNodeVersion(verOut)
+	if err != nil {
+		return false, ToolkitCheckResult{}, fmt.Errorf("Failed to parse node version, error: %s", err)
+	}
+
+	checkRes := ToolkitCheckResult{
+		Path:    binPath,
+		Version: verStr,
+	}
+
+	isVersionOk, err := versions.IsVersionGreaterOrEqual(verStr, toolkit.minVersion())
+	if err != nil {
+		return false, checkRes, fmt.Errorf("Failed to validate installed node version, error: %s", err)
+	}
+
+	return !isVersionOk, checkRes, nil
+}
+
+// IsToolAvailableInPATH ...
+func (toolkit NodeToolkit) IsToolAvailableInPATH() bool {
+	binPath, err := utils.CheckProgramInstalledPath(nodeBinName)
+	return err == nil && len(binPath) > 0
+}
+
+// Bootstrap only warns, Node.js is not installed by the toolkit.
+func (toolkit NodeToolkit) Bootstrap() error {
+	log.Warnf("Node.js (%s) is not installed, the steps using the node toolkit can not run", nodeBinName)
+	return nil
+}
+
+// Install only warns, Node.js is not installed by the toolkit.
+func (toolkit NodeToolkit) Install() error {
+	log.Warnf("Node.js is not installed by bitrise, install Node.js %s or newer (%s) to run the steps using the node toolkit", toolkit.minVersion(), nodeBinName)
+	return nil
+}
+
+func installNodeModules(cmdRunner commandRunner, stepAbsDirPath, cacheDir string) error {
+	if err := os.RemoveAll(cacheDir); err != nil {
+		return fmt.Errorf("failed to remove previous node_modules (%s), error: %s", cacheDir, err)
+	}
+	if err := pathutil.EnsureDirExist(cacheDir); err != nil {
+		return err
+	}
+
+	isLocked := false
+	for _, fileName := range []string{"package.json", "package-lock.json"} {
+		pth := filepath.Join(stepAbsDirPath, fileName)
+		if exists, err := pathutil.IsPathExists(pth); err != nil {
+			return err
+		} else if !exists {
+			continue
+		}
+
+		if err := command.CopyFile(pth, filepath.Join(cacheDir, fileName)); err != nil {
+			return err
+		}
+		isLocked = isLocked || fileName == "package-lock.json"
+	}
+
+	installCmd := command.New("npm", "install", "--production")
+	if isLocked {
+		installCmd = command.New("npm", "ci", "--production")
+	}
+	if _, err := cmdRunner.runForOutput(installCmd.SetDir(cacheDir)); err != nil {
+		if removeErr := os.RemoveAll(cacheDir); removeErr != nil {
+			log.Warnf("Failed to remove node_modules, error: %s", removeErr)
+		}
+		return fmt.Errorf("failed to install step dependencies, error: %s", err)
+	}
+	return nil
+}
+
+// PrepareForStepRun installs the dependencies declared in the step's package.json,
+// the node_modules of a step version is reused.
+func (toolkit NodeToolkit) PrepareForStepRun(step models.StepModel, sIDData models.StepIDData, stepAbsDirPath string) error {
+	isInstallRequired, checkResult, err := toolkit.Check()
+	if err != nil {
+		return err
+	}
+	if isInstallRequired {
+		return fmt.Errorf("Node.js %s or newer is required, available: %s", toolkit.minVersion(), checkResult.Version)
+	}
+
+	if exists, err := pathutil.IsPathExists(filepath.Join(stepAbsDirPath, "package.json")); err != nil {
+		return err
+	} else if !exists {
+		return nil
+	}
+
+	stepModulesPth := filepath.Join(stepAbsDirPath, "node_modules")
+	if info, err := os.Lstat(stepModulesPth); err == nil && info.Mode()&os.ModeSymlink == 0 {
+		// the step ships its own dependencies
+		return nil
+	}
+
+	cacheDir := stepNodeModulesCacheFullPath(sIDData)
+	cachedModulesPth := filepath.Join(cacheDir, "node_modules")
+
+	isCached := false
+	if sIDData.IsUniqueResourceID() {
+		if exists, err := pathutil.IsPathExists(cachedModulesPth); err != nil {
+			log.Warnf("Failed to check cached node_modules for step, error: %s", err)
+		} else {
+			isCached = exists
+		}
+	}
+
+	if !isCached {
+		if err := installNodeModules(&defaultRunner{}, stepAbsDirPath, cacheDir); err != nil {
+			return err
+		}
+	}
+
+	return gows.CreateOrUpdateSymlink(cachedModulesPth, stepModulesPth)
+}
+
+// StepRunCommandArguments ...
+func (toolkit NodeToolkit) StepRunCommandArguments(step models.StepModel, sIDData models.StepIDData, stepAbsDirPath string) ([]string, error) {
+	stepToolkit := step.GetToolkit()
+	if stepToolkit == nil || stepToolkit.Node == nil {
+		return []string{}, errors.New("No Toolkit.Node information specified in step")
+	}
+
+	entryFile := "index.js"
+	if stepToolkit.Node.EntryFile != "" {
+		entryFile = stepToolkit.Node.EntryFile
+	}
+
+	return []string{nodeBinName, filepath.Join(stepAbsDirPath, entryFile)}, nil
+}
+
+// === Toolkit path utility function ===
+
+func nodeToolkitCacheRootPath() string {
+	return filepath.Join(configs.GetBitriseToolkitsDirPath(), "node", "cache")
+}
+
+func stepNodeModulesCacheFullPath(sIDData models.StepIDData) string {
+	return filepath.Join(nodeToolkitCacheRootPath(), stepBinaryFilename(sIDData))
+}
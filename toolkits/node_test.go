@@ -0,0 +1,52 @@
+package toolkits
+
+import (
+	"testing"
+
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseNodeVersion(t *testing.T) {
+	t.Log("valid version output")
+	{
+		ver, err := parseNodeVersion("v16.13.0\n")
+		require.NoError(t, err)
+		require.Equal(t, "16.13.0", ver)
+	}
+
+	t.Log("invalid version output")
+	{
+		_, err := parseNodeVersion("command not found")
+		require.Error(t, err)
+	}
+}
+
+func TestNodeToolkitStepRunCommandArguments(t *testing.T) {
+	sIDData := models.StepIDData{SteplibSource: "https://github.com/bitrise-io/bitrise-steplib.git", IDorURI: "my-step", Version: "1.0.0"}
+
+	t.Log("default entry file")
+	{
+		step := models.StepModel{StepToolkit: &models.StepToolkitModel{Node: &models.NodeStepToolkitModel{MinVersion: "14.0.0"}}}
+		toolkit := ToolkitForStep(step)
+		require.Equal(t, NodeToolkit{MinVersion: "14.0.0"}, toolkit)
+
+		cmd, err := toolkit.StepRunCommandArguments(step, sIDData, "/tmp/step_src")
+		require.NoError(t, err)
+		require.Equal(t, []string{"node", "/tmp/step_src/index.js"}, cmd)
+	}
+
+	t.Log("declared entry file")
+	{
+		step := models.StepModel{StepToolkit: &models.StepToolkitModel{Node: &models.NodeStepToolkitModel{EntryFile: "dist/main.js"}}}
+		cmd, err := NodeToolkit{}.StepRunCommandArguments(step, sIDData, "/tmp/step_src")
+		require.NoError(t, err)
+		require.Equal(t, []string{"node", "/tmp/step_src/dist/main.js"}, cmd)
+	}
+
+	t.Log("missing toolkit.node")
+	{
+		_, err := NodeToolkit{}.StepRunCommandArguments(models.StepModel{}, sIDData, "/tmp/step_src")
+		require.EqualError(t, err, "No Toolkit.Node information specified in step")
+	}
+}
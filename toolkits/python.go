@@ -0,0 +1,172 @@
+package toolkits
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bitrise-io/bitrise/configs"
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/bitrise-io/bitrise/utils"
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-utils/versions"
+)
+
+const (
+	minPythonVersionForToolkit = "3.6.0"
+	pythonBinName              = "python3"
+)
+
+// PythonToolkit runs the step's python entry file in a virtualenv,
+// created per step version with the step's requirements.txt installed.
+type PythonToolkit struct {
+	// MinVersion is the minimum python version required by the step
+	MinVersion string
+}
+
+// ToolkitName ...
+func (toolkit PythonToolkit) ToolkitName() string {
+	return "python"
+}
+
+func (toolkit PythonToolkit) minVersion() string {
+	if toolkit.MinVersion != "" {
+		return toolkit.MinVersion
+	}
+	return minPythonVersionForToolkit
+}
+
+func parsePythonVersion(versionOut string) (string, error) {
+	// example versionOut: Python 3.8.10
+	expRes := regexp.MustCompile(`Python ([0-9.]+)`).FindStringSubmatch(versionOut)
+	if expRes == nil {
+		return "", fmt.Errorf("failed to find version in output: %s", versionOut)
+	}
+	return expRes[1], nil
+}
+
+// Check ...
+func (toolkit PythonToolkit) Check() (bool, ToolkitCheckResult, error) {
+	binPath, err := utils.CheckProgramInstalledPath(pythonBinName)
+	if err != nil {
+		return true, ToolkitCheckResult{}, nil
+	}
+
+	verOut, err := command.RunCommandAndReturnCombinedStdoutAndStderr(binPath, "--version")
+	if err != nil {
+		return false, ToolkitCheckResult{}, fmt.Errorf("Failed to check python version, error: %s", err)
+	}
+
+	verStr, err := parsePythonVersion(verOut)
+	if err != nil {
+		return false, ToolkitCheckResult{}, fmt.Errorf("Failed to parse python version, error: %s", err)
+	}
+
+	checkRes := ToolkitCheckResult{
+		Path:    binPath,
+		Version: verStr,
+	}
+
+	isVersionOk, err := versions.IsVersionGreaterOrEqual(verStr, toolkit.minVersion())
+	if err != nil {
+		return false, checkRes, fmt.Errorf("Failed to validate installed python version, error: %s", err)
+	}
+
+	return !isVersionOk, checkRes, nil
+}
+
+// IsToolAvailableInPATH ...
+func (toolkit PythonToolkit) IsToolAvailableInPATH() bool {
+	binPath, err := utils.CheckProgramInstalledPath(pythonBinName)
+	return err == nil && len(binPath) > 0
+}
+
+// Bootstrap only warns, python is not installed by the toolkit.
+func (toolkit PythonToolkit) Bootstrap() error {
+	log.Warnf("Python (%s) is not installed, the steps using the python toolkit can not run", pythonBinName)
+	return nil
+}
+
+// Install only warns, python is not installed by the toolkit.
+func (toolkit PythonToolkit) Install() error {
+	log.Warnf("Python is not installed by bitrise, install python %s or newer (%s) to run the steps using the python toolkit", toolkit.minVersion(), pythonBinName)
+	return nil
+}
+
+// PrepareForStepRun creates the step's virtualenv and installs the step's requirements.txt,
+// the virtualenv of a step version is reused.
+func (toolkit PythonToolkit) PrepareForStepRun(step models.StepModel, sIDData models.StepIDData, stepAbsDirPath string) error {
+	isInstallRequired, checkResult, err := toolkit.Check()
+	if err != nil {
+		return err
+	}
+	if isInstallRequired {
+		return fmt.Errorf("python %s or newer is required, available: %s", toolkit.minVersion(), checkResult.Version)
+	}
+
+	virtualenvPth := stepVirtualenvCacheFullPath(sIDData)
+	pythonPth := filepath.Join(virtualenvPth, "bin", "python")
+
+	// try to use cached virtualenv, if possible
+	if sIDData.IsUniqueResourceID() {
+		if exists, err := pathutil.IsPathExists(pythonPth); err != nil {
+			log.Warnf("Failed to check cached virtualenv for step, error: %s", err)
+		} else if exists {
+			return nil
+		}
+	}
+
+	if err := os.RemoveAll(virtualenvPth); err != nil {
+		return fmt.Errorf("failed to remove previous virtualenv (%s), error: %s", virtualenvPth, err)
+	}
+
+	cmdRunner := &defaultRunner{}
+	if _, err := cmdRunner.runForOutput(command.New(checkResult.Path, "-m", "venv", virtualenvPth)); err != nil {
+		return fmt.Errorf("failed to create virtualenv, error: %s", err)
+	}
+
+	requirementsPth := filepath.Join(stepAbsDirPath, "requirements.txt")
+	if exists, err := pathutil.IsPathExists(requirementsPth); err != nil {
+		return err
+	} else if exists {
+		installCmd := command.New(pythonPth, "-m", "pip", "install", "--requirement", requirementsPth).SetDir(stepAbsDirPath)
+		if _, err := cmdRunner.runForOutput(installCmd); err != nil {
+			if removeErr := os.RemoveAll(virtualenvPth); removeErr != nil {
+				log.Warnf("Failed to remove virtualenv, error: %s", removeErr)
+			}
+			return fmt.Errorf("failed to install step requirements, error: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// StepRunCommandArguments ...
+func (toolkit PythonToolkit) StepRunCommandArguments(step models.StepModel, sIDData models.StepIDData, stepAbsDirPath string) ([]string, error) {
+	stepToolkit := step.GetToolkit()
+	if stepToolkit == nil || stepToolkit.Python == nil {
+		return []string{}, errors.New("No Toolkit.Python information specified in step")
+	}
+
+	entryFile := "step.py"
+	if stepToolkit.Python.EntryFile != "" {
+		entryFile = stepToolkit.Python.EntryFile
+	}
+
+	pythonPth := filepath.Join(stepVirtualenvCacheFullPath(sIDData), "bin", "python")
+	return []string{pythonPth, filepath.Join(stepAbsDirPath, entryFile)}, nil
+}
+
+// === Toolkit path utility function ===
+
+func pythonToolkitCacheRootPath() string {
+	return filepath.Join(configs.GetBitriseToolkitsDirPath(), "python", "cache")
+}
+
+func stepVirtualenvCacheFullPath(sIDData models.StepIDData) string {
+	return filepath.Join(pythonToolkitCacheRootPath(), stepBinaryFilename(sIDData))
+}
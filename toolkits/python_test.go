@@ -0,0 +1,53 @@
+package toolkits
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/bitrise/models"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parsePythonVersion(t *testing.T) {
+	t.Log("valid version output")
+	{
+		ver, err := parsePythonVersion("Python 3.8.10\n")
+		require.NoError(t, err)
+		require.Equal(t, "3.8.10", ver)
+	}
+
+	t.Log("invalid version output")
+	{
+		_, err := parsePythonVersion("command not found")
+		require.Error(t, err)
+	}
+}
+
+func TestPythonToolkitStepRunCommandArguments(t *testing.T) {
+	sIDData := models.StepIDData{SteplibSource: "https://github.com/bitrise-io/bitrise-steplib.git", IDorURI: "my-step", Version: "1.0.0"}
+	pythonPth := filepath.Join(stepVirtualenvCacheFullPath(sIDData), "bin", "python")
+
+	t.Log("default entry file")
+	{
+		step := models.StepModel{StepToolkit: &models.StepToolkitModel{Python: &models.PythonStepToolkitModel{}}}
+		require.Equal(t, "python", ToolkitForStep(step).ToolkitName())
+
+		cmd, err := PythonToolkit{}.StepRunCommandArguments(step, sIDData, "/tmp/step_src")
+		require.NoError(t, err)
+		require.Equal(t, []string{pythonPth, "/tmp/step_src/step.py"}, cmd)
+	}
+
+	t.Log("declared entry file")
+	{
+		step := models.StepModel{StepToolkit: &models.StepToolkitModel{Python: &models.PythonStepToolkitModel{EntryFile: "src/main.py"}}}
+		cmd, err := PythonToolkit{}.StepRunCommandArguments(step, sIDData, "/tmp/step_src")
+		require.NoError(t, err)
+		require.Equal(t, []string{pythonPth, "/tmp/step_src/src/main.py"}, cmd)
+	}
+
+	t.Log("missing toolkit.python")
+	{
+		_, err := PythonToolkit{}.StepRunCommandArguments(models.StepModel{}, sIDData, "/tmp/step_src")
+		require.EqualError(t, err, "No Toolkit.Python information specified in step")
+	}
+}
@@ -2,7 +2,6 @@ package toolkits
 
 import (
 	"github.com/bitrise-io/bitrise/models"
-	stepmanModels "github.com/bitrise-io/stepman/models"
 )
 
 // ToolkitCheckResult ...
@@ -52,21 +51,24 @@ type Toolkit interface {
 	// the toolkit should/can be "enforced" here (e.g. during the compilation),
 	// BUT ONLY for this function! E.g. don't call `os.Setenv` or something similar
 	// which would affect other functions, just pass the required envs to the compilation command!
-	PrepareForStepRun(step stepmanModels.StepModel, sIDData models.StepIDData, stepAbsDirPath string) error
+	PrepareForStepRun(step models.StepModel, sIDData models.StepIDData, stepAbsDirPath string) error
 
 	// StepRunCommandArguments ...
-	StepRunCommandArguments(step stepmanModels.StepModel, sIDData models.StepIDData, stepAbsDirPath string) ([]string, error)
+	StepRunCommandArguments(step models.StepModel, sIDData models.StepIDData, stepAbsDirPath string) ([]string, error)
 }
 
 //
 // === Utils ===
 
 // ToolkitForStep ...
-func ToolkitForStep(step stepmanModels.StepModel) Toolkit {
-	if step.Toolkit != nil {
-		stepToolkit := step.Toolkit
+func ToolkitForStep(step models.StepModel) Toolkit {
+	if stepToolkit := step.GetToolkit(); stepToolkit != nil {
 		if stepToolkit.Go != nil {
-			return GoToolkit{}
+			return GoToolkit{VersionConstraint: stepToolkit.Go.Version}
+		} else if stepToolkit.Python != nil {
+			return PythonToolkit{MinVersion: stepToolkit.Python.MinVersion}
+		} else if stepToolkit.Node != nil {
+			return NodeToolkit{MinVersion: stepToolkit.Node.MinVersion}
 		} else if stepToolkit.Bash != nil {
 			return BashToolkit{}
 		}
@@ -76,7 +78,33 @@ func ToolkitForStep(step stepmanModels.StepModel) Toolkit {
 	return BashToolkit{}
 }
 
-// AllSupportedToolkits ...
+// ToolkitForWorkflowStep returns the step's toolkit,
+// wrapped into a ContainerToolkit if the workflow runs in a container.
+func ToolkitForWorkflowStep(step models.StepModel, container *models.ContainerModel, sourceDir string) Toolkit {
+	stepToolkit := ToolkitForStep(step)
+	if container == nil {
+		return stepToolkit
+	}
+
+	return ContainerToolkit{
+		Container:   *container,
+		StepToolkit: stepToolkit,
+		SourceDir:   sourceDir,
+	}
+}
+
+// AllSupportedToolkits returns the toolkits set up by bitrise setup and bootstrapped before the build,
+// ContainerToolkit is configured per workflow, so it is not listed here.
 func AllSupportedToolkits() []Toolkit {
-	return []Toolkit{GoToolkit{}, BashToolkit{}}
+	return []Toolkit{GoToolkit{}, BashToolkit{}, PythonToolkit{}, NodeToolkit{}}
+}
+
+// IsInstalledByToolkit returns false for the toolkits using an interpreter, which is not installed by the toolkit (python, node):
+// their Install and Bootstrap only warn, the interpreter is only required by the steps using the toolkit.
+func IsInstalledByToolkit(toolkit Toolkit) bool {
+	switch toolkit.(type) {
+	case PythonToolkit, NodeToolkit:
+		return false
+	}
+	return true
 }
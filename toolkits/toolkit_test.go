@@ -1 +1,29 @@
 package toolkits
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllSupportedToolkits(t *testing.T) {
+	t.Log("every step toolkit is supported")
+	{
+		names := []string{}
+		for _, toolkit := range AllSupportedToolkits() {
+			names = append(names, toolkit.ToolkitName())
+		}
+		require.Equal(t, []string{"go", "bash", "python", "node"}, names)
+	}
+
+	t.Log("the python and node toolkits do not install their interpreter")
+	{
+		require.True(t, IsInstalledByToolkit(GoToolkit{}))
+		require.True(t, IsInstalledByToolkit(BashToolkit{}))
+		for _, toolkit := range []Toolkit{PythonToolkit{}, NodeToolkit{}} {
+			require.False(t, IsInstalledByToolkit(toolkit))
+			require.NoError(t, toolkit.Install())
+			require.NoError(t, toolkit.Bootstrap())
+		}
+	}
+}
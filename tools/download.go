@@ -0,0 +1,227 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bitrise-io/bitrise/configs"
+)
+
+// pinnedSHA256Sums are SHA-256 sums of core tool downloads (envman, stepman), keyed by the original download URL,
+// a pinned sum takes precedence over the checksum file published with the release.
+// The sum of a release: curl -sL <download url> | shasum -a 256
+var pinnedSHA256Sums = map[string]string{}
+
+// releaseChecksumsFileName is the checksum file published next to the binaries of a core tool release,
+// listing the SHA-256 sum of each binary in the sha256sum format (<sum>  <file name>).
+const releaseChecksumsFileName = "checksums.txt"
+
+// releaseChecksumsTimeout limits fetching the checksum file of a release.
+const releaseChecksumsTimeout = time.Minute
+
+// downloadClient limits the time of connecting and of waiting for the response,
+// but not the transfer itself, as a toolkit download can take minutes.
+var downloadClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   30 * time.Second,
+		ResponseHeaderTimeout: time.Minute,
+	},
+}
+
+// mirroredDownloadLocation returns the location of the download in the mirror:
+// the mirror (a base URL or a local directory) joined with the path of the original download URL,
+// e.g. https://github.com/bitrise-io/envman/releases/download/2.3.0/envman-Linux-x86_64 is mirrored at
+// <mirror>/bitrise-io/envman/releases/download/2.3.0/envman-Linux-x86_64.
+// The query of the download URL is kept by a URL mirror, a directory URL (e.g. https://go.dev/dl/?mode=json)
+// is mirrored by the index.json file of the directory in a local directory mirror.
+func mirroredDownloadLocation(downloadURL, mirror string) (string, error) {
+	if mirror == "" {
+		return downloadURL, nil
+	}
+
+	u, err := url.Parse(downloadURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid download url (%s), error: %s", downloadURL, err)
+	}
+
+	if strings.HasPrefix(mirror, "http://") || strings.HasPrefix(mirror, "https://") {
+		location := strings.TrimSuffix(mirror, "/") + u.EscapedPath()
+		if u.RawQuery != "" {
+			location += "?" + u.RawQuery
+		}
+		return location, nil
+	}
+
+	pth := u.Path
+	if strings.HasSuffix(pth, "/") {
+		pth += "index.json"
+	}
+	return filepath.Join(strings.TrimPrefix(mirror, "file://"), filepath.FromSlash(pth)), nil
+}
+
+func openDownloadLocation(location string) (io.ReadCloser, error) {
+	return openLocationWithClient(location, downloadClient)
+}
+
+func openLocationWithClient(location string, client *http.Client) (io.ReadCloser, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return os.Open(location)
+	}
+
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		if err := resp.Body.Close(); err != nil {
+			log.Warnf("failed to close (%s) body", location)
+		}
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+func downloadFile(downloadURL, targetPath, expectedSHA256, mirror string) error {
+	if expectedSHA256 == "" {
+		return fmt.Errorf("no SHA-256 sum available for (%s), refusing to download it unverified", downloadURL)
+	}
+
+	location, err := mirroredDownloadLocation(downloadURL, mirror)
+	if err != nil {
+		return err
+	}
+
+	outFile, err := os.Create(targetPath)
+	if err != nil {
+		return fmt.Errorf("failed to create (%s), error: %s", targetPath, err)
+	}
+	defer func() {
+		if err := outFile.Close(); err != nil {
+			log.Warnf("Failed to close (%s)", targetPath)
+		}
+	}()
+
+	body, err := openDownloadLocation(location)
+	if err != nil {
+		return fmt.Errorf("failed to download from (%s), error: %s", location, err)
+	}
+	defer func() {
+		if err := body.Close(); err != nil {
+			log.Warnf("failed to close (%s) body", location)
+		}
+	}()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(outFile, hash), body); err != nil {
+		return fmt.Errorf("failed to download from (%s), error: %s", location, err)
+	}
+
+	if sum := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(sum, expectedSHA256) {
+		if err := os.Remove(targetPath); err != nil {
+			log.Warnf("Failed to remove (%s), error: %s", targetPath, err)
+		}
+		return fmt.Errorf("checksum mismatch for (%s): expected SHA-256 sum: %s, got: %s", location, expectedSHA256, sum)
+	}
+
+	return nil
+}
+
+// parseSHA256Sums parses a checksum file in the sha256sum format into the sums by file name.
+func parseSHA256Sums(content string) map[string]string {
+	sums := map[string]string{}
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if sum, err := hex.DecodeString(fields[0]); err != nil || len(sum) != sha256.Size {
+			continue
+		}
+		sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return sums
+}
+
+// downloadSHA256 returns the expected SHA-256 sum of a core tool download: its pinned sum if any,
+// otherwise its sum in the checksum file of its release, read through the mirror if configured.
+func downloadSHA256(downloadURL, mirror string) (string, error) {
+	if sum, ok := pinnedSHA256Sums[downloadURL]; ok {
+		return sum, nil
+	}
+
+	u, err := url.Parse(downloadURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid download url (%s), error: %s", downloadURL, err)
+	}
+	fileName := path.Base(u.Path)
+	checksumsURL := *u
+	checksumsURL.Path = path.Join(path.Dir(u.Path), releaseChecksumsFileName)
+	checksumsURL.RawQuery = ""
+
+	content, err := readURL(checksumsURL.String(), mirror, releaseChecksumsTimeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the SHA-256 sum of (%s), error: %s", downloadURL, err)
+	}
+
+	sum, ok := parseSHA256Sums(string(content))[fileName]
+	if !ok {
+		return "", fmt.Errorf("no SHA-256 sum of (%s) in (%s), refusing to download it unverified", fileName, checksumsURL.String())
+	}
+	return sum, nil
+}
+
+// DownloadSHA256 returns the expected SHA-256 sum of a core tool download:
+// its pinned sum if any, otherwise its sum in the checksum file published with its release.
+func DownloadSHA256(downloadURL string) (string, error) {
+	return downloadSHA256(downloadURL, configs.DownloadMirror())
+}
+
+// DownloadFileWithSHA256 downloads the file, through the download mirror if configured,
+// and rejects it if its SHA-256 sum does not match the expected one.
+func DownloadFileWithSHA256(downloadURL, targetPath, expectedSHA256 string) error {
+	return downloadFile(downloadURL, targetPath, expectedSHA256, configs.DownloadMirror())
+}
+
+func readURL(downloadURL, mirror string, timeout time.Duration) ([]byte, error) {
+	location, err := mirroredDownloadLocation(downloadURL, mirror)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Transport: downloadClient.Transport, Timeout: timeout}
+	body, err := openLocationWithClient(location, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch (%s), error: %s", location, err)
+	}
+	defer func() {
+		if err := body.Close(); err != nil {
+			log.Warnf("failed to close (%s) body", location)
+		}
+	}()
+
+	content, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch (%s), error: %s", location, err)
+	}
+	return content, nil
+}
+
+// ReadURL returns the content of the url, fetched through the download mirror if configured.
+// The timeout limits the whole request, including reading the content.
+func ReadURL(downloadURL string, timeout time.Duration) ([]byte, error) {
+	return readURL(downloadURL, configs.DownloadMirror(), timeout)
+}
@@ -0,0 +1,190 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirroredDownloadLocation(t *testing.T) {
+	downloadURL := "https://github.com/bitrise-io/envman/releases/download/2.3.0/envman-Linux-x86_64"
+
+	t.Log("no mirror")
+	{
+		location, err := mirroredDownloadLocation(downloadURL, "")
+		require.NoError(t, err)
+		require.Equal(t, downloadURL, location)
+	}
+
+	t.Log("url mirror")
+	{
+		location, err := mirroredDownloadLocation(downloadURL, "https://mirror.example.com/bitrise/")
+		require.NoError(t, err)
+		require.Equal(t, "https://mirror.example.com/bitrise/bitrise-io/envman/releases/download/2.3.0/envman-Linux-x86_64", location)
+	}
+
+	t.Log("local directory mirror")
+	{
+		location, err := mirroredDownloadLocation(downloadURL, "file:///opt/mirror")
+		require.NoError(t, err)
+		require.Equal(t, "/opt/mirror/bitrise-io/envman/releases/download/2.3.0/envman-Linux-x86_64", location)
+	}
+
+	t.Log("directory url with query")
+	{
+		location, err := mirroredDownloadLocation("https://go.dev/dl/?mode=json&include=all", "https://mirror.example.com/bitrise")
+		require.NoError(t, err)
+		require.Equal(t, "https://mirror.example.com/bitrise/dl/?mode=json&include=all", location)
+
+		location, err = mirroredDownloadLocation("https://go.dev/dl/?mode=json&include=all", "/opt/mirror")
+		require.NoError(t, err)
+		require.Equal(t, "/opt/mirror/dl/index.json", location)
+	}
+}
+
+func TestReadURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bitrise/dl/":
+			_, err := fmt.Fprintf(w, `[{"mode":"%s"}]`, r.URL.Query().Get("mode"))
+			require.NoError(t, err)
+		case "/bitrise/slow":
+			time.Sleep(500 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Log("url mirror")
+	{
+		content, err := readURL("https://go.dev/dl/?mode=json", server.URL+"/bitrise", time.Minute)
+		require.NoError(t, err)
+		require.Equal(t, `[{"mode":"json"}]`, string(content))
+	}
+
+	t.Log("timeout")
+	{
+		_, err := readURL("https://go.dev/slow", server.URL+"/bitrise", 100*time.Millisecond)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), fmt.Sprintf("failed to fetch (%s/bitrise/slow)", server.URL))
+	}
+}
+
+func TestDownloadFileWithSHA256(t *testing.T) {
+	content := "#!/bin/bash\necho envman\n"
+	sum := sha256.Sum256([]byte(content))
+	contentSHA256 := hex.EncodeToString(sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bitrise/envman":
+			_, err := fmt.Fprint(w, content)
+			require.NoError(t, err)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tmpDir, err := pathutil.NormalizedOSTempDirPath("download-test")
+	require.NoError(t, err)
+	targetPth := filepath.Join(tmpDir, "envman")
+	downloadURL := "https://github.com/bitrise/envman"
+
+	t.Log("url mirror, matching sum")
+	{
+		require.NoError(t, downloadFile(downloadURL, targetPth, contentSHA256, server.URL))
+		downloaded, err := fileutil.ReadStringFromFile(targetPth)
+		require.NoError(t, err)
+		require.Equal(t, content, downloaded)
+	}
+
+	t.Log("url mirror, corrupted download")
+	{
+		err := downloadFile(downloadURL, targetPth, "0000", server.URL)
+		require.EqualError(t, err, fmt.Sprintf("checksum mismatch for (%s/bitrise/envman): expected SHA-256 sum: 0000, got: %s", server.URL, contentSHA256))
+
+		exists, err := pathutil.IsPathExists(targetPth)
+		require.NoError(t, err)
+		require.False(t, exists)
+	}
+
+	t.Log("no sum")
+	{
+		err := downloadFile(downloadURL, targetPth, "", server.URL)
+		require.EqualError(t, err, "no SHA-256 sum available for (https://github.com/bitrise/envman), refusing to download it unverified")
+	}
+
+	t.Log("url mirror, missing file")
+	{
+		err := downloadFile("https://github.com/bitrise/stepman", targetPth, contentSHA256, server.URL)
+		require.EqualError(t, err, fmt.Sprintf("failed to download from (%s/bitrise/stepman), error: unexpected status code: 404", server.URL))
+	}
+
+	t.Log("local directory mirror")
+	{
+		mirrorDir, err := pathutil.NormalizedOSTempDirPath("download-mirror")
+		require.NoError(t, err)
+		require.NoError(t, pathutil.EnsureDirExist(filepath.Join(mirrorDir, "bitrise")))
+		require.NoError(t, fileutil.WriteStringToFile(filepath.Join(mirrorDir, "bitrise", "envman"), content))
+
+		require.NoError(t, downloadFile(downloadURL, targetPth, contentSHA256, mirrorDir))
+		downloaded, err := fileutil.ReadStringFromFile(targetPth)
+		require.NoError(t, err)
+		require.Equal(t, content, downloaded)
+	}
+}
+
+func TestDownloadSHA256(t *testing.T) {
+	downloadURL := "https://github.com/bitrise-io/envman/releases/download/2.3.0/envman-Linux-x86_64"
+	linuxSHA256 := "0c3b8b3b0f0d5b8ac1a2e1a0f1f1c6c1c3b1b6a9e7c0c7bdbb4f3a1d0a5f6e7d"
+
+	mirrorDir, err := pathutil.NormalizedOSTempDirPath("checksums-mirror")
+	require.NoError(t, err)
+	releaseDir := filepath.Join(mirrorDir, "bitrise-io", "envman", "releases", "download", "2.3.0")
+	require.NoError(t, pathutil.EnsureDirExist(releaseDir))
+	require.NoError(t, fileutil.WriteStringToFile(filepath.Join(releaseDir, "checksums.txt"),
+		"0C3B8B3B0F0D5B8AC1A2E1A0F1F1C6C1C3B1B6A9E7C0C7BDBB4F3A1D0A5F6E7D  envman-Linux-x86_64\n"+
+			"not-a-sum  envman-Darwin-x86_64\n"))
+
+	t.Log("sum from the checksum file of the release")
+	{
+		sum, err := downloadSHA256(downloadURL, mirrorDir)
+		require.NoError(t, err)
+		require.Equal(t, linuxSHA256, sum)
+	}
+
+	t.Log("no sum in the checksum file")
+	{
+		_, err := downloadSHA256("https://github.com/bitrise-io/envman/releases/download/2.3.0/envman-Darwin-x86_64", mirrorDir)
+		require.EqualError(t, err, "no SHA-256 sum of (envman-Darwin-x86_64) in (https://github.com/bitrise-io/envman/releases/download/2.3.0/checksums.txt), refusing to download it unverified")
+	}
+
+	t.Log("no checksum file")
+	{
+		_, err := downloadSHA256("https://github.com/bitrise-io/envman/releases/download/2.2.0/envman-Linux-x86_64", mirrorDir)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get the SHA-256 sum of (https://github.com/bitrise-io/envman/releases/download/2.2.0/envman-Linux-x86_64)")
+	}
+
+	t.Log("pinned sum")
+	{
+		pinnedURL := "https://github.com/bitrise-io/envman/releases/download/2.2.0/envman-Linux-x86_64"
+		pinnedSHA256Sums[pinnedURL] = linuxSHA256
+		defer delete(pinnedSHA256Sums, pinnedURL)
+
+		sum, err := downloadSHA256(pinnedURL, mirrorDir)
+		require.NoError(t, err)
+		require.Equal(t, linuxSHA256, sum)
+	}
+}
@@ -32,6 +32,11 @@ func (c *Command) SetTimeout(t time.Duration) {
 	c.timeout = t
 }
 
+// SetEnv sets the command's env list, instead of inheriting the current process's environment.
+func (c *Command) SetEnv(envs []string) {
+	c.cmd.Env = append([]string{}, envs...)
+}
+
 // AppendEnv appends and env to the command's env list.
 func (c *Command) AppendEnv(env string) {
 	if c.cmd.Env != nil {
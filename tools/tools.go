@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -54,39 +53,23 @@ func InstallToolFromGitHub(toolname, githubUser, toolVersion string) error {
 	if err != nil {
 		return fmt.Errorf("Failed to determine ARCH: %s", err)
 	}
-	downloadURL := "https://github.com/" + githubUser + "/" + toolname + "/releases/download/" + toolVersion + "/" + toolname + "-" + unameGOOS + "-" + unameGOARCH
+	return InstallFromURL(toolname, GitHubToolDownloadURL(toolname, githubUser, toolVersion, unameGOOS, unameGOARCH))
+}
 
-	return InstallFromURL(toolname, downloadURL)
+// GitHubToolDownloadURL returns the download URL of the tool's release binary for the platform,
+// e.g. https://github.com/bitrise-io/envman/releases/download/2.3.0/envman-Linux-x86_64
+func GitHubToolDownloadURL(toolname, githubUser, toolVersion, unameGOOS, unameGOARCH string) string {
+	return "https://github.com/" + githubUser + "/" + toolname + "/releases/download/" + toolVersion + "/" + toolname + "-" + unameGOOS + "-" + unameGOARCH
 }
 
-// DownloadFile ...
+// DownloadFile downloads the file, through the download mirror if configured,
+// and verifies it against its pinned SHA-256 sum or its sum in the checksum file of its release.
 func DownloadFile(downloadURL, targetDirPath string) error {
-	outFile, err := os.Create(targetDirPath)
-	defer func() {
-		if err := outFile.Close(); err != nil {
-			log.Warnf("Failed to close (%s)", targetDirPath)
-		}
-	}()
-	if err != nil {
-		return fmt.Errorf("failed to create (%s), error: %s", targetDirPath, err)
-	}
-
-	resp, err := http.Get(downloadURL)
-	if err != nil {
-		return fmt.Errorf("failed to download from (%s), error: %s", downloadURL, err)
-	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			log.Warnf("failed to close (%s) body", downloadURL)
-		}
-	}()
-
-	_, err = io.Copy(outFile, resp.Body)
+	sum, err := DownloadSHA256(downloadURL)
 	if err != nil {
-		return fmt.Errorf("failed to download from (%s), error: %s", downloadURL, err)
+		return err
 	}
-
-	return nil
+	return DownloadFileWithSHA256(downloadURL, targetDirPath, sum)
 }
 
 // InstallFromURL ...
@@ -362,6 +345,30 @@ func EnvmanRun(envstorePth,
 	timeout time.Duration,
 	secrets []envmanModels.EnvironmentItemModel,
 	stdInPayload []byte,
+) (int, error) {
+	return envmanRun(envstorePth, workDirPth, cmdArgs, timeout, secrets, stdInPayload, nil)
+}
+
+// EnvmanRunWithEnvironment runs a command through envman,
+// with the given process environment instead of the current process's one.
+func EnvmanRunWithEnvironment(envstorePth,
+	workDirPth string,
+	cmdArgs []string,
+	timeout time.Duration,
+	secrets []envmanModels.EnvironmentItemModel,
+	stdInPayload []byte,
+	environ []string,
+) (int, error) {
+	return envmanRun(envstorePth, workDirPth, cmdArgs, timeout, secrets, stdInPayload, environ)
+}
+
+func envmanRun(envstorePth,
+	workDirPth string,
+	cmdArgs []string,
+	timeout time.Duration,
+	secrets []envmanModels.EnvironmentItemModel,
+	stdInPayload []byte,
+	environ []string,
 ) (int, error) {
 	logLevel := log.GetLevel().String()
 	args := []string{"--loglevel", logLevel, "--path", envstorePth, "run"}
@@ -388,6 +395,9 @@ func EnvmanRun(envstorePth,
 	cmd := timeoutcmd.New(workDirPth, "envman", args...)
 	cmd.SetStandardIO(inReader, outWriter, errWriter)
 	cmd.SetTimeout(timeout)
+	if environ != nil {
+		cmd.SetEnv(environ)
+	}
 	cmd.AppendEnv("PWD=" + workDirPth)
 
 	err := cmd.Start()
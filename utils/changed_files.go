@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ChangedFilesBaseRef returns the git ref the changed files of a build are compared to:
+// the pull request's target branch, or the previous commit.
+func ChangedFilesBaseRef(prTargetBranch string) string {
+	if prTargetBranch != "" {
+		return "origin/" + prTargetBranch
+	}
+	return "HEAD~1"
+}
+
+// GitChangedFiles returns the files changed between baseRef and headRef (HEAD if empty),
+// relative to the repository root, as listed by git diff.
+// The diff is taken from the merge base of the refs, so the target branch's new commits are not included.
+func GitChangedFiles(dir, baseRef, headRef string) ([]string, error) {
+	if headRef == "" {
+		headRef = "HEAD"
+	}
+
+	cmd := exec.Command("git", "diff", "--name-only", "--end-of-options", baseRef+"..."+headRef)
+	cmd.Dir = dir
+	outBytes, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changed files (%s...%s), output: %s, error: %s", baseRef, headRef, strings.TrimSpace(string(outBytes)), err)
+	}
+
+	files := []string{}
+	for _, line := range strings.Split(string(outBytes), "\n") {
+		if file := strings.TrimSpace(line); file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// globPathRegexp converts a path glob to a regexp:
+// ** matches any number of directories, * and ? match within a path segment.
+func globPathRegexp(pattern string) (*regexp.Regexp, error) {
+	var exp strings.Builder
+	exp.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && strings.HasPrefix(pattern[i:], "**/"):
+			exp.WriteString("(.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(pattern[i:], "**"):
+			exp.WriteString(".*")
+			i++
+		case c == '*':
+			exp.WriteString("[^/]*")
+		case c == '?':
+			exp.WriteString("[^/]")
+		default:
+			exp.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	exp.WriteString("$")
+
+	return regexp.Compile(exp.String())
+}
+
+// MatchPathGlob reports whether the slash separated path matches the glob pattern (e.g. "apps/ios/**", "**/*.swift").
+func MatchPathGlob(pattern, pth string) (bool, error) {
+	exp, err := globPathRegexp(path.Clean(pattern))
+	if err != nil {
+		return false, fmt.Errorf("invalid path glob (%s), error: %s", pattern, err)
+	}
+	return exp.MatchString(path.Clean(pth)), nil
+}
+
+// FilterPathsWithGlobs returns the paths matching any of the include globs (every path without include globs)
+// and none of the exclude globs.
+func FilterPathsWithGlobs(paths, includes, excludes []string) ([]string, error) {
+	matchAny := func(pth string, patterns []string) (bool, error) {
+		for _, pattern := range patterns {
+			if match, err := MatchPathGlob(pattern, pth); err != nil {
+				return false, err
+			} else if match {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	filtered := []string{}
+	for _, pth := range paths {
+		if len(includes) > 0 {
+			if included, err := matchAny(pth, includes); err != nil {
+				return nil, err
+			} else if !included {
+				continue
+			}
+		}
+
+		if excluded, err := matchAny(pth, excludes); err != nil {
+			return nil, err
+		} else if excluded {
+			continue
+		}
+
+		filtered = append(filtered, pth)
+	}
+	return filtered, nil
+}
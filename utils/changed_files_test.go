@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchPathGlob(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		pth     string
+		match   bool
+	}{
+		{"apps/ios/**", "apps/ios/App/AppDelegate.swift", true},
+		{"apps/ios/**", "apps/android/build.gradle", false},
+		{"**/*.swift", "AppDelegate.swift", true},
+		{"**/*.swift", "apps/ios/AppDelegate.swift", true},
+		{"*.md", "docs/README.md", false},
+		{"*.md", "README.md", true},
+		{"apps/*/build.gradle", "apps/android/build.gradle", true},
+		{"apps/*/build.gradle", "apps/android/app/build.gradle", false},
+		{"apps/**/build.gradle", "apps/android/app/build.gradle", true},
+		{"file?.txt", "file1.txt", true},
+		{"bitrise.yml", "bitrise.yml", true},
+	} {
+		match, err := MatchPathGlob(tc.pattern, tc.pth)
+		require.NoError(t, err)
+		require.Equal(t, tc.match, match, "pattern: %s, path: %s", tc.pattern, tc.pth)
+	}
+}
+
+func TestFilterPathsWithGlobs(t *testing.T) {
+	paths := []string{"apps/ios/App.swift", "apps/ios/README.md", "apps/android/App.kt", "README.md"}
+
+	t.Log("include")
+	{
+		filtered, err := FilterPathsWithGlobs(paths, []string{"apps/ios/**"}, nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{"apps/ios/App.swift", "apps/ios/README.md"}, filtered)
+	}
+
+	t.Log("include and exclude")
+	{
+		filtered, err := FilterPathsWithGlobs(paths, []string{"apps/ios/**"}, []string{"**/*.md"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"apps/ios/App.swift"}, filtered)
+	}
+
+	t.Log("exclude only")
+	{
+		filtered, err := FilterPathsWithGlobs(paths, nil, []string{"**/*.md"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"apps/ios/App.swift", "apps/android/App.kt"}, filtered)
+	}
+}
+
+func TestGitChangedFiles(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "changed_files")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tmpDir))
+	}()
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = tmpDir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	writeFile := func(pth string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(tmpDir, pth)), 0777))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(tmpDir, pth), []byte(pth), 0666))
+	}
+
+	git("init", "-q")
+	writeFile("README.md")
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+
+	writeFile("apps/ios/App.swift")
+	writeFile("README.md.orig")
+	git("add", "-A")
+	git("commit", "-q", "-m", "ios")
+
+	files, err := GitChangedFiles(tmpDir, ChangedFilesBaseRef(""), "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"README.md.orig", "apps/ios/App.swift"}, files)
+
+	_, err = GitChangedFiles(tmpDir, ChangedFilesBaseRef("not-existing-branch"), "")
+	require.Error(t, err)
+}